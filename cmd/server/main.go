@@ -56,6 +56,7 @@ func main() {
 	}
 	var limiter rateLimiter = security.NewFixedWindowLimiter()
 	var dedupe duplicateDetector = security.NewDuplicateDetector()
+	var bans security.BanStore = security.NewMemoryBanStore()
 
 	if cfg.RedisAddr != "" {
 		redisClient := redis.NewClient(&redis.Options{
@@ -70,12 +71,18 @@ func main() {
 		if pingErr != nil {
 			log.Printf("Redis 连接失败，回退到内存风控: %v", pingErr)
 		} else {
-			log.Printf("Redis 已连接，启用全局限流与去重")
+			log.Printf("Redis 已连接，启用全局限流、去重与封禁共享")
 			limiter = security.NewRedisFixedWindowLimiter(redisClient, cfg.RedisKeyPrefix)
 			dedupe = security.NewRedisDuplicateDetector(redisClient, cfg.RedisKeyPrefix)
+			bans = security.NewRedisBanStore(redisClient, cfg.RedisKeyPrefix)
 		}
 	}
 
+	reputation, err := security.NewIPReputation(cfg.IPAllowCIDRs, cfg.IPDenyCIDRs, bans)
+	if err != nil {
+		log.Fatalf("IP 名单初始化失败: %v", err)
+	}
+
 	challenges := security.NewChallengeManager(
 		cfg.ChallengeTTL,
 		cfg.TimestampSkew,
@@ -97,6 +104,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("公告存储初始化失败: %v", err)
 	}
+	announcementStore.SetRequiredLanguages(cfg.AnnouncementLocales)
 
 	distributionStore, err := store.NewDistributionStore(cfg.DataDir)
 	if err != nil {
@@ -108,6 +116,23 @@ func main() {
 		log.Fatalf("意见征集存储初始化失败: %v", err)
 	}
 
+	var attestation *security.AttestationVerifier
+	if cfg.AttestationEnabled() {
+		rootsPEM, err := os.ReadFile(cfg.AttestationRootCAFile)
+		if err != nil {
+			log.Fatalf("读取设备证明根证书失败: %v", err)
+		}
+		attestationKeys, err := store.NewAttestationKeyStore(cfg.DataDir)
+		if err != nil {
+			log.Fatalf("设备密钥存储初始化失败: %v", err)
+		}
+		attestation, err = security.NewAttestationVerifier(rootsPEM, attestationKeys)
+		if err != nil {
+			log.Fatalf("设备证明初始化失败: %v", err)
+		}
+		log.Printf("设备证明已启用: 默认模式=%s，已登记密钥 %d 个", cfg.AttestationMode, attestationKeys.Count())
+	}
+
 	var reviewer moderation.Reviewer = moderation.AllowAllReviewer{}
 	if cfg.ModerationEnabled {
 		reviewer = moderation.NewOpenAIReviewer(moderation.OpenAIReviewerConfig{
@@ -132,6 +157,8 @@ func main() {
 		announcementStore,
 		distributionStore,
 		surveyStore,
+		attestation,
+		reputation,
 	)
 
 	log.Printf(
@@ -23,6 +23,7 @@ const (
 type adminClient struct {
 	baseURL string
 	token   string
+	actor   string
 	http    *http.Client
 }
 
@@ -39,6 +40,12 @@ func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) (bool, error)
 		return true, runDistribution(args[1:], stdout, stderr)
 	case "survey", "surveys":
 		return true, runSurvey(args[1:], stdin, stdout, stderr)
+	case "ip-ban", "ip-bans":
+		return true, runIPBan(args[1:], stdout, stderr)
+	case "export":
+		return true, runExport(args[1:], stdout, stderr)
+	case "import":
+		return true, runImport(args[1:], stdin, stdout, stderr)
 	case "help", "--help", "-h":
 		writeRootHelp(stdout)
 		return true, nil
@@ -63,6 +70,10 @@ func runAnnouncement(args []string, stdin io.Reader, stdout, stderr io.Writer) e
 		err = runAnnouncementUpdate(args[1:], stdin, stdout, stderr)
 	case "delete":
 		err = runAnnouncementDelete(args[1:], stdout, stderr)
+	case "history":
+		err = runAnnouncementHistory(args[1:], stdout, stderr)
+	case "restore":
+		err = runAnnouncementRestore(args[1:], stdout, stderr)
 	default:
 		return fmt.Errorf("未知公告命令 %q；使用 announcement --help 查看用法", args[0])
 	}
@@ -95,8 +106,12 @@ func runAnnouncementCreate(
 ) error {
 	flags, adminURL := newCommandFlagSet("announcement write", stderr)
 	file := flags.String("file", "", "公告 JSON 文件路径；使用 - 从标准输入读取")
+	publishAt, expireAt := addAnnouncementScheduleFlags(flags)
 	flags.Usage = func() {
-		fmt.Fprintln(stderr, "用法: els-feedback-proxy announcement create --file <路径|-> [--admin-url URL]")
+		fmt.Fprintln(
+			stderr,
+			"用法: els-feedback-proxy announcement create --file <路径|-> [--publish-at RFC3339] [--expire-at RFC3339] [--admin-url URL]",
+		)
 	}
 	if err := parseCommandFlags(flags, args); err != nil {
 		return err
@@ -109,6 +124,10 @@ func runAnnouncementCreate(
 	if err != nil {
 		return err
 	}
+	body, err = applyAnnouncementSchedule(body, *publishAt, *expireAt)
+	if err != nil {
+		return err
+	}
 	client, err := newAdminClient(*adminURL)
 	if err != nil {
 		return err
@@ -121,10 +140,11 @@ func runAnnouncementUpdate(args []string, stdin io.Reader, stdout, stderr io.Wri
 	flags, adminURL := newCommandFlagSet("announcement update", stderr)
 	key := flags.String("key", "", "要更新的公告 key")
 	file := flags.String("file", "", "公告 JSON 文件路径；使用 - 从标准输入读取")
+	publishAt, expireAt := addAnnouncementScheduleFlags(flags)
 	flags.Usage = func() {
 		fmt.Fprintln(
 			stderr,
-			"用法: els-feedback-proxy announcement update --key KEY --file <路径|-> [--admin-url URL]",
+			"用法: els-feedback-proxy announcement update --key KEY --file <路径|-> [--publish-at RFC3339] [--expire-at RFC3339] [--admin-url URL]",
 		)
 	}
 	if err := parseCommandFlags(flags, args); err != nil {
@@ -141,6 +161,10 @@ func runAnnouncementUpdate(args []string, stdin io.Reader, stdout, stderr io.Wri
 	if err != nil {
 		return err
 	}
+	body, err = applyAnnouncementSchedule(body, *publishAt, *expireAt)
+	if err != nil {
+		return err
+	}
 	client, err := newAdminClient(*adminURL)
 	if err != nil {
 		return err
@@ -181,6 +205,52 @@ func runAnnouncementDelete(args []string, stdout, stderr io.Writer) error {
 	return writeJSON(stdout, map[string]any{"success": true, "key": *key})
 }
 
+func runAnnouncementHistory(args []string, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("announcement history", stderr)
+	key := flags.String("key", "", "只查看指定公告 key 的修订；留空查看全部")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy announcement history [--key KEY] [--admin-url URL]")
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	path := "/v1/admin/announcements/revisions"
+	if trimmed := strings.TrimSpace(*key); trimmed != "" {
+		path += "?key=" + url.QueryEscape(trimmed)
+	}
+	return client.request(http.MethodGet, path, nil, stdout)
+}
+
+func runAnnouncementRestore(args []string, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("announcement restore", stderr)
+	revision := flags.Int64("revision", 0, "要恢复到的修订编号")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy announcement restore --revision N [--admin-url URL]")
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+	if *revision <= 0 {
+		return errors.New("必须提供正整数 --revision")
+	}
+
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	return client.request(
+		http.MethodPost,
+		fmt.Sprintf("/v1/admin/announcements/revisions/%d/restore", *revision),
+		nil,
+		stdout,
+	)
+}
+
 func newCommandFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *string) {
 	flags := flag.NewFlagSet(name, flag.ContinueOnError)
 	flags.SetOutput(stderr)
@@ -215,6 +285,7 @@ func newAdminClient(rawURL string) (*adminClient, error) {
 	return &adminClient{
 		baseURL: strings.TrimRight(parsed.String(), "/"),
 		token:   token,
+		actor:   getEnv("ELS_ADMIN_ACTOR", os.Getenv("USER")),
 		http:    &http.Client{Timeout: 15 * time.Second},
 	}, nil
 }
@@ -237,6 +308,9 @@ func (client *adminClient) request(
 
 func (client *adminClient) perform(request *http.Request, stdout io.Writer) error {
 	request.Header.Set("Authorization", "Bearer "+client.token)
+	if client.actor != "" {
+		request.Header.Set("X-ELS-Admin-Actor", client.actor)
+	}
 	request.Header.Set("Accept", "application/json")
 	response, err := client.http.Do(request)
 	if err != nil {
@@ -269,6 +343,59 @@ func (client *adminClient) perform(request *http.Request, stdout io.Writer) erro
 	return writeJSON(stdout, payload)
 }
 
+func addAnnouncementScheduleFlags(flags *flag.FlagSet) (*string, *string) {
+	publishAt := flags.String("publish-at", "", "定时上线时间（RFC3339）；none 表示清除")
+	expireAt := flags.String("expire-at", "", "定时下线时间（RFC3339）；none 表示清除")
+	return publishAt, expireAt
+}
+
+// applyAnnouncementSchedule 用命令行参数覆盖公告 JSON 中的 publish_at 与 expire_at。
+func applyAnnouncementSchedule(body []byte, publishAt, expireAt string) ([]byte, error) {
+	overrides, err := scheduleOverrides(map[string]string{"publish_at": publishAt, "expire_at": expireAt})
+	if err != nil {
+		return nil, err
+	}
+	return overrideJSONFields(body, "公告", overrides)
+}
+
+// scheduleOverrides 把以 JSON 字段名为键的时间参数转为字段值：空值跳过，none 表示清除，
+// 其余必须是 RFC3339 时间。错误提示中的参数名由字段名把下划线换成连字符得到。
+func scheduleOverrides(values map[string]string) (map[string]json.RawMessage, error) {
+	overrides := make(map[string]json.RawMessage, len(values))
+	for name, value := range values {
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			continue
+		case strings.EqualFold(value, "none"):
+			overrides[name] = json.RawMessage("null")
+		default:
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("--%s 必须是 RFC3339 时间，例如 2026-08-01T02:00:00+08:00", strings.ReplaceAll(name, "_", "-"))
+			}
+			encoded, _ := json.Marshal(parsed.UTC())
+			overrides[name] = encoded
+		}
+	}
+	return overrides, nil
+}
+
+// overrideJSONFields 用 overrides 覆盖 JSON 对象中的同名字段；没有需要覆盖的字段时原样返回。
+func overrideJSONFields(body []byte, subject string, overrides map[string]json.RawMessage) ([]byte, error) {
+	if len(overrides) == 0 {
+		return body, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%s JSON 必须是对象", subject)
+	}
+	for name, value := range overrides {
+		fields[name] = value
+	}
+	return json.Marshal(fields)
+}
+
 func readRequestBody(path string, stdin io.Reader) ([]byte, error) {
 	var reader io.Reader
 	if path == "-" {
@@ -313,6 +440,9 @@ func writeRootHelp(writer io.Writer) {
   els-feedback-proxy announcement <命令>    通过管理 API 操作公告
   els-feedback-proxy survey <命令>          通过管理 API 操作意见征集
   els-feedback-proxy distribution <命令>    通过管理 API 操作官方数据
+  els-feedback-proxy ip-ban <命令>          通过管理 API 查看、添加与解除 IP 封禁
+  els-feedback-proxy export --output 路径    导出公告、意见征集与官方数据归档
+  els-feedback-proxy import --file 路径      导入归档，支持 --policy 与 --dry-run
 
 使用对应命令的 --help 查看详细用法。`)
 }
@@ -322,13 +452,24 @@ func writeAnnouncementHelp(writer io.Writer) {
 
 用法:
   els-feedback-proxy announcement list
-  els-feedback-proxy announcement create --file <路径|->
-  els-feedback-proxy announcement update --key KEY --file <路径|->
+  els-feedback-proxy announcement create --file <路径|-> [--publish-at 时间] [--expire-at 时间]
+  els-feedback-proxy announcement update --key KEY --file <路径|-> [--publish-at 时间] [--expire-at 时间]
   els-feedback-proxy announcement delete --key KEY
+  els-feedback-proxy announcement history [--key KEY]
+  els-feedback-proxy announcement restore --revision N
+
+修订历史:
+  每次创建、更新、删除与恢复都会记录一条修订。restore 把公告回滚到指定修订的内容，
+  公告已被删除时会以原 key 重新创建。
+
+定时发布:
+  --publish-at 与 --expire-at 使用 RFC3339 时间，覆盖 JSON 中的 publish_at 与 expire_at；
+  传入 none 清除对应时间。公告仍需 enabled 为 true 才会在时间窗内公开。
 
 环境变量:
   ANNOUNCEMENT_ADMIN_TOKEN  管理口令（必填）
   ELS_ADMIN_URL             管理 API 地址（默认 http://127.0.0.1:8521）
+  ELS_ADMIN_ACTOR           记入修订历史的操作者名称（默认取 $USER）
 
 每个子命令也可以通过 --admin-url 临时指定管理 API 地址。`)
 }
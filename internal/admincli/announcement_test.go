@@ -2,6 +2,7 @@ package admincli
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -88,6 +89,53 @@ func TestAnnouncementCreateReadsJSONFromStdin(t *testing.T) {
 	}
 }
 
+func TestAnnouncementCreateAppliesScheduleFlags(t *testing.T) {
+	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
+	const input = `{"id":1,"type":"warning","title":"维护","body":"正文","enabled":true,"expire_at":"2026-01-01T00:00:00Z"}`
+
+	received := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+			t.Fatalf("解析创建请求失败: %v", err)
+		}
+		received <- payload
+		response.WriteHeader(http.StatusCreated)
+		_, _ = response.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	_, err := Run(
+		[]string{
+			"announcement", "create",
+			"--file", "-",
+			"--publish-at", "2026-08-01T02:00:00+08:00",
+			"--expire-at", "none",
+			"--admin-url", server.URL,
+		},
+		strings.NewReader(input),
+		io.Discard,
+		io.Discard,
+	)
+	if err != nil {
+		t.Fatalf("创建定时公告失败: %v", err)
+	}
+	payload := <-received
+	if payload["publish_at"] != "2026-07-31T18:00:00Z" || payload["expire_at"] != nil || payload["title"] != "维护" {
+		t.Fatalf("定时参数未正确写入请求: %+v", payload)
+	}
+
+	_, err = Run(
+		[]string{"announcement", "create", "--file", "-", "--publish-at", "明天", "--admin-url", server.URL},
+		strings.NewReader(input),
+		io.Discard,
+		io.Discard,
+	)
+	if err == nil || !strings.Contains(err.Error(), "RFC3339") {
+		t.Fatalf("无效时间应被拒绝，实际: %v", err)
+	}
+}
+
 func TestAnnouncementUpdateAndDeleteUseEscapedKey(t *testing.T) {
 	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
 	const input = `{"id":1,"type":"warning","title":"更新","body":"正文","enabled":true}`
@@ -163,3 +211,42 @@ func TestAnnouncementHelpDoesNotRequireToken(t *testing.T) {
 		t.Fatalf("公告帮助输出不正确: %s", stdout.String())
 	}
 }
+
+func TestAnnouncementRestoreSendsActor(t *testing.T) {
+	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
+	t.Setenv("ELS_ADMIN_ACTOR", "alice")
+
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost || request.URL.Path != "/v1/admin/announcements/revisions/7/restore" {
+			t.Fatalf("恢复公告请求不正确: %s %s", request.Method, request.URL.Path)
+		}
+		if request.Header.Get("X-ELS-Admin-Actor") != "alice" {
+			t.Fatalf("恢复请求缺少操作者: %q", request.Header.Get("X-ELS-Admin-Actor"))
+		}
+		response.Header().Set("Content-Type", "application/json")
+		_, _ = response.Write([]byte(`{"success":true,"record":{"key":"test-key"}}`))
+	}))
+	defer server.Close()
+
+	var stdout bytes.Buffer
+	if _, err := Run(
+		[]string{"announcement", "restore", "--revision", "7", "--admin-url", server.URL},
+		strings.NewReader(""),
+		&stdout,
+		io.Discard,
+	); err != nil {
+		t.Fatalf("恢复公告失败: %v", err)
+	}
+	if !strings.Contains(stdout.String(), `"key": "test-key"`) {
+		t.Fatalf("恢复公告输出不正确: %s", stdout.String())
+	}
+
+	if _, err := Run(
+		[]string{"announcement", "restore", "--admin-url", server.URL},
+		strings.NewReader(""),
+		io.Discard,
+		io.Discard,
+	); err == nil {
+		t.Fatalf("缺少 --revision 时应返回错误")
+	}
+}
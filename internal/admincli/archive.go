@@ -0,0 +1,158 @@
+package admincli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"els-feedback-proxy/internal/store"
+)
+
+// archiveTransferTimeout 覆盖默认的 15 秒超时，归档可能包含较大的官方数据文件。
+const archiveTransferTimeout = 10 * time.Minute
+
+func runExport(args []string, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("export", stderr)
+	output := flags.String("output", "", "归档保存路径；- 表示写到标准输出")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy export --output <路径|-> [--admin-url URL]")
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if strings.TrimSpace(*output) == "" {
+		return errors.New("必须提供 --output")
+	}
+
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	client.http.Timeout = archiveTransferTimeout
+	return client.save("/v1/admin/archive", *output, stdout)
+}
+
+// save 把下载内容保存到 output；output 为 - 时直接写到标准输出。
+// 文件先写入同目录临时文件，下载失败时不会留下不完整的结果。
+func (client *adminClient) save(requestPath, output string, stdout io.Writer) error {
+	if output == "-" {
+		return client.download(requestPath, stdout)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(output), ".els-admin-download-*")
+	if err != nil {
+		return fmt.Errorf("创建输出文件失败: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if err := client.download(requestPath, temp); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("写入输出文件失败: %w", err)
+	}
+	if err := os.Rename(temp.Name(), output); err != nil {
+		return fmt.Errorf("保存输出文件失败: %w", err)
+	}
+	return writeJSON(stdout, map[string]any{"success": true, "output": output})
+}
+
+func runImport(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("import", stderr)
+	filePath := flags.String("file", "", "要导入的归档路径；- 表示从标准输入读取")
+	policy := flags.String("policy", store.ImportPolicySkip, "key 冲突时的处理方式：skip、overwrite 或 rename")
+	dryRun := flags.Bool("dry-run", false, "只输出导入计划，不写入任何数据")
+	flags.Usage = func() {
+		fmt.Fprintln(
+			stderr,
+			"用法: els-feedback-proxy import --file <路径|-> [--policy skip|overwrite|rename] [--dry-run]",
+		)
+		fmt.Fprintln(
+			stderr,
+			"导入按官方数据、公告、意见征集的顺序分别写入；后面的存储写入失败时已写入的部分不会回滚，"+
+				"服务端返回 archive_import_partial 并列出已生效的变更；rename 下重新导入会再次另存，请先用 --dry-run 核对。",
+		)
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if strings.TrimSpace(*filePath) == "" {
+		return errors.New("必须提供 --file")
+	}
+	normalizedPolicy, err := store.ValidateImportPolicy(*policy)
+	if err != nil {
+		return err
+	}
+
+	body := stdin
+	if *filePath != "-" {
+		file, err := os.Open(*filePath)
+		if err != nil {
+			return fmt.Errorf("打开归档文件失败: %w", err)
+		}
+		defer file.Close()
+		body = file
+	}
+
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	client.http.Timeout = archiveTransferTimeout
+	query := url.Values{}
+	query.Set("policy", normalizedPolicy)
+	query.Set("dry_run", strconv.FormatBool(*dryRun))
+	request, err := http.NewRequest(
+		http.MethodPost,
+		client.baseURL+"/v1/admin/archive/import?"+query.Encode(),
+		body,
+	)
+	if err != nil {
+		return fmt.Errorf("创建导入请求失败: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/gzip")
+	return client.perform(request, stdout)
+}
+
+// download 把管理 API 的原始响应写入 writer，用于 JSON 以外的下载。
+func (client *adminClient) download(requestPath string, writer io.Writer) error {
+	request, err := http.NewRequest(http.MethodGet, client.baseURL+requestPath, nil)
+	if err != nil {
+		return fmt.Errorf("创建管理请求失败: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+client.token)
+	if client.actor != "" {
+		request.Header.Set("X-ELS-Admin-Actor", client.actor)
+	}
+	response, err := client.http.Do(request)
+	if err != nil {
+		return fmt.Errorf("连接管理 API 失败: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, maxCLIResponseBody))
+		message := strings.TrimSpace(string(responseBody))
+		if message == "" {
+			message = response.Status
+		}
+		return fmt.Errorf("管理 API 返回 %d: %s", response.StatusCode, message)
+	}
+	if _, err := io.Copy(writer, response.Body); err != nil {
+		return fmt.Errorf("下载失败: %w", err)
+	}
+	return nil
+}
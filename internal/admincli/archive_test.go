@@ -0,0 +1,76 @@
+package admincli
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportAndImportTransferRawArchive(t *testing.T) {
+	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
+
+	const archiveData = "archive-bytes"
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer test-admin-token" {
+			t.Fatalf("缺少管理口令")
+		}
+		switch request.Method + " " + request.URL.Path {
+		case "GET /v1/admin/archive":
+			response.Header().Set("Content-Type", "application/gzip")
+			_, _ = response.Write([]byte(archiveData))
+		case "POST /v1/admin/archive/import":
+			body, err := io.ReadAll(request.Body)
+			if err != nil || string(body) != archiveData || request.Header.Get("Content-Type") != "application/gzip" {
+				t.Fatalf("导入请求正文不正确: %q err=%v", body, err)
+			}
+			if request.URL.RawQuery != "dry_run=true&policy=rename" {
+				t.Fatalf("导入参数不正确: %s", request.URL.RawQuery)
+			}
+			response.Header().Set("Content-Type", "application/json")
+			_, _ = response.Write([]byte(`{"success":true,"dry_run":true,"changes":[]}`))
+		default:
+			t.Fatalf("意外的请求: %s %s", request.Method, request.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if _, err := Run(
+		[]string{"export", "--output", output, "--admin-url", server.URL},
+		strings.NewReader(""),
+		io.Discard,
+		io.Discard,
+	); err != nil {
+		t.Fatalf("导出归档失败: %v", err)
+	}
+	if data, err := os.ReadFile(output); err != nil || string(data) != archiveData {
+		t.Fatalf("导出文件内容不正确: %q err=%v", data, err)
+	}
+
+	var importOutput bytes.Buffer
+	if _, err := Run(
+		[]string{"import", "--file", output, "--policy", "rename", "--dry-run", "--admin-url", server.URL},
+		strings.NewReader(""),
+		&importOutput,
+		io.Discard,
+	); err != nil {
+		t.Fatalf("导入归档失败: %v", err)
+	}
+	if !strings.Contains(importOutput.String(), `"dry_run": true`) {
+		t.Fatalf("导入输出不正确: %s", importOutput.String())
+	}
+
+	if _, err := Run(
+		[]string{"import", "--file", output, "--policy", "merge", "--admin-url", server.URL},
+		strings.NewReader(""),
+		io.Discard,
+		io.Discard,
+	); err == nil {
+		t.Fatalf("未知冲突策略应被拒绝")
+	}
+}
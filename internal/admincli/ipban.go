@@ -0,0 +1,125 @@
+package admincli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func runIPBan(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
+		writeIPBanHelp(stdout)
+		return nil
+	}
+
+	var err error
+	switch args[0] {
+	case "list":
+		err = runIPBanList(args[1:], stdout, stderr)
+	case "add":
+		err = runIPBanAdd(args[1:], stdout, stderr)
+	case "lift":
+		err = runIPBanLift(args[1:], stdout, stderr)
+	default:
+		return fmt.Errorf("未知封禁命令 %q；使用 ip-ban --help 查看用法", args[0])
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func runIPBanList(args []string, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("ip-ban list", stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy ip-ban list [--admin-url URL]")
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	return client.request(http.MethodGet, "/v1/admin/ip-bans", nil, stdout)
+}
+
+func runIPBanAdd(args []string, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("ip-ban add", stderr)
+	ip := flags.String("ip", "", "要封禁的 IP")
+	reason := flags.String("reason", "", "封禁原因")
+	duration := flags.Duration("duration", 0, "封禁时长，例如 30m、12h；为 0 时按违规阶梯升级")
+	flags.Usage = func() {
+		fmt.Fprintln(
+			stderr,
+			"用法: els-feedback-proxy ip-ban add --ip IP [--reason 原因] [--duration 时长] [--admin-url URL]",
+		)
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*ip) == "" {
+		return errors.New("必须提供 --ip")
+	}
+	if *duration < 0 {
+		return errors.New("--duration 不能为负数")
+	}
+	body, err := json.Marshal(map[string]any{
+		"ip":               strings.TrimSpace(*ip),
+		"reason":           *reason,
+		"duration_seconds": int64(*duration / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("编码封禁请求失败: %w", err)
+	}
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	return client.request(http.MethodPost, "/v1/admin/ip-bans", body, stdout)
+}
+
+func runIPBanLift(args []string, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("ip-ban lift", stderr)
+	ip := flags.String("ip", "", "要解除封禁的 IP")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy ip-ban lift --ip IP [--admin-url URL]")
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*ip) == "" {
+		return errors.New("必须提供 --ip")
+	}
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	if err := client.request(
+		http.MethodDelete,
+		"/v1/admin/ip-bans/"+url.PathEscape(strings.TrimSpace(*ip)),
+		nil,
+		io.Discard,
+	); err != nil {
+		return err
+	}
+	return writeJSON(stdout, map[string]any{"success": true, "ip": strings.TrimSpace(*ip)})
+}
+
+func writeIPBanHelp(writer io.Writer) {
+	fmt.Fprintln(writer, `IP 封禁管理命令
+
+用法:
+  els-feedback-proxy ip-ban list
+  els-feedback-proxy ip-ban add --ip IP [--reason 原因] [--duration 时长]
+  els-feedback-proxy ip-ban lift --ip IP
+
+--duration 省略或为 0 时按 10m → 1h → 24h 的违规阶梯升级。
+环境变量与 --admin-url 用法和 announcement 命令相同。`)
+}
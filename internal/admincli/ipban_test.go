@@ -0,0 +1,69 @@
+package admincli
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIPBanAddAndLiftUseAdminAPI(t *testing.T) {
+	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
+
+	requests := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		requests <- request.Method + " " + request.URL.EscapedPath()
+		if request.Method == http.MethodDelete {
+			response.WriteHeader(http.StatusNoContent)
+			return
+		}
+		body, err := io.ReadAll(request.Body)
+		if err != nil || string(body) != `{"duration_seconds":5400,"ip":"203.0.113.9","reason":"刷接口"}` {
+			t.Fatalf("封禁请求正文不正确: %s err=%v", body, err)
+		}
+		response.Header().Set("Content-Type", "application/json")
+		response.WriteHeader(http.StatusCreated)
+		_, _ = response.Write([]byte(`{"success":true,"record":{"ip":"203.0.113.9"}}`))
+	}))
+	defer server.Close()
+
+	var addOutput bytes.Buffer
+	_, err := Run(
+		[]string{
+			"ip-ban", "add",
+			"--ip", "203.0.113.9",
+			"--reason", "刷接口",
+			"--duration", "90m",
+			"--admin-url", server.URL,
+		},
+		strings.NewReader(""),
+		&addOutput,
+		io.Discard,
+	)
+	if err != nil {
+		t.Fatalf("添加封禁失败: %v", err)
+	}
+
+	var liftOutput bytes.Buffer
+	_, err = Run(
+		[]string{"ip-ban", "lift", "--ip", "2001:db8::1", "--admin-url", server.URL},
+		strings.NewReader(""),
+		&liftOutput,
+		io.Discard,
+	)
+	if err != nil {
+		t.Fatalf("解除封禁失败: %v", err)
+	}
+
+	if addRequest := <-requests; addRequest != "POST /v1/admin/ip-bans" {
+		t.Fatalf("添加封禁路径不正确: %s", addRequest)
+	}
+	if liftRequest := <-requests; liftRequest != "DELETE /v1/admin/ip-bans/2001:db8::1" {
+		t.Fatalf("解除封禁路径不正确: %s", liftRequest)
+	}
+	if !strings.Contains(liftOutput.String(), `"ip": "2001:db8::1"`) {
+		t.Fatalf("解除封禁输出不正确: %s", liftOutput.String())
+	}
+}
@@ -1,12 +1,14 @@
 package admincli
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -26,8 +28,12 @@ func runSurvey(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 		err = runSurveyUpdate(args[1:], stdin, stdout, stderr)
 	case "delete":
 		err = runSurveyDelete(args[1:], stdout, stderr)
+	case "merge":
+		err = runSurveyMerge(args[1:], stdout, stderr)
 	case "results":
 		err = runSurveyResults(args[1:], stdout, stderr)
+	case "export":
+		err = runSurveyExport(args[1:], stdout, stderr)
 	default:
 		return fmt.Errorf("未知意见征集命令 %q；使用 survey --help 查看用法", args[0])
 	}
@@ -55,8 +61,9 @@ func runSurveyList(args []string, stdout, stderr io.Writer) error {
 func runSurveyCreate(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	flags, adminURL := newCommandFlagSet("survey create", stderr)
 	file := flags.String("file", "", "意见征集 JSON 文件路径；使用 - 从标准输入读取")
+	schedule := addSurveyScheduleFlags(flags)
 	flags.Usage = func() {
-		fmt.Fprintln(stderr, "用法: els-feedback-proxy survey create --file <路径|-> [--admin-url URL]")
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy survey create --file <路径|-> [--opens-at RFC3339] [--closes-at RFC3339] [--max-responses N] [--admin-url URL]")
 	}
 	if err := parseCommandFlags(flags, args); err != nil {
 		return err
@@ -68,6 +75,10 @@ func runSurveyCreate(args []string, stdin io.Reader, stdout, stderr io.Writer) e
 	if err != nil {
 		return err
 	}
+	body, err = schedule(body)
+	if err != nil {
+		return err
+	}
 	client, err := newAdminClient(*adminURL)
 	if err != nil {
 		return err
@@ -79,10 +90,11 @@ func runSurveyUpdate(args []string, stdin io.Reader, stdout, stderr io.Writer) e
 	flags, adminURL := newCommandFlagSet("survey update", stderr)
 	key := flags.String("key", "", "要更新的意见征集 key")
 	file := flags.String("file", "", "意见征集 JSON 文件路径；使用 - 从标准输入读取")
+	schedule := addSurveyScheduleFlags(flags)
 	flags.Usage = func() {
 		fmt.Fprintln(
 			stderr,
-			"用法: els-feedback-proxy survey update --key KEY --file <路径|-> [--admin-url URL]",
+			"用法: els-feedback-proxy survey update --key KEY --file <路径|-> [--opens-at RFC3339] [--closes-at RFC3339] [--max-responses N] [--admin-url URL]",
 		)
 	}
 	if err := parseCommandFlags(flags, args); err != nil {
@@ -95,6 +107,10 @@ func runSurveyUpdate(args []string, stdin io.Reader, stdout, stderr io.Writer) e
 	if err != nil {
 		return err
 	}
+	body, err = schedule(body)
+	if err != nil {
+		return err
+	}
 	client, err := newAdminClient(*adminURL)
 	if err != nil {
 		return err
@@ -134,11 +150,40 @@ func runSurveyDelete(args []string, stdout, stderr io.Writer) error {
 	return writeJSON(stdout, map[string]any{"success": true, "key": *key})
 }
 
+func runSurveyMerge(args []string, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("survey merge", stderr)
+	key := flags.String("key", "", "保留的意见征集 key")
+	source := flags.String("source", "", "要并入为翻译的同编号语言版本 key")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy survey merge --key KEY --source KEY [--admin-url URL]")
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*key) == "" || strings.TrimSpace(*source) == "" {
+		return errors.New("必须提供 --key 和 --source")
+	}
+	body, err := json.Marshal(map[string]string{"source_key": strings.TrimSpace(*source)})
+	if err != nil {
+		return fmt.Errorf("编码合并请求失败: %w", err)
+	}
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	return client.request(http.MethodPost, "/v1/admin/surveys/"+url.PathEscape(*key)+"/merge", body, stdout)
+}
+
 func runSurveyResults(args []string, stdout, stderr io.Writer) error {
 	flags, adminURL := newCommandFlagSet("survey results", stderr)
 	key := flags.String("key", "", "要查看结果的意见征集 key")
+	filters := addSurveyFilterFlags(flags)
+	cross := flags.String("cross", "", "交叉表的两道题 ID，格式为 行题目,列题目")
+	byLanguage := flags.Bool("by-language", false, "另外按征集语言分别统计")
+	raw := flags.Bool("raw", false, "输出全部原始答卷而不是服务端统计")
 	flags.Usage = func() {
-		fmt.Fprintln(stderr, "用法: els-feedback-proxy survey results --key KEY [--admin-url URL]")
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy survey results --key KEY [筛选参数] [--cross ROW,COLUMN] [--by-language] [--raw] [--admin-url URL]")
+		flags.PrintDefaults()
 	}
 	if err := parseCommandFlags(flags, args); err != nil {
 		return err
@@ -150,12 +195,104 @@ func runSurveyResults(args []string, stdout, stderr io.Writer) error {
 	if err != nil {
 		return err
 	}
-	return client.request(
-		http.MethodGet,
-		"/v1/admin/surveys/"+url.PathEscape(*key)+"/results",
-		nil,
-		stdout,
-	)
+	path := "/v1/admin/surveys/" + url.PathEscape(*key)
+	if *raw {
+		return client.request(http.MethodGet, path+"/results", nil, stdout)
+	}
+
+	query := filters()
+	if *cross != "" {
+		row, column, found := strings.Cut(*cross, ",")
+		if !found || strings.TrimSpace(row) == "" || strings.TrimSpace(column) == "" {
+			return errors.New("--cross 格式必须是 行题目,列题目")
+		}
+		query.Set("cross_row", strings.TrimSpace(row))
+		query.Set("cross_column", strings.TrimSpace(column))
+	}
+	if *byLanguage {
+		query.Set("breakdown", "language")
+	}
+	return client.request(http.MethodGet, withQuery(path+"/aggregate", query), nil, stdout)
+}
+
+func runSurveyExport(args []string, stdout, stderr io.Writer) error {
+	flags, adminURL := newCommandFlagSet("survey export", stderr)
+	key := flags.String("key", "", "要导出答卷的意见征集 key")
+	format := flags.String("format", "csv", "导出格式：csv、jsonl 或 xlsx")
+	output := flags.String("output", "", "导出文件路径；- 表示写到标准输出")
+	filters := addSurveyFilterFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "用法: els-feedback-proxy survey export --key KEY --output <路径|-> [--format csv|jsonl|xlsx] [筛选参数] [--admin-url URL]")
+		flags.PrintDefaults()
+	}
+	if err := parseCommandFlags(flags, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*key) == "" {
+		return errors.New("必须提供 --key")
+	}
+	if strings.TrimSpace(*output) == "" {
+		return errors.New("必须提供 --output")
+	}
+	client, err := newAdminClient(*adminURL)
+	if err != nil {
+		return err
+	}
+	client.http.Timeout = archiveTransferTimeout
+
+	query := filters()
+	query.Set("format", strings.ToLower(strings.TrimSpace(*format)))
+	return client.save(withQuery("/v1/admin/surveys/"+url.PathEscape(*key)+"/export", query), *output, stdout)
+}
+
+// addSurveyScheduleFlags 注册开始、截止时间与答卷上限参数，返回的函数在解析后用它们覆盖征集 JSON。
+func addSurveyScheduleFlags(flags *flag.FlagSet) func([]byte) ([]byte, error) {
+	opensAt := flags.String("opens-at", "", "开始接受答卷的时间（RFC3339）；none 表示清除")
+	closesAt := flags.String("closes-at", "", "截止时间（RFC3339）；none 表示清除")
+	maxResponses := flags.String("max-responses", "", "答卷数量上限；0 表示不限")
+	return func(body []byte) ([]byte, error) {
+		overrides, err := scheduleOverrides(map[string]string{"opens_at": *opensAt, "closes_at": *closesAt})
+		if err != nil {
+			return nil, err
+		}
+		if raw := strings.TrimSpace(*maxResponses); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil || limit < 0 {
+				return nil, errors.New("--max-responses 必须是非负整数")
+			}
+			overrides["max_responses"] = json.RawMessage(strconv.Itoa(limit))
+		}
+		return overrideJSONFields(body, "意见征集", overrides)
+	}
+}
+
+// addSurveyFilterFlags 注册 results 与 export 共用的答卷筛选参数，返回的函数在解析后生成查询参数。
+func addSurveyFilterFlags(flags *flag.FlagSet) func() url.Values {
+	values := map[string]*string{
+		"platform":       flags.String("platform", "", "只包含该平台的答卷：iOS 或 watchOS"),
+		"language":       flags.String("language", "", "只包含该语言的答卷，zh 可匹配 zh-Hans"),
+		"min_build":      flags.String("min-build", "", "只包含不低于该构建号的答卷"),
+		"max_build":      flags.String("max-build", "", "只包含不高于该构建号的答卷"),
+		"from":           flags.String("from", "", "只包含该时间及之后提交的答卷（RFC3339）"),
+		"to":             flags.String("to", "", "只包含该时间之前提交的答卷（RFC3339）"),
+		"survey_version": flags.String("survey-version", "", "只包含回答该征集版本的答卷"),
+	}
+	return func() url.Values {
+		query := url.Values{}
+		for name, value := range values {
+			if trimmed := strings.TrimSpace(*value); trimmed != "" {
+				query.Set(name, trimmed)
+			}
+		}
+		return query
+	}
+}
+
+func withQuery(path string, query url.Values) string {
+	if encoded := query.Encode(); encoded != "" {
+		return path + "?" + encoded
+	}
+	return path
 }
 
 func writeSurveyHelp(writer io.Writer) {
@@ -163,10 +300,23 @@ func writeSurveyHelp(writer io.Writer) {
 
 用法:
   els-feedback-proxy survey list
-  els-feedback-proxy survey create --file <路径|->
-  els-feedback-proxy survey update --key KEY --file <路径|->
+  els-feedback-proxy survey create --file <路径|-> [定时参数]
+  els-feedback-proxy survey update --key KEY --file <路径|-> [定时参数]
   els-feedback-proxy survey delete --key KEY
-  els-feedback-proxy survey results --key KEY
+  els-feedback-proxy survey merge --key KEY --source KEY
+  els-feedback-proxy survey results --key KEY [筛选参数] [--cross ROW,COLUMN] [--by-language] [--raw]
+  els-feedback-proxy survey export --key KEY --output <路径|-> [--format csv|jsonl|xlsx] [筛选参数]
+
+定时参数: --opens-at T --closes-at T --max-responses N
+筛选参数: --platform P --language L --min-build N --max-build N --from T --to T --survey-version N
+
+定时参数覆盖 JSON 中的 opens_at、closes_at 与 max_responses；时间使用 RFC3339，none 表示清除，
+--max-responses 0 表示不限。到达截止时间或收满答卷后征集自动停止。
+
+results 默认输出服务端统计：各题的作答数、选项占比、评分分布与文字回答；
+--cross 输出两道题的交叉表，--by-language 另外按征集语言分别统计，--raw 输出全部原始答卷。
+merge 把同一编号的旧式单语言征集并入 --key 成为一种翻译：答卷改为属于 --key 并合并统计，--source 随后被删除。
+export 每份答卷一行：多选题与排序题每个选项一列（<题目>:<选项>），自定义回答另占一列（<题目>#other）。
 
 环境变量与 --admin-url 用法和 announcement 命令相同。`)
 }
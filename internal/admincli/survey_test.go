@@ -2,9 +2,12 @@ package admincli
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -13,9 +16,9 @@ func TestSurveyCreateAndResultsUseAdminAPI(t *testing.T) {
 	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
 	const input = `{"id":1,"title":"测试","questions":[{"id":"q","question":"选择","type":"single_select","options":[{"id":"a","label":"A"}]}],"enabled":false}`
 
-	requests := make(chan string, 2)
+	requests := make(chan string, 3)
 	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
-		requests <- request.Method + " " + request.URL.EscapedPath()
+		requests <- request.Method + " " + request.URL.RequestURI()
 		if request.Header.Get("Authorization") != "Bearer test-admin-token" {
 			t.Fatalf("意见征集命令缺少管理鉴权")
 		}
@@ -46,7 +49,10 @@ func TestSurveyCreateAndResultsUseAdminAPI(t *testing.T) {
 
 	var resultsOutput bytes.Buffer
 	_, err = Run(
-		[]string{"survey", "results", "--key", "survey key", "--admin-url", server.URL},
+		[]string{
+			"survey", "results", "--key", "survey key", "--platform", "iOS",
+			"--min-build", "120", "--cross", "q,rating", "--admin-url", server.URL,
+		},
 		strings.NewReader(""),
 		&resultsOutput,
 		io.Discard,
@@ -54,14 +60,146 @@ func TestSurveyCreateAndResultsUseAdminAPI(t *testing.T) {
 	if err != nil {
 		t.Fatalf("查看意见征集结果失败: %v", err)
 	}
+	_, err = Run(
+		[]string{"survey", "results", "--key", "survey key", "--raw", "--admin-url", server.URL},
+		strings.NewReader(""),
+		io.Discard,
+		io.Discard,
+	)
+	if err != nil {
+		t.Fatalf("查看原始答卷失败: %v", err)
+	}
 
 	if createRequest := <-requests; createRequest != "POST /v1/admin/surveys" {
 		t.Fatalf("创建意见征集路径不正确: %s", createRequest)
 	}
-	if resultsRequest := <-requests; resultsRequest != "GET /v1/admin/surveys/survey%20key/results" {
-		t.Fatalf("意见征集结果路径不正确: %s", resultsRequest)
+	if resultsRequest := <-requests; resultsRequest !=
+		"GET /v1/admin/surveys/survey%20key/aggregate?cross_column=rating&cross_row=q&min_build=120&platform=iOS" {
+		t.Fatalf("意见征集统计路径不正确: %s", resultsRequest)
+	}
+	if rawRequest := <-requests; rawRequest != "GET /v1/admin/surveys/survey%20key/results" {
+		t.Fatalf("原始答卷路径不正确: %s", rawRequest)
 	}
 	if !strings.Contains(resultsOutput.String(), `"response_count": 2`) {
 		t.Fatalf("意见征集结果输出不正确: %s", resultsOutput.String())
 	}
 }
+
+func TestSurveyExportWritesFile(t *testing.T) {
+	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
+
+	const exported = "response_key,q\nr1,a\n"
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if got := request.Method + " " + request.URL.RequestURI(); got !=
+			"GET /v1/admin/surveys/survey%20key/export?format=jsonl&language=zh&platform=iOS" {
+			t.Fatalf("导出答卷路径不正确: %s", got)
+		}
+		response.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = response.Write([]byte(exported))
+	}))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "responses.jsonl")
+	if _, err := Run(
+		[]string{
+			"survey", "export", "--key", "survey key", "--format", "JSONL", "--platform", "iOS",
+			"--language", "zh", "--output", output, "--admin-url", server.URL,
+		},
+		strings.NewReader(""),
+		io.Discard,
+		io.Discard,
+	); err != nil {
+		t.Fatalf("导出答卷失败: %v", err)
+	}
+	if data, err := os.ReadFile(output); err != nil || string(data) != exported {
+		t.Fatalf("导出文件内容不正确: %q err=%v", data, err)
+	}
+
+	if _, err := Run(
+		[]string{"survey", "export", "--key", "survey key", "--admin-url", server.URL},
+		strings.NewReader(""),
+		io.Discard,
+		io.Discard,
+	); err == nil || !strings.Contains(err.Error(), "--output") {
+		t.Fatalf("缺少 --output 时应报错: %v", err)
+	}
+}
+
+func TestSurveyUpdateAppliesScheduleFlags(t *testing.T) {
+	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
+	const input = `{"id":1,"title":"测试","questions":[],"enabled":true,"max_responses":10}`
+
+	received := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+			t.Fatalf("解析更新请求失败: %v", err)
+		}
+		received <- payload
+		_, _ = response.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	if _, err := Run(
+		[]string{
+			"survey", "update", "--key", "survey-key", "--file", "-",
+			"--closes-at", "2026-08-01T02:00:00+08:00", "--opens-at", "none", "--max-responses", "500",
+			"--admin-url", server.URL,
+		},
+		strings.NewReader(input),
+		io.Discard,
+		io.Discard,
+	); err != nil {
+		t.Fatalf("更新定时征集失败: %v", err)
+	}
+	payload := <-received
+	if payload["closes_at"] != "2026-07-31T18:00:00Z" || payload["opens_at"] != nil ||
+		payload["max_responses"] != float64(500) || payload["title"] != "测试" {
+		t.Fatalf("定时参数未正确写入请求: %+v", payload)
+	}
+
+	if _, err := Run(
+		[]string{"survey", "update", "--key", "k", "--file", "-", "--max-responses", "-1", "--admin-url", server.URL},
+		strings.NewReader(input),
+		io.Discard,
+		io.Discard,
+	); err == nil || !strings.Contains(err.Error(), "--max-responses") {
+		t.Fatalf("负数答卷上限应被拒绝，实际: %v", err)
+	}
+}
+
+func TestSurveyMergeAndLanguageBreakdownUseAdminAPI(t *testing.T) {
+	t.Setenv("ANNOUNCEMENT_ADMIN_TOKEN", "test-admin-token")
+
+	requests := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		requests <- request.Method + " " + request.URL.RequestURI() + " " + string(body)
+		_, _ = response.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	for _, args := range [][]string{
+		{"survey", "merge", "--key", "survey zh", "--source", "survey-en", "--admin-url", server.URL},
+		{"survey", "results", "--key", "survey zh", "--by-language", "--admin-url", server.URL},
+	} {
+		if _, err := Run(args, strings.NewReader(""), io.Discard, io.Discard); err != nil {
+			t.Fatalf("执行 %v 失败: %v", args[:2], err)
+		}
+	}
+	if merge := <-requests; merge != `POST /v1/admin/surveys/survey%20zh/merge {"source_key":"survey-en"}` {
+		t.Fatalf("合并语言版本请求不正确: %s", merge)
+	}
+	if results := <-requests; results != "GET /v1/admin/surveys/survey%20zh/aggregate?breakdown=language " {
+		t.Fatalf("按语言统计请求不正确: %s", results)
+	}
+
+	if _, err := Run(
+		[]string{"survey", "merge", "--key", "survey zh", "--admin-url", server.URL},
+		strings.NewReader(""),
+		io.Discard,
+		io.Discard,
+	); err == nil || !strings.Contains(err.Error(), "--source") {
+		t.Fatalf("缺少 --source 时应报错: %v", err)
+	}
+}
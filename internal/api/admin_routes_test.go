@@ -28,6 +28,8 @@ func TestSelfUpdateRoutesOnlyRegisterOnAdminListener(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
+		nil,
 	)
 
 	publicResponse := httptest.NewRecorder()
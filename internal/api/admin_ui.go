@@ -9,6 +9,7 @@ import (
 	"html/template"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -22,8 +23,12 @@ const (
 	announcementAdminCookieName = "els_announcement_admin"
 	announcementAdminSessionTTL = 12 * time.Hour
 	maxAdminLoginBody           = 8 << 10
+	adminActorContextKey        = "admin_actor"
+	adminActorHeader            = "X-ELS-Admin-Actor"
 )
 
+var adminActorNamePattern = regexp.MustCompile(`^[A-Za-z0-9._@-]{1,32}$`)
+
 //go:embed web/*
 var announcementAdminWeb embed.FS
 
@@ -55,7 +60,7 @@ type adminPageData struct {
 }
 
 func (s *Server) adminInterfaceEnabled() bool {
-	return (s.announcements != nil || s.distribution != nil || s.surveys != nil) &&
+	return (s.announcements != nil || s.distribution != nil || s.surveys != nil || s.reputation != nil) &&
 		strings.TrimSpace(s.cfg.AnnouncementAdminToken) != "" &&
 		strings.TrimSpace(s.cfg.AdminListenAddr) != ""
 }
@@ -230,10 +235,12 @@ func (s *Server) adminPageData() adminPageData {
 
 func (s *Server) requireAdmin(c *gin.Context) {
 	if s.adminBearerIsValid(c.Request) {
+		c.Set(adminActorContextKey, bearerAdminActor(c.Request))
 		c.Next()
 		return
 	}
-	if !s.adminSessionIsValid(c.Request) {
+	session, ok := s.adminSession(c.Request)
+	if !ok {
 		writeError(c, http.StatusUnauthorized, "管理会话无效或已过期")
 		c.Abort()
 		return
@@ -244,9 +251,30 @@ func (s *Server) requireAdmin(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	c.Set(adminActorContextKey, sessionAdminActor(session))
 	c.Next()
 }
 
+// adminActor 返回 requireAdmin 识别出的操作者，用于修订历史的作者字段。
+func adminActor(c *gin.Context) string {
+	return c.GetString(adminActorContextKey)
+}
+
+// bearerAdminActor 以 cli 标识令牌请求；CLI 可通过 X-ELS-Admin-Actor 附带本机用户名。
+func bearerAdminActor(request *http.Request) string {
+	name := strings.TrimSpace(request.Header.Get(adminActorHeader))
+	if adminActorNamePattern.MatchString(name) {
+		return "cli:" + name
+	}
+	return "cli"
+}
+
+// sessionAdminActor 以会话签名摘要区分不同浏览器会话，不暴露会话本身。
+func sessionAdminActor(session string) string {
+	digest := sha256.Sum256([]byte(session))
+	return "web:" + hex.EncodeToString(digest[:4])
+}
+
 func (s *Server) makeAdminSession(expiresAt time.Time) string {
 	expires := strconv.FormatInt(expiresAt.Unix(), 10)
 	mac := hmac.New(sha256.New, []byte(s.cfg.AnnouncementAdminToken))
@@ -255,21 +283,29 @@ func (s *Server) makeAdminSession(expiresAt time.Time) string {
 }
 
 func (s *Server) adminSessionIsValid(request *http.Request) bool {
+	_, ok := s.adminSession(request)
+	return ok
+}
+
+func (s *Server) adminSession(request *http.Request) (string, bool) {
 	cookie, err := request.Cookie(announcementAdminCookieName)
 	if err != nil {
-		return false
+		return "", false
 	}
 	parts := strings.Split(cookie.Value, ".")
 	if len(parts) != 2 {
-		return false
+		return "", false
 	}
 	expiresUnix, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil || time.Now().UTC().Unix() >= expiresUnix {
-		return false
+		return "", false
 	}
 
 	expected := s.makeAdminSession(time.Unix(expiresUnix, 0).UTC())
-	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(expected)) == 1
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(expected)) != 1 {
+		return "", false
+	}
+	return cookie.Value, true
 }
 
 func (s *Server) adminBearerIsValid(request *http.Request) bool {
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/store"
+)
+
+const maxAnnouncementEventRequestBody = 8 << 10
+
+// registerAnnouncementEventRoutes 注册公告触达上报接口。上报与答卷一样需要 challenge 签名与 PoW，
+// 服务端只按公告、平台与构建号累计次数，IP 仅用于内存中的限流与去重。
+func (s *Server) registerAnnouncementEventRoutes() {
+	if s.announcements == nil || s.challenges == nil {
+		return
+	}
+
+	s.signedPOST("/v1/announcements/events", signedRoute{
+		rateAction:   "announcement-event",
+		rateLimit:    s.cfg.QueryLimitPerWindow,
+		rateMessage:  "公告事件上报过于频繁",
+		attestation:  "announcement",
+		maxBodyBytes: maxAnnouncementEventRequestBody,
+	}, s.handleReportAnnouncementEvents)
+}
+
+func (s *Server) handleReportAnnouncementEvents(c *gin.Context) {
+	clientIP := c.ClientIP()
+	body := signedBody(c)
+
+	var report store.AnnouncementEventReport
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&report); err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "请求体格式无效")
+		return
+	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "请求体只能包含一个 JSON 对象")
+		return
+	}
+
+	if s.dedupe != nil {
+		dedupeKey := hashString(clientIP + "|announcement-events|" + string(body))
+		if s.dedupe.SeenRecently(dedupeKey, s.cfg.DuplicateWindow) {
+			writeCodedError(c, http.StatusConflict, "duplicate_submission", "检测到短时间重复提交")
+			return
+		}
+	}
+
+	recorded, err := s.announcements.RecordEvents(report)
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "recorded": recorded})
+}
+
+// handleAdminAnnouncementTelemetry 返回按平台与构建号聚合的触达统计，可用 id 限定单条公告。
+func (s *Server) handleAdminAnnouncementTelemetry(c *gin.Context) {
+	announcementID := 0
+	if raw := strings.TrimSpace(c.Query("id")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeCodedError(c, http.StatusBadRequest, "query_invalid", "公告 ID 必须是正整数")
+			return
+		}
+		announcementID = parsed
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"records": s.announcements.Telemetry(announcementID),
+	})
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -16,12 +18,26 @@ import (
 
 const maxAnnouncementRequestBody = 64 << 10
 
+// adminAnnouncementRecord 在管理响应中附带尚未覆盖的必需语言。
+type adminAnnouncementRecord struct {
+	store.AnnouncementRecord
+	MissingLanguages []string `json:"missing_languages,omitempty"`
+}
+
+func (s *Server) adminAnnouncementRecord(record store.AnnouncementRecord) adminAnnouncementRecord {
+	return adminAnnouncementRecord{
+		AnnouncementRecord: record,
+		MissingLanguages:   s.announcements.MissingLanguages(record),
+	}
+}
+
 func (s *Server) registerAnnouncementRoutes() {
 	if s.announcements == nil {
 		return
 	}
 
 	s.engine.GET("/v1/announcements", s.handleListAnnouncements)
+	s.registerAnnouncementEventRoutes()
 }
 
 func (s *Server) registerAnnouncementAdminRoutes() {
@@ -31,35 +47,92 @@ func (s *Server) registerAnnouncementAdminRoutes() {
 	adminAPI.POST("", s.handleAdminCreateAnnouncement)
 	adminAPI.PUT("/:key", s.handleAdminUpdateAnnouncement)
 	adminAPI.DELETE("/:key", s.handleAdminDeleteAnnouncement)
+	adminAPI.GET("/revisions", s.handleAdminListAnnouncementRevisions)
+	adminAPI.POST("/revisions/:revision/restore", s.handleAdminRestoreAnnouncementRevision)
+	adminAPI.GET("/telemetry", s.handleAdminAnnouncementTelemetry)
 }
 
 func (s *Server) handleListAnnouncements(c *gin.Context) {
-	payload, err := json.Marshal(s.announcements.PublicList())
+	audience, err := store.ParseAnnouncementAudience(
+		c.Query("platform"),
+		c.Query("build"),
+		c.Query("locale"),
+		c.Query("channel"),
+	)
 	if err != nil {
-		writeError(c, http.StatusInternalServerError, "编码公告失败")
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", err.Error())
 		return
 	}
 
-	etag := payloadETag(payload)
-	cacheMaxAge := s.cfg.AnnouncementCacheMaxAge
-	if cacheMaxAge < 30 {
-		cacheMaxAge = 300
+	since, deltaRequested, ok := parseSyncSince(c)
+	if !ok {
+		return
 	}
 
-	c.Header("Cache-Control", "public, max-age=60, stale-if-error=86400")
-	c.Header(
-		"Cloudflare-CDN-Cache-Control",
-		fmt.Sprintf("public, max-age=%d, stale-while-revalidate=60, stale-if-error=86400", cacheMaxAge),
-	)
-	c.Header("ETag", etag)
-	c.Header("Vary", "Accept-Encoding")
-	c.Header("X-Content-Type-Options", "nosniff")
-	if etagMatches(c.GetHeader("If-None-Match"), etag) {
-		c.Status(http.StatusNotModified)
+	now := time.Now()
+	delta, nextTransition := s.announcements.PublicSyncAt(now, audience, since)
+	s.resolveAnnouncementImages(delta.Records)
+	var payload []byte
+	if deltaRequested {
+		payload, err = json.Marshal(delta)
+	} else {
+		payload, err = json.Marshal(delta.Records)
+	}
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, "编码公告失败")
 		return
 	}
 
-	c.Data(http.StatusOK, "application/json; charset=utf-8", payload)
+	s.writePublicList(c, payload, delta.Revision, now, nextTransition)
+}
+
+// resolveAnnouncementImages 为引用官方数据图片的公告填充下载地址；
+// 图片已停用或删除时去掉 image 字段，避免客户端请求不存在的文件。
+func (s *Server) resolveAnnouncementImages(records []store.PublicAnnouncement) {
+	for index := range records {
+		image := records[index].Image
+		if image == nil {
+			continue
+		}
+		if s.distribution == nil {
+			records[index].Image = nil
+			continue
+		}
+		file, ok := s.distribution.PublicImage(image.SHA256)
+		if !ok {
+			records[index].Image = nil
+			continue
+		}
+		image.URL = distributionFileURL(file)
+	}
+}
+
+// checkAnnouncementImage 确认公告引用的图片已作为官方数据上传并启用。
+func (s *Server) checkAnnouncementImage(c *gin.Context, record store.AnnouncementRecord) bool {
+	if record.Image == nil || strings.TrimSpace(record.Image.SHA256) == "" {
+		return true
+	}
+	if s.distribution == nil {
+		writeCodedError(c, http.StatusBadRequest, "announcement_invalid", "未启用官方数据存储，公告无法引用图片")
+		return false
+	}
+	if _, ok := s.distribution.PublicImage(record.Image.SHA256); !ok {
+		writeCodedError(c, http.StatusBadRequest, "announcement_invalid", "图片必须是已启用的官方数据图片文件")
+		return false
+	}
+	return true
+}
+
+// announcementImageAvailable 返回存储层使用的图片检查，规则与 checkAnnouncementImage 相同；
+// 未启用官方数据存储时返回 nil，表示不能引用任何图片。
+func (s *Server) announcementImageAvailable() func(checksum string) bool {
+	if s.distribution == nil {
+		return nil
+	}
+	return func(checksum string) bool {
+		_, ok := s.distribution.PublicImage(checksum)
+		return ok
+	}
 }
 
 func payloadETag(payload []byte) string {
@@ -71,53 +144,67 @@ func (s *Server) handleAdminListAnnouncements(c *gin.Context) {
 	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"records": s.announcements.List(),
+		"records": s.adminAnnouncementRecords(s.announcements.List()),
 	})
 }
 
+func (s *Server) adminAnnouncementRecords(records []store.AnnouncementRecord) []adminAnnouncementRecord {
+	result := make([]adminAnnouncementRecord, 0, len(records))
+	for _, record := range records {
+		result = append(result, s.adminAnnouncementRecord(record))
+	}
+	return result
+}
+
 func (s *Server) handleAdminCreateAnnouncement(c *gin.Context) {
 	var record store.AnnouncementRecord
 	if err := decodeAnnouncementJSON(c, &record); err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
+		return
+	}
+	if !s.checkAnnouncementImage(c, record) {
 		return
 	}
 
-	created, err := s.announcements.Create(record)
+	created, err := s.announcements.Create(record, adminActor(c))
 	if err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeStoreError(c, err)
 		return
 	}
 
 	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
-		"record":  created,
+		"record":  s.adminAnnouncementRecord(created),
 	})
 }
 
 func (s *Server) handleAdminUpdateAnnouncement(c *gin.Context) {
 	var replacement store.AnnouncementRecord
 	if err := decodeAnnouncementJSON(c, &replacement); err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
+		return
+	}
+	if !s.checkAnnouncementImage(c, replacement) {
 		return
 	}
 
-	updated, err := s.announcements.Update(c.Param("key"), replacement)
+	updated, err := s.announcements.Update(c.Param("key"), replacement, adminActor(c))
 	if err != nil {
-		writeAnnouncementStoreError(c, err)
+		writeStoreError(c, err)
 		return
 	}
 
 	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"record":  updated,
+		"record":  s.adminAnnouncementRecord(updated),
 	})
 }
 
 func (s *Server) handleAdminDeleteAnnouncement(c *gin.Context) {
-	if err := s.announcements.Delete(c.Param("key")); err != nil {
-		writeAnnouncementStoreError(c, err)
+	if err := s.announcements.Delete(c.Param("key"), adminActor(c)); err != nil {
+		writeStoreError(c, err)
 		return
 	}
 
@@ -125,6 +212,36 @@ func (s *Server) handleAdminDeleteAnnouncement(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// handleAdminListAnnouncementRevisions 返回修订历史，可用 key 限定单条公告（含已删除的公告）。
+func (s *Server) handleAdminListAnnouncementRevisions(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"revisions": s.announcements.Revisions(c.Query("key")),
+	})
+}
+
+// handleAdminRestoreAnnouncementRevision 把公告回滚到指定修订；公告已删除时会以原 key 恢复。
+func (s *Server) handleAdminRestoreAnnouncementRevision(c *gin.Context) {
+	revisionID, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+	if err != nil || revisionID <= 0 {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "修订编号必须是正整数")
+		return
+	}
+
+	restored, err := s.announcements.Restore(revisionID, adminActor(c), s.announcementImageAvailable())
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"record":  s.adminAnnouncementRecord(restored),
+	})
+}
+
 func decodeAnnouncementJSON(c *gin.Context, target any) error {
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAnnouncementRequestBody)
 	decoder := json.NewDecoder(c.Request.Body)
@@ -138,14 +255,6 @@ func decodeAnnouncementJSON(c *gin.Context, target any) error {
 	return nil
 }
 
-func writeAnnouncementStoreError(c *gin.Context, err error) {
-	if strings.Contains(err.Error(), "不存在") {
-		writeError(c, http.StatusNotFound, err.Error())
-		return
-	}
-	writeError(c, http.StatusBadRequest, err.Error())
-}
-
 func etagMatches(headerValue, etag string) bool {
 	for _, candidate := range strings.Split(headerValue, ",") {
 		if strings.TrimSpace(candidate) == etag {
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"els-feedback-proxy/internal/config"
+	"els-feedback-proxy/internal/security"
 	"els-feedback-proxy/internal/store"
 )
 
@@ -134,6 +136,332 @@ func TestAnnouncementAdminCRUDPublishesOnlyEnabledRecords(t *testing.T) {
 	}
 }
 
+func TestPublicAnnouncementsCacheStopsAtNextTransition(t *testing.T) {
+	const adminToken = "test-admin-token"
+	server := newAnnouncementTestServer(t, adminToken)
+
+	publishAt := time.Now().Add(2 * time.Minute).UTC().Format(time.RFC3339)
+	createResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		`{"id":1,"type":"warning","title":"维护通知","body":"正文","enabled":true,"publish_at":"`+publishAt+`"}`,
+		adminToken,
+	)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建定时公告期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+
+	response := httptest.NewRecorder()
+	server.engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/v1/announcements", nil))
+	if response.Body.String() != "[]" {
+		t.Fatalf("未到上线时间的公告不应公开: %s", response.Body.String())
+	}
+	cdnPolicy := response.Header().Get("Cloudflare-CDN-Cache-Control")
+	var maxAge int
+	if _, err := fmt.Sscanf(cdnPolicy, "public, max-age=%d", &maxAge); err != nil ||
+		maxAge < 1 || maxAge > 120 || strings.Contains(cdnPolicy, "stale-while-revalidate") {
+		t.Fatalf("CDN 缓存应在定时上线前失效: %s", cdnPolicy)
+	}
+	if strings.Contains(cdnPolicy, "stale-if-error") {
+		t.Fatalf("即将定时上线时 CDN 不应在出错时继续提供过期列表: %s", cdnPolicy)
+	}
+	var browserMaxAge, browserStale int
+	browserPolicy := response.Header().Get("Cache-Control")
+	if _, err := fmt.Sscanf(browserPolicy, "public, max-age=%d, stale-if-error=%d", &browserMaxAge, &browserStale); err == nil &&
+		browserMaxAge+browserStale > 120 {
+		t.Fatalf("浏览器缓存连同出错时的过期内容不能越过定时上线时间: %s", browserPolicy)
+	}
+}
+
+func TestPublicAnnouncementsFilterByQuery(t *testing.T) {
+	const adminToken = "test-admin-token"
+	server := newAnnouncementTestServer(t, adminToken)
+	for _, body := range []string{
+		`{"id":1,"type":"info","language":"en","title":"Maintenance","body":"正文","enabled":true}`,
+		`{"id":1,"type":"info","language":"zh-Hans","title":"维护","body":"正文","enabled":true}`,
+		`{"id":2,"type":"info","platform":"watchOS","title":"手表","body":"正文","enabled":true}`,
+	} {
+		if response := performAdminRequest(server, http.MethodPost, "/v1/admin/announcements", body, adminToken); response.Code != http.StatusCreated {
+			t.Fatalf("创建公告失败: %d %s", response.Code, response.Body.String())
+		}
+	}
+
+	response := httptest.NewRecorder()
+	server.engine.ServeHTTP(
+		response,
+		httptest.NewRequest(http.MethodGet, "/v1/announcements?platform=iOS&locale=zh-Hans-CN&build=120", nil),
+	)
+	var records []store.PublicAnnouncement
+	if err := json.Unmarshal(response.Body.Bytes(), &records); err != nil {
+		t.Fatalf("解析公告失败: %v", err)
+	}
+	if response.Code != http.StatusOK || len(records) != 1 || records[0].Title != "维护" {
+		t.Fatalf("服务端筛选结果不正确: %d %s", response.Code, response.Body.String())
+	}
+
+	invalidResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(invalidResponse, httptest.NewRequest(http.MethodGet, "/v1/announcements?build=abc", nil))
+	assertErrorCode(t, invalidResponse, http.StatusBadRequest, "query_invalid")
+}
+
+func TestAnnouncementAdminRevisionsRestoreDeletedRecord(t *testing.T) {
+	const adminToken = "revision-admin-token"
+	server := newAnnouncementTestServer(t, adminToken)
+
+	createRequest := httptest.NewRequest(
+		http.MethodPost,
+		"/v1/admin/announcements",
+		strings.NewReader(`{"id":1,"type":"info","title":"标题","body":"正文","enabled":true}`),
+	)
+	createRequest.Header.Set("Authorization", "Bearer "+adminToken)
+	createRequest.Header.Set("Content-Type", "application/json")
+	createRequest.Header.Set("X-ELS-Admin-Actor", "alice")
+	createResponse := httptest.NewRecorder()
+	server.adminEngine.ServeHTTP(createResponse, createRequest)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建公告期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+	var created struct {
+		Record store.AnnouncementRecord `json:"record"`
+	}
+	if err := json.Unmarshal(createResponse.Body.Bytes(), &created); err != nil {
+		t.Fatalf("解析创建响应失败: %v", err)
+	}
+
+	deleteResponse := performAdminRequest(
+		server,
+		http.MethodDelete,
+		"/v1/admin/announcements/"+created.Record.Key,
+		"",
+		adminToken,
+	)
+	if deleteResponse.Code != http.StatusNoContent {
+		t.Fatalf("删除公告期望 204，实际 %d", deleteResponse.Code)
+	}
+
+	historyResponse := performAdminRequest(
+		server,
+		http.MethodGet,
+		"/v1/admin/announcements/revisions?key="+created.Record.Key,
+		"",
+		adminToken,
+	)
+	var history struct {
+		Revisions []store.AnnouncementRevision `json:"revisions"`
+	}
+	if err := json.Unmarshal(historyResponse.Body.Bytes(), &history); err != nil {
+		t.Fatalf("解析修订历史失败: %v", err)
+	}
+	if historyResponse.Code != http.StatusOK || len(history.Revisions) != 2 ||
+		history.Revisions[0].Action != store.AnnouncementActionDelete || history.Revisions[0].Author != "cli" ||
+		history.Revisions[1].Author != "cli:alice" {
+		t.Fatalf("修订历史不正确: code=%d body=%s", historyResponse.Code, historyResponse.Body.String())
+	}
+
+	restoreResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		fmt.Sprintf("/v1/admin/announcements/revisions/%d/restore", history.Revisions[0].ID),
+		"",
+		adminToken,
+	)
+	if restoreResponse.Code != http.StatusOK || len(server.announcements.List()) != 1 {
+		t.Fatalf("撤销删除失败: code=%d body=%s", restoreResponse.Code, restoreResponse.Body.String())
+	}
+
+	missingResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/announcements/revisions/999/restore",
+		"",
+		adminToken,
+	)
+	assertErrorCode(t, missingResponse, http.StatusNotFound, "announcement_revision_not_found")
+}
+
+func TestAnnouncementAdminRestoreChecksImage(t *testing.T) {
+	const adminToken = "revision-admin-token"
+	server := newAnnouncementTestServer(t, adminToken)
+	distributionStore, err := store.NewDistributionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化官方数据存储失败: %v", err)
+	}
+	server.distribution = distributionStore
+	file, err := distributionStore.Create(
+		store.DistributionInput{Name: "截图", DestinationPath: "/Documents/Images", Enabled: true},
+		store.DistributionUpload{FileName: "update.png", ContentType: "image/png", Data: []byte("png")},
+	)
+	if err != nil {
+		t.Fatalf("上传图片失败: %v", err)
+	}
+
+	createResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		fmt.Sprintf(`{"id":1,"type":"info","title":"标题","body":"正文","image":{"sha256":"%s"},"enabled":true}`, file.SHA256),
+		adminToken,
+	)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建公告期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+	var created struct {
+		Record store.AnnouncementRecord `json:"record"`
+	}
+	if err := json.Unmarshal(createResponse.Body.Bytes(), &created); err != nil {
+		t.Fatalf("解析创建响应失败: %v", err)
+	}
+	deleteResponse := performAdminRequest(
+		server,
+		http.MethodDelete,
+		"/v1/admin/announcements/"+created.Record.Key,
+		"",
+		adminToken,
+	)
+	if deleteResponse.Code != http.StatusNoContent {
+		t.Fatalf("删除公告期望 204，实际 %d", deleteResponse.Code)
+	}
+	if err := distributionStore.Delete(file.Key); err != nil {
+		t.Fatalf("删除图片失败: %v", err)
+	}
+
+	revisions := server.announcements.Revisions(created.Record.Key)
+	restoreResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		fmt.Sprintf("/v1/admin/announcements/revisions/%d/restore", revisions[0].ID),
+		"",
+		adminToken,
+	)
+	assertErrorCode(t, restoreResponse, http.StatusBadRequest, "announcement_invalid")
+	if records := server.announcements.List(); len(records) != 0 {
+		t.Fatalf("图片已删除时不应恢复公告: %+v", records)
+	}
+}
+
+func TestAnnouncementAdminReportsMissingTranslations(t *testing.T) {
+	const adminToken = "localization-admin-token"
+	server := newAnnouncementTestServer(t, adminToken)
+	server.announcements.SetRequiredLanguages([]string{"zh-Hans", "en"})
+
+	const bundle = `{
+		"id": 9,
+		"type": "info",
+		"language": "zh-Hans",
+		"title": "新功能",
+		"body": "支持多语言公告。",
+		"localizations": [],
+		"enabled": %t
+	}`
+	publishResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		fmt.Sprintf(bundle, true),
+		adminToken,
+	)
+	if publishResponse.Code != http.StatusCreated {
+		t.Fatalf("单语言公告不受必需语言拦截，期望 201，实际 %d body=%s", publishResponse.Code, publishResponse.Body.String())
+	}
+
+	listResponse := performAdminRequest(server, http.MethodGet, "/v1/admin/announcements", "", adminToken)
+	if !strings.Contains(listResponse.Body.String(), `"missing_languages":["en"]`) {
+		t.Fatalf("管理列表应提示缺失的翻译: %s", listResponse.Body.String())
+	}
+
+	invalidResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		`{
+			"id": 10,
+			"type": "info",
+			"language": "zh-Hans",
+			"title": "新功能",
+			"body": "支持多语言公告。",
+			"localizations": [{"language": "ja", "title": "新機能", "body": "多言語に対応しました。"}],
+			"enabled": true
+		}`,
+		adminToken,
+	)
+	assertErrorCode(t, invalidResponse, http.StatusBadRequest, "announcement_translation_missing")
+}
+
+func TestPublicAnnouncementsResolveDistributionImages(t *testing.T) {
+	const adminToken = "rich-content-admin-token"
+	server := newAnnouncementTestServer(t, adminToken)
+	distributionStore, err := store.NewDistributionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化官方数据存储失败: %v", err)
+	}
+	server.distribution = distributionStore
+
+	const announcement = `{
+		"id": 11,
+		"type": "blocking",
+		"title": "请更新",
+		"body": "新版本修复了**重要问题**，详见[说明](https://example.com/notes)。",
+		"body_format": "markdown",
+		"actions": [
+			{"type": "url", "label": "前往更新", "url": "itms-apps://apps.apple.com/app/id000000000"},
+			{"type": "dismiss", "label": "稍后"}
+		],
+		"image": {"sha256": "%s", "alt": "新版本截图"},
+		"enabled": true
+	}`
+	missingResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		fmt.Sprintf(announcement, strings.Repeat("a", 64)),
+		adminToken,
+	)
+	assertErrorCode(t, missingResponse, http.StatusBadRequest, "announcement_invalid")
+
+	file, err := distributionStore.Create(
+		store.DistributionInput{Name: "截图", DestinationPath: "/Documents/Images", Enabled: true},
+		store.DistributionUpload{FileName: "update.png", ContentType: "image/png", Data: []byte("png")},
+	)
+	if err != nil {
+		t.Fatalf("上传图片失败: %v", err)
+	}
+	createResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		fmt.Sprintf(announcement, file.SHA256),
+		adminToken,
+	)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建富内容公告期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+
+	publicResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(publicResponse, httptest.NewRequest(http.MethodGet, "/v1/announcements", nil))
+	var public []store.PublicAnnouncement
+	if err := json.Unmarshal(publicResponse.Body.Bytes(), &public); err != nil {
+		t.Fatalf("解析公开公告失败: %v", err)
+	}
+	if len(public) != 1 || public[0].Image == nil || len(public[0].Actions) != 2 ||
+		public[0].Image.URL != "/v1/distribution/files/"+file.SHA256+"/update.png" {
+		t.Fatalf("公开公告富内容不正确: %s", publicResponse.Body.String())
+	}
+
+	if _, err := distributionStore.Update(
+		file.Key,
+		store.DistributionInput{Name: "截图", DestinationPath: "/Documents/Images", Enabled: false},
+		nil,
+	); err != nil {
+		t.Fatalf("停用图片失败: %v", err)
+	}
+	hiddenResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(hiddenResponse, httptest.NewRequest(http.MethodGet, "/v1/announcements", nil))
+	if strings.Contains(hiddenResponse.Body.String(), `"image"`) {
+		t.Fatalf("图片停用后不应继续公开: %s", hiddenResponse.Body.String())
+	}
+}
+
 func TestAnnouncementAdminLoginCreatesProtectedSession(t *testing.T) {
 	const adminToken = "browser-admin-token"
 	server := newAnnouncementTestServer(t, adminToken)
@@ -285,6 +613,41 @@ func newAnnouncementTestServer(t *testing.T, adminToken string) *Server {
 		announcementStore,
 		nil,
 		nil,
+		nil,
+		nil,
+	)
+}
+
+// newAnnouncementEventTestServer 额外启用 challenge，用于测试需要签名的公告事件上报。
+func newAnnouncementEventTestServer(t *testing.T, adminToken string) *Server {
+	t.Helper()
+	announcementStore, err := store.NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	return NewServer(
+		config.Config{
+			AdminListenAddr:          "127.0.0.1:8081",
+			AnnouncementAdminToken:   adminToken,
+			AnnouncementCacheMaxAge:  300,
+			AdminLoginLimitPerWindow: 10,
+			QueryLimitPerWindow:      60,
+			RateWindow:               15 * time.Minute,
+			DuplicateWindow:          5 * time.Minute,
+			RequiredUAKeyword:        "ETOS",
+		},
+		nil,
+		&announcementTestLimiter{},
+		nil,
+		security.NewChallengeManager(2*time.Minute, 90*time.Second, 5, 10*time.Minute),
+		nil,
+		nil,
+		nil,
+		announcementStore,
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 }
 
@@ -304,3 +667,79 @@ func performAdminRequest(
 	server.adminEngine.ServeHTTP(response, request)
 	return response
 }
+
+func TestAnnouncementEventsAreAggregatedForAdmin(t *testing.T) {
+	const adminToken = "announcement-admin-token"
+	server := newAnnouncementEventTestServer(t, adminToken)
+	createResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		`{"id":12,"type":"warning","title":"服务维护","body":"今晚维护","enabled":true}`,
+		adminToken,
+	)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建公告期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+
+	report := func(body string) *httptest.ResponseRecorder {
+		const path = "/v1/announcements/events"
+		bundle := server.challenges.Issue("192.0.2.1", 0)
+		timestamp := fmt.Sprintf("%d", time.Now().Unix())
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		request.RemoteAddr = "192.0.2.1:12345"
+		request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-ELS-Challenge-Id", bundle.ChallengeID)
+		request.Header.Set("X-ELS-Timestamp", timestamp)
+		request.Header.Set("X-ELS-Signature", signSurveyTestRequest(bundle, timestamp, path, []byte(body)))
+		response := httptest.NewRecorder()
+		server.engine.ServeHTTP(response, request)
+		return response
+	}
+
+	accepted := report(`{"platform":"iOS","app_build":"120","events":[{"announcement_id":12,"event":"shown"},{"announcement_id":12,"event":"dismissed"}]}`)
+	if accepted.Code != http.StatusAccepted {
+		t.Fatalf("上报公告事件期望 202，实际 %d body=%s", accepted.Code, accepted.Body.String())
+	}
+	assertErrorCode(
+		t,
+		report(`{"events":[{"announcement_id":99,"event":"shown"}]}`),
+		http.StatusBadRequest,
+		"announcement_event_invalid",
+	)
+	assertErrorCode(
+		t,
+		report(`{"events":[{"announcement_id":12,"event":"shown"}],"device_id":"abc"}`),
+		http.StatusBadRequest,
+		"request_invalid",
+	)
+
+	telemetryResponse := performAdminRequest(server, http.MethodGet, "/v1/admin/announcements/telemetry?id=12", "", adminToken)
+	if telemetryResponse.Code != http.StatusOK {
+		t.Fatalf("读取触达统计期望 200，实际 %d body=%s", telemetryResponse.Code, telemetryResponse.Body.String())
+	}
+	var payload struct {
+		Records []store.AnnouncementTelemetryBucket `json:"records"`
+	}
+	if err := json.Unmarshal(telemetryResponse.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("解析触达统计失败: %v", err)
+	}
+	if len(payload.Records) != 1 {
+		t.Fatalf("期望 1 组统计，实际 %+v", payload.Records)
+	}
+	bucket := payload.Records[0]
+	if bucket.Platform != "iOS" || bucket.AppBuild != "120" || bucket.Shown != 1 || bucket.Dismissed != 1 {
+		t.Fatalf("触达统计不正确: %+v", bucket)
+	}
+	if strings.Contains(telemetryResponse.Body.String(), "192.0.2.1") {
+		t.Fatalf("触达统计不应包含客户端 IP")
+	}
+
+	assertErrorCode(
+		t,
+		performAdminRequest(server, http.MethodGet, "/v1/admin/announcements/telemetry?id=abc", "", adminToken),
+		http.StatusBadRequest,
+		"query_invalid",
+	)
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/store"
+)
+
+const (
+	// maxArchiveImportBody 限制上传的压缩归档大小，maxArchiveContentBytes 限制解压后的总大小。
+	maxArchiveImportBody   = 512 << 20
+	maxArchiveContentBytes = 1 << 30
+)
+
+func (s *Server) registerArchiveAdminRoutes() {
+	adminAPI := s.adminEngine.Group("/v1/admin/archive")
+	adminAPI.Use(s.requireAdmin)
+	adminAPI.GET("", s.handleAdminExportArchive)
+	adminAPI.POST("/import", s.handleAdminImportArchive)
+}
+
+// handleAdminExportArchive 把公告、意见征集、匿名答卷与官方数据导出为一个 tar.gz 归档。
+// 归档先写入临时文件，避免导出中途失败时客户端收到不完整的 200 响应。
+func (s *Server) handleAdminExportArchive(c *gin.Context) {
+	now := time.Now().UTC()
+	archive := store.Archive{Manifest: store.ArchiveManifest{CreatedAt: now}}
+	if s.announcements != nil {
+		archive.Announcements = s.announcements.List()
+	}
+	if s.surveys != nil {
+		archive.Surveys, archive.SurveyResponses = s.surveys.Export()
+	}
+	var blobs store.ArchiveBlobSource
+	if s.distribution != nil {
+		archive.Distribution = s.distribution.List()
+		blobs = s.distribution
+	}
+
+	temp, err := os.CreateTemp("", "els-admin-archive-*.tar.gz")
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, "创建归档临时文件失败")
+		return
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+	if err := store.WriteArchive(temp, archive, blobs); err != nil {
+		writeError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	size, err := temp.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = temp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, "读取归档临时文件失败")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(http.StatusOK, size, "application/gzip", temp, map[string]string{
+		"Content-Disposition": fmt.Sprintf(
+			`attachment; filename="els-admin-archive-%s.tar.gz"`,
+			now.Format("20060102-150405"),
+		),
+	})
+}
+
+// handleAdminImportArchive 按 policy 合并归档内容。dry_run 为 true 时只返回计划；
+// 实际导入前也会先对全部存储做一次试运行，任何记录无效都不会写入。
+func (s *Server) handleAdminImportArchive(c *gin.Context) {
+	policy, err := store.ValidateImportPolicy(c.Query("policy"))
+	if err != nil {
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", err.Error())
+		return
+	}
+	dryRun := false
+	if raw := strings.TrimSpace(c.Query("dry_run")); raw != "" {
+		dryRun, err = strconv.ParseBool(raw)
+		if err != nil {
+			writeCodedError(c, http.StatusBadRequest, "query_invalid", "dry_run 必须是 true 或 false")
+			return
+		}
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxArchiveImportBody)
+	archive, err := store.ReadArchive(c.Request.Body, maxArchiveContentBytes)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			writeCodedError(c, http.StatusRequestEntityTooLarge, errorCodePayloadTooLarge, "归档不能超过 512 MiB")
+			return
+		}
+		writeCodedError(c, http.StatusBadRequest, "archive_invalid", err.Error())
+		return
+	}
+	if message := s.missingArchiveStore(archive); message != "" {
+		writeCodedError(c, http.StatusBadRequest, "archive_invalid", message)
+		return
+	}
+
+	changes, err := s.importArchive(archive, policy, true, adminActor(c))
+	if err == nil && !dryRun {
+		changes, err = s.importArchive(archive, policy, false, adminActor(c))
+		if err != nil && archiveChangesApplied(changes) {
+			// 各存储分别落盘，前面已写入的存储不会回滚；返回已生效的变更，便于运维核对后重新导入。
+			c.Header("Cache-Control", "no-store")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "归档只导入了一部分，已写入的变更不会回滚: " + err.Error(),
+				"code":    "archive_import_partial",
+				"policy":  policy,
+				"changes": changes,
+			})
+			return
+		}
+	}
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"dry_run":  dryRun,
+		"policy":   policy,
+		"manifest": archive.Manifest,
+		"changes":  changes,
+	})
+}
+
+func (s *Server) missingArchiveStore(archive store.Archive) string {
+	switch {
+	case len(archive.Announcements) > 0 && s.announcements == nil:
+		return "当前服务未启用公告存储，无法导入公告"
+	case (len(archive.Surveys) > 0 || len(archive.SurveyResponses) > 0) && s.surveys == nil:
+		return "当前服务未启用意见征集存储，无法导入意见征集"
+	case len(archive.Distribution) > 0 && s.distribution == nil:
+		return "当前服务未启用官方数据存储，无法导入官方数据"
+	}
+	return ""
+}
+
+// importArchive 依次导入官方数据、公告与意见征集，每个存储各自原子写入。
+// 某个存储失败时返回此前已完成的存储的变更，调用方据此判断是否已部分写入。
+func (s *Server) importArchive(
+	archive store.Archive,
+	policy string,
+	dryRun bool,
+	author string,
+) ([]store.ImportChange, error) {
+	changes := make([]store.ImportChange, 0)
+	var imageAvailable func(checksum string) bool
+	if s.distribution != nil {
+		// 先导入官方数据，公告引用的图片才能在同一次导入中解析。
+		imported, err := s.distribution.ImportDistribution(archive.Distribution, archive.Blobs, policy, dryRun)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, imported...)
+		imageAvailable = s.archiveImageResolver(archive, imported, dryRun)
+	}
+	if s.announcements != nil {
+		imported, err := s.announcements.ImportAnnouncements(archive.Announcements, policy, dryRun, author, imageAvailable)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, imported...)
+	}
+	if s.surveys != nil {
+		imported, err := s.surveys.ImportSurveys(archive.Surveys, archive.SurveyResponses, policy, dryRun)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, imported...)
+	}
+	return changes, nil
+}
+
+// archiveChangesApplied 判断已完成导入的存储中是否有记录被实际写入。
+func archiveChangesApplied(changes []store.ImportChange) bool {
+	for _, change := range changes {
+		switch change.Action {
+		case store.ImportActionCreate, store.ImportActionUpdate, store.ImportActionRename:
+			return true
+		}
+	}
+	return false
+}
+
+// archiveImageResolver 返回公告导入时使用的图片检查，与创建、更新公告时的 checkAnnouncementImage 规则相同。
+// 实际导入时官方数据已经写入，直接查询存储；试运行时官方数据尚未写入，
+// 归档中按计划会被导入或保持不变的已启用图片也视为可用。
+func (s *Server) archiveImageResolver(
+	archive store.Archive,
+	distributionChanges []store.ImportChange,
+	dryRun bool,
+) func(checksum string) bool {
+	planned := make(map[string]bool)
+	if dryRun {
+		actions := make(map[string]string, len(distributionChanges))
+		for _, change := range distributionChanges {
+			actions[change.Key] = change.Action
+		}
+		for _, record := range archive.Distribution {
+			if action := actions[record.Key]; action == "" || action == store.ImportActionSkip {
+				continue
+			}
+			if record.Enabled && strings.HasPrefix(record.ContentType, "image/") {
+				planned[strings.ToLower(record.SHA256)] = true
+			}
+		}
+	}
+	return func(checksum string) bool {
+		if planned[strings.ToLower(checksum)] {
+			return true
+		}
+		_, ok := s.distribution.PublicImage(checksum)
+		return ok
+	}
+}
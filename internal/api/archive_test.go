@@ -0,0 +1,221 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"els-feedback-proxy/internal/store"
+)
+
+func TestAdminArchiveExportAndImport(t *testing.T) {
+	const adminToken = "announcement-admin-token"
+	source := newAnnouncementTestServer(t, adminToken)
+	createResponse := performAdminRequest(
+		source,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		`{"id":5,"type":"info","title":"新版本","body":"已发布","enabled":true}`,
+		adminToken,
+	)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建公告期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+
+	exported := performAdminRequest(source, http.MethodGet, "/v1/admin/archive", "", adminToken)
+	if exported.Code != http.StatusOK ||
+		exported.Header().Get("Content-Type") != "application/gzip" ||
+		!strings.Contains(exported.Header().Get("Content-Disposition"), "els-admin-archive-") {
+		t.Fatalf("导出归档失败: %d headers=%v", exported.Code, exported.Header())
+	}
+	archive := exported.Body.Bytes()
+
+	target := newAnnouncementTestServer(t, adminToken)
+	importArchive := func(query string, body []byte) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/v1/admin/archive/import"+query, bytes.NewReader(body))
+		request.Header.Set("Authorization", "Bearer "+adminToken)
+		request.Header.Set("Content-Type", "application/gzip")
+		response := httptest.NewRecorder()
+		target.adminEngine.ServeHTTP(response, request)
+		return response
+	}
+
+	var payload struct {
+		DryRun  bool `json:"dry_run"`
+		Changes []struct {
+			Kind   string `json:"kind"`
+			Action string `json:"action"`
+		} `json:"changes"`
+	}
+	dryRun := importArchive("?dry_run=true", archive)
+	if dryRun.Code != http.StatusOK {
+		t.Fatalf("试运行导入期望 200，实际 %d body=%s", dryRun.Code, dryRun.Body.String())
+	}
+	if err := json.Unmarshal(dryRun.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("解析导入响应失败: %v", err)
+	}
+	if !payload.DryRun || len(payload.Changes) != 1 || payload.Changes[0].Action != "create" {
+		t.Fatalf("试运行导入计划不正确: %s", dryRun.Body.String())
+	}
+	if records := target.announcements.List(); len(records) != 0 {
+		t.Fatalf("试运行不应写入公告: %+v", records)
+	}
+
+	if imported := importArchive("?policy=overwrite", archive); imported.Code != http.StatusOK {
+		t.Fatalf("导入期望 200，实际 %d body=%s", imported.Code, imported.Body.String())
+	}
+	if records := target.announcements.List(); len(records) != 1 || records[0].Title != "新版本" {
+		t.Fatalf("导入后的公告不正确: %+v", records)
+	}
+
+	assertErrorCode(t, importArchive("?policy=merge", archive), http.StatusBadRequest, "query_invalid")
+	assertErrorCode(t, importArchive("", []byte("not an archive")), http.StatusBadRequest, "archive_invalid")
+}
+
+func TestAdminArchiveImportChecksAnnouncementImages(t *testing.T) {
+	const adminToken = "announcement-admin-token"
+	newServer := func(withDistribution bool) *Server {
+		server := newAnnouncementTestServer(t, adminToken)
+		if withDistribution {
+			distributionStore, err := store.NewDistributionStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("初始化官方数据存储失败: %v", err)
+			}
+			server.distribution = distributionStore
+		}
+		return server
+	}
+	source := newServer(true)
+	file, err := source.distribution.Create(
+		store.DistributionInput{Name: "截图", DestinationPath: "/Documents/Images", Enabled: true},
+		store.DistributionUpload{FileName: "update.png", ContentType: "image/png", Data: []byte("png")},
+	)
+	if err != nil {
+		t.Fatalf("上传图片失败: %v", err)
+	}
+	createResponse := performAdminRequest(
+		source,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		fmt.Sprintf(`{"id":6,"type":"info","title":"新图标","body":"见图","image":{"sha256":"%s"},"enabled":true}`, file.SHA256),
+		adminToken,
+	)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建公告期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+	archive := performAdminRequest(source, http.MethodGet, "/v1/admin/archive", "", adminToken).Body.Bytes()
+
+	importArchive := func(target *Server, query string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/v1/admin/archive/import"+query, bytes.NewReader(archive))
+		request.Header.Set("Authorization", "Bearer "+adminToken)
+		request.Header.Set("Content-Type", "application/gzip")
+		response := httptest.NewRecorder()
+		target.adminEngine.ServeHTTP(response, request)
+		return response
+	}
+
+	// 试运行时图片尚未写入，但会随同一份归档导入，公告仍应能通过检查。
+	target := newServer(true)
+	if dryRun := importArchive(target, "?dry_run=true"); dryRun.Code != http.StatusOK {
+		t.Fatalf("试运行导入期望 200，实际 %d body=%s", dryRun.Code, dryRun.Body.String())
+	}
+	if imported := importArchive(target, ""); imported.Code != http.StatusOK {
+		t.Fatalf("导入期望 200，实际 %d body=%s", imported.Code, imported.Body.String())
+	}
+	if records := target.announcements.List(); len(records) != 1 || records[0].Image == nil {
+		t.Fatalf("导入后的公告不正确: %+v", records)
+	}
+
+	// 未启用官方数据存储时图片无从解析，试运行与实际导入都应拒绝。
+	withoutDistribution := newServer(false)
+	assertErrorCode(t, importArchive(withoutDistribution, "?dry_run=true"), http.StatusBadRequest, "archive_invalid")
+	assertErrorCode(t, importArchive(withoutDistribution, ""), http.StatusBadRequest, "archive_invalid")
+	if records := withoutDistribution.announcements.List(); len(records) != 0 {
+		t.Fatalf("拒绝导入后不应写入公告: %+v", records)
+	}
+}
+
+func TestAdminArchiveImportReportsPartialWrites(t *testing.T) {
+	const adminToken = "announcement-admin-token"
+	newServer := func(surveyDir string) *Server {
+		server := newAnnouncementTestServer(t, adminToken)
+		surveys, err := store.NewSurveyStore(surveyDir)
+		if err != nil {
+			t.Fatalf("初始化意见征集存储失败: %v", err)
+		}
+		server.surveys = surveys
+		return server
+	}
+	source := newServer(t.TempDir())
+	createResponse := performAdminRequest(
+		source,
+		http.MethodPost,
+		"/v1/admin/announcements",
+		`{"id":5,"type":"info","title":"新版本","body":"已发布","enabled":true}`,
+		adminToken,
+	)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建公告期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+	if _, err := source.surveys.Create(store.SurveyRecord{
+		ID:       2026072401,
+		Title:    "界面方案征集",
+		Language: "zh-Hans",
+		Platform: "iOS",
+		Enabled:  true,
+		Questions: []store.SurveyQuestion{{
+			ID:       "design",
+			Question: "你更喜欢哪种布局？",
+			Type:     "single_select",
+			Required: true,
+			Options: []store.SurveyOption{
+				{ID: "compact", Label: "紧凑布局"},
+				{ID: "relaxed", Label: "宽松布局"},
+			},
+		}},
+	}); err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	archive := performAdminRequest(source, http.MethodGet, "/v1/admin/archive", "", adminToken).Body.Bytes()
+
+	// 用同名文件替换意见征集的数据目录，使公告写入后意见征集落盘失败。
+	surveyDir := filepath.Join(t.TempDir(), "surveys")
+	target := newServer(surveyDir)
+	if err := os.RemoveAll(surveyDir); err != nil {
+		t.Fatalf("删除意见征集目录失败: %v", err)
+	}
+	if err := os.WriteFile(surveyDir, nil, 0o644); err != nil {
+		t.Fatalf("创建占位文件失败: %v", err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/admin/archive/import", bytes.NewReader(archive))
+	request.Header.Set("Authorization", "Bearer "+adminToken)
+	request.Header.Set("Content-Type", "application/gzip")
+	response := httptest.NewRecorder()
+	target.adminEngine.ServeHTTP(response, request)
+
+	var payload struct {
+		Code    string `json:"code"`
+		Changes []struct {
+			Kind   string `json:"kind"`
+			Action string `json:"action"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("解析导入响应失败: %v", err)
+	}
+	if response.Code != http.StatusInternalServerError || payload.Code != "archive_import_partial" ||
+		len(payload.Changes) != 1 || payload.Changes[0].Kind != store.ImportKindAnnouncement ||
+		payload.Changes[0].Action != store.ImportActionCreate {
+		t.Fatalf("部分导入应返回已写入的变更: %d body=%s", response.Code, response.Body.String())
+	}
+	if records := target.announcements.List(); len(records) != 1 {
+		t.Fatalf("已写入的公告不应回滚: %+v", records)
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/security"
+)
+
+const (
+	attestationKeysPath        = "/v1/attestation/keys"
+	maxAttestationRequestBytes = 64 << 10
+)
+
+type registerAttestationKeyRequest struct {
+	KeyID            string   `json:"key_id"`
+	CertificateChain []string `json:"certificate_chain"`
+	Proof            string   `json:"proof"`
+}
+
+func (s *Server) registerAttestationRoutes() {
+	if s.attestation == nil {
+		return
+	}
+	s.signedPOST(attestationKeysPath, signedRoute{
+		rateAction:   "attestation",
+		rateLimit:    s.cfg.SubmitLimitPerWindow,
+		rateMessage:  "设备登记过于频繁",
+		maxBodyBytes: maxAttestationRequestBytes,
+	}, s.handleRegisterAttestationKey)
+}
+
+// handleRegisterAttestationKey 登记设备密钥。请求本身仍需完成普通 challenge 签名与 PoW。
+func (s *Server) handleRegisterAttestationKey(c *gin.Context) {
+	var req registerAttestationKeyRequest
+	if err := json.Unmarshal(signedBody(c), &req); err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "请求体格式无效")
+		return
+	}
+	chain := make([][]byte, 0, len(req.CertificateChain))
+	for _, encoded := range req.CertificateChain {
+		certificate, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			writeCodedError(c, http.StatusBadRequest, "request_invalid", "certificate_chain 必须是 Base64 编码的 DER 证书")
+			return
+		}
+		chain = append(chain, certificate)
+	}
+	proof, err := base64.StdEncoding.DecodeString(strings.TrimSpace(req.Proof))
+	if err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "proof 必须是 Base64 编码")
+		return
+	}
+
+	if err := s.attestation.Register(req.KeyID, chain, signedChallengeID(c), proof); err != nil {
+		switch {
+		case errors.Is(err, security.ErrAttestationMissing):
+			writeCodedError(c, http.StatusBadRequest, security.ErrAttestationMissing.Code, err.Error())
+		case errors.Is(err, security.ErrAttestationInvalid):
+			writeCodedError(c, http.StatusUnauthorized, security.ErrAttestationInvalid.Code, err.Error())
+		default:
+			writeError(c, http.StatusInternalServerError, fmt.Sprintf("登记设备密钥失败: %v", err))
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"key_id":  strings.ToLower(strings.TrimSpace(req.KeyID)),
+	})
+}
+
+// issueChallenge 按客户端登记的签名公钥与设备密钥选择 challenge 模式。
+// 携带已登记设备密钥的客户端获得低 PoW challenge。
+func (s *Server) issueChallenge(c *gin.Context, clientIP string, signingKey ed25519.PublicKey) security.ChallengeBundle {
+	options := security.ChallengeOptions{
+		PoWBits:    s.cfg.PoWDifficultyBits,
+		SigningKey: signingKey,
+	}
+	keyID := strings.TrimSpace(c.GetHeader("X-ELS-Attest-Key-Id"))
+	if s.attestation != nil && keyID != "" && s.cfg.AttestationPoWBits < s.cfg.PoWDifficultyBits &&
+		s.attestation.KnownKey(keyID) {
+		options.PoWBits = s.cfg.AttestationPoWBits
+		options.AttestationKeyID = keyID
+	}
+	return s.challenges.IssueWithOptions(clientIP, options)
+}
+
+func attestationEvidence(c *gin.Context) security.AttestationEvidence {
+	evidence := security.AttestationEvidence{
+		KeyID: strings.TrimSpace(c.GetHeader("X-ELS-Attest-Key-Id")),
+	}
+	if raw := strings.TrimSpace(c.GetHeader("X-ELS-Attest-Assertion")); raw != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(raw); err == nil {
+			evidence.Assertion = decoded
+		}
+	}
+	return evidence
+}
@@ -48,18 +48,22 @@ func (s *Server) registerDistributionAdminRoutes() {
 	adminAPI.DELETE("/:key", s.handleAdminDeleteDistribution)
 }
 
+func distributionFileURL(record store.DistributionRecord) string {
+	return fmt.Sprintf(
+		"/v1/distribution/files/%s/%s",
+		record.SHA256,
+		url.PathEscape(record.FileName),
+	)
+}
+
 func (s *Server) handleDistributionManifest(c *gin.Context) {
 	records := s.distribution.PublicList()
 	downloads := make([]publicDistributionEntry, 0, len(records))
 	for _, record := range records {
 		downloads = append(downloads, publicDistributionEntry{
-			Name: record.Name,
-			Path: record.DestinationPath,
-			URL: fmt.Sprintf(
-				"/v1/distribution/files/%s/%s",
-				record.SHA256,
-				url.PathEscape(record.FileName),
-			),
+			Name:     record.Name,
+			Path:     record.DestinationPath,
+			URL:      distributionFileURL(record),
 			FileName: record.FileName,
 			SHA256:   record.SHA256,
 			Size:     record.Size,
@@ -128,13 +132,13 @@ func (s *Server) handleAdminCreateDistribution(c *gin.Context) {
 		defer cleanup()
 	}
 	if err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
 		return
 	}
 
 	created, err := s.distribution.Create(input, *upload)
 	if err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeStoreError(c, err)
 		return
 	}
 	c.Header("Cache-Control", "no-store")
@@ -150,13 +154,13 @@ func (s *Server) handleAdminUpdateDistribution(c *gin.Context) {
 		defer cleanup()
 	}
 	if err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
 		return
 	}
 
 	updated, err := s.distribution.Update(c.Param("key"), input, upload)
 	if err != nil {
-		writeDistributionStoreError(c, err)
+		writeStoreError(c, err)
 		return
 	}
 	c.Header("Cache-Control", "no-store")
@@ -168,7 +172,7 @@ func (s *Server) handleAdminUpdateDistribution(c *gin.Context) {
 
 func (s *Server) handleAdminDeleteDistribution(c *gin.Context) {
 	if err := s.distribution.Delete(c.Param("key")); err != nil {
-		writeDistributionStoreError(c, err)
+		writeStoreError(c, err)
 		return
 	}
 	c.Header("Cache-Control", "no-store")
@@ -237,11 +241,3 @@ func decodeDistributionMultipart(
 		Data:        data,
 	}, cleanup, nil
 }
-
-func writeDistributionStoreError(c *gin.Context, err error) {
-	if strings.Contains(err.Error(), "不存在") {
-		writeError(c, http.StatusNotFound, err.Error())
-		return
-	}
-	writeError(c, http.StatusBadRequest, err.Error())
-}
@@ -235,6 +235,8 @@ func newDistributionTestServer(t *testing.T, adminToken string) *Server {
 		nil,
 		distributionStore,
 		nil,
+		nil,
+		nil,
 	)
 }
 
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/store"
+)
+
+// 通用错误码。没有更具体错误码的响应按 HTTP 状态使用这些值，保证每个错误响应都带 code。
+const (
+	errorCodeBadRequest      = "bad_request"
+	errorCodeUnauthorized    = "unauthorized"
+	errorCodeForbidden       = "forbidden"
+	errorCodeNotFound        = "not_found"
+	errorCodeConflict        = "conflict"
+	errorCodeGone            = "gone"
+	errorCodePayloadTooLarge = "body_too_large"
+	errorCodeRateLimited     = "rate_limited"
+	errorCodeInternal        = "internal_error"
+	errorCodeUpstream        = "upstream_error"
+)
+
+// writeError 写入错误响应，错误码由 HTTP 状态推导。
+func writeError(c *gin.Context, status int, message string) {
+	writeCodedError(c, status, errorCodeForStatus(status), message)
+}
+
+// writeCodedError 在错误响应中附带供客户端分支判断的稳定错误码。
+func writeCodedError(c *gin.Context, status int, code string, message string) {
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   message,
+		"code":    code,
+	})
+}
+
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return errorCodeBadRequest
+	case http.StatusUnauthorized:
+		return errorCodeUnauthorized
+	case http.StatusForbidden:
+		return errorCodeForbidden
+	case http.StatusNotFound:
+		return errorCodeNotFound
+	case http.StatusConflict:
+		return errorCodeConflict
+	case http.StatusGone:
+		return errorCodeGone
+	case http.StatusRequestEntityTooLarge:
+		return errorCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return errorCodeRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return errorCodeUpstream
+	default:
+		if status >= http.StatusInternalServerError {
+			return errorCodeInternal
+		}
+		return errorCodeBadRequest
+	}
+}
+
+// writeStoreError 按存储错误类别映射 HTTP 状态；未分类的错误视为服务端故障。
+// 带 Reason 的错误在响应中附加 reason 字段，例如意见征集停止的具体原因。
+func writeStoreError(c *gin.Context, err error) {
+	var typed *store.Error
+	if !errors.As(err, &typed) {
+		writeError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch typed.Kind {
+	case store.ErrorNotFound:
+		status = http.StatusNotFound
+	case store.ErrorConflict:
+		status = http.StatusConflict
+	case store.ErrorGone:
+		status = http.StatusGone
+	}
+	if typed.Reason != "" {
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   typed.Message,
+			"code":    typed.Code,
+			"reason":  typed.Reason,
+		})
+		return
+	}
+	writeCodedError(c, status, typed.Code, typed.Message)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"els-feedback-proxy/internal/store"
+)
+
+func TestErrorResponsesAlwaysCarryCode(t *testing.T) {
+	const adminToken = "survey-admin-token"
+	server := newSurveyTestServer(t, adminToken)
+
+	missingResponse := performAdminRequest(
+		server,
+		http.MethodGet,
+		"/v1/admin/surveys/missing/results",
+		"",
+		adminToken,
+	)
+	assertErrorCode(t, missingResponse, http.StatusNotFound, "survey_not_found")
+
+	unauthorizedResponse := performAdminRequest(server, http.MethodGet, "/v1/admin/surveys", "", "wrong-token")
+	assertErrorCode(t, unauthorizedResponse, http.StatusUnauthorized, errorCodeUnauthorized)
+
+	invalidResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/surveys",
+		`{"id":1,"title":"","enabled":true,"questions":[]}`,
+		adminToken,
+	)
+	assertErrorCode(t, invalidResponse, http.StatusBadRequest, "survey_invalid")
+
+	createResponse := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/surveys",
+		`{
+			"id": 2026072402,
+			"title": "已停止的征集",
+			"enabled": false,
+			"questions": [{
+				"id": "design",
+				"question": "你更喜欢哪种布局？",
+				"type": "single_select",
+				"options": [{"id": "compact", "label": "紧凑布局"}]
+			}]
+		}`,
+		adminToken,
+	)
+	if createResponse.Code != http.StatusCreated {
+		t.Fatalf("创建意见征集期望 201，实际 %d body=%s", createResponse.Code, createResponse.Body.String())
+	}
+	var createdPayload struct {
+		Record store.SurveyRecord `json:"record"`
+	}
+	if err := json.Unmarshal(createResponse.Body.Bytes(), &createdPayload); err != nil {
+		t.Fatalf("解析创建响应失败: %v", err)
+	}
+
+	body := []byte(`{"answers":[{"question_id":"design","selected_option_ids":["compact"]}]}`)
+	path := "/v1/surveys/" + createdPayload.Record.Key + "/responses"
+	bundle := server.challenges.Issue("192.0.2.1", 0)
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(body)))
+	request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+	request.Header.Set("X-ELS-Challenge-Id", bundle.ChallengeID)
+	request.Header.Set("X-ELS-Timestamp", timestamp)
+	request.Header.Set("X-ELS-Signature", signSurveyTestRequest(bundle, timestamp, path, body))
+	closedResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(closedResponse, request)
+	assertErrorCode(t, closedResponse, http.StatusGone, "survey_closed")
+	if !strings.Contains(closedResponse.Body.String(), `"reason":"disabled"`) {
+		t.Fatalf("停止的征集应返回停止原因: %s", closedResponse.Body.String())
+	}
+
+	badUAResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(badUAResponse, httptest.NewRequest(http.MethodPost, "/v1/feedback/challenge", nil))
+	assertErrorCode(t, badUAResponse, http.StatusForbidden, "client_ua_invalid")
+
+	unknownResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(unknownResponse, httptest.NewRequest(http.MethodGet, "/v1/unknown", nil))
+	assertErrorCode(t, unknownResponse, http.StatusNotFound, "route_not_found")
+}
+
+func assertErrorCode(t *testing.T, response *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	var payload struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+		Code    string `json:"code"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("解析错误响应失败: %v body=%s", err, response.Body.String())
+	}
+	if response.Code != status || payload.Success || payload.Code != code || payload.Error == "" {
+		t.Fatalf(
+			"期望 %d/%s，实际 %d body=%s",
+			status,
+			code,
+			response.Code,
+			response.Body.String(),
+		)
+	}
+}
@@ -168,6 +168,8 @@ func newGitHubWebhookTestServer() *Server {
 		nil,
 		nil,
 		nil,
+		nil,
+		nil,
 	)
 }
 
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/store"
+)
+
+const maxPreviewInstallationID = 128
+
+// previewRolloutExclusion 说明某条内容因分阶段放量未命中而不会在该安装 ID 上显示。
+type previewRolloutExclusion struct {
+	Kind string `json:"kind"`
+	// Key 仅用于意见征集；公开公告不含 key，按编号识别。
+	Key            string `json:"key,omitempty"`
+	ID             int    `json:"id"`
+	Bucket         int    `json:"bucket"`
+	RolloutPercent int    `json:"rollout_percent"`
+}
+
+func (s *Server) registerPreviewAdminRoutes() {
+	adminAPI := s.adminEngine.Group("/v1/admin/preview")
+	adminAPI.Use(s.requireAdmin)
+	adminAPI.GET("", s.handleAdminPreview)
+}
+
+// handleAdminPreview 模拟指定平台、构建号、语言与渠道的客户端，返回它实际会收到的公告与意见征集。
+// 提供 installation_id 时还会按客户端算法应用分阶段放量；at 可预览定时发布后的结果。
+func (s *Server) handleAdminPreview(c *gin.Context) {
+	audience, err := store.ParseAnnouncementAudience(
+		c.Query("platform"),
+		c.Query("build"),
+		c.Query("locale"),
+		c.Query("channel"),
+	)
+	if err != nil {
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", err.Error())
+		return
+	}
+	installationID := strings.TrimSpace(c.Query("installation_id"))
+	if len(installationID) > maxPreviewInstallationID {
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", "installation_id 不能超过 128 个字符")
+		return
+	}
+	now := time.Now().UTC()
+	if raw := strings.TrimSpace(c.Query("at")); raw != "" {
+		now, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeCodedError(c, http.StatusBadRequest, "query_invalid", "at 必须是 RFC3339 时间")
+			return
+		}
+	}
+	includeDrafts := false
+	if raw := strings.TrimSpace(c.Query("include_drafts")); raw != "" {
+		includeDrafts, err = strconv.ParseBool(raw)
+		if err != nil {
+			writeCodedError(c, http.StatusBadRequest, "query_invalid", "include_drafts 必须是 true 或 false")
+			return
+		}
+	}
+
+	announcements := make([]store.PublicAnnouncement, 0)
+	surveys := make([]store.PublicSurvey, 0)
+	excluded := make([]previewRolloutExclusion, 0)
+	if s.announcements != nil {
+		for _, record := range s.announcements.PreviewAnnouncements(now, audience, includeDrafts) {
+			if exclusion, hidden := previewRollout(
+				"announcement", "", record.ID, record.RolloutPercent, record.RolloutSalt, installationID,
+			); hidden {
+				excluded = append(excluded, exclusion)
+				continue
+			}
+			announcements = append(announcements, record)
+		}
+		s.resolveAnnouncementImages(announcements)
+	}
+	if s.surveys != nil {
+		for _, record := range s.surveys.PreviewSurveys(now, audience, includeDrafts) {
+			if exclusion, hidden := previewRollout(
+				"survey", record.Key, record.ID, record.RolloutPercent, record.RolloutSalt, installationID,
+			); hidden {
+				excluded = append(excluded, exclusion)
+				continue
+			}
+			surveys = append(surveys, record)
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"at":               now,
+		"announcements":    announcements,
+		"surveys":          surveys,
+		"rollout_excluded": excluded,
+	})
+}
+
+// previewRollout 判断放量中的内容是否对 installationID 隐藏；未提供安装 ID 时不做放量筛选。
+func previewRollout(
+	kind string,
+	key string,
+	id int,
+	percent int,
+	salt string,
+	installationID string,
+) (previewRolloutExclusion, bool) {
+	if installationID == "" || percent <= 0 {
+		return previewRolloutExclusion{}, false
+	}
+	bucket := store.RolloutBucket(salt, installationID)
+	if bucket < percent {
+		return previewRolloutExclusion{}, false
+	}
+	return previewRolloutExclusion{Kind: kind, Key: key, ID: id, Bucket: bucket, RolloutPercent: percent}, true
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"els-feedback-proxy/internal/store"
+)
+
+func TestAdminPreviewSimulatesClient(t *testing.T) {
+	const adminToken = "announcement-admin-token"
+	server := newAnnouncementTestServer(t, adminToken)
+	for _, body := range []string{
+		`{"id":21,"type":"info","title":"测试渠道","body":"仅 TestFlight","channel":"testflight","enabled":true}`,
+		`{"id":22,"type":"warning","title":"逐步开放","body":"放量中","rollout_percent":40,"enabled":true}`,
+	} {
+		if response := performAdminRequest(server, http.MethodPost, "/v1/admin/announcements", body, adminToken); response.Code != http.StatusCreated {
+			t.Fatalf("创建公告期望 201，实际 %d body=%s", response.Code, response.Body.String())
+		}
+	}
+
+	var payload struct {
+		Announcements []store.PublicAnnouncement `json:"announcements"`
+		Surveys       []store.PublicSurvey       `json:"surveys"`
+		Excluded      []struct {
+			ID     int `json:"id"`
+			Bucket int `json:"bucket"`
+		} `json:"rollout_excluded"`
+	}
+	preview := func(query string) {
+		t.Helper()
+		response := performAdminRequest(server, http.MethodGet, "/v1/admin/preview?"+query, "", adminToken)
+		if response.Code != http.StatusOK {
+			t.Fatalf("预览期望 200，实际 %d body=%s", response.Code, response.Body.String())
+		}
+		payload.Announcements, payload.Excluded = nil, nil
+		if err := json.Unmarshal(response.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("解析预览响应失败: %v", err)
+		}
+		if payload.Surveys == nil {
+			t.Fatalf("未启用意见征集时也应返回空数组")
+		}
+	}
+
+	preview("platform=iOS&channel=testflight")
+	if len(payload.Announcements) != 2 {
+		t.Fatalf("声明 testflight 渠道的客户端应收到两条公告: %+v", payload.Announcements)
+	}
+	preview("platform=iOS")
+	if len(payload.Announcements) != 1 || payload.Announcements[0].ID != 22 {
+		t.Fatalf("未声明渠道的客户端只应收到全渠道公告: %+v", payload.Announcements)
+	}
+
+	salt := payload.Announcements[0].RolloutSalt
+	hiddenID, visibleID := "", ""
+	for index := 0; hiddenID == "" || visibleID == ""; index++ {
+		candidate := fmt.Sprintf("install-%d", index)
+		if store.RolloutBucket(salt, candidate) < 40 {
+			visibleID = candidate
+		} else {
+			hiddenID = candidate
+		}
+	}
+	preview("platform=iOS&installation_id=" + visibleID)
+	if len(payload.Announcements) != 1 || len(payload.Excluded) != 0 {
+		t.Fatalf("命中放量的安装 ID 应收到公告: %+v", payload)
+	}
+	preview("platform=iOS&installation_id=" + hiddenID)
+	if len(payload.Announcements) != 0 || len(payload.Excluded) != 1 || payload.Excluded[0].ID != 22 {
+		t.Fatalf("未命中放量的安装 ID 应列入 rollout_excluded: %+v", payload)
+	}
+
+	assertErrorCode(
+		t,
+		performAdminRequest(server, http.MethodGet, "/v1/admin/preview?at=tomorrow", "", adminToken),
+		http.StatusBadRequest,
+		"query_invalid",
+	)
+}
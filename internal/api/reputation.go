@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/security"
+)
+
+type banIPRequest struct {
+	IP              string `json:"ip"`
+	Reason          string `json:"reason"`
+	DurationSeconds int64  `json:"duration_seconds"`
+}
+
+// checkIPReputation 在公开路由做任何处理前拦截拒绝名单与封禁中的 IP。
+func (s *Server) checkIPReputation(c *gin.Context) {
+	if s.reputation == nil {
+		c.Next()
+		return
+	}
+
+	verdict := s.reputation.Check(c.ClientIP())
+	if verdict.Allowed {
+		c.Next()
+		return
+	}
+	if verdict.Ban != nil {
+		retryAfter := int(math.Ceil(time.Until(verdict.Ban.Until).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		writeCodedError(c, http.StatusForbidden, "ip_banned", "IP 已被临时封禁")
+	} else {
+		writeCodedError(c, http.StatusForbidden, "ip_denied", "IP 不允许访问")
+	}
+	c.Abort()
+}
+
+// reportSignatureOffense 接收 ChallengeManager 的签名失败上报，按违规阶梯升级封禁。
+func (s *Server) reportSignatureOffense(clientIP string) {
+	ban, banned := s.reputation.ReportOffense(clientIP, "签名校验失败次数过多")
+	if banned {
+		log.Printf(
+			"IP 已自动封禁: ip_hash=%s level=%d until=%s",
+			hashString(clientIP)[:12],
+			ban.Level,
+			ban.Until.UTC().Format(time.RFC3339),
+		)
+	}
+}
+
+func (s *Server) registerIPBanAdminRoutes() {
+	adminAPI := s.adminEngine.Group("/v1/admin/ip-bans")
+	adminAPI.Use(s.requireAdmin)
+	adminAPI.GET("", s.handleAdminListIPBans)
+	adminAPI.POST("", s.handleAdminCreateIPBan)
+	adminAPI.DELETE("/:ip", s.handleAdminLiftIPBan)
+}
+
+func (s *Server) handleAdminListIPBans(c *gin.Context) {
+	bans, err := s.reputation.List()
+	if err != nil {
+		writeError(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"records":     bans,
+		"allow_cidrs": s.reputation.AllowCIDRs(),
+		"deny_cidrs":  s.reputation.DenyCIDRs(),
+	})
+}
+
+func (s *Server) handleAdminCreateIPBan(c *gin.Context) {
+	var req banIPRequest
+	if err := decodeAnnouncementJSON(c, &req); err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
+		return
+	}
+	if req.DurationSeconds < 0 || req.DurationSeconds > int64(365*24*time.Hour/time.Second) {
+		writeError(c, http.StatusBadRequest, "duration_seconds 必须在 0 到 365 天之间")
+		return
+	}
+
+	ban, err := s.reputation.Ban(req.IP, req.Reason, time.Duration(req.DurationSeconds)*time.Second)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"record":  ban,
+	})
+}
+
+func (s *Server) handleAdminLiftIPBan(c *gin.Context) {
+	lifted, err := s.reputation.Lift(c.Param("ip"))
+	if err != nil {
+		if errors.Is(err, security.ErrInvalidIP) {
+			writeCodedError(c, http.StatusBadRequest, security.ErrInvalidIP.Code, err.Error())
+			return
+		}
+		writeError(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	if !lifted {
+		writeCodedError(c, http.StatusNotFound, "ip_ban_not_found", fmt.Sprintf("IP %s 没有封禁记录", c.Param("ip")))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusNoContent)
+}
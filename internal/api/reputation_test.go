@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"els-feedback-proxy/internal/config"
+	"els-feedback-proxy/internal/security"
+)
+
+func TestIPBanBlocksPublicRoutesUntilLifted(t *testing.T) {
+	const adminToken = "test-admin-token-123"
+	reputation, err := security.NewIPReputation(nil, []string{"198.51.100.0/24"}, security.NewMemoryBanStore())
+	if err != nil {
+		t.Fatalf("初始化 IP 信誉失败: %v", err)
+	}
+	server := NewServer(
+		config.Config{
+			AdminListenAddr:          "127.0.0.1:8081",
+			AnnouncementAdminToken:   adminToken,
+			AdminLoginLimitPerWindow: 10,
+			RateWindow:               15 * time.Minute,
+		},
+		nil,
+		&announcementTestLimiter{},
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		reputation,
+	)
+
+	// httptest 请求的默认来源地址为 192.0.2.1。
+	healthz := func(remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/v1/healthz", nil)
+		if remoteAddr != "" {
+			request.RemoteAddr = remoteAddr
+		}
+		response := httptest.NewRecorder()
+		server.engine.ServeHTTP(response, request)
+		return response
+	}
+
+	if response := healthz("198.51.100.8:4321"); response.Code != http.StatusForbidden {
+		t.Fatalf("拒绝名单内 IP 应被拦截，实际 %d", response.Code)
+	}
+
+	created := performAdminRequest(
+		server,
+		http.MethodPost,
+		"/v1/admin/ip-bans",
+		`{"ip":"192.0.2.1","reason":"测试","duration_seconds":3600}`,
+		adminToken,
+	)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("添加封禁失败: %d %s", created.Code, created.Body.String())
+	}
+
+	blocked := healthz("")
+	if blocked.Code != http.StatusForbidden || blocked.Header().Get("Retry-After") == "" {
+		t.Fatalf("封禁中的 IP 应被拦截并返回 Retry-After: %d %v", blocked.Code, blocked.Header())
+	}
+
+	listed := performAdminRequest(server, http.MethodGet, "/v1/admin/ip-bans", "", adminToken)
+	if listed.Code != http.StatusOK || !strings.Contains(listed.Body.String(), `"ip":"192.0.2.1"`) ||
+		!strings.Contains(listed.Body.String(), "198.51.100.0/24") {
+		t.Fatalf("封禁列表不正确: %d %s", listed.Code, listed.Body.String())
+	}
+
+	lifted := performAdminRequest(server, http.MethodDelete, "/v1/admin/ip-bans/192.0.2.1", "", adminToken)
+	if lifted.Code != http.StatusNoContent {
+		t.Fatalf("解除封禁失败: %d %s", lifted.Code, lifted.Body.String())
+	}
+	if response := healthz(""); response.Code != http.StatusOK {
+		t.Fatalf("解除封禁后应恢复访问，实际 %d", response.Code)
+	}
+	if missing := performAdminRequest(server, http.MethodDelete, "/v1/admin/ip-bans/192.0.2.1", "", adminToken); missing.Code != http.StatusNotFound {
+		t.Fatalf("重复解除封禁应返回 404，实际 %d", missing.Code)
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -9,7 +10,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -26,6 +26,11 @@ import (
 	"els-feedback-proxy/internal/store"
 )
 
+const (
+	maxIssueRequestBody   = 512 << 10
+	maxCommentRequestBody = 32 << 10
+)
+
 // Server HTTP 服务封装
 type Server struct {
 	cfg           config.Config
@@ -41,6 +46,8 @@ type Server struct {
 	surveys       *store.SurveyStore
 	reviewer      moderation.Reviewer
 	archives      *store.BlockedArchiveStore
+	attestation   *security.AttestationVerifier
+	reputation    *security.IPReputation
 	developers    map[string]struct{}
 	engine        *gin.Engine
 	adminEngine   *gin.Engine
@@ -78,6 +85,8 @@ func NewServer(
 	announcements *store.AnnouncementStore,
 	distribution *store.DistributionStore,
 	surveys *store.SurveyStore,
+	attestation *security.AttestationVerifier,
+	reputation *security.IPReputation,
 ) *Server {
 	gin.SetMode(gin.ReleaseMode)
 
@@ -112,12 +121,24 @@ func NewServer(
 		surveys:       surveys,
 		reviewer:      reviewer,
 		archives:      archives,
+		attestation:   attestation,
+		reputation:    reputation,
 		developers:    buildDeveloperLoginSet(cfg),
 		engine:        publicEngine,
 		adminEngine:   adminEngine,
 	}
 
-	server.engine.Use(gin.Recovery())
+	if attestation != nil && challenges != nil {
+		challenges.SetAssertionVerifier(attestation)
+	}
+	if reputation != nil && challenges != nil {
+		challenges.SetOffenseReporter(server.reportSignatureOffense)
+	}
+
+	server.engine.Use(gin.Recovery(), server.checkIPReputation)
+	server.engine.NoRoute(func(c *gin.Context) {
+		writeCodedError(c, http.StatusNotFound, "route_not_found", "接口不存在")
+	})
 	server.adminEngine.Use(gin.Recovery())
 	server.registerRoutes()
 	server.registerAdminRoutes()
@@ -159,10 +180,23 @@ func (s *Server) registerRoutes() {
 	s.registerAnnouncementRoutes()
 	s.registerDistributionRoutes()
 	s.registerSurveyRoutes()
+	s.registerAttestationRoutes()
 	s.engine.POST("/v1/feedback/challenge", s.handleChallenge)
-	s.engine.POST("/v1/feedback/issues", s.handleCreateIssue)
+	s.signedPOST(s.cfg.IssuesPath, signedRoute{
+		rateAction:   "submit",
+		rateLimit:    s.cfg.SubmitLimitPerWindow,
+		attestation:  "issue",
+		maxBodyBytes: maxIssueRequestBody,
+	}, s.handleCreateIssue)
 	s.engine.GET("/v1/feedback/issues/:issueNumber", s.handleGetIssueStatus)
-	s.engine.POST("/v1/feedback/issues/:issueNumber/comments", s.handleCreateIssueComment)
+	s.signedPOST("/v1/feedback/issues/:issueNumber/comments", signedRoute{
+		rateAction:   "comment",
+		rateLimit:    s.cfg.CommentLimitPerWindow,
+		rateMessage:  "评论提交过于频繁",
+		attestation:  "comment",
+		maxBodyBytes: maxCommentRequestBody,
+		before:       []gin.HandlerFunc{s.requireIssueTicket},
+	}, s.handleCreateIssueComment)
 	if s.selfUpdater != nil && strings.TrimSpace(s.cfg.GitHubWebhookSecret) != "" {
 		s.engine.POST("/v1/github/webhooks", s.handleGitHubWebhook)
 	}
@@ -180,6 +214,11 @@ func (s *Server) registerAdminRoutes() {
 		if s.surveys != nil {
 			s.registerSurveyAdminRoutes()
 		}
+		if s.reputation != nil {
+			s.registerIPBanAdminRoutes()
+		}
+		s.registerArchiveAdminRoutes()
+		s.registerPreviewAdminRoutes()
 	}
 	if s.selfUpdater != nil {
 		s.adminEngine.POST("/v1/admin/self-update", s.handleSelfUpdate)
@@ -194,95 +233,71 @@ func (s *Server) adminServerEnabled() bool {
 
 func (s *Server) handleChallenge(c *gin.Context) {
 	if !s.validateUA(c) {
-		writeError(c, http.StatusForbidden, "无效客户端 UA")
+		writeCodedError(c, http.StatusForbidden, "client_ua_invalid", "无效客户端 UA")
 		return
 	}
 
 	clientIP := c.ClientIP()
 	if !s.allowRate("challenge", clientIP, s.cfg.ChallengeLimitPerWindow) {
-		writeError(c, http.StatusTooManyRequests, "请求过于频繁")
-		return
-	}
-
-	bundle := s.challenges.Issue(clientIP, s.cfg.PoWDifficultyBits)
-	c.JSON(http.StatusOK, gin.H{
-		"success":       true,
-		"challenge_id":  bundle.ChallengeID,
-		"client_secret": bundle.ClientSecret,
-		"nonce":         bundle.Nonce,
-		"pow_bits":      bundle.PoWBits,
-		"pow_salt":      bundle.PoWSalt,
-		"expires_at":    bundle.ExpiresAt.UTC().Format(time.RFC3339),
-	})
-}
-
-func (s *Server) handleCreateIssue(c *gin.Context) {
-	if !s.validateUA(c) {
-		writeError(c, http.StatusForbidden, "无效客户端 UA")
+		writeCodedError(c, http.StatusTooManyRequests, "rate_limited", "请求过于频繁")
 		return
 	}
 
-	clientIP := c.ClientIP()
-	if !s.allowRate("submit", clientIP, s.cfg.SubmitLimitPerWindow) {
-		writeError(c, http.StatusTooManyRequests, "提交过于频繁")
-		return
+	var signingKey ed25519.PublicKey
+	if encoded := strings.TrimSpace(c.GetHeader("X-ELS-Signing-Key")); encoded != "" {
+		parsed, err := security.ParseSigningKey(encoded)
+		if err != nil {
+			writeCodedError(
+				c,
+				http.StatusBadRequest,
+				security.ErrSigningKeyInvalid.Code,
+				"X-ELS-Signing-Key 必须是 Base64 编码的 Ed25519 公钥",
+			)
+			return
+		}
+		signingKey = parsed
 	}
 
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		writeError(c, http.StatusBadRequest, "读取请求体失败")
-		return
+	bundle := s.issueChallenge(c, clientIP, signingKey)
+	response := gin.H{
+		"success":      true,
+		"challenge_id": bundle.ChallengeID,
+		"sig_alg":      bundle.SigAlg,
+		"nonce":        bundle.Nonce,
+		"pow_bits":     bundle.PoWBits,
+		"pow_salt":     bundle.PoWSalt,
+		"expires_at":   bundle.ExpiresAt.UTC().Format(time.RFC3339),
 	}
-
-	challengeID := strings.TrimSpace(c.GetHeader("X-ELS-Challenge-Id"))
-	timestamp := strings.TrimSpace(c.GetHeader("X-ELS-Timestamp"))
-	signature := strings.TrimSpace(c.GetHeader("X-ELS-Signature"))
-	powNonce := strings.TrimSpace(c.GetHeader("X-ELS-PoW-Nonce"))
-	powHash := strings.TrimSpace(c.GetHeader("X-ELS-PoW-Hash"))
-	if challengeID == "" || timestamp == "" || signature == "" {
-		writeError(c, http.StatusUnauthorized, "缺少签名请求头")
-		return
+	if bundle.ClientSecret != "" {
+		response["client_secret"] = bundle.ClientSecret
 	}
-
-	verifyErr := s.challenges.VerifySubmission(
-		clientIP,
-		challengeID,
-		timestamp,
-		signature,
-		powNonce,
-		powHash,
-		http.MethodPost,
-		s.cfg.IssuesPath,
-		body,
-	)
-	if verifyErr != nil {
-		if errors.Is(verifyErr, security.ErrClientBlocked) {
-			writeError(c, http.StatusTooManyRequests, "签名校验失败次数过多，已临时封禁")
-			return
-		}
-		writeError(c, http.StatusUnauthorized, fmt.Sprintf("签名校验失败: %s", verifyErr.Error()))
-		return
+	if bundle.AttestationKeyID != "" {
+		response["attestation_key_id"] = bundle.AttestationKeyID
 	}
+	c.JSON(http.StatusOK, response)
+}
 
+func (s *Server) handleCreateIssue(c *gin.Context) {
+	clientIP := c.ClientIP()
 	var req SubmitIssueRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		writeError(c, http.StatusBadRequest, "请求体格式无效")
+	if err := json.Unmarshal(signedBody(c), &req); err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "请求体格式无效")
 		return
 	}
 
 	req.Normalize()
 	if err := req.Validate(); err != nil {
 		if typed, ok := err.(apiError); ok {
-			writeError(c, typed.Code, typed.Message)
+			writeCodedError(c, typed.Status, typed.Code, typed.Message)
 			return
 		}
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
 		return
 	}
 
 	dedupeKey := s.dedupeKey(clientIP, req)
 	if s.dedupe.SeenRecently(dedupeKey, s.cfg.DuplicateWindow) {
-		writeError(c, http.StatusConflict, "检测到短时间重复提交")
+		writeCodedError(c, http.StatusConflict, "duplicate_submission", "检测到短时间重复提交")
 		return
 	}
 
@@ -375,18 +390,18 @@ func (s *Server) handleCreateIssue(c *gin.Context) {
 
 func (s *Server) handleGetIssueStatus(c *gin.Context) {
 	if !s.validateUA(c) {
-		writeError(c, http.StatusForbidden, "无效客户端 UA")
+		writeCodedError(c, http.StatusForbidden, "client_ua_invalid", "无效客户端 UA")
 		return
 	}
 
 	issueNumber, err := parseIssueNumber(c.Param("issueNumber"))
 	if err != nil {
-		writeError(c, http.StatusBadRequest, "issue_number 无效")
+		writeCodedError(c, http.StatusBadRequest, "issue_number_invalid", "issue_number 无效")
 		return
 	}
 
 	if !s.validateTicketToken(issueNumber, strings.TrimSpace(c.Query("ticket_token"))) {
-		writeError(c, http.StatusForbidden, "ticket_token 无效")
+		writeCodedError(c, http.StatusForbidden, "ticket_token_invalid", "ticket_token 无效")
 		return
 	}
 
@@ -445,78 +460,39 @@ func (s *Server) handleGetIssueStatus(c *gin.Context) {
 	})
 }
 
-func (s *Server) handleCreateIssueComment(c *gin.Context) {
-	if !s.validateUA(c) {
-		writeError(c, http.StatusForbidden, "无效客户端 UA")
-		return
-	}
-
-	clientIP := c.ClientIP()
-	if !s.allowRate("comment", clientIP, s.cfg.CommentLimitPerWindow) {
-		writeError(c, http.StatusTooManyRequests, "评论提交过于频繁")
-		return
-	}
-
+// requireIssueTicket 在消耗 challenge 之前校验工单编号与 ticket_token。
+func (s *Server) requireIssueTicket(c *gin.Context) {
 	issueNumber, err := parseIssueNumber(c.Param("issueNumber"))
 	if err != nil {
-		writeError(c, http.StatusBadRequest, "issue_number 无效")
+		writeCodedError(c, http.StatusBadRequest, "issue_number_invalid", "issue_number 无效")
+		c.Abort()
 		return
 	}
-	ticketToken := strings.TrimSpace(c.Query("ticket_token"))
-	if !s.validateTicketToken(issueNumber, ticketToken) {
-		writeError(c, http.StatusForbidden, "ticket_token 无效")
-		return
-	}
-
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		writeError(c, http.StatusBadRequest, "读取请求体失败")
-		return
-	}
-
-	challengeID := strings.TrimSpace(c.GetHeader("X-ELS-Challenge-Id"))
-	timestamp := strings.TrimSpace(c.GetHeader("X-ELS-Timestamp"))
-	signature := strings.TrimSpace(c.GetHeader("X-ELS-Signature"))
-	powNonce := strings.TrimSpace(c.GetHeader("X-ELS-PoW-Nonce"))
-	powHash := strings.TrimSpace(c.GetHeader("X-ELS-PoW-Hash"))
-	if challengeID == "" || timestamp == "" || signature == "" {
-		writeError(c, http.StatusUnauthorized, "缺少签名请求头")
+	if !s.validateTicketToken(issueNumber, strings.TrimSpace(c.Query("ticket_token"))) {
+		writeCodedError(c, http.StatusForbidden, "ticket_token_invalid", "ticket_token 无效")
+		c.Abort()
 		return
 	}
+	c.Set("issueNumber", issueNumber)
+	c.Next()
+}
 
-	commentPath := fmt.Sprintf("%s/%d/comments", s.cfg.IssuesPath, issueNumber)
-	verifyErr := s.challenges.VerifySubmission(
-		clientIP,
-		challengeID,
-		timestamp,
-		signature,
-		powNonce,
-		powHash,
-		http.MethodPost,
-		commentPath,
-		body,
-	)
-	if verifyErr != nil {
-		if errors.Is(verifyErr, security.ErrClientBlocked) {
-			writeError(c, http.StatusTooManyRequests, "签名校验失败次数过多，已临时封禁")
-			return
-		}
-		writeError(c, http.StatusUnauthorized, fmt.Sprintf("签名校验失败: %s", verifyErr.Error()))
-		return
-	}
+func (s *Server) handleCreateIssueComment(c *gin.Context) {
+	clientIP := c.ClientIP()
+	issueNumber := c.GetInt("issueNumber")
 
 	var req SubmitCommentRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		writeError(c, http.StatusBadRequest, "请求体格式无效")
+	if err := json.Unmarshal(signedBody(c), &req); err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "请求体格式无效")
 		return
 	}
 	req.Normalize()
 	if err := req.Validate(); err != nil {
 		if typed, ok := err.(apiError); ok {
-			writeError(c, typed.Code, typed.Message)
+			writeCodedError(c, typed.Status, typed.Code, typed.Message)
 			return
 		}
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
 		return
 	}
 
@@ -532,7 +508,7 @@ func (s *Server) handleCreateIssueComment(c *gin.Context) {
 		req.Body,
 	}, "|"))
 	if s.dedupe.SeenRecently(commentDedupeKey, s.cfg.DuplicateWindow) {
-		writeError(c, http.StatusConflict, "检测到短时间重复评论")
+		writeCodedError(c, http.StatusConflict, "duplicate_submission", "检测到短时间重复评论")
 		return
 	}
 
@@ -867,10 +843,3 @@ func hashString(value string) string {
 	digest := sha256.Sum256([]byte(value))
 	return hex.EncodeToString(digest[:])
 }
-
-func writeError(c *gin.Context, code int, message string) {
-	c.JSON(code, gin.H{
-		"success": false,
-		"error":   message,
-	})
-}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/security"
+)
+
+const (
+	signedBodyContextKey      = "els.signed_body"
+	signedChallengeContextKey = "els.signed_challenge_id"
+	defaultSignedBodyBytes    = 64 << 10
+)
+
+// signedRoute 声明一个公开写入路由的签名要求。
+// 注册时经由 signedPOST 串联 UA 校验、限流、前置检查与签名校验，处理函数只需读取 signedBody。
+type signedRoute struct {
+	// rateAction 与 rateLimit 决定该路由独立的限流桶。
+	rateAction  string
+	rateLimit   int
+	rateMessage string
+	// attestation 为 ATTESTATION_ROUTE_MODES 中的路由名；为空时只校验 challenge 自身要求的设备断言。
+	attestation string
+	// maxBodyBytes 为 0 时使用 defaultSignedBodyBytes。
+	maxBodyBytes int64
+	// before 在签名校验之前执行，适合放置不消耗 challenge 的廉价检查，例如 ticket 校验。
+	before []gin.HandlerFunc
+}
+
+// signedRequestError 描述签名校验失败时对客户端的响应。
+type signedRequestError struct {
+	status  int
+	code    string
+	message string
+}
+
+// signedPOST 注册一个需要 challenge 签名的公开 POST 路由。
+func (s *Server) signedPOST(path string, route signedRoute, handler gin.HandlerFunc) {
+	chain := []gin.HandlerFunc{s.requireClientUA, s.limitPublicWrite(route)}
+	chain = append(chain, route.before...)
+	chain = append(chain, s.verifySignedRequest(route), handler)
+	s.engine.POST(path, chain...)
+}
+
+func (s *Server) requireClientUA(c *gin.Context) {
+	if !s.validateUA(c) {
+		writeCodedError(c, http.StatusForbidden, "client_ua_invalid", "无效客户端 UA")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) limitPublicWrite(route signedRoute) gin.HandlerFunc {
+	message := route.rateMessage
+	if message == "" {
+		message = "提交过于频繁"
+	}
+	return func(c *gin.Context) {
+		if !s.allowRate(route.rateAction, c.ClientIP(), route.rateLimit) {
+			writeCodedError(c, http.StatusTooManyRequests, "rate_limited", message)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// verifySignedRequest 读取受限大小的请求体，校验 challenge 签名（HMAC 或 Ed25519）、PoW 与设备证明。
+// 签名串中的 PATH 使用请求的实际路径。
+func (s *Server) verifySignedRequest(route signedRoute) gin.HandlerFunc {
+	maxBodyBytes := route.maxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultSignedBodyBytes
+	}
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes)
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeCodedError(c, http.StatusRequestEntityTooLarge, "body_too_large", "请求体过大")
+			} else {
+				writeCodedError(c, http.StatusBadRequest, "body_unreadable", "读取请求体失败")
+			}
+			c.Abort()
+			return
+		}
+
+		if failure := s.verifySignature(c, route, body); failure != nil {
+			writeCodedError(c, failure.status, failure.code, failure.message)
+			c.Abort()
+			return
+		}
+
+		c.Set(signedBodyContextKey, body)
+		c.Set(signedChallengeContextKey, strings.TrimSpace(c.GetHeader("X-ELS-Challenge-Id")))
+		c.Next()
+	}
+}
+
+func (s *Server) verifySignature(c *gin.Context, route signedRoute, body []byte) *signedRequestError {
+	challengeID := strings.TrimSpace(c.GetHeader("X-ELS-Challenge-Id"))
+	timestamp := strings.TrimSpace(c.GetHeader("X-ELS-Timestamp"))
+	signature := strings.TrimSpace(c.GetHeader("X-ELS-Signature"))
+	powNonce := strings.TrimSpace(c.GetHeader("X-ELS-PoW-Nonce"))
+	powHash := strings.TrimSpace(c.GetHeader("X-ELS-PoW-Hash"))
+	if challengeID == "" || timestamp == "" || signature == "" {
+		return &signedRequestError{
+			status:  http.StatusUnauthorized,
+			code:    "signature_headers_missing",
+			message: "缺少签名请求头",
+		}
+	}
+
+	clientIP := c.ClientIP()
+	result, err := s.challenges.VerifyAttestedSubmission(
+		attestationEvidence(c),
+		clientIP,
+		challengeID,
+		timestamp,
+		signature,
+		powNonce,
+		powHash,
+		http.MethodPost,
+		c.Request.URL.Path,
+		body,
+	)
+	if err == nil && route.attestation != "" {
+		switch s.cfg.AttestationModeFor(route.attestation) {
+		case security.AttestationModeEnforce:
+			if !result.Verified {
+				err = result.Err
+			}
+		case security.AttestationModeLog:
+			if !result.Verified {
+				log.Printf(
+					"设备证明未通过: route=%s ip_hash=%s err=%v",
+					route.attestation,
+					hashString(clientIP)[:12],
+					result.Err,
+				)
+			}
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	return mapSignedRequestError(err)
+}
+
+// mapSignedRequestError 直接使用 security.Error 的错误码；连续失败被封禁时返回 429。
+func mapSignedRequestError(err error) *signedRequestError {
+	if errors.Is(err, security.ErrClientBlocked) {
+		return &signedRequestError{
+			status:  http.StatusTooManyRequests,
+			code:    security.ErrClientBlocked.Code,
+			message: "签名校验失败次数过多，已临时封禁",
+		}
+	}
+	code := security.ErrSignatureInvalid.Code
+	var typed *security.Error
+	if errors.As(err, &typed) {
+		code = typed.Code
+	}
+	return &signedRequestError{
+		status:  http.StatusUnauthorized,
+		code:    code,
+		message: "签名校验失败: " + err.Error(),
+	}
+}
+
+// signedBody 返回 verifySignedRequest 已校验过的请求体。
+func signedBody(c *gin.Context) []byte {
+	body, _ := c.Get(signedBodyContextKey)
+	raw, _ := body.([]byte)
+	return raw
+}
+
+func signedChallengeID(c *gin.Context) string {
+	return c.GetString(signedChallengeContextKey)
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSignedRouteVerifiesOnceAndReportsErrorCodes(t *testing.T) {
+	server := newSurveyTestServer(t, "signed-admin-token")
+	var received string
+	server.signedPOST("/v1/test/signed", signedRoute{
+		rateAction:   "signed-test",
+		rateLimit:    10,
+		maxBodyBytes: 32,
+	}, func(c *gin.Context) {
+		received = string(signedBody(c))
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+
+	send := func(body string, headers map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/v1/test/signed", strings.NewReader(body))
+		request.RemoteAddr = "192.0.2.1:12345"
+		request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		response := httptest.NewRecorder()
+		server.engine.ServeHTTP(response, request)
+		return response
+	}
+	signedHeaders := func(body string) map[string]string {
+		bundle := server.challenges.Issue("192.0.2.1", 0)
+		timestamp := fmt.Sprintf("%d", time.Now().Unix())
+		return map[string]string{
+			"X-ELS-Challenge-Id": bundle.ChallengeID,
+			"X-ELS-Timestamp":    timestamp,
+			"X-ELS-Signature":    signSurveyTestRequest(bundle, timestamp, "/v1/test/signed", []byte(body)),
+		}
+	}
+	assertCode := func(response *httptest.ResponseRecorder, status int, code string) {
+		t.Helper()
+		var payload struct {
+			Code string `json:"code"`
+		}
+		_ = json.Unmarshal(response.Body.Bytes(), &payload)
+		if response.Code != status || payload.Code != code {
+			t.Fatalf("期望 %d/%s，实际 %d body=%s", status, code, response.Code, response.Body.String())
+		}
+	}
+
+	assertCode(send(`{}`, nil), http.StatusUnauthorized, "signature_headers_missing")
+
+	oversized := strings.Repeat("x", 64)
+	assertCode(send(oversized, signedHeaders(oversized)), http.StatusRequestEntityTooLarge, "body_too_large")
+
+	headers := signedHeaders(`{"ok":true}`)
+	if response := send(`{"ok":true}`, headers); response.Code != http.StatusOK || received != `{"ok":true}` {
+		t.Fatalf("有效签名应通过并向处理函数传递请求体: %d %s", response.Code, response.Body.String())
+	}
+	assertCode(send(`{"ok":true}`, headers), http.StatusUnauthorized, "challenge_missing")
+
+	tampered := signedHeaders(`{"ok":true}`)
+	assertCode(send(`{"ok":false}`, tampered), http.StatusUnauthorized, "signature_invalid")
+}
+
+func TestChallengeWithSigningKeyOmitsClientSecret(t *testing.T) {
+	server := newSurveyTestServer(t, "signed-admin-token")
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成 Ed25519 密钥失败: %v", err)
+	}
+
+	requestChallenge := func(signingKey string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/v1/surveys/challenge", nil)
+		request.RemoteAddr = "192.0.2.1:12345"
+		request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+		request.Header.Set("X-ELS-Signing-Key", signingKey)
+		response := httptest.NewRecorder()
+		server.engine.ServeHTTP(response, request)
+		return response
+	}
+
+	assertErrorCode(t, requestChallenge("not-a-key"), http.StatusBadRequest, "signing_key_invalid")
+
+	response := requestChallenge(base64.StdEncoding.EncodeToString(publicKey))
+	var bundle struct {
+		ChallengeID  string  `json:"challenge_id"`
+		SigAlg       string  `json:"sig_alg"`
+		ClientSecret *string `json:"client_secret"`
+		Nonce        string  `json:"nonce"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &bundle); err != nil {
+		t.Fatalf("解析 challenge 响应失败: %v", err)
+	}
+	if response.Code != http.StatusOK || bundle.SigAlg != "ed25519" || bundle.ClientSecret != nil {
+		t.Fatalf("Ed25519 challenge 响应不正确: %d %s", response.Code, response.Body.String())
+	}
+
+	var received bool
+	server.signedPOST("/v1/test/ed25519", signedRoute{rateAction: "signed-test", rateLimit: 10}, func(c *gin.Context) {
+		received = true
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	body := []byte(`{"ok":true}`)
+	bodyHash := sha256.Sum256(body)
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	signingText := fmt.Sprintf(
+		"%s\n%s\n%s\n%s\n%s",
+		http.MethodPost,
+		"/v1/test/ed25519",
+		timestamp,
+		hex.EncodeToString(bodyHash[:]),
+		bundle.Nonce,
+	)
+	request := httptest.NewRequest(http.MethodPost, "/v1/test/ed25519", strings.NewReader(string(body)))
+	request.RemoteAddr = "192.0.2.1:12345"
+	request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+	request.Header.Set("X-ELS-Challenge-Id", bundle.ChallengeID)
+	request.Header.Set("X-ELS-Timestamp", timestamp)
+	request.Header.Set("X-ELS-Signature", hex.EncodeToString(ed25519.Sign(privateKey, []byte(signingText))))
+	submitResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(submitResponse, request)
+	if submitResponse.Code != http.StatusOK || !received {
+		t.Fatalf("Ed25519 签名提交应通过: %d %s", submitResponse.Code, submitResponse.Body.String())
+	}
+}
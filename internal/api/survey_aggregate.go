@@ -0,0 +1,81 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/store"
+)
+
+// handleAdminSurveyAggregate 在服务端统计答卷，避免把全部原始答卷发给浏览器或 CLI。
+// 支持按平台、构建号范围、语言、提交时间与征集版本筛选，并可对两道题做交叉表或按征集语言拆分统计。
+func (s *Server) handleAdminSurveyAggregate(c *gin.Context) {
+	query, err := parseSurveyResultQuery(c)
+	if err != nil {
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", err.Error())
+		return
+	}
+	aggregate, err := s.surveys.Aggregate(c.Param("key"), query)
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, struct {
+		Success bool `json:"success"`
+		store.SurveyAggregate
+	}{Success: true, SurveyAggregate: aggregate})
+}
+
+func parseSurveyResultQuery(c *gin.Context) (store.SurveyResultQuery, error) {
+	query := store.SurveyResultQuery{
+		Platform:    strings.TrimSpace(c.Query("platform")),
+		Language:    strings.TrimSpace(c.Query("language")),
+		CrossRow:    strings.TrimSpace(c.Query("cross_row")),
+		CrossColumn: strings.TrimSpace(c.Query("cross_column")),
+	}
+	if len([]rune(query.Language)) > 32 {
+		return query, fmt.Errorf("language 不能超过 32 个字符")
+	}
+	switch breakdown := strings.TrimSpace(c.Query("breakdown")); breakdown {
+	case "":
+	case "language":
+		query.ByLanguage = true
+	default:
+		return query, fmt.Errorf("breakdown 仅支持 language")
+	}
+	for _, field := range []struct {
+		name   string
+		target *int
+	}{{"min_build", &query.MinBuild}, {"max_build", &query.MaxBuild}, {"survey_version", &query.SurveyVersion}} {
+		raw := strings.TrimSpace(c.Query(field.name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return query, fmt.Errorf("%s 必须是非负整数", field.name)
+		}
+		*field.target = parsed
+	}
+	for _, field := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		raw := strings.TrimSpace(c.Query(field.name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return query, fmt.Errorf("%s 必须是 RFC3339 时间", field.name)
+		}
+		*field.target = parsed
+	}
+	return query, nil
+}
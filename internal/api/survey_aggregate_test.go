@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"els-feedback-proxy/internal/store"
+)
+
+func TestAdminSurveyAggregateFiltersResponses(t *testing.T) {
+	const adminToken = "survey-admin-token"
+	server := newSurveyTestServer(t, adminToken)
+	created, err := server.surveys.Create(store.SurveyRecord{
+		ID:      2026081001,
+		Title:   "满意度",
+		Enabled: true,
+		Questions: []store.SurveyQuestion{
+			{ID: "plan", Question: "使用哪个方案？", Type: "single_select", Options: []store.SurveyOption{
+				{ID: "free", Label: "免费"},
+				{ID: "pro", Label: "专业"},
+			}},
+			{ID: "stars", Question: "满意度", Type: "rating", Scale: 5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	value := func(number float64) *float64 { return &number }
+	for _, input := range []store.SurveyResponseInput{
+		{Platform: "iOS", AppBuild: "120", Answers: []store.SurveyAnswer{
+			{QuestionID: "plan", SelectedOptionIDs: []string{"free"}},
+			{QuestionID: "stars", Value: value(3)},
+		}},
+		{Platform: "watchOS", AppBuild: "140", Answers: []store.SurveyAnswer{
+			{QuestionID: "plan", SelectedOptionIDs: []string{"pro"}},
+			{QuestionID: "stars", Value: value(5)},
+		}},
+	} {
+		if _, err := server.surveys.Submit(created.Key, input); err != nil {
+			t.Fatalf("提交答卷失败: %v", err)
+		}
+	}
+
+	path := "/v1/admin/surveys/" + created.Key + "/aggregate"
+	response := performAdminRequest(server, http.MethodGet, path+"?platform=ios&min_build=130&cross_row=plan&cross_column=stars", "", adminToken)
+	if response.Code != http.StatusOK || response.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("统计接口期望 200，实际 %d body=%s", response.Code, response.Body.String())
+	}
+	var payload struct {
+		Success bool `json:"success"`
+		store.SurveyAggregate
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("解析统计响应失败: %v", err)
+	}
+	if !payload.Success || payload.TotalCount != 2 || payload.ResponseCount != 0 {
+		t.Fatalf("iOS 且构建号不低于 130 的答卷应为 0 份: %+v", payload.SurveyAggregate)
+	}
+
+	response = performAdminRequest(server, http.MethodGet, path+"?max_build=130&cross_row=plan&cross_column=stars", "", adminToken)
+	payload.CrossTab = nil
+	if err := json.Unmarshal(response.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("解析统计响应失败: %v", err)
+	}
+	if payload.ResponseCount != 1 || payload.Questions[1].Values.Average != 3 ||
+		payload.CrossTab == nil || payload.CrossTab.Counts[0][2] != 1 {
+		t.Fatalf("构建号筛选或交叉表不正确: %s", response.Body.String())
+	}
+
+	for _, query := range []string{"?from=yesterday", "?min_build=-1", "?platform=android", "?cross_row=plan"} {
+		assertErrorCode(t, performAdminRequest(server, http.MethodGet, path+query, "", adminToken), http.StatusBadRequest, "query_invalid")
+	}
+	assertErrorCode(
+		t,
+		performAdminRequest(server, http.MethodGet, "/v1/admin/surveys/missing/aggregate", "", adminToken),
+		http.StatusNotFound,
+		"survey_not_found",
+	)
+}
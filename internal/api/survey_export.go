@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/store"
+)
+
+// surveyExportFormats 是答卷导出支持的格式与对应的 Content-Type。
+var surveyExportFormats = map[string]string{
+	"csv":   "text/csv; charset=utf-8",
+	"jsonl": "application/x-ndjson",
+	"xlsx":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// handleAdminSurveyExport 把答卷按每份一行导出为 CSV、JSONL 或 XLSX，筛选参数与统计接口相同。
+// 导出内容先完整写入内存，编码失败时不会返回不完整的 200 响应。
+func (s *Server) handleAdminSurveyExport(c *gin.Context) {
+	format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "csv")))
+	contentType, supported := surveyExportFormats[format]
+	if !supported {
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", "format 仅支持 csv、jsonl 或 xlsx")
+		return
+	}
+	query, err := parseSurveyResultQuery(c)
+	if err != nil {
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", err.Error())
+		return
+	}
+	table, err := s.surveys.ExportTable(c.Param("key"), query)
+	if err != nil {
+		writeStoreError(c, err)
+		return
+	}
+
+	var payload bytes.Buffer
+	switch format {
+	case "csv":
+		err = writeSurveyCSV(&payload, table)
+	case "jsonl":
+		err = writeSurveyJSONL(&payload, table)
+	case "xlsx":
+		err = writeXLSX(&payload, []xlsxSheet{
+			{Name: "responses", Columns: table.Columns, Rows: table.Rows},
+			{Name: "columns", Columns: []string{"column", "label"}, Rows: surveyColumnLabelRows(table)},
+		})
+	}
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, "导出答卷失败")
+		return
+	}
+
+	filename := fmt.Sprintf("survey-%d", table.Survey.ID)
+	if table.Survey.Language != "" {
+		filename += "-" + table.Survey.Language
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	c.Data(http.StatusOK, contentType, payload.Bytes())
+}
+
+func writeSurveyCSV(writer io.Writer, table store.SurveyTable) error {
+	encoder := csv.NewWriter(writer)
+	if err := encoder.Write(table.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(table.Columns))
+	for _, row := range table.Rows {
+		for index, cell := range row {
+			record[index] = csvCell(cell)
+		}
+		if err := encoder.Write(record); err != nil {
+			return err
+		}
+	}
+	encoder.Flush()
+	return encoder.Error()
+}
+
+// csvCell 把单元格转为文本。以 = + - @ 等开头的文字会被电子表格当作公式执行，
+// 因此在前面加单引号；数字单元格不受影响。
+func csvCell(cell any) string {
+	switch value := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			return "'" + value
+		}
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case int:
+		return strconv.Itoa(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// writeSurveyJSONL 每行输出一个 JSON 对象，键的顺序与 CSV 列顺序相同，未作答为 null。
+func writeSurveyJSONL(writer io.Writer, table store.SurveyTable) error {
+	var line bytes.Buffer
+	for _, row := range table.Rows {
+		line.Reset()
+		line.WriteByte('{')
+		for index, cell := range row {
+			if index > 0 {
+				line.WriteByte(',')
+			}
+			key, err := json.Marshal(table.Columns[index])
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(cell)
+			if err != nil {
+				return err
+			}
+			line.Write(key)
+			line.WriteByte(':')
+			line.Write(value)
+		}
+		line.WriteString("}\n")
+		if _, err := writer.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func surveyColumnLabelRows(table store.SurveyTable) [][]any {
+	rows := make([][]any, len(table.Columns))
+	for index, column := range table.Columns {
+		rows[index] = []any{column, table.Labels[index]}
+	}
+	return rows
+}
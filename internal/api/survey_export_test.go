@@ -0,0 +1,96 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"els-feedback-proxy/internal/store"
+)
+
+func TestAdminSurveyExportFormats(t *testing.T) {
+	const adminToken = "survey-admin-token"
+	server := newSurveyTestServer(t, adminToken)
+	created, err := server.surveys.Create(store.SurveyRecord{
+		ID:       2026081501,
+		Title:    "使用习惯",
+		Language: "zh-Hans",
+		Enabled:  true,
+		Questions: []store.SurveyQuestion{
+			{ID: "features", Question: "常用功能", Type: "multi_select", AllowOther: true, Options: []store.SurveyOption{
+				{ID: "chat", Label: "对话"},
+				{ID: "image", Label: "图片"},
+			}},
+			{ID: "stars", Question: "满意度", Type: "rating", Scale: 5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	stars := 4.0
+	if _, err := server.surveys.Submit(created.Key, store.SurveyResponseInput{
+		Platform: "iOS",
+		Answers: []store.SurveyAnswer{
+			{QuestionID: "features", SelectedOptionIDs: []string{"image"}, OtherText: "=HYPERLINK()"},
+			{QuestionID: "stars", Value: &stars},
+		},
+	}); err != nil {
+		t.Fatalf("提交答卷失败: %v", err)
+	}
+	path := "/v1/admin/surveys/" + created.Key + "/export"
+
+	response := performAdminRequest(server, http.MethodGet, path, "", adminToken)
+	if response.Code != http.StatusOK || !strings.Contains(response.Header().Get("Content-Disposition"), "survey-2026081501-zh-Hans.csv") {
+		t.Fatalf("CSV 导出期望 200，实际 %d headers=%v", response.Code, response.Header())
+	}
+	records, err := csv.NewReader(response.Body).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("CSV 应包含表头和一行答卷: %v err=%v", records, err)
+	}
+	header := strings.Join(records[0], ",")
+	if !strings.HasSuffix(header, "features:chat,features:image,features#other,stars") {
+		t.Fatalf("多选题应展开为每个选项一列: %s", header)
+	}
+	row := records[1][len(records[1])-4:]
+	if strings.Join(row, "|") != "false|true|'=HYPERLINK()|4" {
+		t.Fatalf("CSV 单元格不正确，公式应被转义: %v", row)
+	}
+
+	response = performAdminRequest(server, http.MethodGet, path+"?format=jsonl", "", adminToken)
+	var line map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(response.Body.Bytes()), &line); err != nil ||
+		line["features:image"] != true || line["features#other"] != "=HYPERLINK()" || line["stars"] != 4.0 {
+		t.Fatalf("JSONL 导出不正确: %s err=%v", response.Body.String(), err)
+	}
+	if !strings.HasPrefix(response.Body.String(), `{"response_key":`) {
+		t.Fatalf("JSONL 键顺序应与列顺序一致: %s", response.Body.String())
+	}
+
+	response = performAdminRequest(server, http.MethodGet, path+"?format=xlsx&platform=iOS", "", adminToken)
+	archive, err := zip.NewReader(bytes.NewReader(response.Body.Bytes()), int64(response.Body.Len()))
+	if err != nil {
+		t.Fatalf("XLSX 应为 zip 包: %v", err)
+	}
+	sheets := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("读取 XLSX 部件失败: %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		sheets[file.Name] = string(content)
+	}
+	if !strings.Contains(sheets["xl/worksheets/sheet1.xml"], `<c r="K2"><v>4</v></c>`) ||
+		!strings.Contains(sheets["xl/worksheets/sheet2.xml"], "常用功能 / 图片") ||
+		sheets["[Content_Types].xml"] == "" {
+		t.Fatalf("XLSX 工作表内容不正确: %v", sheets)
+	}
+
+	assertErrorCode(t, performAdminRequest(server, http.MethodGet, path+"?format=pdf", "", adminToken), http.StatusBadRequest, "query_invalid")
+}
@@ -2,20 +2,39 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
-	"els-feedback-proxy/internal/security"
+	"els-feedback-proxy/internal/moderation"
 	"els-feedback-proxy/internal/store"
 )
 
-const maxSurveyRequestBody = 128 << 10
+const (
+	maxSurveyRequestBody = 128 << 10
+	// maxSurveyAdminRequestBody 是管理端保存征集的请求体上限，需要容纳多种语言的翻译。
+	maxSurveyAdminRequestBody = 1 << 20
+)
+
+// adminSurveyRecord 在管理响应中附带答卷数量与停止原因，用于展示配额进度。
+// 创建与更新请求也按该结构解析，管理响应原样提交时只读字段会被忽略。
+type adminSurveyRecord struct {
+	store.SurveyRecord
+	store.SurveyState
+}
+
+func (s *Server) adminSurveyRecord(record store.SurveyRecord) adminSurveyRecord {
+	return adminSurveyRecord{
+		SurveyRecord: record,
+		SurveyState:  s.surveys.StateAt(record, time.Now()),
+	}
+}
 
 func (s *Server) registerSurveyRoutes() {
 	if s.surveys == nil {
@@ -24,7 +43,12 @@ func (s *Server) registerSurveyRoutes() {
 
 	s.engine.GET("/v1/surveys", s.handleListSurveys)
 	s.engine.POST("/v1/surveys/challenge", s.handleChallenge)
-	s.engine.POST("/v1/surveys/:key/responses", s.handleSubmitSurveyResponse)
+	s.signedPOST("/v1/surveys/:key/responses", signedRoute{
+		rateAction:   "survey-submit",
+		rateLimit:    s.cfg.SubmitLimitPerWindow,
+		attestation:  "survey",
+		maxBodyBytes: maxSurveyRequestBody,
+	}, s.handleSubmitSurveyResponse)
 }
 
 func (s *Server) registerSurveyAdminRoutes() {
@@ -34,163 +58,175 @@ func (s *Server) registerSurveyAdminRoutes() {
 	adminAPI.POST("", s.handleAdminCreateSurvey)
 	adminAPI.PUT("/:key", s.handleAdminUpdateSurvey)
 	adminAPI.DELETE("/:key", s.handleAdminDeleteSurvey)
+	adminAPI.POST("/:key/merge", s.handleAdminMergeSurvey)
 	adminAPI.GET("/:key/results", s.handleAdminSurveyResults)
+	adminAPI.GET("/:key/aggregate", s.handleAdminSurveyAggregate)
+	adminAPI.GET("/:key/export", s.handleAdminSurveyExport)
 }
 
 func (s *Server) handleListSurveys(c *gin.Context) {
-	payload, err := json.Marshal(s.surveys.PublicList())
-	if err != nil {
-		writeError(c, http.StatusInternalServerError, "编码意见征集失败")
-		return
-	}
-
-	etag := payloadETag(payload)
-	cacheMaxAge := s.cfg.AnnouncementCacheMaxAge
-	if cacheMaxAge < 30 {
-		cacheMaxAge = 300
-	}
-
-	c.Header("Cache-Control", "public, max-age=60, stale-if-error=86400")
-	c.Header(
-		"Cloudflare-CDN-Cache-Control",
-		fmt.Sprintf("public, max-age=%d, stale-while-revalidate=60, stale-if-error=86400", cacheMaxAge),
-	)
-	c.Header("ETag", etag)
-	c.Header("Vary", "Accept-Encoding")
-	c.Header("X-Content-Type-Options", "nosniff")
-	if etagMatches(c.GetHeader("If-None-Match"), etag) {
-		c.Status(http.StatusNotModified)
+	since, deltaRequested, ok := parseSyncSince(c)
+	if !ok {
 		return
 	}
-	c.Data(http.StatusOK, "application/json; charset=utf-8", payload)
-}
 
-func (s *Server) handleSubmitSurveyResponse(c *gin.Context) {
-	if !s.validateUA(c) {
-		writeError(c, http.StatusForbidden, "无效客户端 UA")
+	locale := strings.TrimSpace(c.Query("locale"))
+	if len([]rune(locale)) > 32 {
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", "locale 不能超过 32 个字符")
 		return
 	}
 
-	clientIP := c.ClientIP()
-	if !s.allowRate("survey-submit", clientIP, s.cfg.SubmitLimitPerWindow) {
-		writeError(c, http.StatusTooManyRequests, "提交过于频繁")
-		return
+	now := time.Now()
+	delta, nextTransition := s.surveys.PublicSyncAt(now, locale, since)
+	var payload []byte
+	var err error
+	if deltaRequested {
+		payload, err = json.Marshal(delta)
+	} else {
+		payload, err = json.Marshal(delta.Records)
 	}
-
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSurveyRequestBody)
-	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		writeError(c, http.StatusBadRequest, "读取请求体失败")
+		writeError(c, http.StatusInternalServerError, "编码意见征集失败")
 		return
 	}
 
-	path := "/v1/surveys/" + c.Param("key") + "/responses"
-	if err := s.verifySignedSubmission(c, clientIP, path, body); err != nil {
-		if errors.Is(err, security.ErrClientBlocked) {
-			writeError(c, http.StatusTooManyRequests, "签名校验失败次数过多，已临时封禁")
-			return
-		}
-		writeError(c, http.StatusUnauthorized, fmt.Sprintf("签名校验失败: %s", err.Error()))
-		return
-	}
+	s.writePublicList(c, payload, delta.Revision, now, nextTransition)
+}
+
+func (s *Server) handleSubmitSurveyResponse(c *gin.Context) {
+	clientIP := c.ClientIP()
+	body := signedBody(c)
 
 	var input store.SurveyResponseInput
 	decoder := json.NewDecoder(bytes.NewReader(body))
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(&input); err != nil {
-		writeError(c, http.StatusBadRequest, "请求体格式无效")
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "请求体格式无效")
 		return
 	}
 	if err := decoder.Decode(&struct{}{}); err != io.EOF {
-		writeError(c, http.StatusBadRequest, "请求体只能包含一个 JSON 对象")
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", "请求体只能包含一个 JSON 对象")
 		return
 	}
 
 	if s.dedupe != nil {
 		dedupeKey := hashString(clientIP + "|" + c.Param("key") + "|" + string(body))
 		if s.dedupe.SeenRecently(dedupeKey, s.cfg.DuplicateWindow) {
-			writeError(c, http.StatusConflict, "检测到短时间重复提交")
+			writeCodedError(c, http.StatusConflict, "duplicate_submission", "检测到短时间重复提交")
 			return
 		}
 	}
 
+	// 先按保存规则检查，只把会被保存的答卷送审，避免无效或重复的提交消耗审核调用。
+	if err := s.surveys.ValidateResponse(c.Param("key"), input); err != nil {
+		writeStoreError(c, err)
+		return
+	}
+	if texts := store.SurveyResponseTexts(input.Answers); len(texts) > 0 {
+		input.Moderation = s.reviewSurveyTexts(c.Request.Context(), texts)
+	}
 	if _, err := s.surveys.Submit(c.Param("key"), input); err != nil {
-		writeSurveyStoreError(c, err)
+		writeStoreError(c, err)
 		return
 	}
 	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusCreated, gin.H{"success": true})
 }
 
-func (s *Server) verifySignedSubmission(c *gin.Context, clientIP, path string, body []byte) error {
-	challengeID := strings.TrimSpace(c.GetHeader("X-ELS-Challenge-Id"))
-	timestamp := strings.TrimSpace(c.GetHeader("X-ELS-Timestamp"))
-	signature := strings.TrimSpace(c.GetHeader("X-ELS-Signature"))
-	powNonce := strings.TrimSpace(c.GetHeader("X-ELS-PoW-Nonce"))
-	powHash := strings.TrimSpace(c.GetHeader("X-ELS-PoW-Hash"))
-	if challengeID == "" || timestamp == "" || signature == "" {
-		return fmt.Errorf("缺少签名请求头")
-	}
-	return s.challenges.VerifySubmission(
-		clientIP,
-		challengeID,
-		timestamp,
-		signature,
-		powNonce,
-		powHash,
-		http.MethodPost,
-		path,
-		body,
-	)
+// reviewSurveyTexts 把一份答卷的文字回答一并送审。未通过或审核异常的答卷只做标记，仍然保存，
+// 由管理员在统计页面查看；审核异常时与反馈一致，按保护策略标记。
+func (s *Server) reviewSurveyTexts(ctx context.Context, texts []string) *store.SurveyModeration {
+	decision, err := s.reviewer.Review(ctx, moderation.ReviewInput{
+		Type:         "survey",
+		Title:        "意见征集文字回答",
+		Detail:       strings.Join(texts, "\n\n"),
+		ExtraContext: "以上是匿名意见征集中用户自由填写的回答，每段一条，只会在管理后台展示。",
+	})
+	if err != nil {
+		return &store.SurveyModeration{Flagged: true, Reasons: []string{fmt.Sprintf("AI 审核异常，已按保护策略标记：%v", err)}}
+	}
+	if decision.Allow {
+		return &store.SurveyModeration{}
+	}
+	return &store.SurveyModeration{Flagged: true, Reasons: decision.Reasons, Categories: decision.Categories}
 }
 
 func (s *Server) handleAdminListSurveys(c *gin.Context) {
 	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"records": s.surveys.List(),
+		"records": s.adminSurveyRecords(s.surveys.List()),
 	})
 }
 
+func (s *Server) adminSurveyRecords(records []store.SurveyRecord) []adminSurveyRecord {
+	result := make([]adminSurveyRecord, 0, len(records))
+	for _, record := range records {
+		result = append(result, s.adminSurveyRecord(record))
+	}
+	return result
+}
+
 func (s *Server) handleAdminCreateSurvey(c *gin.Context) {
-	var record store.SurveyRecord
-	if err := decodeSurveyJSON(c, &record); err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+	var input adminSurveyRecord
+	if err := decodeSurveyJSON(c, &input); err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
 		return
 	}
-	created, err := s.surveys.Create(record)
+	created, err := s.surveys.Create(input.SurveyRecord)
 	if err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+		writeStoreError(c, err)
 		return
 	}
 	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
-		"record":  created,
+		"record":  s.adminSurveyRecord(created),
 	})
 }
 
 func (s *Server) handleAdminUpdateSurvey(c *gin.Context) {
-	var replacement store.SurveyRecord
-	if err := decodeSurveyJSON(c, &replacement); err != nil {
-		writeError(c, http.StatusBadRequest, err.Error())
+	var input adminSurveyRecord
+	if err := decodeSurveyJSON(c, &input); err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
+		return
+	}
+	updated, err := s.surveys.Update(c.Param("key"), input.SurveyRecord)
+	if err != nil {
+		writeStoreError(c, err)
 		return
 	}
-	updated, err := s.surveys.Update(c.Param("key"), replacement)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"record":  s.adminSurveyRecord(updated),
+	})
+}
+
+// handleAdminMergeSurvey 把同一编号的旧式单语言征集 source_key 并入 key，成为它的一种翻译。
+func (s *Server) handleAdminMergeSurvey(c *gin.Context) {
+	var input struct {
+		SourceKey string `json:"source_key"`
+	}
+	if err := decodeSurveyJSON(c, &input); err != nil {
+		writeCodedError(c, http.StatusBadRequest, "request_invalid", err.Error())
+		return
+	}
+	merged, err := s.surveys.MergeLanguageVariant(c.Param("key"), input.SourceKey)
 	if err != nil {
-		writeSurveyStoreError(c, err)
+		writeStoreError(c, err)
 		return
 	}
 	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"record":  updated,
+		"record":  s.adminSurveyRecord(merged),
 	})
 }
 
 func (s *Server) handleAdminDeleteSurvey(c *gin.Context) {
 	if err := s.surveys.Delete(c.Param("key")); err != nil {
-		writeSurveyStoreError(c, err)
+		writeStoreError(c, err)
 		return
 	}
 	c.Header("Cache-Control", "no-store")
@@ -200,7 +236,7 @@ func (s *Server) handleAdminDeleteSurvey(c *gin.Context) {
 func (s *Server) handleAdminSurveyResults(c *gin.Context) {
 	survey, responses, err := s.surveys.Results(c.Param("key"))
 	if err != nil {
-		writeSurveyStoreError(c, err)
+		writeStoreError(c, err)
 		return
 	}
 	c.Header("Cache-Control", "no-store")
@@ -213,7 +249,7 @@ func (s *Server) handleAdminSurveyResults(c *gin.Context) {
 }
 
 func decodeSurveyJSON(c *gin.Context, target any) error {
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSurveyRequestBody)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSurveyAdminRequestBody)
 	decoder := json.NewDecoder(c.Request.Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(target); err != nil {
@@ -224,17 +260,3 @@ func decodeSurveyJSON(c *gin.Context, target any) error {
 	}
 	return nil
 }
-
-func writeSurveyStoreError(c *gin.Context, err error) {
-	message := err.Error()
-	switch {
-	case strings.Contains(message, "不存在"):
-		writeError(c, http.StatusNotFound, message)
-	case strings.Contains(message, "已停止"):
-		writeError(c, http.StatusGone, message)
-	case strings.Contains(message, "不能修改"), strings.Contains(message, "不能删除"):
-		writeError(c, http.StatusConflict, message)
-	default:
-		writeError(c, http.StatusBadRequest, message)
-	}
-}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"els-feedback-proxy/internal/config"
+	"els-feedback-proxy/internal/moderation"
 	"els-feedback-proxy/internal/security"
 	"els-feedback-proxy/internal/store"
 )
@@ -118,8 +120,23 @@ func TestSurveyPublicSubmissionAndPrivateResults(t *testing.T) {
 		string(createEnvelope.Record),
 		adminToken,
 	)
-	if updateResponse.Code != http.StatusConflict {
-		t.Fatalf("收到答卷后修改定义应返回 409，实际 %d body=%s", updateResponse.Code, updateResponse.Body.String())
+	if updateResponse.Code != http.StatusOK ||
+		!strings.Contains(updateResponse.Body.String(), `"version":2`) ||
+		!strings.Contains(updateResponse.Body.String(), `"title":"界面方案征集"`) {
+		t.Fatalf("收到答卷后修改文字应生成新版本，实际 %d body=%s", updateResponse.Code, updateResponse.Body.String())
+	}
+
+	incompatible := strings.Replace(string(createEnvelope.Record), `"single_select"`, `"multi_select"`, 1)
+	updateResponse = performAdminRequest(
+		server,
+		http.MethodPut,
+		"/v1/admin/surveys/"+createdPayload.Record.Key,
+		incompatible,
+		adminToken,
+	)
+	if updateResponse.Code != http.StatusConflict ||
+		!strings.Contains(updateResponse.Body.String(), `"code":"survey_has_responses"`) {
+		t.Fatalf("收到答卷后修改题型应返回 409，实际 %d body=%s", updateResponse.Code, updateResponse.Body.String())
 	}
 }
 
@@ -176,6 +193,8 @@ func newSurveyTestServer(t *testing.T, adminToken string) *Server {
 		nil,
 		nil,
 		surveys,
+		nil,
+		nil,
 	)
 }
 
@@ -198,3 +217,271 @@ func signSurveyTestRequest(
 	_, _ = mac.Write([]byte(signingText))
 	return hex.EncodeToString(mac.Sum(nil))
 }
+
+func TestSurveyQuotaClosesSurveyAndReportsProgress(t *testing.T) {
+	const adminToken = "survey-admin-token"
+	server := newSurveyTestServer(t, adminToken)
+	record := store.SurveyRecord{
+		ID:           2026080101,
+		Title:        "配额征集",
+		Enabled:      true,
+		MaxResponses: 1,
+		Questions: []store.SurveyQuestion{{
+			ID:       "design",
+			Question: "你更喜欢哪种布局？",
+			Type:     store.SurveyTypeSingleSelect,
+			Options:  []store.SurveyOption{{ID: "compact", Label: "紧凑布局"}},
+		}},
+	}
+	created, err := server.surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	input := store.SurveyResponseInput{Answers: []store.SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}}}
+	if _, err := server.surveys.Submit(created.Key, input); err != nil {
+		t.Fatalf("提交答卷失败: %v", err)
+	}
+
+	publicResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(publicResponse, httptest.NewRequest(http.MethodGet, "/v1/surveys", nil))
+	if strings.TrimSpace(publicResponse.Body.String()) != "[]" {
+		t.Fatalf("收满答卷后应从公开列表移除: %s", publicResponse.Body.String())
+	}
+
+	listResponse := performAdminRequest(server, http.MethodGet, "/v1/admin/surveys", "", adminToken)
+	if !strings.Contains(listResponse.Body.String(), `"response_count":1`) ||
+		!strings.Contains(listResponse.Body.String(), `"closed_reason":"quota_reached"`) {
+		t.Fatalf("管理列表应返回配额进度: %s", listResponse.Body.String())
+	}
+
+	body := []byte(`{"answers":[{"question_id":"design","selected_option_ids":["compact"]}]}`)
+	path := "/v1/surveys/" + created.Key + "/responses"
+	bundle := server.challenges.Issue("192.0.2.1", 0)
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(body)))
+	request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+	request.Header.Set("X-ELS-Challenge-Id", bundle.ChallengeID)
+	request.Header.Set("X-ELS-Timestamp", timestamp)
+	request.Header.Set("X-ELS-Signature", signSurveyTestRequest(bundle, timestamp, path, body))
+	closedResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(closedResponse, request)
+	assertErrorCode(t, closedResponse, http.StatusGone, "survey_closed")
+	if !strings.Contains(closedResponse.Body.String(), `"reason":"quota_reached"`) {
+		t.Fatalf("收满答卷后应返回 quota_reached: %s", closedResponse.Body.String())
+	}
+}
+
+func TestSurveyRejectsRepeatedDeviceSubmission(t *testing.T) {
+	server := newSurveyTestServer(t, "survey-admin-token")
+	created, err := server.surveys.Create(store.SurveyRecord{
+		ID:                   2026080201,
+		Title:                "每台设备一次",
+		Enabled:              true,
+		OneResponsePerDevice: true,
+		Questions: []store.SurveyQuestion{{
+			ID:       "design",
+			Question: "你更喜欢哪种布局？",
+			Type:     store.SurveyTypeSingleSelect,
+			Options:  []store.SurveyOption{{ID: "compact", Label: "紧凑布局"}, {ID: "relaxed", Label: "宽松布局"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+
+	publicResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(publicResponse, httptest.NewRequest(http.MethodGet, "/v1/surveys", nil))
+	var surveys []store.PublicSurvey
+	if err := json.Unmarshal(publicResponse.Body.Bytes(), &surveys); err != nil || len(surveys) != 1 || surveys[0].RespondentSalt == "" {
+		t.Fatalf("公开定义应包含 respondent_salt: %s", publicResponse.Body.String())
+	}
+	token := store.RespondentToken(surveys[0].RespondentSalt, "installation-1")
+	if _, err := server.surveys.Submit(created.Key, store.SurveyResponseInput{
+		Answers:         []store.SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+		RespondentToken: token,
+	}); err != nil {
+		t.Fatalf("首次提交失败: %v", err)
+	}
+
+	// 换一个回答绕过短时去重，同一设备仍不能再次提交。
+	body := []byte(`{"answers":[{"question_id":"design","selected_option_ids":["relaxed"]}],"respondent_token":"` + token + `"}`)
+	path := "/v1/surveys/" + created.Key + "/responses"
+	bundle := server.challenges.Issue("192.0.2.1", 0)
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(body)))
+	request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+	request.Header.Set("X-ELS-Challenge-Id", bundle.ChallengeID)
+	request.Header.Set("X-ELS-Timestamp", timestamp)
+	request.Header.Set("X-ELS-Signature", signSurveyTestRequest(bundle, timestamp, path, body))
+	response := httptest.NewRecorder()
+	server.engine.ServeHTTP(response, request)
+	assertErrorCode(t, response, http.StatusConflict, "survey_already_answered")
+}
+
+// keywordSurveyReviewer 拒绝包含关键词的内容，用于验证文字回答只被标记而不被丢弃。
+type keywordSurveyReviewer struct {
+	keyword string
+	inputs  []moderation.ReviewInput
+}
+
+func (r *keywordSurveyReviewer) Review(_ context.Context, input moderation.ReviewInput) (moderation.Decision, error) {
+	r.inputs = append(r.inputs, input)
+	if strings.Contains(input.Detail, r.keyword) {
+		return moderation.Decision{Allow: false, Reasons: []string{"包含辱骂"}, Categories: []string{"骚扰辱骂"}}, nil
+	}
+	return moderation.Decision{Allow: true}, nil
+}
+
+func TestSurveyFlagsModeratedTextAnswers(t *testing.T) {
+	const adminToken = "survey-admin-token"
+	server := newSurveyTestServer(t, adminToken)
+	reviewer := &keywordSurveyReviewer{keyword: "笨蛋"}
+	server.reviewer = reviewer
+	created, err := server.surveys.Create(store.SurveyRecord{
+		ID:      2026090101,
+		Title:   "功能建议",
+		Enabled: true,
+		Questions: []store.SurveyQuestion{{
+			ID:         "feature",
+			Question:   "最想要哪个功能？",
+			Type:       store.SurveyTypeSingleSelect,
+			AllowOther: true,
+			Options:    []store.SurveyOption{{ID: "sync", Label: "云同步"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+
+	path := "/v1/surveys/" + created.Key + "/responses"
+	for _, body := range []string{
+		`{"answers":[{"question_id":"feature","selected_option_ids":["sync"]}]}`,
+		`{"answers":[{"question_id":"feature","other_text":"深色模式！"}]}`,
+		`{"answers":[{"question_id":"feature","other_text":"开发者是笨蛋，深色模式"}]}`,
+	} {
+		bundle := server.challenges.Issue("192.0.2.1", 0)
+		timestamp := fmt.Sprintf("%d", time.Now().Unix())
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+		request.Header.Set("X-ELS-Challenge-Id", bundle.ChallengeID)
+		request.Header.Set("X-ELS-Timestamp", timestamp)
+		request.Header.Set("X-ELS-Signature", signSurveyTestRequest(bundle, timestamp, path, []byte(body)))
+		response := httptest.NewRecorder()
+		server.engine.ServeHTTP(response, request)
+		if response.Code != http.StatusCreated {
+			t.Fatalf("未通过审核的答卷也应保存，实际 %d body=%s", response.Code, response.Body.String())
+		}
+	}
+	if len(reviewer.inputs) != 2 || reviewer.inputs[0].Type != "survey" {
+		t.Fatalf("只有包含文字回答的答卷需要送审: %+v", reviewer.inputs)
+	}
+
+	// 不会被保存的答卷在送审前就被拒绝，不消耗审核调用。
+	for _, rejected := range []struct {
+		path, body, code string
+		status           int
+	}{
+		{path, `{"answers":[{"question_id":"missing","other_text":"深色模式"}]}`, "survey_response_invalid", http.StatusBadRequest},
+		{"/v1/surveys/missing/responses", `{"answers":[{"question_id":"feature","other_text":"深色模式"}]}`, "survey_not_found", http.StatusNotFound},
+	} {
+		bundle := server.challenges.Issue("192.0.2.1", 0)
+		timestamp := fmt.Sprintf("%d", time.Now().Unix())
+		request := httptest.NewRequest(http.MethodPost, rejected.path, strings.NewReader(rejected.body))
+		request.Header.Set("User-Agent", "ETOS LLM Studio/120")
+		request.Header.Set("X-ELS-Challenge-Id", bundle.ChallengeID)
+		request.Header.Set("X-ELS-Timestamp", timestamp)
+		request.Header.Set("X-ELS-Signature", signSurveyTestRequest(bundle, timestamp, rejected.path, []byte(rejected.body)))
+		response := httptest.NewRecorder()
+		server.engine.ServeHTTP(response, request)
+		assertErrorCode(t, response, rejected.status, rejected.code)
+	}
+	if len(reviewer.inputs) != 2 {
+		t.Fatalf("无效的答卷不应送审: %+v", reviewer.inputs)
+	}
+
+	response := performAdminRequest(server, http.MethodGet, "/v1/admin/surveys/"+created.Key+"/aggregate", "", adminToken)
+	var payload store.SurveyAggregate
+	if err := json.Unmarshal(response.Body.Bytes(), &payload); err != nil || response.Code != http.StatusOK {
+		t.Fatalf("读取统计失败: %d %s", response.Code, response.Body.String())
+	}
+	question := payload.Questions[0]
+	if payload.ResponseCount != 3 || payload.FlaggedCount != 1 || question.OtherCount != 2 {
+		t.Fatalf("被标记的答卷仍应参与统计: %s", response.Body.String())
+	}
+	if len(question.Texts) != 2 || !question.Texts[0].Flagged || question.Texts[0].FlagReasons[0] != "包含辱骂" || question.Texts[1].Flagged {
+		t.Fatalf("文字回答的审核标记不正确: %+v", question.Texts)
+	}
+}
+
+func TestSurveyLocalizationsAreResolvedAndMerged(t *testing.T) {
+	const adminToken = "survey-admin-token"
+	server := newSurveyTestServer(t, adminToken)
+	question := store.SurveyQuestion{
+		ID:       "design",
+		Question: "你更喜欢哪种布局？",
+		Type:     store.SurveyTypeSingleSelect,
+		Options:  []store.SurveyOption{{ID: "compact", Label: "紧凑布局"}, {ID: "relaxed", Label: "宽松布局"}},
+	}
+	chinese, err := server.surveys.Create(store.SurveyRecord{
+		ID: 2026100101, Title: "界面方案征集", Language: "zh-Hans", Enabled: true, Questions: []store.SurveyQuestion{question},
+	})
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	question.Question = "Which layout do you prefer?"
+	question.Options = []store.SurveyOption{{ID: "compact", Label: "Compact"}, {ID: "relaxed", Label: "Relaxed"}}
+	english, err := server.surveys.Create(store.SurveyRecord{
+		ID: 2026100101, Title: "Layout survey", Language: "en", Enabled: true, Questions: []store.SurveyQuestion{question},
+	})
+	if err != nil {
+		t.Fatalf("创建英文版本失败: %v", err)
+	}
+	for _, key := range []string{chinese.Key, english.Key} {
+		if _, err := server.surveys.Submit(key, store.SurveyResponseInput{
+			Answers: []store.SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+		}); err != nil {
+			t.Fatalf("提交答卷失败: %v", err)
+		}
+	}
+
+	mergeResponse := performAdminRequest(
+		server, http.MethodPost, "/v1/admin/surveys/"+chinese.Key+"/merge", `{"source_key":"`+english.Key+`"}`, adminToken,
+	)
+	if mergeResponse.Code != http.StatusOK || !strings.Contains(mergeResponse.Body.String(), `"localizations":[{"language":"en"`) {
+		t.Fatalf("合并语言版本失败: %d %s", mergeResponse.Code, mergeResponse.Body.String())
+	}
+	repeated := performAdminRequest(
+		server, http.MethodPost, "/v1/admin/surveys/"+chinese.Key+"/merge", `{"source_key":"`+english.Key+`"}`, adminToken,
+	)
+	assertErrorCode(t, repeated, http.StatusNotFound, "survey_not_found")
+
+	publicResponse := httptest.NewRecorder()
+	server.engine.ServeHTTP(publicResponse, httptest.NewRequest(http.MethodGet, "/v1/surveys?locale=en-US", nil))
+	var surveys []store.PublicSurvey
+	if err := json.Unmarshal(publicResponse.Body.Bytes(), &surveys); err != nil || len(surveys) != 1 {
+		t.Fatalf("公开列表应只剩一份征集: %s", publicResponse.Body.String())
+	}
+	if surveys[0].Key != chinese.Key || surveys[0].Language != "en" || surveys[0].Title != "Layout survey" ||
+		surveys[0].Questions[0].Options[0].Label != "Compact" {
+		t.Fatalf("应按 locale 返回英文文字: %+v", surveys[0])
+	}
+	invalidLocale := httptest.NewRecorder()
+	server.engine.ServeHTTP(invalidLocale, httptest.NewRequest(http.MethodGet, "/v1/surveys?locale="+strings.Repeat("a", 33), nil))
+	assertErrorCode(t, invalidLocale, http.StatusBadRequest, "query_invalid")
+
+	response := performAdminRequest(
+		server, http.MethodGet, "/v1/admin/surveys/"+chinese.Key+"/aggregate?breakdown=language", "", adminToken,
+	)
+	var payload store.SurveyAggregate
+	if err := json.Unmarshal(response.Body.Bytes(), &payload); err != nil || response.Code != http.StatusOK {
+		t.Fatalf("读取统计失败: %d %s", response.Code, response.Body.String())
+	}
+	if payload.ResponseCount != 2 || payload.Questions[0].Options[0].Count != 2 || len(payload.Languages) != 2 ||
+		payload.Languages[1].Language != "en" || payload.Languages[1].ResponseCount != 1 {
+		t.Fatalf("合并后的答卷应合并统计并可按语言拆分: %s", response.Body.String())
+	}
+	invalidBreakdown := performAdminRequest(
+		server, http.MethodGet, "/v1/admin/surveys/"+chinese.Key+"/aggregate?breakdown=platform", "", adminToken,
+	)
+	assertErrorCode(t, invalidBreakdown, http.StatusBadRequest, "query_invalid")
+}
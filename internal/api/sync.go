@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// syncRevisionHeader 在公告与意见征集的完整列表中返回当前同步修订号，客户端下次可据此传入 since。
+const syncRevisionHeader = "X-ELS-Revision"
+
+// parseSyncSince 解析增量同步参数。未提供 since 时返回 requested 为 false，接口保持完整列表响应。
+func parseSyncSince(c *gin.Context) (since int64, requested bool, ok bool) {
+	raw, requested := c.GetQuery("since")
+	if !requested {
+		return 0, false, true
+	}
+	since, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil || since < 0 {
+		writeCodedError(c, http.StatusBadRequest, "query_invalid", "since 必须是非负整数")
+		return 0, true, false
+	}
+	return since, true, true
+}
+
+// writePublicList 写出公告或意见征集的公开列表及其缓存头。next 为下一次定时变化的时间，
+// 非零时缓存必须在此之前失效，且不能继续提供过期内容。
+func (s *Server) writePublicList(c *gin.Context, payload []byte, revision int64, now, next time.Time) {
+	cacheMaxAge := s.cfg.AnnouncementCacheMaxAge
+	if cacheMaxAge < 30 {
+		cacheMaxAge = 300
+	}
+	browserMaxAge := 60
+	staleWhileRevalidate := ", stale-while-revalidate=60"
+	// staleLimit 是缓存可以提供内容的总时长上限；有定时变化时不能超过变化时间，包括出错时继续提供的过期内容。
+	staleLimit := -1
+	encodedRevision := strconv.FormatInt(revision, 10)
+	etagSource := append(append([]byte{}, payload...), encodedRevision...)
+	if !next.IsZero() {
+		untilTransition := int(math.Ceil(next.Sub(now).Seconds()))
+		if untilTransition < cacheMaxAge+60 {
+			cacheMaxAge = max(untilTransition, 1)
+			staleWhileRevalidate = ""
+		}
+		browserMaxAge = min(browserMaxAge, max(untilTransition, 1))
+		staleLimit = untilTransition
+		etagSource = append(etagSource, next.UTC().Format(time.RFC3339)...)
+	}
+	etag := payloadETag(etagSource)
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d%s", browserMaxAge, staleIfError(browserMaxAge, staleLimit)))
+	c.Header(
+		"Cloudflare-CDN-Cache-Control",
+		fmt.Sprintf("public, max-age=%d%s%s", cacheMaxAge, staleWhileRevalidate, staleIfError(cacheMaxAge, staleLimit)),
+	)
+	c.Header("ETag", etag)
+	c.Header(syncRevisionHeader, encodedRevision)
+	c.Header("Vary", "Accept-Encoding")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", payload)
+}
+
+// staleIfError 返回出错时允许继续提供过期内容的缓存指令。limit 小于 0 表示没有定时变化，
+// 否则过期内容只能提供到 limit 秒为止，剩余时间不足时不输出该指令。
+func staleIfError(maxAge, limit int) string {
+	seconds := 86400
+	if limit >= 0 {
+		seconds = min(seconds, limit-maxAge)
+	}
+	if seconds <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(", stale-if-error=%d", seconds)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"els-feedback-proxy/internal/config"
+	"els-feedback-proxy/internal/store"
+)
+
+func TestPublicListsSupportIncrementalSync(t *testing.T) {
+	const adminToken = "announcement-admin-token"
+	server := newAnnouncementTestServer(t, adminToken)
+	get := func(path string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		server.engine.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+		return response
+	}
+	create := func(body string) {
+		t.Helper()
+		if response := performAdminRequest(server, http.MethodPost, "/v1/admin/announcements", body, adminToken); response.Code != http.StatusCreated {
+			t.Fatalf("创建公告期望 201，实际 %d body=%s", response.Code, response.Body.String())
+		}
+	}
+
+	create(`{"id":31,"type":"info","title":"第一条","body":"正文","enabled":true}`)
+	full := get("/v1/announcements")
+	revision := full.Header().Get("X-ELS-Revision")
+	if full.Code != http.StatusOK || revision != "1" {
+		t.Fatalf("完整列表应返回同步修订号: %d revision=%q", full.Code, revision)
+	}
+	var records []store.PublicAnnouncement
+	if err := json.Unmarshal(full.Body.Bytes(), &records); err != nil || len(records) != 1 {
+		t.Fatalf("不带 since 时应保持数组响应: %s", full.Body.String())
+	}
+
+	create(`{"id":32,"type":"warning","title":"第二条","body":"正文","enabled":true}`)
+	deltaResponse := get("/v1/announcements?since=" + revision)
+	var delta store.AnnouncementDelta
+	if err := json.Unmarshal(deltaResponse.Body.Bytes(), &delta); err != nil {
+		t.Fatalf("解析增量响应失败: %v body=%s", err, deltaResponse.Body.String())
+	}
+	if delta.Full || delta.Revision != 2 || len(delta.Records) != 1 || delta.Records[0].ID != 32 {
+		t.Fatalf("增量响应应只包含新公告: %+v", delta)
+	}
+	if deltaResponse.Header().Get("ETag") == "" || deltaResponse.Header().Get("Cloudflare-CDN-Cache-Control") == "" {
+		t.Fatalf("增量响应仍应可缓存: %v", deltaResponse.Header())
+	}
+
+	stale := get("/v1/announcements?since=100")
+	if err := json.Unmarshal(stale.Body.Bytes(), &delta); err != nil || !delta.Full || len(delta.Records) != 2 {
+		t.Fatalf("无法增量计算时应回退为完整列表: %s", stale.Body.String())
+	}
+	assertErrorCode(t, get("/v1/announcements?since=-1"), http.StatusBadRequest, "query_invalid")
+
+	surveys := newSurveyTestServer(t, adminToken)
+	surveyResponse := httptest.NewRecorder()
+	surveys.engine.ServeHTTP(surveyResponse, httptest.NewRequest(http.MethodGet, "/v1/surveys?since=0", nil))
+	var surveyDelta store.SurveyDelta
+	if err := json.Unmarshal(surveyResponse.Body.Bytes(), &surveyDelta); err != nil ||
+		!surveyDelta.Full || surveyDelta.Records == nil || surveyResponse.Header().Get("X-ELS-Revision") != "0" {
+		t.Fatalf("空意见征集的同步响应不正确: %s", surveyResponse.Body.String())
+	}
+}
+
+func TestPublicListStaleIfErrorStopsAtNextTransition(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{cfg: config.Config{AnnouncementCacheMaxAge: 300}}
+	now := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	for _, testCase := range []struct {
+		next         time.Time
+		browser, cdn string
+	}{
+		{
+			next:    time.Time{},
+			browser: "public, max-age=60, stale-if-error=86400",
+			cdn:     "public, max-age=300, stale-while-revalidate=60, stale-if-error=86400",
+		},
+		{
+			next:    now.Add(10 * time.Minute),
+			browser: "public, max-age=60, stale-if-error=540",
+			cdn:     "public, max-age=300, stale-while-revalidate=60, stale-if-error=300",
+		},
+		{
+			next:    now.Add(45 * time.Second),
+			browser: "public, max-age=45",
+			cdn:     "public, max-age=45",
+		},
+	} {
+		response := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(response)
+		c.Request = httptest.NewRequest(http.MethodGet, "/v1/announcements", nil)
+		server.writePublicList(c, []byte("[]"), 1, now, testCase.next)
+		if browser := response.Header().Get("Cache-Control"); browser != testCase.browser {
+			t.Fatalf("下次变化 %v 时浏览器缓存头应为 %q，实际 %q", testCase.next, testCase.browser, browser)
+		}
+		if cdn := response.Header().Get("Cloudflare-CDN-Cache-Control"); cdn != testCase.cdn {
+			t.Fatalf("下次变化 %v 时 CDN 缓存头应为 %q，实际 %q", testCase.next, testCase.cdn, cdn)
+		}
+	}
+}
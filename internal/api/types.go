@@ -1,6 +1,9 @@
 package api
 
-import "strings"
+import (
+	"net/http"
+	"strings"
+)
 
 // SubmitIssueRequest 客户端提交反馈请求体
 type SubmitIssueRequest struct {
@@ -89,9 +92,11 @@ func (r *SubmitCommentRequest) Validate() error {
 	return nil
 }
 
+// apiError 是请求校验失败时携带 HTTP 状态与稳定错误码的错误。
 type apiError struct {
 	Message string
-	Code    int
+	Status  int
+	Code    string
 }
 
 func (e apiError) Error() string {
@@ -99,5 +104,5 @@ func (e apiError) Error() string {
 }
 
 func errBadRequest(message string) error {
-	return apiError{Message: message, Code: 400}
+	return apiError{Message: message, Status: http.StatusBadRequest, Code: "request_invalid"}
 }
@@ -2,6 +2,9 @@
 
 const state = {
   records: [],
+  revisions: [],
+  telemetry: [],
+  images: [],
   selectedKey: "",
   toastTimer: 0,
 };
@@ -25,8 +28,12 @@ const elements = {
   enabled: document.querySelector("#record-enabled"),
   language: document.querySelector("#record-language"),
   platform: document.querySelector("#record-platform"),
+  channel: document.querySelector("#record-channel"),
   minBuild: document.querySelector("#record-min-build"),
   maxBuild: document.querySelector("#record-max-build"),
+  rollout: document.querySelector("#record-rollout"),
+  publishAt: document.querySelector("#record-publish-at"),
+  expireAt: document.querySelector("#record-expire-at"),
   title: document.querySelector("#record-title"),
   body: document.querySelector("#record-body"),
   bodyCount: document.querySelector("#body-count"),
@@ -34,9 +41,49 @@ const elements = {
   previewAudience: document.querySelector("#preview-audience"),
   previewTitle: document.querySelector("#preview-title"),
   previewBody: document.querySelector("#preview-body"),
+  previewImage: document.querySelector("#preview-image"),
+  previewActions: document.querySelector("#preview-actions"),
+  bodyFormat: document.querySelector("#record-body-format"),
+  image: document.querySelector("#record-image"),
+  imageAlt: document.querySelector("#record-image-alt"),
+  addActionButton: document.querySelector("#add-action-button"),
+  actionList: document.querySelector("#action-list"),
+  actionTemplate: document.querySelector("#action-template"),
+  addLocalizationButton: document.querySelector("#add-localization-button"),
+  localizationList: document.querySelector("#localization-list"),
+  localizationTemplate: document.querySelector("#localization-template"),
+  missingLanguages: document.querySelector("#missing-languages"),
+  missingLanguagesText: document.querySelector("#missing-languages-text"),
+  historyScope: document.querySelector("#history-scope"),
+  historyList: document.querySelector("#history-list"),
+  telemetryTotal: document.querySelector("#telemetry-total"),
+  telemetryList: document.querySelector("#telemetry-list"),
+  simulateForm: document.querySelector("#simulate-form"),
+  simulatePlatform: document.querySelector("#simulate-platform"),
+  simulateBuild: document.querySelector("#simulate-build"),
+  simulateLocale: document.querySelector("#simulate-locale"),
+  simulateChannel: document.querySelector("#simulate-channel"),
+  simulateInstallation: document.querySelector("#simulate-installation"),
+  simulateAt: document.querySelector("#simulate-at"),
+  simulateDrafts: document.querySelector("#simulate-drafts"),
+  simulateTotal: document.querySelector("#simulate-total"),
+  simulateList: document.querySelector("#simulate-list"),
   toast: document.querySelector("#toast"),
 };
 
+const revisionActionLabels = {
+  create: "创建",
+  update: "更新",
+  delete: "删除",
+  restore: "恢复",
+};
+
+const actionTargetPlaceholders = {
+  url: "https://… 或 itms-apps://…",
+  route: "/settings/…",
+  dismiss: "无需填写",
+};
+
 const typePresentation = {
   info: { label: "普通", className: "type-info" },
   warning: { label: "提醒", className: "type-warning" },
@@ -74,8 +121,14 @@ async function requestJSON(path, options = {}) {
 }
 
 async function loadRecords(preferredKey = state.selectedKey) {
-  const payload = await requestJSON("/v1/admin/announcements");
+  const [payload, history, telemetry] = await Promise.all([
+    requestJSON("/v1/admin/announcements"),
+    requestJSON("/v1/admin/announcements/revisions"),
+    requestJSON("/v1/admin/announcements/telemetry"),
+  ]);
   state.records = payload.records || [];
+  state.revisions = history.revisions || [];
+  state.telemetry = telemetry.records || [];
   renderSummary();
   renderList();
 
@@ -86,13 +139,214 @@ async function loadRecords(preferredKey = state.selectedKey) {
   } else if (state.records.length === 0) {
     resetEditor();
   }
+  renderHistory();
+  renderTelemetry();
+}
+
+function renderHistory() {
+  const scope = elements.historyScope.value;
+  const liveKeys = new Set(state.records.map((record) => record.key));
+  let revisions = state.revisions;
+  if (scope === "selected") {
+    revisions = revisions.filter((revision) => revision.key === state.selectedKey);
+  } else if (scope === "deleted") {
+    const seen = new Set();
+    revisions = revisions.filter((revision) => {
+      if (liveKeys.has(revision.key) || seen.has(revision.key)) {
+        return false;
+      }
+      seen.add(revision.key);
+      return true;
+    });
+  }
+
+  elements.historyList.replaceChildren();
+  if (revisions.length === 0) {
+    const empty = document.createElement("p");
+    empty.className = "empty-state";
+    empty.textContent = scope === "deleted" ? "没有已删除的公告。" : "暂无修订记录。";
+    elements.historyList.append(empty);
+    return;
+  }
+
+  for (const revision of revisions) {
+    const item = document.createElement("div");
+    item.className = "history-item";
+
+    const summary = document.createElement("div");
+    const title = document.createElement("strong");
+    const action = revisionActionLabels[revision.action] || revision.action;
+    title.textContent = `#${revision.id} ${action} · ${revision.record.title}`;
+    const meta = document.createElement("div");
+    meta.className = "history-item-meta";
+    const fields = (revision.changes || []).map((change) => change.field);
+    meta.textContent = [
+      formatUpdatedAt(revision.created_at).replace("更新于 ", ""),
+      revision.author,
+      revision.restored_from ? `来自修订 #${revision.restored_from}` : "",
+      revision.action === "update" && fields.length > 0 ? `变更：${fields.join("、")}` : "",
+    ]
+      .filter(Boolean)
+      .join(" · ");
+    summary.append(title, meta);
+
+    const restore = document.createElement("button");
+    restore.type = "button";
+    restore.className = "button button-secondary";
+    restore.textContent = liveKeys.has(revision.key) ? "恢复到此版本" : "撤销删除";
+    restore.addEventListener("click", () => restoreRevision(revision));
+
+    item.append(summary, restore);
+    elements.historyList.append(item);
+  }
+}
+
+// renderTelemetry 展示当前公告 ID 按平台与构建号聚合的触达次数，同一 ID 的各语言版本合并统计。
+function renderTelemetry() {
+  const record = state.records.find((item) => item.key === state.selectedKey);
+  const buckets = record ? state.telemetry.filter((bucket) => bucket.announcement_id === record.id) : [];
+
+  elements.telemetryList.replaceChildren();
+  const totals = { shown: 0, dismissed: 0, action_tapped: 0 };
+  for (const bucket of buckets) {
+    totals.shown += bucket.shown;
+    totals.dismissed += bucket.dismissed;
+    totals.action_tapped += bucket.action_tapped;
+  }
+  elements.telemetryTotal.textContent = record
+    ? `ID ${record.id} · 展示 ${totals.shown} · 关闭 ${totals.dismissed} · 点击按钮 ${totals.action_tapped}`
+    : "";
+
+  if (buckets.length === 0) {
+    const empty = document.createElement("p");
+    empty.className = "empty-state";
+    empty.textContent = record ? "客户端尚未上报这条公告的展示情况。" : "选择公告后查看触达统计。";
+    elements.telemetryList.append(empty);
+    return;
+  }
+
+  for (const bucket of buckets) {
+    const item = document.createElement("div");
+    item.className = "history-item";
+
+    const summary = document.createElement("div");
+    const title = document.createElement("strong");
+    const build = bucket.app_build === "other"
+      ? "其他构建"
+      : bucket.app_build ? `构建 ${bucket.app_build}` : "未知构建";
+    title.textContent = `${bucket.platform || "未知平台"} · ${build}`;
+    const meta = document.createElement("div");
+    meta.className = "history-item-meta";
+    meta.textContent = `最近上报 ${new Date(bucket.updated_at).toLocaleDateString()}`;
+    summary.append(title, meta);
+
+    const counts = document.createElement("div");
+    counts.className = "history-item-meta";
+    counts.textContent = `展示 ${bucket.shown} · 关闭 ${bucket.dismissed} · 点击按钮 ${bucket.action_tapped}`;
+
+    item.append(summary, counts);
+    elements.telemetryList.append(item);
+  }
+}
+
+// runSimulation 按客户端的筛选规则预览指定平台、构建号、语言与渠道实际会收到的公告和意见征集。
+async function runSimulation(event) {
+  event.preventDefault();
+  const query = new URLSearchParams({ platform: elements.simulatePlatform.value });
+  const optional = {
+    build: elements.simulateBuild.value.trim(),
+    locale: elements.simulateLocale.value.trim(),
+    channel: elements.simulateChannel.value.trim().toLocaleLowerCase(),
+    installation_id: elements.simulateInstallation.value.trim(),
+    at: fromLocalInputValue(elements.simulateAt.value) || "",
+  };
+  for (const [name, value] of Object.entries(optional)) {
+    if (value) {
+      query.set(name, value);
+    }
+  }
+  if (elements.simulateDrafts.checked) {
+    query.set("include_drafts", "true");
+  }
+
+  try {
+    renderSimulation(await requestJSON(`/v1/admin/preview?${query}`));
+  } catch (error) {
+    showToast(error.message, true);
+  }
+}
+
+function renderSimulation(payload) {
+  const announcements = payload.announcements || [];
+  const surveys = payload.surveys || [];
+  const excluded = payload.rollout_excluded || [];
+  elements.simulateTotal.textContent = `公告 ${announcements.length} · 意见征集 ${surveys.length}`;
+  elements.simulateList.replaceChildren();
+
+  const appendItem = (titleText, metaText) => {
+    const item = document.createElement("div");
+    item.className = "history-item";
+    const summary = document.createElement("div");
+    const title = document.createElement("strong");
+    title.textContent = titleText;
+    const meta = document.createElement("div");
+    meta.className = "history-item-meta";
+    meta.textContent = metaText;
+    summary.append(title, meta);
+    item.append(summary);
+    elements.simulateList.append(item);
+  };
+  const describe = (kind, record) => {
+    const parts = [kind, `ID ${record.id}`, record.language || "默认语言"];
+    if (record.rollout_percent) {
+      parts.push(`放量 ${record.rollout_percent}%`);
+    }
+    return parts.join(" · ");
+  };
+
+  for (const record of announcements) {
+    const type = typePresentation[record.type] || typePresentation.info;
+    appendItem(record.title, describe(`${type.label}公告`, record));
+  }
+  for (const record of surveys) {
+    appendItem(record.title, describe("意见征集", record));
+  }
+  for (const record of excluded) {
+    const kind = record.kind === "survey" ? "意见征集" : "公告";
+    appendItem(
+      `${kind} ID ${record.id}（未命中放量）`,
+      `桶号 ${record.bucket}，放量比例 ${record.rollout_percent}%`,
+    );
+  }
+  if (announcements.length === 0 && surveys.length === 0 && excluded.length === 0) {
+    const empty = document.createElement("p");
+    empty.className = "empty-state";
+    empty.textContent = "该客户端不会收到任何公告或意见征集。";
+    elements.simulateList.append(empty);
+  }
+}
+
+async function restoreRevision(revision) {
+  if (!window.confirm(`确定把“${revision.record.title}”恢复到修订 #${revision.id} 的内容吗？`)) {
+    return;
+  }
+  try {
+    const response = await requestJSON(`/v1/admin/announcements/revisions/${revision.id}/restore`, {
+      method: "POST",
+    });
+    await loadRecords(response.record.key);
+    showToast("公告已恢复。");
+  } catch (error) {
+    showToast(error.message, true);
+  }
 }
 
 function renderSummary() {
-  const published = state.records.filter((record) => record.enabled).length;
+  const published = state.records.filter((record) => publishStatus(record) === "发布中").length;
+  const drafts = state.records.filter((record) => !record.enabled).length;
   elements.summaryTotal.textContent = String(state.records.length);
   elements.summaryPublished.textContent = String(published);
-  elements.summaryDrafts.textContent = String(state.records.length - published);
+  elements.summaryDrafts.textContent = String(drafts);
 }
 
 function renderList() {
@@ -101,7 +355,7 @@ function renderList() {
     if (!query) {
       return true;
     }
-    return [record.id, record.title, record.body, record.language, record.platform]
+    return [record.id, record.title, record.body, record.language, record.platform, record.channel]
       .filter(Boolean)
       .some((value) => String(value).toLocaleLowerCase().includes(query));
   });
@@ -137,10 +391,20 @@ function renderList() {
     const meta = document.createElement("span");
     meta.className = "record-card-meta";
     const audience = document.createElement("span");
-    audience.textContent = `${record.language || "全部语言"} · ${platformLabel(record.platform)}`;
+    const translations = (record.localizations || []).length;
+    audience.textContent = [
+      record.language || "全部语言",
+      translations > 0 ? `+${translations} 种翻译` : "",
+      platformLabel(record.platform),
+      record.channel,
+      record.rollout_percent ? `放量 ${record.rollout_percent}%` : "",
+    ]
+      .filter(Boolean)
+      .join(" · ");
+    const status = publishStatus(record);
     const published = document.createElement("span");
-    published.className = `publish-indicator${record.enabled ? " is-published" : ""}`;
-    published.textContent = record.enabled ? "发布中" : "草稿";
+    published.className = `publish-indicator${status === "发布中" ? " is-published" : ""}`;
+    published.textContent = status;
     meta.append(audience, published);
 
     button.append(header, meta);
@@ -160,16 +424,27 @@ function selectRecord(key) {
   elements.enabled.checked = Boolean(record.enabled);
   elements.language.value = record.language || "";
   elements.platform.value = record.platform || "";
+  elements.channel.value = record.channel || "";
   elements.minBuild.value = record.min_build || "";
   elements.maxBuild.value = record.max_build || "";
+  elements.rollout.value = record.rollout_percent || "";
+  elements.publishAt.value = toLocalInputValue(record.publish_at);
+  elements.expireAt.value = toLocalInputValue(record.expire_at);
   elements.title.value = record.title;
   elements.body.value = record.body;
-  elements.editorMode.textContent = record.enabled ? "正在发布" : "草稿";
+  elements.bodyFormat.value = record.body_format || "";
+  selectImage(record.image);
+  renderActions(record.actions || []);
+  renderLocalizations(record.localizations || []);
+  renderMissingLanguages(record.missing_languages || []);
+  elements.editorMode.textContent = publishStatus(record);
   elements.editorTitle.textContent = record.title;
   elements.saveState.textContent = formatUpdatedAt(record.updated_at);
   elements.duplicateButton.disabled = false;
   elements.deleteButton.disabled = false;
   renderList();
+  renderHistory();
+  renderTelemetry();
   updatePreview();
 }
 
@@ -179,12 +454,19 @@ function resetEditor() {
   elements.id.value = String(nextAnnouncementID());
   elements.type.value = "info";
   elements.enabled.checked = false;
+  elements.bodyFormat.value = "";
+  selectImage(null);
+  renderActions([]);
+  renderLocalizations([]);
+  renderMissingLanguages([]);
   elements.editorMode.textContent = "新公告";
   elements.editorTitle.textContent = "编辑内容";
   elements.saveState.textContent = "尚未保存";
   elements.duplicateButton.disabled = true;
   elements.deleteButton.disabled = true;
   renderList();
+  renderHistory();
+  renderTelemetry();
   updatePreview();
   elements.title.focus();
 }
@@ -197,6 +479,8 @@ function duplicateSelected() {
   state.selectedKey = "";
   elements.enabled.checked = false;
   elements.language.value = "";
+  renderLocalizations([]);
+  renderMissingLanguages([]);
   elements.editorMode.textContent = "新语言版本";
   elements.editorTitle.textContent = record.title;
   elements.saveState.textContent = "复制内容尚未保存";
@@ -208,19 +492,160 @@ function duplicateSelected() {
 }
 
 function collectRecord() {
+  const actions = collectActions();
   return {
     id: Number(elements.id.value),
     type: elements.type.value,
     min_build: elements.minBuild.value.trim(),
     max_build: elements.maxBuild.value.trim(),
+    rollout_percent: Number(elements.rollout.value) || 0,
+    publish_at: fromLocalInputValue(elements.publishAt.value),
+    expire_at: fromLocalInputValue(elements.expireAt.value),
     language: elements.language.value.trim(),
     platform: elements.platform.value,
+    channel: elements.channel.value.trim().toLowerCase(),
     title: elements.title.value.trim(),
     body: elements.body.value.trim(),
+    body_format: elements.bodyFormat.value,
+    actions,
+    image: elements.image.value
+      ? { sha256: elements.image.value, alt: elements.imageAlt.value.trim() }
+      : null,
+    localizations: [...elements.localizationList.querySelectorAll(".question-card")].map((card) => ({
+      language: card.querySelector(".localization-language").value.trim(),
+      title: card.querySelector(".localization-title").value.trim(),
+      body: card.querySelector(".localization-body").value.trim(),
+      actions: localizedActions(actions, card.querySelector(".localization-action-labels").value),
+    })),
     enabled: elements.enabled.checked,
   };
 }
 
+function collectActions() {
+  return [...elements.actionList.querySelectorAll(".action-row")].map((row) => {
+    const type = row.querySelector(".action-type").value;
+    const target = row.querySelector(".action-target").value.trim();
+    return {
+      type,
+      label: row.querySelector(".action-label").value.trim(),
+      url: type === "url" ? target : "",
+      route: type === "route" ? target : "",
+    };
+  });
+}
+
+// localizedActions 把翻译的按钮文字套用到默认语言的按钮上；留空时沿用默认语言。
+function localizedActions(actions, labels) {
+  const values = labels
+    .split("|")
+    .map((label) => label.trim())
+    .filter(Boolean);
+  if (values.length === 0) {
+    return [];
+  }
+  return values.map((label, index) => ({ ...(actions[index] || { type: "dismiss" }), label }));
+}
+
+function renderActions(actions) {
+  elements.actionList.replaceChildren();
+  for (const action of actions) {
+    addAction(action);
+  }
+  updatePreview();
+}
+
+function addAction(action = null) {
+  if (elements.actionList.children.length >= 3) {
+    showToast("每条公告最多添加 3 个按钮。", true);
+    return;
+  }
+  const fragment = elements.actionTemplate.content.cloneNode(true);
+  const row = fragment.querySelector(".action-row");
+  const type = row.querySelector(".action-type");
+  const target = row.querySelector(".action-target");
+  type.value = action?.type || "url";
+  row.querySelector(".action-label").value = action?.label || "";
+  target.value = action?.url || action?.route || "";
+  const syncTarget = () => {
+    target.disabled = type.value === "dismiss";
+    target.required = type.value !== "dismiss";
+    target.placeholder = actionTargetPlaceholders[type.value];
+    if (target.disabled) {
+      target.value = "";
+    }
+    updatePreview();
+  };
+  type.addEventListener("change", syncTarget);
+  row.querySelector(".action-label").addEventListener("input", updatePreview);
+  row.querySelector(".remove-action").addEventListener("click", () => {
+    row.remove();
+    updatePreview();
+  });
+  elements.actionList.append(row);
+  syncTarget();
+  if (!action) {
+    row.querySelector(".action-label").focus();
+  }
+}
+
+async function loadImages() {
+  try {
+    const payload = await requestJSON("/v1/admin/distribution");
+    state.images = (payload.records || []).filter(
+      (record) => record.enabled && String(record.content_type).startsWith("image/"),
+    );
+  } catch {
+    // 未启用官方数据时只能发布不带配图的公告。
+    state.images = [];
+  }
+}
+
+function selectImage(image) {
+  const options = [new Option("不使用配图", "")];
+  for (const record of state.images) {
+    options.push(new Option(`${record.name} · ${record.file_name}`, record.sha256));
+  }
+  if (image?.sha256 && !state.images.some((record) => record.sha256 === image.sha256)) {
+    options.push(new Option(`已停用或删除的图片 ${image.sha256.slice(0, 12)}…`, image.sha256));
+  }
+  elements.image.replaceChildren(...options);
+  elements.image.value = image?.sha256 || "";
+  elements.imageAlt.value = image?.alt || "";
+}
+
+function renderLocalizations(localizations) {
+  elements.localizationList.replaceChildren();
+  for (const localization of localizations) {
+    addLocalization(localization);
+  }
+}
+
+function addLocalization(localization = null) {
+  if (!localization && !elements.language.value.trim()) {
+    showToast("请先填写默认语言，再添加翻译。", true);
+    elements.language.focus();
+    return;
+  }
+  const fragment = elements.localizationTemplate.content.cloneNode(true);
+  const card = fragment.querySelector(".question-card");
+  card.querySelector(".localization-language").value = localization?.language || "";
+  card.querySelector(".localization-title").value = localization?.title || "";
+  card.querySelector(".localization-body").value = localization?.body || "";
+  card.querySelector(".localization-action-labels").value = (localization?.actions || [])
+    .map((action) => action.label)
+    .join(" | ");
+  card.querySelector(".remove-localization").addEventListener("click", () => card.remove());
+  elements.localizationList.append(card);
+  if (!localization) {
+    card.querySelector(".localization-language").focus();
+  }
+}
+
+function renderMissingLanguages(languages) {
+  elements.missingLanguages.hidden = languages.length === 0;
+  elements.missingLanguagesText.textContent = `缺少以下语言：${languages.join("、")}。包含翻译的公告需要补齐后才能发布。`;
+}
+
 async function saveRecord(event) {
   event.preventDefault();
   if (!elements.form.reportValidity()) {
@@ -285,10 +710,99 @@ function updatePreview() {
   elements.previewType.className = `type-badge ${presentation.className}`;
   elements.previewAudience.textContent = `${elements.language.value.trim() || "全部语言"} · ${platformLabel(elements.platform.value)}`;
   elements.previewTitle.textContent = elements.title.value.trim() || "公告标题预览";
-  elements.previewBody.textContent = elements.body.value.trim() || "公告正文会显示在这里。";
+  renderPreviewBody(elements.body.value.trim() || "公告正文会显示在这里。", elements.bodyFormat.value);
+  renderPreviewExtras();
   elements.bodyCount.textContent = String(elements.body.value.length);
 }
 
+function renderPreviewExtras() {
+  const image = state.images.find((record) => record.sha256 === elements.image.value);
+  elements.previewImage.hidden = !elements.image.value;
+  elements.previewImage.textContent = image
+    ? `配图：${image.file_name}${elements.imageAlt.value.trim() ? `（${elements.imageAlt.value.trim()}）` : ""}`
+    : "配图不可用，公开接口不会下发该图片";
+
+  const actions = collectActions();
+  elements.previewActions.hidden = actions.length === 0;
+  elements.previewActions.replaceChildren(
+    ...actions.map((action, index) => {
+      const button = document.createElement("span");
+      button.className = `button ${index === 0 && action.type !== "dismiss" ? "button-primary" : "button-secondary"}`;
+      button.textContent = action.label || "按钮";
+      return button;
+    }),
+  );
+}
+
+// renderPreviewBody 按客户端支持的 Markdown 子集生成预览，全部通过 DOM 节点构建，不解析 HTML。
+function renderPreviewBody(text, format) {
+  elements.previewBody.replaceChildren();
+  if (format !== "markdown") {
+    const paragraph = document.createElement("p");
+    paragraph.textContent = text;
+    elements.previewBody.append(paragraph);
+    return;
+  }
+
+  for (const block of text.split(/\n{2,}/)) {
+    const lines = block.split("\n");
+    const heading = /^(#{1,3})\s+(.*)$/.exec(lines[0]);
+    if (heading && lines.length === 1) {
+      const node = document.createElement("h4");
+      appendInlineMarkdown(node, heading[2]);
+      elements.previewBody.append(node);
+    } else if (lines.every((line) => /^\s*[-*]\s+/.test(line))) {
+      elements.previewBody.append(markdownList("ul", lines, /^\s*[-*]\s+/));
+    } else if (lines.every((line) => /^\s*\d+\.\s+/.test(line))) {
+      elements.previewBody.append(markdownList("ol", lines, /^\s*\d+\.\s+/));
+    } else {
+      const paragraph = document.createElement("p");
+      appendInlineMarkdown(paragraph, lines.join("\n"));
+      elements.previewBody.append(paragraph);
+    }
+  }
+}
+
+function markdownList(tagName, lines, marker) {
+  const list = document.createElement(tagName);
+  for (const line of lines) {
+    const item = document.createElement("li");
+    appendInlineMarkdown(item, line.replace(marker, ""));
+    list.append(item);
+  }
+  return list;
+}
+
+function appendInlineMarkdown(parent, text) {
+  const pattern = /(\*\*[^*]+\*\*|\*[^*]+\*|`[^`]+`|\[[^\]]+\]\(https:\/\/[^)\s]+\))/g;
+  let cursor = 0;
+  for (const match of text.matchAll(pattern)) {
+    parent.append(text.slice(cursor, match.index));
+    const token = match[0];
+    let node;
+    if (token.startsWith("**")) {
+      node = document.createElement("strong");
+      node.textContent = token.slice(2, -2);
+    } else if (token.startsWith("*")) {
+      node = document.createElement("em");
+      node.textContent = token.slice(1, -1);
+    } else if (token.startsWith("`")) {
+      node = document.createElement("code");
+      node.textContent = token.slice(1, -1);
+    } else {
+      const link = /^\[([^\]]+)\]\((.+)\)$/.exec(token);
+      node = document.createElement("a");
+      node.textContent = link[1];
+      node.href = link[2];
+      node.target = "_blank";
+      node.rel = "noopener noreferrer";
+    }
+    parent.append(node);
+    cursor = match.index + token.length;
+  }
+  parent.append(text.slice(cursor));
+}
+
 function nextAnnouncementID() {
   const now = new Date();
   const prefix = [
@@ -305,6 +819,39 @@ function nextAnnouncementID() {
   return `${prefix}${String(suffix).padStart(2, "0")}`;
 }
 
+function publishStatus(record) {
+  if (!record.enabled) {
+    return "草稿";
+  }
+  const now = Date.now();
+  if (record.publish_at && new Date(record.publish_at).getTime() > now) {
+    return "待上线";
+  }
+  if (record.expire_at && new Date(record.expire_at).getTime() <= now) {
+    return "已下线";
+  }
+  return "发布中";
+}
+
+function toLocalInputValue(value) {
+  if (!value) {
+    return "";
+  }
+  const date = new Date(value);
+  if (Number.isNaN(date.getTime())) {
+    return "";
+  }
+  const local = new Date(date.getTime() - date.getTimezoneOffset() * 60000);
+  return local.toISOString().slice(0, 16);
+}
+
+function fromLocalInputValue(value) {
+  if (!value) {
+    return null;
+  }
+  return new Date(value).toISOString();
+}
+
 function platformLabel(platform) {
   if (platform === "iOS") {
     return "仅 iOS";
@@ -346,6 +893,10 @@ elements.newButton.addEventListener("click", resetEditor);
 elements.duplicateButton.addEventListener("click", duplicateSelected);
 elements.deleteButton.addEventListener("click", deleteSelected);
 elements.search.addEventListener("input", renderList);
+elements.historyScope.addEventListener("change", renderHistory);
+elements.simulateForm.addEventListener("submit", runSimulation);
+elements.addLocalizationButton.addEventListener("click", () => addLocalization());
+elements.addActionButton.addEventListener("click", () => addAction());
 
 for (const input of [
   elements.type,
@@ -353,6 +904,9 @@ for (const input of [
   elements.platform,
   elements.title,
   elements.body,
+  elements.bodyFormat,
+  elements.image,
+  elements.imageAlt,
 ]) {
   input.addEventListener("input", updatePreview);
   input.addEventListener("change", updatePreview);
@@ -365,6 +919,8 @@ document.addEventListener("keydown", (event) => {
   }
 });
 
-loadRecords().catch((error) => {
-  showToast(error.message, true);
-});
+loadImages()
+  .then(() => loadRecords())
+  .catch((error) => {
+    showToast(error.message, true);
+  });
@@ -4,6 +4,7 @@ const state = {
   records: [],
   selectedKey: "",
   responseCount: 0,
+  resultsKey: "",
   definitionLocked: false,
   toastTimer: 0,
 };
@@ -20,6 +21,13 @@ const elements = {
   questionList: document.querySelector("#question-list"),
   questionTemplate: document.querySelector("#question-template"),
   optionTemplate: document.querySelector("#option-template"),
+  conditionTemplate: document.querySelector("#condition-template"),
+  addLocalizationButton: document.querySelector("#add-localization-button"),
+  localizationList: document.querySelector("#localization-list"),
+  localizationTemplate: document.querySelector("#localization-template"),
+  mergeControls: document.querySelector("#merge-controls"),
+  mergeSource: document.querySelector("#merge-source"),
+  mergeButton: document.querySelector("#merge-button"),
   editorMode: document.querySelector("#editor-mode"),
   editorTitle: document.querySelector("#editor-title"),
   saveState: document.querySelector("#save-state"),
@@ -33,11 +41,29 @@ const elements = {
   platform: document.querySelector("#record-platform"),
   minBuild: document.querySelector("#record-min-build"),
   maxBuild: document.querySelector("#record-max-build"),
+  rollout: document.querySelector("#record-rollout"),
+  opensAt: document.querySelector("#record-opens-at"),
+  closesAt: document.querySelector("#record-closes-at"),
+  maxResponses: document.querySelector("#record-max-responses"),
+  onePerDevice: document.querySelector("#record-one-per-device"),
   title: document.querySelector("#record-title"),
   description: document.querySelector("#record-description"),
   resultsSection: document.querySelector("#results-section"),
   responseCount: document.querySelector("#response-count"),
+  quotaProgress: document.querySelector("#quota-progress"),
   environmentSummary: document.querySelector("#environment-summary"),
+  filterPlatform: document.querySelector("#filter-platform"),
+  filterLanguage: document.querySelector("#filter-language"),
+  filterMinBuild: document.querySelector("#filter-min-build"),
+  filterMaxBuild: document.querySelector("#filter-max-build"),
+  filterFrom: document.querySelector("#filter-from"),
+  filterTo: document.querySelector("#filter-to"),
+  filterVersion: document.querySelector("#filter-version"),
+  filterBreakdown: document.querySelector("#filter-breakdown"),
+  crossRow: document.querySelector("#cross-row"),
+  crossColumn: document.querySelector("#cross-column"),
+  crossTab: document.querySelector("#cross-tab"),
+  exportLinks: document.querySelectorAll(".results-export a"),
   resultsList: document.querySelector("#results-list"),
   toast: document.querySelector("#toast"),
 };
@@ -90,7 +116,7 @@ async function loadRecords(preferredKey = state.selectedKey) {
 function renderSummary() {
   elements.summaryTotal.textContent = String(state.records.length);
   elements.summaryPublished.textContent = String(
-    state.records.filter((record) => record.enabled).length,
+    state.records.filter((record) => record.enabled && !record.closed_reason).length,
   );
   elements.summaryResponses.textContent = String(state.responseCount);
 }
@@ -101,7 +127,14 @@ function renderList() {
     if (!query) {
       return true;
     }
-    return [record.id, record.title, record.description, record.language, record.platform]
+    return [
+      record.id,
+      record.title,
+      record.description,
+      record.language,
+      record.platform,
+      ...(record.localizations || []).flatMap((localization) => [localization.language, localization.title]),
+    ]
       .filter(Boolean)
       .some((value) => String(value).toLocaleLowerCase().includes(query));
   });
@@ -138,10 +171,20 @@ function renderList() {
     const meta = document.createElement("span");
     meta.className = "record-card-meta";
     const audience = document.createElement("span");
-    audience.textContent = `${record.language || "全部语言"} · ${platformLabel(record.platform)}`;
+    const translations = (record.localizations || []).length;
+    audience.textContent = [
+      record.language || "全部语言",
+      translations > 0 ? `+${translations} 种翻译` : "",
+      platformLabel(record.platform),
+      record.rollout_percent ? `放量 ${record.rollout_percent}%` : "",
+      record.max_responses ? `答卷 ${record.response_count || 0}/${record.max_responses}` : "",
+      record.version > 1 ? `第 ${record.version} 版` : "",
+    ]
+      .filter(Boolean)
+      .join(" · ");
     const published = document.createElement("span");
-    published.className = `publish-indicator${record.enabled ? " is-published" : ""}`;
-    published.textContent = record.enabled ? "征集中" : "草稿";
+    published.className = `publish-indicator${record.enabled && !record.closed_reason ? " is-published" : ""}`;
+    published.textContent = surveyStatus(record);
     meta.append(audience, published);
 
     button.append(header, meta);
@@ -162,14 +205,21 @@ async function selectRecord(key) {
   elements.platform.value = record.platform || "";
   elements.minBuild.value = record.min_build || "";
   elements.maxBuild.value = record.max_build || "";
+  elements.rollout.value = record.rollout_percent || "";
+  elements.opensAt.value = toLocalInputValue(record.opens_at);
+  elements.closesAt.value = toLocalInputValue(record.closes_at);
+  elements.maxResponses.value = record.max_responses || "";
+  elements.onePerDevice.checked = Boolean(record.one_response_per_device);
   elements.title.value = record.title;
   elements.description.value = record.description || "";
-  elements.editorMode.textContent = record.enabled ? "正在征集" : "草稿";
+  elements.editorMode.textContent = surveyStatus(record);
   elements.editorTitle.textContent = record.title;
   elements.saveState.textContent = formatUpdatedAt(record.updated_at);
   elements.duplicateButton.disabled = false;
   elements.deleteButton.disabled = false;
   renderQuestions(record.questions || []);
+  renderLocalizations(record.localizations || []);
+  renderMergeChoices(record);
   renderList();
   await loadResults(record.key);
 }
@@ -188,6 +238,8 @@ function resetEditor() {
   elements.resultsSection.hidden = true;
   renderQuestions([]);
   addQuestion();
+  renderLocalizations([]);
+  renderMergeChoices(null);
   setDefinitionLocked(false);
   renderList();
   renderSummary();
@@ -203,6 +255,8 @@ function duplicateSelected() {
   state.responseCount = 0;
   elements.enabled.checked = false;
   elements.language.value = "";
+  renderLocalizations([]);
+  renderMergeChoices(null);
   elements.editorMode.textContent = "新语言版本";
   elements.saveState.textContent = "复制内容尚未保存";
   elements.duplicateButton.disabled = true;
@@ -231,10 +285,18 @@ function addQuestion(question = null) {
   const fragment = elements.questionTemplate.content.cloneNode(true);
   const card = fragment.querySelector(".question-card");
   card.dataset.questionId = question?.id || newIdentifier("q");
+  // 已保存的题目在收到答卷后只能修改文字，新增的题目不受限制。
+  card.dataset.saved = String(Boolean(question));
   card.querySelector(".question-title").value = question?.question || "";
   card.querySelector(".question-type").value = question?.type || "single_select";
   card.querySelector(".question-required").checked = Boolean(question?.required);
   card.querySelector(".question-other").checked = Boolean(question?.allow_other);
+  card.querySelector(".question-min-length").value = question?.min_length || "";
+  card.querySelector(".question-max-length").value = question?.max_length || "";
+  card.querySelector(".question-scale").value = question?.scale || 5;
+  card.querySelector(".question-min").value = question?.min ?? "";
+  card.querySelector(".question-max").value = question?.max ?? "";
+  card.querySelector(".question-type").addEventListener("change", () => updateQuestionType(card));
 
   card.querySelector(".remove-question").addEventListener("click", () => {
     if (elements.questionList.children.length === 1) {
@@ -245,15 +307,143 @@ function addQuestion(question = null) {
     updateQuestionNumbers();
   });
   card.querySelector(".add-option").addEventListener("click", () => addOption(card));
+  card.querySelector(".add-condition").addEventListener("click", () => addCondition(card));
 
   elements.questionList.append(card);
   const options = question?.options?.length ? question.options : [null, null];
   for (const option of options) {
     addOption(card, option);
   }
+  for (const condition of question?.show_if || []) {
+    addCondition(card, condition);
+  }
+  updateQuestionType(card);
   updateQuestionNumbers();
 }
 
+function updateQuestionType(card) {
+  const type = card.querySelector(".question-type").value;
+  for (const section of card.querySelectorAll(".question-settings")) {
+    section.hidden = !section.dataset.types.split(" ").includes(type);
+  }
+  const isSelect = type === "single_select" || type === "multi_select";
+  card.querySelector(".question-other-field").hidden = !isSelect;
+  if (!isSelect) {
+    card.querySelector(".question-other").checked = false;
+  }
+  // 隐藏的选项不参与表单校验，切回带选项的题型时再恢复必填。
+  for (const input of card.querySelectorAll(".option-label")) {
+    input.required = usesOptions(type);
+  }
+}
+
+function usesOptions(type) {
+  return type === "single_select" || type === "multi_select" || type === "ranking";
+}
+
+function addCondition(card, condition = null) {
+  const list = card.querySelector(".condition-list");
+  if (list.children.length >= 5) {
+    showToast("每道题最多添加 5 个显示条件。", true);
+    return;
+  }
+
+  const fragment = elements.conditionTemplate.content.cloneNode(true);
+  const row = fragment.querySelector(".condition-row");
+  row.dataset.questionId = condition?.question_id || "";
+  row.dataset.optionIds = (condition?.option_ids || []).join(" ");
+  row.querySelector(".condition-min").value = condition?.min ?? "";
+  row.querySelector(".condition-max").value = condition?.max ?? "";
+  row.querySelector(".condition-question").addEventListener("change", (event) => {
+    row.dataset.questionId = event.target.value;
+    row.dataset.optionIds = "";
+    refreshConditionRow(card, row);
+  });
+  row.querySelector(".condition-options").addEventListener("change", (event) => {
+    row.dataset.optionIds = [...event.target.selectedOptions].map((option) => option.value).join(" ");
+    refreshConditionRow(card, row);
+  });
+  row.querySelector(".remove-condition").addEventListener("click", () => {
+    row.remove();
+    refreshConditions();
+  });
+  list.append(row);
+  refreshConditionRow(card, row);
+}
+
+// refreshConditions 在题目、题型或选项变化后重建条件中的题目与选项列表。
+function refreshConditions() {
+  [...elements.questionList.children].forEach((card, index) => {
+    const rows = card.querySelectorAll(".condition-row");
+    card.querySelector(".condition-section").hidden = index === 0 && rows.length === 0;
+    for (const row of rows) {
+      refreshConditionRow(card, row);
+    }
+  });
+}
+
+function refreshConditionRow(card, row) {
+  const cards = [...elements.questionList.children];
+  const earlier = cards.slice(0, Math.max(0, cards.indexOf(card)));
+  const questionSelect = row.querySelector(".condition-question");
+  questionSelect.replaceChildren(
+    new Option("选择前面的题目", ""),
+    ...earlier.map((source, index) => new Option(
+      `问题 ${index + 1}：${source.querySelector(".question-title").value.trim() || "未命名"}`,
+      source.dataset.questionId,
+    )),
+  );
+  // 条件只能引用前面的题目；引用失效时清空选择，由表单校验提示重新选择。
+  const source = earlier.find((candidate) => candidate.dataset.questionId === row.dataset.questionId);
+  questionSelect.value = source ? source.dataset.questionId : "";
+
+  const type = source?.querySelector(".question-type").value || "";
+  const isSelect = type === "single_select" || type === "multi_select";
+  const isValue = type === "rating" || type === "nps" || type === "number";
+  const selected = row.dataset.optionIds.split(" ").filter(Boolean);
+  const optionSelect = row.querySelector(".condition-options");
+  optionSelect.replaceChildren(
+    ...(isSelect ? [...source.querySelectorAll(".option-row")] : []).map((optionRow) => new Option(
+      optionRow.querySelector(".option-label").value.trim() || "未命名选项",
+      optionRow.dataset.optionId,
+      false,
+      selected.includes(optionRow.dataset.optionId),
+    )),
+  );
+  optionSelect.hidden = !isSelect;
+  row.querySelector(".condition-min").hidden = !isValue;
+  row.querySelector(".condition-max").hidden = !isValue;
+
+  let hint = "";
+  if (isSelect) {
+    hint = optionSelect.selectedOptions.length > 0 ? "选中任意一项时显示" : "作答后显示";
+  } else if (isValue) {
+    hint = "回答在范围内时显示";
+  } else if (source) {
+    hint = "作答后显示";
+  }
+  row.querySelector(".condition-hint").textContent = hint;
+}
+
+function collectConditions(card) {
+  return [...card.querySelectorAll(".condition-row")].map((row) => {
+    const condition = { question_id: row.querySelector(".condition-question").value };
+    const optionSelect = row.querySelector(".condition-options");
+    if (!optionSelect.hidden && optionSelect.selectedOptions.length > 0) {
+      condition.option_ids = [...optionSelect.selectedOptions].map((option) => option.value);
+    }
+    if (!row.querySelector(".condition-min").hidden) {
+      for (const field of ["min", "max"]) {
+        const value = row.querySelector(`.condition-${field}`).value;
+        if (value !== "") {
+          condition[field] = Number(value);
+        }
+      }
+    }
+    return condition;
+  });
+}
+
 function addOption(card, option = null) {
   const list = card.querySelector(".option-list");
   if (list.children.length >= 20) {
@@ -264,7 +454,11 @@ function addOption(card, option = null) {
   const fragment = elements.optionTemplate.content.cloneNode(true);
   const row = fragment.querySelector(".option-row");
   row.dataset.optionId = option?.id || newIdentifier("o");
+  row.dataset.saved = String(Boolean(option));
   row.querySelector(".option-label").value = option?.label || "";
+  row.querySelector(".option-label").required = usesOptions(
+    card.querySelector(".question-type").value,
+  );
   row.querySelector(".remove-option").addEventListener("click", () => {
     if (list.children.length === 1) {
       showToast("至少保留一个选项。", true);
@@ -272,6 +466,8 @@ function addOption(card, option = null) {
     }
     row.remove();
     updateOptionNumbers(card);
+    refreshConditions();
+    refreshLocalizations();
   });
   list.append(row);
   updateOptionNumbers(card);
@@ -282,6 +478,8 @@ function updateQuestionNumbers() {
     card.querySelector(".question-number").textContent = `问题 ${index + 1}`;
     updateOptionNumbers(card);
   });
+  refreshConditions();
+  refreshLocalizations();
 }
 
 function updateOptionNumbers(card) {
@@ -290,18 +488,168 @@ function updateOptionNumbers(card) {
   });
 }
 
-function collectRecord() {
-  const questions = [...elements.questionList.querySelectorAll(".question-card")].map((card) => ({
-    id: card.dataset.questionId,
-    question: card.querySelector(".question-title").value.trim(),
-    type: card.querySelector(".question-type").value,
-    allow_other: card.querySelector(".question-other").checked,
-    required: card.querySelector(".question-required").checked,
-    options: [...card.querySelectorAll(".option-row")].map((row) => ({
-      id: row.dataset.optionId,
-      label: row.querySelector(".option-label").value.trim(),
-    })),
+function renderLocalizations(localizations) {
+  elements.localizationList.replaceChildren();
+  for (const localization of localizations) {
+    addLocalization(localization);
+  }
+}
+
+function addLocalization(localization = null) {
+  if (!localization && !elements.language.value.trim()) {
+    showToast("请先填写默认语言，再添加翻译。", true);
+    elements.language.focus();
+    return;
+  }
+  const fragment = elements.localizationTemplate.content.cloneNode(true);
+  const card = fragment.querySelector(".question-card");
+  card.querySelector(".localization-language").value = localization?.language || "";
+  card.querySelector(".localization-title").value = localization?.title || "";
+  card.querySelector(".localization-description").value = localization?.description || "";
+  card.querySelector(".remove-localization").addEventListener("click", () => card.remove());
+  elements.localizationList.append(card);
+  renderLocalizationQuestions(card, translatedTexts(localization?.questions || []));
+  if (!localization) {
+    card.querySelector(".localization-language").focus();
+  }
+}
+
+// translatedTexts 把翻译中的题目与选项文字按 ID 索引，供重建翻译表单时保留已填写的内容。
+function translatedTexts(questions) {
+  return new Map(
+    questions.map((question) => [
+      question.id,
+      {
+        question: question.question || "",
+        options: new Map((question.options || []).map((option) => [option.id, option.label])),
+      },
+    ]),
+  );
+}
+
+// refreshLocalizations 在题目或选项变化后按当前题目重建每种翻译的题目文字输入框。
+function refreshLocalizations() {
+  for (const card of elements.localizationList.querySelectorAll(".question-card")) {
+    renderLocalizationQuestions(card, translatedTexts(collectLocalizedQuestions(card)));
+  }
+}
+
+// renderLocalizationQuestions 为每道题和每个选项生成译文输入框，占位文字为默认语言的原文，留空表示沿用原文。
+function renderLocalizationQuestions(card, translated) {
+  const container = card.querySelector(".localization-questions");
+  container.replaceChildren();
+  [...elements.questionList.querySelectorAll(".question-card")].forEach((questionCard, index) => {
+    const existing = translated.get(questionCard.dataset.questionId);
+    const section = document.createElement("div");
+    section.className = "localization-question";
+    section.dataset.questionId = questionCard.dataset.questionId;
+
+    const label = document.createElement("label");
+    const caption = document.createElement("span");
+    caption.textContent = `问题 ${index + 1}`;
+    const input = document.createElement("input");
+    input.className = "localization-question-text";
+    input.type = "text";
+    input.maxLength = 500;
+    input.placeholder = questionCard.querySelector(".question-title").value.trim() || "沿用默认语言";
+    input.value = existing?.question || "";
+    label.append(caption, input);
+    section.append(label);
+
+    if (usesOptions(questionCard.querySelector(".question-type").value)) {
+      [...questionCard.querySelectorAll(".option-row")].forEach((optionRow, optionIndex) => {
+        const row = document.createElement("div");
+        row.className = "localization-option";
+        row.dataset.optionId = optionRow.dataset.optionId;
+        const number = document.createElement("span");
+        number.className = "option-index";
+        number.textContent = String(optionIndex + 1);
+        const optionInput = document.createElement("input");
+        optionInput.className = "localization-option-label";
+        optionInput.type = "text";
+        optionInput.maxLength = 200;
+        optionInput.placeholder = optionRow.querySelector(".option-label").value.trim() || "沿用默认语言";
+        optionInput.value = existing?.options.get(optionRow.dataset.optionId) || "";
+        row.append(number, optionInput);
+        section.append(row);
+      });
+    }
+    container.append(section);
+  });
+}
+
+function collectLocalizations() {
+  return [...elements.localizationList.querySelectorAll(".question-card")].map((card) => ({
+    language: card.querySelector(".localization-language").value.trim(),
+    title: card.querySelector(".localization-title").value.trim(),
+    description: card.querySelector(".localization-description").value.trim(),
+    questions: collectLocalizedQuestions(card),
   }));
+}
+
+// collectLocalizedQuestions 只收集填写了译文的题目与选项，其余沿用默认语言。
+function collectLocalizedQuestions(card) {
+  const questions = [];
+  for (const section of card.querySelectorAll(".localization-question")) {
+    const question = {
+      id: section.dataset.questionId,
+      question: section.querySelector(".localization-question-text").value.trim(),
+      options: [...section.querySelectorAll(".localization-option")]
+        .map((row) => ({
+          id: row.dataset.optionId,
+          label: row.querySelector(".localization-option-label").value.trim(),
+        }))
+        .filter((option) => option.label),
+    };
+    if (question.question || question.options.length > 0) {
+      questions.push(question);
+    }
+  }
+  return questions;
+}
+
+// renderMergeChoices 列出同一编号、尚未包含翻译的其他语言版本，可将其并入当前征集。
+function renderMergeChoices(record) {
+  const candidates = record
+    ? state.records.filter(
+        (item) =>
+          item.key !== record.key &&
+          item.id === record.id &&
+          item.language &&
+          record.language &&
+          !(item.localizations || []).length,
+      )
+    : [];
+  elements.mergeSource.replaceChildren(
+    ...candidates.map((item) => new Option(`${item.language} · ${item.title}`, item.key)),
+  );
+  elements.mergeControls.hidden = candidates.length === 0;
+}
+
+async function mergeSelected() {
+  const record = state.records.find((item) => item.key === state.selectedKey);
+  const source = state.records.find((item) => item.key === elements.mergeSource.value);
+  if (!record || !source) {
+    return;
+  }
+  if (!window.confirm(`确定把“${source.title}”（${source.language}）并入当前征集吗？它的答卷将合并统计，原征集会被删除。`)) {
+    return;
+  }
+
+  try {
+    await requestJSON(`/v1/admin/surveys/${encodeURIComponent(record.key)}/merge`, {
+      method: "POST",
+      body: JSON.stringify({ source_key: source.key }),
+    });
+    await loadRecords(record.key);
+    showToast(`${source.language} 版本已并入为翻译。`);
+  } catch (error) {
+    showToast(error.message, true);
+  }
+}
+
+function collectRecord() {
+  const questions = [...elements.questionList.querySelectorAll(".question-card")].map(collectQuestion);
 
   return {
     id: Number(elements.id.value),
@@ -309,13 +657,54 @@ function collectRecord() {
     description: elements.description.value.trim(),
     min_build: elements.minBuild.value.trim(),
     max_build: elements.maxBuild.value.trim(),
+    rollout_percent: Number(elements.rollout.value) || 0,
+    opens_at: fromLocalInputValue(elements.opensAt.value),
+    closes_at: fromLocalInputValue(elements.closesAt.value),
+    max_responses: Number(elements.maxResponses.value) || 0,
+    one_response_per_device: elements.onePerDevice.checked,
     language: elements.language.value.trim(),
     platform: elements.platform.value,
     questions,
+    localizations: collectLocalizations(),
     enabled: elements.enabled.checked,
   };
 }
 
+function collectQuestion(card) {
+  const type = card.querySelector(".question-type").value;
+  const question = {
+    id: card.dataset.questionId,
+    question: card.querySelector(".question-title").value.trim(),
+    type,
+    allow_other: card.querySelector(".question-other").checked,
+    required: card.querySelector(".question-required").checked,
+    options: [],
+    show_if: collectConditions(card),
+  };
+  if (usesOptions(type)) {
+    question.options = [...card.querySelectorAll(".option-row")].map((row) => ({
+      id: row.dataset.optionId,
+      label: row.querySelector(".option-label").value.trim(),
+    }));
+  }
+  if (type === "text") {
+    question.min_length = Number(card.querySelector(".question-min-length").value) || 0;
+    question.max_length = Number(card.querySelector(".question-max-length").value) || 0;
+  }
+  if (type === "rating") {
+    question.scale = Number(card.querySelector(".question-scale").value) || 5;
+  }
+  if (type === "number") {
+    for (const field of ["min", "max"]) {
+      const value = card.querySelector(`.question-${field}`).value;
+      if (value !== "") {
+        question[field] = Number(value);
+      }
+    }
+  }
+  return question;
+}
+
 async function saveRecord(event) {
   event.preventDefault();
   if (!elements.form.reportValidity()) {
@@ -366,45 +755,166 @@ async function deleteSelected() {
 }
 
 async function loadResults(key) {
+  if (state.resultsKey !== key) {
+    state.resultsKey = key;
+    elements.crossRow.value = "";
+    elements.crossColumn.value = "";
+    elements.filterVersion.value = "";
+    elements.filterBreakdown.value = "";
+  }
+  const query = resultsQuery();
   const payload = await requestJSON(
-    `/v1/admin/surveys/${encodeURIComponent(key)}/results`,
+    `/v1/admin/surveys/${encodeURIComponent(key)}/aggregate${query ? `?${query}` : ""}`,
   );
   if (state.selectedKey !== key) {
     return;
   }
 
-  state.responseCount = payload.response_count || 0;
-  elements.responseCount.textContent = `${state.responseCount} 份`;
+  state.responseCount = payload.total_count || 0;
+  elements.responseCount.textContent = [
+    payload.response_count === state.responseCount
+      ? `${state.responseCount} 份`
+      : `${payload.response_count} / ${state.responseCount} 份`,
+    payload.flagged_count > 0 ? `${payload.flagged_count} 份被审核标记` : "",
+  ].filter(Boolean).join(" · ");
   elements.resultsSection.hidden = false;
+  renderQuotaProgress(state.records.find((record) => record.key === key));
   setDefinitionLocked(state.responseCount > 0);
-  renderResults(payload.survey, payload.responses || []);
+  renderVersionChoices(payload.survey_versions || []);
+  elements.filterBreakdown.closest("label").hidden = !(payload.survey.localizations || []).length;
+  renderCrossTabChoices(payload.survey);
+  updateExportLinks(key);
+  renderResults(payload);
   renderSummary();
 }
 
-function renderResults(survey, responses) {
+// renderQuotaProgress 显示收满答卷的进度，未设置答卷上限时隐藏。
+function renderQuotaProgress(record) {
+  const limit = record?.max_responses || 0;
+  elements.quotaProgress.hidden = limit === 0;
+  if (limit === 0) {
+    return;
+  }
+  const progress = elements.quotaProgress.querySelector("progress");
+  progress.max = limit;
+  progress.value = Math.min(state.responseCount, limit);
+  elements.quotaProgress.querySelector("small").textContent =
+    `已收集 ${state.responseCount} / ${limit} 份（${Math.floor((state.responseCount / limit) * 100)}%）`;
+}
+
+function resultsQuery() {
+  const query = new URLSearchParams();
+  const fields = [
+    ["platform", elements.filterPlatform.value],
+    ["language", elements.filterLanguage.value.trim()],
+    ["min_build", elements.filterMinBuild.value],
+    ["max_build", elements.filterMaxBuild.value],
+    ["survey_version", elements.filterVersion.value],
+    ["breakdown", elements.filterBreakdown.value],
+    ["cross_row", elements.crossRow.value],
+    ["cross_column", elements.crossColumn.value],
+  ];
+  for (const [name, value] of fields) {
+    if (value) {
+      query.set(name, value);
+    }
+  }
+  // 日期按浏览器所在时区的整天计算，结束日期当天也包含在内。
+  if (elements.filterFrom.value) {
+    query.set("from", new Date(`${elements.filterFrom.value}T00:00:00`).toISOString());
+  }
+  if (elements.filterTo.value) {
+    const end = new Date(`${elements.filterTo.value}T00:00:00`);
+    end.setDate(end.getDate() + 1);
+    query.set("to", end.toISOString());
+  }
+  if (!query.has("cross_row") || !query.has("cross_column")) {
+    query.delete("cross_row");
+    query.delete("cross_column");
+  }
+  return query.toString();
+}
+
+// updateExportLinks 让导出链接使用与统计相同的筛选条件，交叉表与语言拆分参数对导出没有意义。
+function updateExportLinks(key) {
+  const query = new URLSearchParams(resultsQuery());
+  query.delete("cross_row");
+  query.delete("cross_column");
+  query.delete("breakdown");
+  for (const link of elements.exportLinks) {
+    query.set("format", link.dataset.format);
+    link.href = `/v1/admin/surveys/${encodeURIComponent(key)}/export?${query}`;
+  }
+}
+
+async function refreshResults() {
+  if (!state.selectedKey) {
+    return;
+  }
+  try {
+    await loadResults(state.selectedKey);
+  } catch (error) {
+    showToast(error.message, true);
+  }
+}
+
+// renderVersionChoices 列出征集的各个版本及答卷数，只有一个版本时不需要筛选。
+function renderVersionChoices(versions) {
+  const current = elements.filterVersion.value;
+  elements.filterVersion.replaceChildren(
+    new Option("全部版本", ""),
+    ...versions.map((item) => new Option(`第 ${item.version} 版（${item.count} 份）`, String(item.version))),
+  );
+  elements.filterVersion.value = versions.some((item) => String(item.version) === current) ? current : "";
+  elements.filterVersion.closest("label").hidden = versions.length < 2;
+}
+
+function renderCrossTabChoices(survey) {
+  const eligible = (survey.questions || []).filter((question) =>
+    ["single_select", "multi_select", "rating", "nps"].includes(question.type),
+  );
+  for (const select of [elements.crossRow, elements.crossColumn]) {
+    const current = select.value;
+    select.replaceChildren(
+      new Option("不生成", ""),
+      ...eligible.map((question, index) => new Option(`${index + 1}. ${question.question}`, question.id)),
+    );
+    select.value = eligible.some((question) => question.id === current) ? current : "";
+  }
+}
+
+function renderResults(aggregate) {
   elements.resultsList.replaceChildren();
-  renderEnvironmentSummary(responses);
-  if (responses.length === 0) {
+  renderEnvironmentSummary(aggregate.response_count > 0 ? aggregate.environment : null);
+  renderCrossTab(aggregate.survey, aggregate.cross_tab);
+  if (aggregate.response_count === 0) {
     const empty = document.createElement("p");
     empty.className = "results-empty";
-    empty.textContent = "还没有收到答卷。";
+    empty.textContent = aggregate.total_count > 0 ? "没有符合筛选条件的答卷。" : "还没有收到答卷。";
     elements.resultsList.append(empty);
     return;
   }
 
+  appendQuestionResults(aggregate.survey, aggregate.questions || [], aggregate.response_count);
+  for (const breakdown of aggregate.languages || []) {
+    const heading = document.createElement("h4");
+    heading.className = "results-language-heading";
+    heading.textContent = `${breakdown.language} · ${breakdown.response_count} 份`;
+    elements.resultsList.append(heading);
+    if (breakdown.response_count > 0) {
+      appendQuestionResults(aggregate.survey, breakdown.questions || [], breakdown.response_count);
+    }
+  }
+}
+
+// appendQuestionResults 按题目顺序追加统计卡片，responseCount 是这些统计所基于的答卷数。
+function appendQuestionResults(survey, questions, responseCount) {
+  const summaries = new Map(questions.map((summary) => [summary.question_id, summary]));
   for (const question of survey.questions || []) {
-    const answerRows = responses
-      .map((response) => ({
-        response,
-        answer: (response.answers || []).find(
-          (candidate) => candidate.question_id === question.id,
-        ),
-      }))
-      .filter((row) => row.answer);
-    const answeredCount = answerRows.filter(
-      ({ answer }) =>
-        (answer.selected_option_ids || []).length > 0 || Boolean(answer.other_text),
-    ).length;
+    const summary = summaries.get(question.id);
+    if (!summary) {
+      continue;
+    }
 
     const card = document.createElement("article");
     card.className = "result-card";
@@ -413,69 +923,231 @@ function renderResults(survey, responses) {
     const title = document.createElement("strong");
     title.textContent = question.question;
     const count = document.createElement("span");
-    const skippedCount = responses.length - answeredCount;
-    const answerRate = responses.length > 0
-      ? Math.round((answeredCount / responses.length) * 100)
-      : 0;
-    count.textContent = `${answeredCount} 人回答 · ${skippedCount} 人跳过 · 作答率 ${answerRate}%`;
+    const asked = responseCount - (summary.not_asked || 0);
+    const answerRate = asked > 0 ? Math.round((summary.answered / asked) * 100) : 0;
+    count.textContent = [
+      `${summary.answered} 人回答`,
+      `${summary.skipped} 人跳过`,
+      summary.hidden > 0 ? `${summary.hidden} 人未显示` : "",
+      summary.not_asked > 0 ? `${summary.not_asked} 份答卷作答时还没有此题` : "",
+      `作答率 ${answerRate}%`,
+    ].filter(Boolean).join(" · ");
     heading.append(title, count);
     card.append(heading);
 
-    for (const option of question.options || []) {
-      const optionCount = answerRows.filter(({ answer }) =>
-        (answer.selected_option_ids || []).includes(option.id),
-      ).length;
-      const percentage = answeredCount > 0 ? (optionCount / answeredCount) * 100 : 0;
-      const row = document.createElement("div");
-      row.className = "result-option";
-
-      const label = document.createElement("div");
-      const optionLabel = document.createElement("span");
-      optionLabel.textContent = option.label;
-      const optionValue = document.createElement("strong");
-      optionValue.textContent = `${optionCount} · ${Math.round(percentage)}%`;
-      label.append(optionLabel, optionValue);
-
-      const track = document.createElement("span");
-      track.className = "result-track";
-      const bar = document.createElement("span");
-      bar.style.width = `${Math.min(100, percentage)}%`;
-      track.append(bar);
-      row.append(label, track);
-      card.append(row);
+    switch (question.type) {
+      case "ranking":
+        renderRankingResult(card, question, summary);
+        break;
+      case "rating":
+      case "nps":
+      case "number":
+        renderValueResult(card, question, summary);
+        break;
+      case "text":
+        appendThemeList(card, summary.themes || []);
+        appendAnswerList(card, "文字回答", summary.answered, summary.texts || []);
+        break;
+      default:
+        for (const option of summary.options || []) {
+          appendResultRow(card, option.label, `${option.count} · ${Math.round(option.percent)}%`, option.percent);
+        }
+        appendThemeList(card, summary.themes || []);
+        appendAnswerList(card, "自定义回答", summary.other_count || 0, summary.texts || []);
     }
+    elements.resultsList.append(card);
+  }
+}
 
-    const customAnswers = answerRows.filter(({ answer }) => answer.other_text);
-    if (customAnswers.length > 0) {
-      const customSection = document.createElement("details");
-      customSection.className = "custom-answers";
-      const summary = document.createElement("summary");
-      summary.textContent = `自定义回答（${customAnswers.length}）`;
-      customSection.append(summary);
-      for (const { response, answer } of customAnswers) {
-        const item = document.createElement("div");
-        const text = document.createElement("p");
-        text.textContent = answer.other_text;
-        const time = document.createElement("span");
-        time.textContent = [
-          formatClientVersion(response),
-          platformLabel(response.platform),
-          response.language || "未知语言",
-          formatSubmittedAt(response.submitted_at),
-        ].filter(Boolean).join(" · ");
-        item.append(text, time);
-        customSection.append(item);
-      }
-      card.append(customSection);
+function renderRankingResult(card, question, summary) {
+  const optionCount = (question.options || []).length;
+  for (const option of summary.options || []) {
+    // 平均名次越靠前，条形越长。
+    const percentage = optionCount > 1 && summary.answered > 0
+      ? ((optionCount - option.average_position) / (optionCount - 1)) * 100
+      : 100;
+    appendResultRow(
+      card,
+      option.label,
+      `平均第 ${formatNumber(option.average_position || 0)} 名 · ${option.count} 次第一`,
+      percentage,
+    );
+  }
+}
+
+function renderValueResult(card, question, summary) {
+  const values = summary.values;
+  if (!values) {
+    return;
+  }
+  if (question.type === "nps") {
+    const nps = values.nps;
+    appendResultStats(card, [["NPS", String(nps.score)], ["平均", formatNumber(values.average)]]);
+    appendValueRow(card, "推荐者（9–10）", nps.promoters, summary.answered);
+    appendValueRow(card, "中立者（7–8）", nps.passives, summary.answered);
+    appendValueRow(card, "贬损者（0–6）", nps.detractors, summary.answered);
+    return;
+  }
+  if (question.type === "rating") {
+    appendResultStats(card, [["平均", `${formatNumber(values.average)} / ${question.scale}`]]);
+    for (const bucket of [...(values.distribution || [])].reverse()) {
+      appendResultRow(card, `${bucket.value} 星`, `${bucket.count} · ${Math.round(bucket.percent)}%`, bucket.percent);
     }
-    elements.resultsList.append(card);
+    return;
+  }
+  appendResultStats(card, [
+    ["平均", formatNumber(values.average)],
+    ["中位数", formatNumber(values.median)],
+    ["最小", formatNumber(values.min)],
+    ["最大", formatNumber(values.max)],
+  ]);
+}
+
+function renderCrossTab(survey, table) {
+  elements.crossTab.replaceChildren();
+  elements.crossTab.hidden = !table;
+  if (!table) {
+    return;
+  }
+  const questionTitle = (id) =>
+    (survey.questions || []).find((question) => question.id === id)?.question || id;
+
+  const caption = document.createElement("caption");
+  caption.textContent = `${questionTitle(table.row_question_id)} × ${questionTitle(table.column_question_id)}（${table.total} 份）`;
+  const head = document.createElement("tr");
+  head.append(document.createElement("th"));
+  for (const column of table.columns) {
+    const cell = document.createElement("th");
+    cell.scope = "col";
+    cell.textContent = column.label;
+    head.append(cell);
+  }
+  const body = document.createElement("tbody");
+  table.rows.forEach((row, rowIndex) => {
+    const line = document.createElement("tr");
+    const label = document.createElement("th");
+    label.scope = "row";
+    label.textContent = row.label;
+    line.append(label);
+    for (const count of table.counts[rowIndex]) {
+      const cell = document.createElement("td");
+      cell.textContent = String(count);
+      line.append(cell);
+    }
+    body.append(line);
+  });
+  const thead = document.createElement("thead");
+  thead.append(head);
+  const element = document.createElement("table");
+  element.append(caption, thead, body);
+  elements.crossTab.append(element);
+}
+
+function appendResultStats(card, stats) {
+  const container = document.createElement("div");
+  container.className = "result-stats";
+  for (const [label, value] of stats) {
+    const item = document.createElement("span");
+    const amount = document.createElement("strong");
+    amount.textContent = value;
+    item.append(label, amount);
+    container.append(item);
   }
+  card.append(container);
+}
+
+function appendValueRow(card, label, count, total) {
+  const percentage = total > 0 ? (count / total) * 100 : 0;
+  appendResultRow(card, label, `${count} · ${Math.round(percentage)}%`, percentage);
+}
+
+function appendResultRow(card, labelText, valueText, percentage) {
+  const row = document.createElement("div");
+  row.className = "result-option";
+
+  const label = document.createElement("div");
+  const optionLabel = document.createElement("span");
+  optionLabel.textContent = labelText;
+  const optionValue = document.createElement("strong");
+  optionValue.textContent = valueText;
+  label.append(optionLabel, optionValue);
+
+  const track = document.createElement("span");
+  track.className = "result-track";
+  const bar = document.createElement("span");
+  bar.style.width = `${Math.max(0, Math.min(100, percentage))}%`;
+  track.append(bar);
+  row.append(label, track);
+  card.append(row);
 }
 
-function renderEnvironmentSummary(responses) {
+// appendThemeList 按回答数列出服务端聚类的主题，每个主题显示出现最多的原文。
+function appendThemeList(card, themes) {
+  // 每个主题只有一条回答时聚类没有意义，直接看回答列表即可。
+  if (!themes.some((theme) => theme.count > 1)) {
+    return;
+  }
+  const section = document.createElement("details");
+  section.className = "custom-answers text-themes";
+  section.open = true;
+  const summary = document.createElement("summary");
+  summary.textContent = `相似回答主题（${themes.length}）`;
+  section.append(summary);
+  for (const theme of themes) {
+    const item = document.createElement("div");
+    item.classList.toggle("flagged", theme.flagged > 0);
+    const text = document.createElement("p");
+    text.textContent = theme.text;
+    const meta = document.createElement("span");
+    meta.textContent = [
+      `${theme.count} 条`,
+      theme.variants > 1 ? `${theme.variants} 种写法` : "",
+      theme.flagged > 0 ? `${theme.flagged} 条被审核标记` : "",
+    ].filter(Boolean).join(" · ");
+    item.append(text, meta);
+    section.append(item);
+  }
+  card.append(section);
+}
+
+function appendAnswerList(card, title, total, texts) {
+  if (texts.length === 0) {
+    return;
+  }
+  const section = document.createElement("details");
+  section.className = "custom-answers";
+  const summary = document.createElement("summary");
+  summary.textContent = texts.length < total
+    ? `${title}（最新 ${texts.length} 条，共 ${total} 条）`
+    : `${title}（${texts.length}）`;
+  section.append(summary);
+  for (const answer of texts) {
+    const item = document.createElement("div");
+    item.classList.toggle("flagged", Boolean(answer.flagged));
+    const text = document.createElement("p");
+    text.textContent = answer.text;
+    const time = document.createElement("span");
+    time.textContent = [
+      answer.flagged ? `审核标记：${(answer.flag_reasons || []).join("；") || "不适合展示"}` : "",
+      formatClientVersion(answer),
+      platformLabel(answer.platform),
+      answer.language || "未知语言",
+      formatSubmittedAt(answer.submitted_at),
+    ].filter(Boolean).join(" · ");
+    item.append(text, time);
+    section.append(item);
+  }
+  card.append(section);
+}
+
+function formatNumber(value) {
+  return Number.isInteger(value) ? String(value) : value.toFixed(1);
+}
+
+function renderEnvironmentSummary(environment) {
   elements.environmentSummary.replaceChildren();
-  elements.environmentSummary.hidden = responses.length === 0;
-  if (responses.length === 0) {
+  elements.environmentSummary.hidden = !environment;
+  if (!environment) {
     return;
   }
 
@@ -491,9 +1163,9 @@ function renderEnvironmentSummary(responses) {
   card.append(heading);
 
   const groups = [
-    ["版本与构建", countValues(responses, formatClientVersion)],
-    ["平台", countValues(responses, (response) => platformLabel(response.platform))],
-    ["语言", countValues(responses, (response) => response.language || "未知语言")],
+    ["版本与构建", (environment.versions || []).map((item) => [formatClientVersion(item), item.count])],
+    ["平台", (environment.platforms || []).map((item) => [platformLabel(item.value), item.count])],
+    ["语言", (environment.languages || []).map((item) => [item.value || "未知语言", item.count])],
   ];
 
   const grid = document.createElement("div");
@@ -521,17 +1193,6 @@ function renderEnvironmentSummary(responses) {
   elements.environmentSummary.append(card);
 }
 
-function countValues(responses, selector) {
-  const counts = new Map();
-  for (const response of responses) {
-    const value = selector(response);
-    counts.set(value, (counts.get(value) || 0) + 1);
-  }
-  return [...counts.entries()].sort((left, right) =>
-    right[1] - left[1] || left[0].localeCompare(right[0], "zh-CN"),
-  );
-}
-
 function formatClientVersion(response) {
   const version = response.app_version || "";
   const build = response.app_build || "";
@@ -547,19 +1208,28 @@ function formatClientVersion(response) {
   return "未知版本";
 }
 
+// setDefinitionLocked 在收到答卷后锁定已保存题目的结构。文字、新增题目和新增选项仍可编辑，
+// 保存时由服务端判断修改是否兼容并生成新版本。
 function setDefinitionLocked(locked) {
   state.definitionLocked = locked;
   elements.lockedNotice.hidden = !locked;
   for (const input of elements.form.querySelectorAll("[data-definition]")) {
-    input.disabled = locked;
+    input.disabled = locked && isSavedStructure(input);
   }
-  elements.addQuestionButton.disabled = locked;
   elements.deleteButton.disabled = locked || !state.selectedKey;
   for (const button of elements.form.querySelectorAll(
-    ".remove-question, .remove-option, .add-option",
+    ".remove-question, .add-condition, .remove-condition",
   )) {
-    button.disabled = locked;
+    button.disabled = locked && isSavedStructure(button);
   }
+  for (const button of elements.form.querySelectorAll(".remove-option")) {
+    button.disabled = locked && button.closest(".option-row").dataset.saved === "true";
+  }
+}
+
+function isSavedStructure(element) {
+  const card = element.closest(".question-card");
+  return !card || card.dataset.saved === "true";
 }
 
 function setSaving(isSaving) {
@@ -594,6 +1264,42 @@ function newIdentifier(prefix) {
   return `${prefix}-${Date.now()}-${Math.random().toString(16).slice(2)}`;
 }
 
+// surveyStatus 按服务端返回的停止原因描述征集状态。
+function surveyStatus(record) {
+  switch (record.closed_reason) {
+    case undefined:
+    case "":
+      return "征集中";
+    case "not_open":
+      return "未开始";
+    case "ended":
+      return "已截止";
+    case "quota_reached":
+      return "已收满";
+    default:
+      return "草稿";
+  }
+}
+
+function toLocalInputValue(value) {
+  if (!value) {
+    return "";
+  }
+  const date = new Date(value);
+  if (Number.isNaN(date.getTime())) {
+    return "";
+  }
+  const local = new Date(date.getTime() - date.getTimezoneOffset() * 60000);
+  return local.toISOString().slice(0, 16);
+}
+
+function fromLocalInputValue(value) {
+  if (!value) {
+    return null;
+  }
+  return new Date(value).toISOString();
+}
+
 function platformLabel(platform) {
   if (platform === "iOS") {
     return "仅 iOS";
@@ -648,7 +1354,27 @@ elements.newButton.addEventListener("click", resetEditor);
 elements.duplicateButton.addEventListener("click", duplicateSelected);
 elements.deleteButton.addEventListener("click", deleteSelected);
 elements.addQuestionButton.addEventListener("click", () => addQuestion());
+elements.addLocalizationButton.addEventListener("click", () => addLocalization());
+elements.mergeButton.addEventListener("click", mergeSelected);
 elements.search.addEventListener("input", renderList);
+for (const control of elements.resultsSection.querySelectorAll(".results-filters input, .results-filters select")) {
+  control.addEventListener("change", refreshResults);
+  // 筛选框位于编辑表单内，回车只刷新统计，不提交表单。
+  control.addEventListener("keydown", (event) => {
+    if (event.key === "Enter") {
+      event.preventDefault();
+      refreshResults();
+    }
+  });
+}
+for (const type of ["input", "change"]) {
+  elements.questionList.addEventListener(type, (event) => {
+    if (!event.target.closest(".condition-row")) {
+      refreshConditions();
+      refreshLocalizations();
+    }
+  });
+}
 
 document.addEventListener("keydown", (event) => {
   if ((event.metaKey || event.ctrlKey) && event.key.toLocaleLowerCase() === "s") {
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xlsxSheet 是写入 XLSX 的一个工作表，第一行为 Columns，单元格取值规则与 store.SurveyTable 相同。
+type xlsxSheet struct {
+	Name    string
+	Columns []string
+	Rows    [][]any
+}
+
+const (
+	xlsxMainNamespace         = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelationshipNamespace = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xlsxPackageRelationships  = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+// writeXLSX 写出只包含内联字符串、数字与布尔值的最小 Office Open XML 工作簿，不依赖外部库。
+func writeXLSX(writer io.Writer, sheets []xlsxSheet) error {
+	archive := zip.NewWriter(writer)
+
+	var contentTypes, workbook, workbookRelationships strings.Builder
+	contentTypes.WriteString(xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	workbook.WriteString(xml.Header + `<workbook xmlns="` + xlsxMainNamespace + `" xmlns:r="` + xlsxRelationshipNamespace + `"><sheets>`)
+	workbookRelationships.WriteString(xml.Header + `<Relationships xmlns="` + xlsxPackageRelationships + `">`)
+	for index, sheet := range sheets {
+		number := strconv.Itoa(index + 1)
+		contentTypes.WriteString(`<Override PartName="/xl/worksheets/sheet` + number +
+			`.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`)
+		workbook.WriteString(`<sheet name="` + xlsxEscape(sheet.Name) + `" sheetId="` + number + `" r:id="rId` + number + `"/>`)
+		workbookRelationships.WriteString(`<Relationship Id="rId` + number + `" Type="` + xlsxRelationshipNamespace +
+			`/worksheet" Target="worksheets/sheet` + number + `.xml"/>`)
+	}
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	workbookRelationships.WriteString(`</Relationships>`)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="` + xlsxPackageRelationships + `">` +
+			`<Relationship Id="rId1" Type="` + xlsxRelationshipNamespace + `/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRelationships.String()},
+	}
+	for _, part := range parts {
+		entry, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return err
+		}
+	}
+	for index, sheet := range sheets {
+		entry, err := archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", index+1))
+		if err != nil {
+			return err
+		}
+		if err := writeXLSXSheet(entry, sheet); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeXLSXSheet(writer io.Writer, sheet xlsxSheet) error {
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteString(xml.Header + `<worksheet xmlns="` + xlsxMainNamespace + `"><sheetData>`)
+	header := make([]any, len(sheet.Columns))
+	for index, column := range sheet.Columns {
+		header[index] = column
+	}
+	for rowIndex, row := range append([][]any{header}, sheet.Rows...) {
+		number := strconv.Itoa(rowIndex + 1)
+		buffered.WriteString(`<row r="` + number + `">`)
+		for columnIndex, cell := range row {
+			reference := xlsxColumnName(columnIndex) + number
+			switch value := cell.(type) {
+			case nil:
+				continue
+			case string:
+				buffered.WriteString(`<c r="` + reference + `" t="inlineStr"><is><t xml:space="preserve">` +
+					xlsxEscape(value) + `</t></is></c>`)
+			case bool:
+				flag := "0"
+				if value {
+					flag = "1"
+				}
+				buffered.WriteString(`<c r="` + reference + `" t="b"><v>` + flag + `</v></c>`)
+			case int:
+				buffered.WriteString(`<c r="` + reference + `"><v>` + strconv.Itoa(value) + `</v></c>`)
+			case float64:
+				buffered.WriteString(`<c r="` + reference + `"><v>` + strconv.FormatFloat(value, 'g', -1, 64) + `</v></c>`)
+			default:
+				buffered.WriteString(`<c r="` + reference + `" t="inlineStr"><is><t>` +
+					xlsxEscape(fmt.Sprint(value)) + `</t></is></c>`)
+			}
+		}
+		buffered.WriteString(`</row>`)
+	}
+	buffered.WriteString(`</sheetData></worksheet>`)
+	return buffered.Flush()
+}
+
+// xlsxColumnName 把从 0 开始的列序号转为 A、B、…、Z、AA 形式的列名。
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxEscape 转义 XML 特殊字符，并把 XML 不允许的控制字符替换为 U+FFFD。
+func xlsxEscape(value string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
@@ -27,6 +27,7 @@ type Config struct {
 	AnnouncementAdminToken   string
 	AdminWebAuthDisabled     bool
 	AnnouncementCacheMaxAge  int
+	AnnouncementLocales      []string
 	GitHubTokenLogin         string
 	DeveloperLogins          []string
 	DataDir                  string
@@ -36,6 +37,8 @@ type Config struct {
 	RedisDB                  int
 	RedisKeyPrefix           string
 	TrustedProxyCIDRs        []string
+	IPAllowCIDRs             []string
+	IPDenyCIDRs              []string
 	IssuesPath               string
 	RateWindow               time.Duration
 	ChallengeTTL             time.Duration
@@ -56,8 +59,15 @@ type Config struct {
 	ModerationTimeout        time.Duration
 	ModerationMaxRetries     int
 	ModerationTemperature    float64
+	AttestationMode          string
+	AttestationRouteModes    map[string]string
+	AttestationRootCAFile    string
+	AttestationPoWBits       int
 }
 
+// AttestationRoutes 是支持单独配置设备证明模式的公开写入路由。
+var AttestationRoutes = []string{"issue", "comment", "survey", "announcement"}
+
 // Load 从环境变量加载配置
 func Load() (Config, error) {
 	cfg := Config{
@@ -76,6 +86,7 @@ func Load() (Config, error) {
 		AnnouncementAdminToken:   strings.TrimSpace(os.Getenv("ANNOUNCEMENT_ADMIN_TOKEN")),
 		AdminWebAuthDisabled:     getEnvAsBool("ADMIN_WEB_AUTH_DISABLED", false),
 		AnnouncementCacheMaxAge:  clampInt(getEnvAsInt("ANNOUNCEMENT_CACHE_MAX_AGE_SECONDS", 300), 30, 3600),
+		AnnouncementLocales:      getEnvAsStringSlice("ANNOUNCEMENT_REQUIRED_LANGUAGES"),
 		GitHubTokenLogin:         strings.TrimSpace(os.Getenv("GITHUB_TOKEN_LOGIN")),
 		DeveloperLogins:          getEnvAsStringSlice("DEVELOPER_GITHUB_LOGINS"),
 		DataDir:                  getEnv("DATA_DIR", "./data"),
@@ -85,6 +96,8 @@ func Load() (Config, error) {
 		RedisDB:                  getEnvAsInt("REDIS_DB", 0),
 		RedisKeyPrefix:           getEnv("REDIS_KEY_PREFIX", "els-feedback"),
 		TrustedProxyCIDRs:        parseCommaSeparated(getEnv("TRUSTED_PROXY_CIDRS", "127.0.0.1/32,::1/128")),
+		IPAllowCIDRs:             parseCommaSeparated(os.Getenv("IP_ALLOW_CIDRS")),
+		IPDenyCIDRs:              parseCommaSeparated(os.Getenv("IP_DENY_CIDRS")),
 		IssuesPath:               "/v1/feedback/issues",
 		RateWindow:               15 * time.Minute,
 		ChallengeTTL:             120 * time.Second,
@@ -105,6 +118,9 @@ func Load() (Config, error) {
 		ModerationTimeout:        time.Duration(clampInt(getEnvAsInt("MODERATION_TIMEOUT_SECONDS", 15), 3, 120)) * time.Second,
 		ModerationMaxRetries:     clampInt(getEnvAsInt("MODERATION_MAX_RETRIES", 3), 1, 5),
 		ModerationTemperature:    clampFloat(getEnvAsFloat("MODERATION_TEMPERATURE", 0), 0, 2),
+		AttestationMode:          strings.ToLower(getEnv("ATTESTATION_MODE", "off")),
+		AttestationRootCAFile:    strings.TrimSpace(os.Getenv("ATTESTATION_ROOT_CA_FILE")),
+		AttestationPoWBits:       clampInt(getEnvAsInt("ATTESTATION_POW_BITS", 0), 0, 30),
 	}
 
 	if cfg.GitHubToken == "" {
@@ -125,6 +141,27 @@ func Load() (Config, error) {
 			return Config{}, fmt.Errorf("TRUSTED_PROXY_CIDRS 包含无效网段 %q", trustedProxy)
 		}
 	}
+	for _, cidr := range cfg.IPAllowCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return Config{}, fmt.Errorf("IP_ALLOW_CIDRS 包含无效网段 %q", cidr)
+		}
+	}
+	for _, cidr := range cfg.IPDenyCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return Config{}, fmt.Errorf("IP_DENY_CIDRS 包含无效网段 %q", cidr)
+		}
+	}
+	routeModes, err := parseAttestationRouteModes(os.Getenv("ATTESTATION_ROUTE_MODES"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.AttestationRouteModes = routeModes
+	if !validAttestationMode(cfg.AttestationMode) {
+		return Config{}, fmt.Errorf("ATTESTATION_MODE 仅支持 off、log 或 enforce")
+	}
+	if cfg.AttestationEnabled() && cfg.AttestationRootCAFile == "" {
+		return Config{}, errors.New("启用设备证明时必须配置 ATTESTATION_ROOT_CA_FILE")
+	}
 	if cfg.ModerationEnabled {
 		if cfg.ModerationAPIBaseURL == "" {
 			return Config{}, errors.New("缺少 MODERATION_API_BASE_URL")
@@ -141,6 +178,59 @@ func Load() (Config, error) {
 	return cfg, nil
 }
 
+// AttestationModeFor 返回指定公开写入路由的设备证明模式。
+func (cfg Config) AttestationModeFor(route string) string {
+	if mode, ok := cfg.AttestationRouteModes[route]; ok {
+		return mode
+	}
+	if cfg.AttestationMode == "" {
+		return "off"
+	}
+	return cfg.AttestationMode
+}
+
+// AttestationEnabled 表示是否有任意路由需要校验设备证明。
+func (cfg Config) AttestationEnabled() bool {
+	for _, route := range AttestationRoutes {
+		if cfg.AttestationModeFor(route) != "off" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAttestationRouteModes(raw string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, item := range parseCommaSeparated(raw) {
+		route, mode, found := strings.Cut(item, "=")
+		route = strings.ToLower(strings.TrimSpace(route))
+		mode = strings.ToLower(strings.TrimSpace(mode))
+		if !found || !validAttestationRoute(route) || !validAttestationMode(mode) {
+			return nil, fmt.Errorf("ATTESTATION_ROUTE_MODES 包含无效配置 %q", item)
+		}
+		result[route] = mode
+	}
+	return result, nil
+}
+
+func validAttestationRoute(route string) bool {
+	for _, candidate := range AttestationRoutes {
+		if candidate == route {
+			return true
+		}
+	}
+	return false
+}
+
+func validAttestationMode(mode string) bool {
+	switch mode {
+	case "off", "log", "enforce":
+		return true
+	default:
+		return false
+	}
+}
+
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
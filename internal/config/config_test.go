@@ -33,3 +33,39 @@ func TestLoadAdminWebAuthDisabledRequiresSessionSecret(t *testing.T) {
 		t.Fatalf("缺少会话签名口令时应拒绝免登录配置，实际错误: %v", err)
 	}
 }
+
+func TestLoadAttestationRouteModes(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-github-token")
+	t.Setenv("MODERATION_ENABLED", "false")
+	t.Setenv("ATTESTATION_MODE", "log")
+	t.Setenv("ATTESTATION_ROUTE_MODES", "issue=enforce, survey=off")
+	t.Setenv("ATTESTATION_ROOT_CA_FILE", "/etc/els/attestation-roots.pem")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("加载设备证明配置失败: %v", err)
+	}
+	if cfg.AttestationModeFor("issue") != "enforce" ||
+		cfg.AttestationModeFor("comment") != "log" ||
+		cfg.AttestationModeFor("survey") != "off" {
+		t.Fatalf("设备证明路由模式不正确: %#v", cfg.AttestationRouteModes)
+	}
+}
+
+func TestLoadAttestationRequiresRootCA(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-github-token")
+	t.Setenv("MODERATION_ENABLED", "false")
+	t.Setenv("ATTESTATION_MODE", "off")
+	t.Setenv("ATTESTATION_ROUTE_MODES", "comment=enforce")
+	t.Setenv("ATTESTATION_ROOT_CA_FILE", "")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "ATTESTATION_ROOT_CA_FILE") {
+		t.Fatalf("启用设备证明但缺少根证书时应拒绝配置，实际错误: %v", err)
+	}
+
+	t.Setenv("ATTESTATION_ROUTE_MODES", "status=enforce")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "ATTESTATION_ROUTE_MODES") {
+		t.Fatalf("未知路由应被拒绝，实际错误: %v", err)
+	}
+}
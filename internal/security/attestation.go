@@ -0,0 +1,189 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 设备证明的执行模式。
+const (
+	AttestationModeOff     = "off"
+	AttestationModeLog     = "log"
+	AttestationModeEnforce = "enforce"
+)
+
+const maxAttestationChainLength = 4
+
+var (
+	ErrAttestationMissing    = newError("attestation_missing", "缺少设备证明")
+	ErrAttestationKeyUnknown = newError("attestation_key_unknown", "设备密钥未登记")
+	ErrAttestationInvalid    = newError("attestation_invalid", "设备证明无效")
+	ErrAttestationRequired   = newError("attestation_required", "该 challenge 要求设备证明")
+)
+
+// AttestationEvidence 是客户端随提交附带的设备密钥断言。
+type AttestationEvidence struct {
+	KeyID     string
+	Assertion []byte
+}
+
+// AttestationResult 描述一次提交的设备证明校验结果。
+type AttestationResult struct {
+	KeyID    string
+	Verified bool
+	Err      error
+}
+
+// AssertionVerifier 校验设备密钥对 challenge 绑定数据的签名。
+type AssertionVerifier interface {
+	KnownKey(keyID string) bool
+	VerifyAssertion(keyID string, clientData []byte, assertion []byte) error
+}
+
+// AttestationKeyRegistry 保存已通过证书链校验的设备公钥。
+type AttestationKeyRegistry interface {
+	AttestationPublicKey(keyID string) ([]byte, bool)
+	RegisterAttestationKey(keyID string, publicKeyDER []byte, subject string) error
+}
+
+// AttestationVerifier 使用本地信任根校验设备密钥登记，并用已登记公钥校验断言。
+type AttestationVerifier struct {
+	roots *x509.CertPool
+	keys  AttestationKeyRegistry
+	now   func() time.Time
+}
+
+func NewAttestationVerifier(rootsPEM []byte, keys AttestationKeyRegistry) (*AttestationVerifier, error) {
+	if keys == nil {
+		return nil, errors.New("设备密钥存储未初始化")
+	}
+	roots := x509.NewCertPool()
+	count := 0
+	for rest := rootsPEM; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析设备证明根证书失败: %w", err)
+		}
+		roots.AddCert(certificate)
+		count++
+	}
+	if count == 0 {
+		return nil, errors.New("设备证明根证书为空")
+	}
+	return &AttestationVerifier{
+		roots: roots,
+		keys:  keys,
+		now:   time.Now,
+	}, nil
+}
+
+// AttestationKeyID 根据 PKIX 公钥计算设备密钥 ID。
+func AttestationKeyID(publicKeyDER []byte) string {
+	digest := sha256.Sum256(publicKeyDER)
+	return hex.EncodeToString(digest[:])
+}
+
+// RegistrationMessage 是登记设备密钥时由该密钥签名的文本，绑定一次性 challenge。
+func RegistrationMessage(keyID, challengeID string) string {
+	return fmt.Sprintf("els-attest-key\n%s\n%s", keyID, challengeID)
+}
+
+// Register 校验证书链与持有证明后登记设备公钥。
+func (v *AttestationVerifier) Register(
+	keyID string,
+	chain [][]byte,
+	challengeID string,
+	proof []byte,
+) error {
+	keyID = strings.ToLower(strings.TrimSpace(keyID))
+	if keyID == "" || len(chain) == 0 || len(proof) == 0 {
+		return ErrAttestationMissing
+	}
+	if len(chain) > maxAttestationChainLength {
+		return ErrAttestationInvalid
+	}
+
+	certificates := make([]*x509.Certificate, 0, len(chain))
+	for _, raw := range chain {
+		certificate, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return ErrAttestationInvalid
+		}
+		certificates = append(certificates, certificate)
+	}
+	leaf := certificates[0]
+	intermediates := x509.NewCertPool()
+	for _, certificate := range certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return ErrAttestationInvalid
+	}
+
+	publicKey, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return ErrAttestationInvalid
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return ErrAttestationInvalid
+	}
+	if AttestationKeyID(publicKeyDER) != keyID {
+		return ErrAttestationInvalid
+	}
+	digest := sha256.Sum256([]byte(RegistrationMessage(keyID, challengeID)))
+	if !ecdsa.VerifyASN1(publicKey, digest[:], proof) {
+		return ErrAttestationInvalid
+	}
+
+	return v.keys.RegisterAttestationKey(keyID, publicKeyDER, leaf.Subject.String())
+}
+
+func (v *AttestationVerifier) KnownKey(keyID string) bool {
+	_, ok := v.keys.AttestationPublicKey(strings.ToLower(strings.TrimSpace(keyID)))
+	return ok
+}
+
+func (v *AttestationVerifier) VerifyAssertion(keyID string, clientData []byte, assertion []byte) error {
+	keyID = strings.ToLower(strings.TrimSpace(keyID))
+	if keyID == "" || len(assertion) == 0 {
+		return ErrAttestationMissing
+	}
+	publicKeyDER, ok := v.keys.AttestationPublicKey(keyID)
+	if !ok {
+		return ErrAttestationKeyUnknown
+	}
+	parsed, err := x509.ParsePKIXPublicKey(publicKeyDER)
+	if err != nil {
+		return ErrAttestationInvalid
+	}
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return ErrAttestationInvalid
+	}
+	digest := sha256.Sum256(clientData)
+	if !ecdsa.VerifyASN1(publicKey, digest[:], assertion) {
+		return ErrAttestationInvalid
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type memoryAttestationKeys map[string][]byte
+
+func (m memoryAttestationKeys) AttestationPublicKey(keyID string) ([]byte, bool) {
+	key, ok := m[keyID]
+	return key, ok
+}
+
+func (m memoryAttestationKeys) RegisterAttestationKey(keyID string, publicKeyDER []byte, _ string) error {
+	m[keyID] = publicKeyDER
+	return nil
+}
+
+type attestationTestAuthority struct {
+	key         *ecdsa.PrivateKey
+	certificate *x509.Certificate
+	pem         []byte
+}
+
+func newAttestationTestAuthority(t *testing.T, name string) attestationTestAuthority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成根密钥失败: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("生成根证书失败: %v", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("解析根证书失败: %v", err)
+	}
+	return attestationTestAuthority{
+		key:         key,
+		certificate: certificate,
+		pem:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (a attestationTestAuthority) issueDeviceKey(t *testing.T) (*ecdsa.PrivateKey, []byte, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成设备密钥失败: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test-device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.certificate, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatalf("签发设备证书失败: %v", err)
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("编码设备公钥失败: %v", err)
+	}
+	return key, der, AttestationKeyID(publicKeyDER)
+}
+
+func signForAttestationTest(t *testing.T, key *ecdsa.PrivateKey, message []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(message)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("生成设备签名失败: %v", err)
+	}
+	return signature
+}
+
+func TestAttestationRegisterAndAssertSubmission(t *testing.T) {
+	authority := newAttestationTestAuthority(t, "test-root")
+	verifier, err := NewAttestationVerifier(authority.pem, memoryAttestationKeys{})
+	if err != nil {
+		t.Fatalf("初始化设备证明失败: %v", err)
+	}
+	deviceKey, leaf, keyID := authority.issueDeviceKey(t)
+
+	proof := signForAttestationTest(t, deviceKey, []byte(RegistrationMessage(keyID, "challenge-1")))
+	if err := verifier.Register(keyID, [][]byte{leaf}, "challenge-1", proof); err != nil {
+		t.Fatalf("登记设备密钥失败: %v", err)
+	}
+	if !verifier.KnownKey(keyID) {
+		t.Fatalf("登记后应能识别设备密钥")
+	}
+
+	manager := NewChallengeManager(2*time.Minute, 90*time.Second, 5, 10*time.Minute)
+	manager.SetAssertionVerifier(verifier)
+	clientIP := "127.0.0.1"
+	bundle := manager.IssueAttested(clientIP, 0, keyID)
+	if bundle.AttestationKeyID != keyID || bundle.PoWBits != 0 {
+		t.Fatalf("设备 challenge 不正确: %#v", bundle)
+	}
+
+	body := []byte(`{"title":"hello"}`)
+	timestamp, signature, signingText := signSubmissionForAttestationTest(bundle, body)
+
+	_, err = manager.VerifyAttestedSubmission(
+		AttestationEvidence{},
+		clientIP,
+		bundle.ChallengeID,
+		timestamp,
+		signature,
+		"",
+		"",
+		http.MethodPost,
+		"/v1/feedback/issues",
+		body,
+	)
+	if !errors.Is(err, ErrAttestationRequired) {
+		t.Fatalf("缺少断言时应拒绝设备 challenge，实际: %v", err)
+	}
+
+	bundle = manager.IssueAttested(clientIP, 0, keyID)
+	timestamp, signature, signingText = signSubmissionForAttestationTest(bundle, body)
+	result, err := manager.VerifyAttestedSubmission(
+		AttestationEvidence{KeyID: keyID, Assertion: signForAttestationTest(t, deviceKey, []byte(signingText))},
+		clientIP,
+		bundle.ChallengeID,
+		timestamp,
+		signature,
+		"",
+		"",
+		http.MethodPost,
+		"/v1/feedback/issues",
+		body,
+	)
+	if err != nil || !result.Verified {
+		t.Fatalf("有效断言应通过校验: result=%#v err=%v", result, err)
+	}
+}
+
+func TestAttestationRegisterRejectsUntrustedRoot(t *testing.T) {
+	trusted := newAttestationTestAuthority(t, "trusted-root")
+	untrusted := newAttestationTestAuthority(t, "untrusted-root")
+	keys := memoryAttestationKeys{}
+	verifier, err := NewAttestationVerifier(trusted.pem, keys)
+	if err != nil {
+		t.Fatalf("初始化设备证明失败: %v", err)
+	}
+
+	deviceKey, leaf, keyID := untrusted.issueDeviceKey(t)
+	proof := signForAttestationTest(t, deviceKey, []byte(RegistrationMessage(keyID, "challenge-1")))
+	if err := verifier.Register(keyID, [][]byte{leaf}, "challenge-1", proof); !errors.Is(err, ErrAttestationInvalid) {
+		t.Fatalf("不受信任的证书链应被拒绝，实际: %v", err)
+	}
+
+	trustedKey, trustedLeaf, trustedKeyID := trusted.issueDeviceKey(t)
+	wrongProof := signForAttestationTest(t, trustedKey, []byte(RegistrationMessage(trustedKeyID, "challenge-2")))
+	if err := verifier.Register(trustedKeyID, [][]byte{trustedLeaf}, "challenge-1", wrongProof); !errors.Is(err, ErrAttestationInvalid) {
+		t.Fatalf("绑定其他 challenge 的持有证明应被拒绝，实际: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("校验失败时不应登记密钥")
+	}
+}
+
+func signSubmissionForAttestationTest(bundle ChallengeBundle, body []byte) (string, string, string) {
+	bodyHash := sha256.Sum256(body)
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	signingText := fmt.Sprintf(
+		"%s\n%s\n%s\n%s\n%s",
+		http.MethodPost,
+		"/v1/feedback/issues",
+		timestamp,
+		hex.EncodeToString(bodyHash[:]),
+		bundle.Nonce,
+	)
+	mac := hmac.New(sha256.New, []byte(bundle.ClientSecret))
+	_, _ = mac.Write([]byte(signingText))
+	return timestamp, hex.EncodeToString(mac.Sum(nil)), signingText
+}
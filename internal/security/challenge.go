@@ -1,12 +1,13 @@
 package security
 
 import (
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -15,25 +16,48 @@ import (
 )
 
 var (
-	ErrChallengeMissing    = errors.New("challenge 不存在")
-	ErrChallengeExpired    = errors.New("challenge 已过期")
-	ErrChallengeUsed       = errors.New("challenge 已使用")
-	ErrSignatureInvalid    = errors.New("签名无效")
-	ErrTimestampInvalid    = errors.New("时间戳无效")
-	ErrPoWMissing          = errors.New("缺少 PoW nonce")
-	ErrPoWInvalid          = errors.New("PoW 校验失败")
-	ErrClientBlocked       = errors.New("客户端暂时封禁")
-	ErrChallengeIPMismatch = errors.New("challenge IP 不匹配")
+	ErrChallengeMissing    = newError("challenge_missing", "challenge 不存在")
+	ErrChallengeExpired    = newError("challenge_expired", "challenge 已过期")
+	ErrChallengeUsed       = newError("challenge_used", "challenge 已使用")
+	ErrSignatureInvalid    = newError("signature_invalid", "签名无效")
+	ErrTimestampInvalid    = newError("timestamp_invalid", "时间戳无效")
+	ErrPoWMissing          = newError("pow_missing", "缺少 PoW nonce")
+	ErrPoWInvalid          = newError("pow_invalid", "PoW 校验失败")
+	ErrClientBlocked       = newError("client_blocked", "客户端暂时封禁")
+	ErrChallengeIPMismatch = newError("challenge_ip_mismatch", "challenge IP 不匹配")
+	ErrSigningKeyInvalid   = newError("signing_key_invalid", "签名公钥无效")
 )
 
+// challenge 的签名算法，通过 ChallengeBundle.SigAlg 告知客户端。
+const (
+	// SigAlgHMACSHA256 使用服务端下发的 client_secret 计算 HMAC-SHA256。
+	SigAlgHMACSHA256 = "hmac-sha256"
+	// SigAlgEd25519 使用客户端登记的临时 Ed25519 公钥验签，服务端不下发任何共享密钥。
+	SigAlgEd25519 = "ed25519"
+)
+
+// ChallengeOptions 描述签发 challenge 时的可选要求。
+type ChallengeOptions struct {
+	PoWBits int
+	// AttestationKeyID 非空时提交必须附带该设备密钥的有效断言。
+	AttestationKeyID string
+	// SigningKey 非空时使用 Ed25519 模式，提交签名必须由对应私钥生成。
+	SigningKey ed25519.PublicKey
+}
+
 // ChallengeBundle 返回给客户端的一次性 challenge
 type ChallengeBundle struct {
 	ChallengeID  string    `json:"challenge_id"`
-	ClientSecret string    `json:"client_secret"`
+	SigAlg       string    `json:"sig_alg"`
+	ClientSecret string    `json:"client_secret,omitempty"`
 	Nonce        string    `json:"nonce"`
 	PoWBits      int       `json:"pow_bits"`
 	PoWSalt      string    `json:"pow_salt"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	// 非空时表示 challenge 因设备证明降低了 PoW，提交必须附带该密钥的断言。
+	AttestationKeyID string `json:"attestation_key_id,omitempty"`
+	// SigningKey 仅保存在服务端，用于 Ed25519 模式验签。
+	SigningKey ed25519.PublicKey `json:"-"`
 }
 
 type challengeRecord struct {
@@ -52,6 +76,8 @@ type ChallengeManager struct {
 	mu              sync.Mutex
 	records         map[string]*challengeRecord
 	blockedClientIP map[string]time.Time
+	assertions      AssertionVerifier
+	offenses        func(clientIP string)
 }
 
 func NewChallengeManager(ttl, timestampSkew time.Duration, failThreshold int, blockDuration time.Duration) *ChallengeManager {
@@ -65,7 +91,31 @@ func NewChallengeManager(ttl, timestampSkew time.Duration, failThreshold int, bl
 	}
 }
 
+// SetAssertionVerifier 启用提交时的设备断言校验。
+func (m *ChallengeManager) SetAssertionVerifier(verifier AssertionVerifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.assertions = verifier
+}
+
+// SetOffenseReporter 将签名失败达到阈值的 IP 交给外部封禁系统处理，替代内置的固定时长封禁。
+func (m *ChallengeManager) SetOffenseReporter(report func(clientIP string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.offenses = report
+}
+
 func (m *ChallengeManager) Issue(clientIP string, powBits int) ChallengeBundle {
+	return m.IssueWithOptions(clientIP, ChallengeOptions{PoWBits: powBits})
+}
+
+// IssueAttested 为已登记设备密钥签发 challenge；keyID 非空时提交必须附带该密钥的有效断言。
+func (m *ChallengeManager) IssueAttested(clientIP string, powBits int, keyID string) ChallengeBundle {
+	return m.IssueWithOptions(clientIP, ChallengeOptions{PoWBits: powBits, AttestationKeyID: keyID})
+}
+
+// IssueWithOptions 按 options 签发 challenge。Ed25519 模式下不生成 client_secret。
+func (m *ChallengeManager) IssueWithOptions(clientIP string, options ChallengeOptions) ChallengeBundle {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -73,13 +123,20 @@ func (m *ChallengeManager) Issue(clientIP string, powBits int) ChallengeBundle {
 	m.cleanup(now)
 
 	bundle := ChallengeBundle{
-		ChallengeID:  randomHex(16),
-		ClientSecret: randomHex(32),
-		Nonce:        randomHex(12),
-		PoWBits:      powBits,
-		PoWSalt:      randomHex(8),
-		ExpiresAt:    now.Add(m.ttl),
+		ChallengeID: randomHex(16),
+		SigAlg:      SigAlgHMACSHA256,
+		Nonce:       randomHex(12),
+		PoWBits:     options.PoWBits,
+		PoWSalt:     randomHex(8),
+		ExpiresAt:   now.Add(m.ttl),
 	}
+	if len(options.SigningKey) == ed25519.PublicKeySize {
+		bundle.SigAlg = SigAlgEd25519
+		bundle.SigningKey = append(ed25519.PublicKey(nil), options.SigningKey...)
+	} else {
+		bundle.ClientSecret = randomHex(32)
+	}
+	bundle.AttestationKeyID = strings.ToLower(strings.TrimSpace(options.AttestationKeyID))
 
 	m.records[bundle.ChallengeID] = &challengeRecord{
 		Bundle:   bundle,
@@ -100,6 +157,42 @@ func (m *ChallengeManager) VerifySubmission(
 	path string,
 	body []byte,
 ) error {
+	_, err := m.VerifyAttestedSubmission(
+		AttestationEvidence{},
+		clientIP,
+		challengeID,
+		timestampRaw,
+		signatureRaw,
+		powNonceRaw,
+		powHashRaw,
+		method,
+		path,
+		body,
+	)
+	return err
+}
+
+// VerifyAttestedSubmission 在签名与 PoW 校验之外返回设备断言结果。
+// 断言签名的数据与 HMAC 签名串相同，因此绑定了一次性 challenge nonce。
+func (m *ChallengeManager) VerifyAttestedSubmission(
+	evidence AttestationEvidence,
+	clientIP string,
+	challengeID string,
+	timestampRaw string,
+	signatureRaw string,
+	powNonceRaw string,
+	powHashRaw string,
+	method string,
+	path string,
+	body []byte,
+) (AttestationResult, error) {
+	// 上报违规可能访问 Redis，必须在释放 m.mu 之后进行，避免阻塞其他 challenge 的签发与校验。
+	var report func(clientIP string)
+	defer func() {
+		if report != nil {
+			report(clientIP)
+		}
+	}()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -107,30 +200,30 @@ func (m *ChallengeManager) VerifySubmission(
 	m.cleanup(now)
 
 	if blockedUntil, blocked := m.blockedClientIP[clientIP]; blocked && now.Before(blockedUntil) {
-		return ErrClientBlocked
+		return AttestationResult{}, ErrClientBlocked
 	}
 
 	record, exists := m.records[challengeID]
 	if !exists {
-		return ErrChallengeMissing
+		return AttestationResult{}, ErrChallengeMissing
 	}
 
 	if record.Used {
-		return ErrChallengeUsed
+		return AttestationResult{}, ErrChallengeUsed
 	}
 
 	if record.Bundle.ExpiresAt.Before(now) {
 		delete(m.records, challengeID)
-		return ErrChallengeExpired
+		return AttestationResult{}, ErrChallengeExpired
 	}
 
 	if record.IssuedIP != clientIP {
-		return ErrChallengeIPMismatch
+		return AttestationResult{}, ErrChallengeIPMismatch
 	}
 
 	timestampUnix, err := strconv.ParseInt(timestampRaw, 10, 64)
 	if err != nil {
-		return ErrTimestampInvalid
+		return AttestationResult{}, ErrTimestampInvalid
 	}
 
 	timestamp := time.Unix(timestampUnix, 0)
@@ -139,7 +232,7 @@ func (m *ChallengeManager) VerifySubmission(
 		delta = -delta
 	}
 	if delta > m.timestampSkew {
-		return ErrTimestampInvalid
+		return AttestationResult{}, ErrTimestampInvalid
 	}
 
 	bodyHash := sha256.Sum256(body)
@@ -148,12 +241,12 @@ func (m *ChallengeManager) VerifySubmission(
 	if record.Bundle.PoWBits > 0 {
 		powNonce := strings.TrimSpace(powNonceRaw)
 		if powNonce == "" {
-			m.registerFailure(now, clientIP, challengeID, record)
-			return ErrPoWMissing
+			report = m.registerFailure(now, clientIP, challengeID, record)
+			return AttestationResult{}, ErrPoWMissing
 		}
 		if len(powNonce) > 128 {
-			m.registerFailure(now, clientIP, challengeID, record)
-			return ErrPoWInvalid
+			report = m.registerFailure(now, clientIP, challengeID, record)
+			return AttestationResult{}, ErrPoWInvalid
 		}
 
 		powMessage := buildPoWMessage(
@@ -167,40 +260,91 @@ func (m *ChallengeManager) VerifySubmission(
 		)
 		powDigest := sha256.Sum256([]byte(powMessage))
 		if !hasLeadingZeroBits(powDigest[:], record.Bundle.PoWBits) {
-			m.registerFailure(now, clientIP, challengeID, record)
-			return ErrPoWInvalid
+			report = m.registerFailure(now, clientIP, challengeID, record)
+			return AttestationResult{}, ErrPoWInvalid
 		}
 		if strings.TrimSpace(powHashRaw) != "" {
 			expectedPowHash := hex.EncodeToString(powDigest[:])
 			if subtle.ConstantTimeCompare([]byte(strings.ToLower(expectedPowHash)), []byte(strings.ToLower(strings.TrimSpace(powHashRaw)))) != 1 {
-				m.registerFailure(now, clientIP, challengeID, record)
-				return ErrPoWInvalid
+				report = m.registerFailure(now, clientIP, challengeID, record)
+				return AttestationResult{}, ErrPoWInvalid
 			}
 		}
 	}
 
 	signingText := fmt.Sprintf("%s\n%s\n%s\n%s\n%s", strings.ToUpper(method), path, timestampRaw, bodyHashHex, record.Bundle.Nonce)
+	if !verifyChallengeSignature(record.Bundle, []byte(signingText), signatureRaw) {
+		report = m.registerFailure(now, clientIP, challengeID, record)
+		return AttestationResult{}, ErrSignatureInvalid
+	}
 
-	mac := hmac.New(sha256.New, []byte(record.Bundle.ClientSecret))
-	_, _ = mac.Write([]byte(signingText))
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-
-	if subtle.ConstantTimeCompare([]byte(strings.ToLower(expectedSignature)), []byte(strings.ToLower(signatureRaw))) != 1 {
-		m.registerFailure(now, clientIP, challengeID, record)
-		return ErrSignatureInvalid
+	attestation := m.verifyAssertionLocked(evidence, []byte(signingText))
+	if record.Bundle.AttestationKeyID != "" &&
+		(!attestation.Verified || attestation.KeyID != record.Bundle.AttestationKeyID) {
+		report = m.registerFailure(now, clientIP, challengeID, record)
+		return attestation, ErrAttestationRequired
 	}
 
 	record.Used = true
 	delete(m.records, challengeID)
-	return nil
+	return attestation, nil
 }
 
-func (m *ChallengeManager) registerFailure(now time.Time, clientIP, challengeID string, record *challengeRecord) {
+// verifyChallengeSignature 按 challenge 的签名算法校验十六进制签名。
+func verifyChallengeSignature(bundle ChallengeBundle, signingText []byte, signatureRaw string) bool {
+	if bundle.SigAlg == SigAlgEd25519 {
+		signature, err := hex.DecodeString(strings.TrimSpace(signatureRaw))
+		if err != nil || len(signature) != ed25519.SignatureSize {
+			return false
+		}
+		return ed25519.Verify(bundle.SigningKey, signingText, signature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(bundle.ClientSecret))
+	_, _ = mac.Write(signingText)
+	expectedSignature := hex.EncodeToString(mac.Sum(nil))
+	return subtle.ConstantTimeCompare([]byte(strings.ToLower(expectedSignature)), []byte(strings.ToLower(signatureRaw))) == 1
+}
+
+// ParseSigningKey 解析客户端登记的 Base64 Ed25519 公钥。
+func ParseSigningKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, ErrSigningKeyInvalid
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+func (m *ChallengeManager) verifyAssertionLocked(evidence AttestationEvidence, clientData []byte) AttestationResult {
+	result := AttestationResult{KeyID: strings.ToLower(strings.TrimSpace(evidence.KeyID))}
+	if result.KeyID == "" || len(evidence.Assertion) == 0 {
+		result.Err = ErrAttestationMissing
+		return result
+	}
+	if m.assertions == nil {
+		result.Err = ErrAttestationKeyUnknown
+		return result
+	}
+	if err := m.assertions.VerifyAssertion(result.KeyID, clientData, evidence.Assertion); err != nil {
+		result.Err = err
+		return result
+	}
+	result.Verified = true
+	return result
+}
+
+// registerFailure 记录一次失败；达到阈值且设置了外部封禁时返回上报函数，由调用方在释放锁后调用。
+func (m *ChallengeManager) registerFailure(now time.Time, clientIP, challengeID string, record *challengeRecord) func(clientIP string) {
 	record.FailCount++
-	if record.FailCount >= m.failThreshold {
-		m.blockedClientIP[clientIP] = now.Add(m.blockDuration)
-		delete(m.records, challengeID)
+	if record.FailCount < m.failThreshold {
+		return nil
 	}
+	delete(m.records, challengeID)
+	if m.offenses != nil {
+		return m.offenses
+	}
+	m.blockedClientIP[clientIP] = now.Add(m.blockDuration)
+	return nil
 }
 
 func buildPoWMessage(method, path, timestampRaw, bodyHashHex, challengeID, powSalt, powNonce string) string {
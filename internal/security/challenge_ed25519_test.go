@@ -0,0 +1,81 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerifySubmissionWithEd25519SigningKey(t *testing.T) {
+	manager := NewChallengeManager(2*time.Minute, 90*time.Second, 5, 10*time.Minute)
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成 Ed25519 密钥失败: %v", err)
+	}
+	parsed, err := ParseSigningKey(base64.StdEncoding.EncodeToString(publicKey))
+	if err != nil {
+		t.Fatalf("解析签名公钥失败: %v", err)
+	}
+
+	clientIP := "127.0.0.1"
+	body := []byte(`{"title":"hello"}`)
+	bodyHash := sha256.Sum256(body)
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	sign := func(bundle ChallengeBundle, key ed25519.PrivateKey) string {
+		signingText := fmt.Sprintf(
+			"%s\n%s\n%s\n%s\n%s",
+			http.MethodPost,
+			"/v1/feedback/issues",
+			timestamp,
+			hex.EncodeToString(bodyHash[:]),
+			bundle.Nonce,
+		)
+		return hex.EncodeToString(ed25519.Sign(key, []byte(signingText)))
+	}
+	verify := func(bundle ChallengeBundle, signature string) error {
+		return manager.VerifySubmission(
+			clientIP,
+			bundle.ChallengeID,
+			timestamp,
+			signature,
+			"",
+			"",
+			http.MethodPost,
+			"/v1/feedback/issues",
+			body,
+		)
+	}
+
+	bundle := manager.IssueWithOptions(clientIP, ChallengeOptions{SigningKey: parsed})
+	if bundle.SigAlg != SigAlgEd25519 || bundle.ClientSecret != "" {
+		t.Fatalf("Ed25519 challenge 不应下发 client_secret: %+v", bundle)
+	}
+	if err := verify(bundle, sign(bundle, privateKey)); err != nil {
+		t.Fatalf("期望 Ed25519 签名校验成功，实际失败: %v", err)
+	}
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成 Ed25519 密钥失败: %v", err)
+	}
+	forged := manager.IssueWithOptions(clientIP, ChallengeOptions{SigningKey: parsed})
+	if err := verify(forged, sign(forged, otherKey)); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("其他私钥的签名应被拒绝，实际: %v", err)
+	}
+
+	hmacBundle := manager.Issue(clientIP, 0)
+	if hmacBundle.SigAlg != SigAlgHMACSHA256 || hmacBundle.ClientSecret == "" {
+		t.Fatalf("默认 challenge 应使用 HMAC 并下发 client_secret: %+v", hmacBundle)
+	}
+
+	if _, err := ParseSigningKey(base64.StdEncoding.EncodeToString(publicKey[:16])); !errors.Is(err, ErrSigningKeyInvalid) {
+		t.Fatalf("长度错误的公钥应被拒绝，实际: %v", err)
+	}
+}
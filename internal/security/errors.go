@@ -0,0 +1,15 @@
+package security
+
+// Error 是带稳定错误码的安全校验错误，Code 可直接返回给客户端。
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
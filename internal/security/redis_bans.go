@@ -0,0 +1,204 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBanStore 使用 Redis 在多实例间共享封禁与违规等级。
+// 当 Redis 不可用时，会回退到内存封禁。
+type RedisBanStore struct {
+	client    *redis.Client
+	keyPrefix string
+	fallback  *MemoryBanStore
+	timeout   time.Duration
+}
+
+// 已在封禁中时直接返回当前状态；否则等级加一并按阶梯写入新的截止时间。
+var escalateBanScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local current = redis.call("HMGET", KEYS[1], "level", "until")
+local level = tonumber(current[1] or "0") or 0
+local untilMillis = tonumber(current[2] or "0") or 0
+if untilMillis > now then
+  return {level, untilMillis, 0}
+end
+level = level + 1
+local steps = #ARGV - 4
+local index = level
+if index > steps then
+  index = steps
+end
+untilMillis = now + tonumber(ARGV[4 + index])
+redis.call("HSET", KEYS[1], "level", level, "until", untilMillis, "reason", ARGV[2], "created_at", now, "manual", 0)
+redis.call("PEXPIRE", KEYS[1], untilMillis - now + tonumber(ARGV[3]))
+redis.call("ZADD", KEYS[2], untilMillis, ARGV[4])
+return {level, untilMillis, 1}
+`)
+
+func NewRedisBanStore(client *redis.Client, keyPrefix string) *RedisBanStore {
+	return &RedisBanStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+		fallback:  NewMemoryBanStore(),
+		timeout:   800 * time.Millisecond,
+	}
+}
+
+func (s *RedisBanStore) Active(ip string, now time.Time) (IPBan, bool) {
+	if s.client == nil {
+		return s.fallback.Active(ip, now)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	values, err := s.client.HGetAll(ctx, s.banKey(ip)).Result()
+	if err != nil {
+		return s.fallback.Active(ip, now)
+	}
+	ban, ok := decodeRedisBan(ip, values)
+	if !ok || !now.Before(ban.Until) {
+		return IPBan{}, false
+	}
+	return ban, true
+}
+
+func (s *RedisBanStore) Escalate(ip, reason string, ladder []time.Duration, now time.Time) IPBan {
+	if s.client == nil {
+		return s.fallback.Escalate(ip, reason, ladder, now)
+	}
+	if len(ladder) == 0 {
+		ladder = DefaultBanLadder
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	args := []any{now.UnixMilli(), reason, banOffenseMemory.Milliseconds(), ip}
+	for _, step := range ladder {
+		args = append(args, step.Milliseconds())
+	}
+	result, err := escalateBanScript.Run(ctx, s.client, []string{s.banKey(ip), s.indexKey()}, args...).Int64Slice()
+	if err != nil || len(result) != 3 {
+		return s.fallback.Escalate(ip, reason, ladder, now)
+	}
+	if result[2] == 0 {
+		if ban, ok := s.Active(ip, now); ok {
+			return ban
+		}
+	}
+	return IPBan{
+		IP:        ip,
+		Level:     int(result[0]),
+		Reason:    reason,
+		CreatedAt: now.UTC(),
+		Until:     time.UnixMilli(result[1]).UTC(),
+	}
+}
+
+func (s *RedisBanStore) Put(ban IPBan) error {
+	if s.client == nil {
+		return s.fallback.Put(ban)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	key := s.banKey(ban.IP)
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, key,
+		"level", ban.Level,
+		"until", ban.Until.UnixMilli(),
+		"reason", ban.Reason,
+		"created_at", ban.CreatedAt.UnixMilli(),
+		"manual", boolToRedis(ban.Manual),
+	)
+	pipe.PExpireAt(ctx, key, ban.Until.Add(banOffenseMemory))
+	pipe.ZAdd(ctx, s.indexKey(), redis.Z{Score: float64(ban.Until.UnixMilli()), Member: ban.IP})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("写入 Redis 封禁失败: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisBanStore) Lift(ip string) (bool, error) {
+	if s.client == nil {
+		return s.fallback.Lift(ip)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	pipe := s.client.TxPipeline()
+	deleted := pipe.Del(ctx, s.banKey(ip))
+	pipe.ZRem(ctx, s.indexKey(), ip)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("解除 Redis 封禁失败: %w", err)
+	}
+	// 同时清理回退存储，避免 Redis 恢复前写入的记录继续生效。
+	_, _ = s.fallback.Lift(ip)
+	return deleted.Val() > 0, nil
+}
+
+func (s *RedisBanStore) List(now time.Time) ([]IPBan, error) {
+	if s.client == nil {
+		return s.fallback.List(now)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	nowMillis := strconv.FormatInt(now.UnixMilli(), 10)
+	if err := s.client.ZRemRangeByScore(ctx, s.indexKey(), "-inf", "("+nowMillis).Err(); err != nil {
+		return nil, fmt.Errorf("读取 Redis 封禁失败: %w", err)
+	}
+	members, err := s.client.ZRangeByScore(ctx, s.indexKey(), &redis.ZRangeBy{Min: nowMillis, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取 Redis 封禁失败: %w", err)
+	}
+
+	result := make([]IPBan, 0, len(members))
+	for _, ip := range members {
+		values, err := s.client.HGetAll(ctx, s.banKey(ip)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("读取 Redis 封禁失败: %w", err)
+		}
+		if ban, ok := decodeRedisBan(ip, values); ok && now.Before(ban.Until) {
+			result = append(result, ban)
+		}
+	}
+	sortIPBans(result)
+	return result, nil
+}
+
+func (s *RedisBanStore) banKey(ip string) string {
+	return fmt.Sprintf("%s:ban:%s", s.keyPrefix, ip)
+}
+
+func (s *RedisBanStore) indexKey() string {
+	return fmt.Sprintf("%s:bans", s.keyPrefix)
+}
+
+func decodeRedisBan(ip string, values map[string]string) (IPBan, bool) {
+	untilMillis, err := strconv.ParseInt(values["until"], 10, 64)
+	if err != nil || untilMillis <= 0 {
+		return IPBan{}, false
+	}
+	level, _ := strconv.Atoi(values["level"])
+	createdMillis, _ := strconv.ParseInt(values["created_at"], 10, 64)
+	return IPBan{
+		IP:        ip,
+		Level:     level,
+		Reason:    values["reason"],
+		Manual:    values["manual"] == "1",
+		CreatedAt: time.UnixMilli(createdMillis).UTC(),
+		Until:     time.UnixMilli(untilMillis).UTC(),
+	}, true
+}
+
+func boolToRedis(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
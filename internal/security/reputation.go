@@ -0,0 +1,377 @@
+package security
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 封禁时长按违规次数逐级升级，超过阶梯长度后保持最后一级。
+var DefaultBanLadder = []time.Duration{10 * time.Minute, time.Hour, 24 * time.Hour}
+
+// banOffenseMemory 是封禁结束后仍记住违规等级的时间，超过后重新从第一级开始。
+const banOffenseMemory = 7 * 24 * time.Hour
+
+// 每个公开请求都要查询封禁，查询结果在进程内缓存 banCacheTTL，避免每次请求都访问 Redis。
+// 其他实例写入的封禁或解除最多延迟这么久生效；本实例的变更会立即更新缓存。
+const (
+	banCacheTTL        = 5 * time.Second
+	maxBanCacheEntries = 10000
+)
+
+var ErrInvalidIP = newError("ip_invalid", "IP 地址无效")
+
+// IPBan 描述一个 IP 的当前封禁状态。
+type IPBan struct {
+	IP        string    `json:"ip"`
+	Level     int       `json:"level"`
+	Reason    string    `json:"reason"`
+	Manual    bool      `json:"manual"`
+	CreatedAt time.Time `json:"created_at"`
+	Until     time.Time `json:"until"`
+}
+
+// IPVerdict 是公开路由入口的访问判定。
+type IPVerdict struct {
+	Allowed     bool
+	Allowlisted bool
+	Denylisted  bool
+	Ban         *IPBan
+}
+
+// BanStore 保存封禁记录；Redis 实现会在多实例间共享。
+type BanStore interface {
+	// Active 返回仍在生效的封禁。
+	Active(ip string, now time.Time) (IPBan, bool)
+	// Escalate 记录一次违规：已在封禁中时保持不变，否则按阶梯升级。
+	Escalate(ip, reason string, ladder []time.Duration, now time.Time) IPBan
+	// Put 写入手动封禁，保留现有违规等级。
+	Put(ban IPBan) error
+	// Lift 解除封禁并清空违规等级。
+	Lift(ip string) (bool, error)
+	// List 返回所有生效中的封禁。
+	List(now time.Time) ([]IPBan, error)
+}
+
+// IPReputation 组合静态 CIDR 名单与动态封禁。
+type IPReputation struct {
+	allow  []*net.IPNet
+	deny   []*net.IPNet
+	bans   BanStore
+	ladder []time.Duration
+	now    func() time.Time
+
+	cacheMu sync.Mutex
+	cache   map[string]banCacheEntry
+}
+
+type banCacheEntry struct {
+	ban       IPBan
+	banned    bool
+	expiresAt time.Time
+}
+
+func NewIPReputation(allowCIDRs, denyCIDRs []string, bans BanStore) (*IPReputation, error) {
+	allow, err := parseCIDRList(allowCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parseCIDRList(denyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	if bans == nil {
+		bans = NewMemoryBanStore()
+	}
+	return &IPReputation{
+		allow:  allow,
+		deny:   deny,
+		bans:   bans,
+		ladder: DefaultBanLadder,
+		now:    time.Now,
+		cache:  make(map[string]banCacheEntry),
+	}, nil
+}
+
+// Check 按允许名单、拒绝名单、动态封禁的顺序判定。允许名单优先于其他规则。
+func (r *IPReputation) Check(rawIP string) IPVerdict {
+	ip := net.ParseIP(strings.TrimSpace(rawIP))
+	if ip == nil {
+		return IPVerdict{Allowed: true}
+	}
+	if containsIP(r.allow, ip) {
+		return IPVerdict{Allowed: true, Allowlisted: true}
+	}
+	if containsIP(r.deny, ip) {
+		return IPVerdict{Denylisted: true}
+	}
+	if ban, banned := r.activeBan(ip.String(), r.now()); banned {
+		return IPVerdict{Ban: &ban}
+	}
+	return IPVerdict{Allowed: true}
+}
+
+// activeBan 优先使用进程内缓存的查询结果，缓存过期后才查询封禁存储。
+func (r *IPReputation) activeBan(ip string, now time.Time) (IPBan, bool) {
+	r.cacheMu.Lock()
+	entry, cached := r.cache[ip]
+	r.cacheMu.Unlock()
+	if cached && now.Before(entry.expiresAt) {
+		return entry.ban, entry.banned && now.Before(entry.ban.Until)
+	}
+
+	ban, banned := r.bans.Active(ip, now)
+	r.cacheBan(ip, ban, banned, now)
+	return ban, banned
+}
+
+func (r *IPReputation) cacheBan(ip string, ban IPBan, banned bool, now time.Time) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if _, exists := r.cache[ip]; !exists && len(r.cache) >= maxBanCacheEntries {
+		for cachedIP, entry := range r.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(r.cache, cachedIP)
+			}
+		}
+		if len(r.cache) >= maxBanCacheEntries {
+			clear(r.cache)
+		}
+	}
+	r.cache[ip] = banCacheEntry{ban: ban, banned: banned, expiresAt: now.Add(banCacheTTL)}
+}
+
+func (r *IPReputation) forgetBan(ip string) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	delete(r.cache, ip)
+}
+
+// ReportOffense 为自动检测到的违规升级封禁；允许名单内的 IP 不会被封禁。
+func (r *IPReputation) ReportOffense(rawIP, reason string) (IPBan, bool) {
+	ip := net.ParseIP(strings.TrimSpace(rawIP))
+	if ip == nil || containsIP(r.allow, ip) {
+		return IPBan{}, false
+	}
+	now := r.now()
+	ban := r.bans.Escalate(ip.String(), reason, r.ladder, now)
+	r.cacheBan(ban.IP, ban, true, now)
+	return ban, true
+}
+
+// Ban 手动封禁 IP；duration 为 0 时按违规阶梯升级。
+func (r *IPReputation) Ban(rawIP, reason string, duration time.Duration) (IPBan, error) {
+	ip := net.ParseIP(strings.TrimSpace(rawIP))
+	if ip == nil {
+		return IPBan{}, ErrInvalidIP
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "管理员手动封禁"
+	}
+	if len([]rune(reason)) > 200 {
+		return IPBan{}, fmt.Errorf("封禁原因不能超过 200 个字符")
+	}
+	if duration < 0 || duration > 365*24*time.Hour {
+		return IPBan{}, fmt.Errorf("封禁时长必须在 0 到 365 天之间")
+	}
+	now := r.now().UTC()
+	if duration == 0 {
+		ban := r.bans.Escalate(ip.String(), reason, r.ladder, now)
+		r.cacheBan(ban.IP, ban, true, now)
+		return ban, nil
+	}
+	ban := IPBan{
+		IP:        ip.String(),
+		Reason:    reason,
+		Manual:    true,
+		CreatedAt: now,
+		Until:     now.Add(duration),
+	}
+	if current, ok := r.bans.Active(ban.IP, now); ok {
+		ban.Level = current.Level
+	}
+	if err := r.bans.Put(ban); err != nil {
+		return IPBan{}, err
+	}
+	r.cacheBan(ban.IP, ban, true, now)
+	return ban, nil
+}
+
+func (r *IPReputation) Lift(rawIP string) (bool, error) {
+	ip := net.ParseIP(strings.TrimSpace(rawIP))
+	if ip == nil {
+		return false, ErrInvalidIP
+	}
+	defer r.forgetBan(ip.String())
+	return r.bans.Lift(ip.String())
+}
+
+func (r *IPReputation) List() ([]IPBan, error) {
+	return r.bans.List(r.now())
+}
+
+// AllowCIDRs 与 DenyCIDRs 返回静态名单，供管理接口展示。
+func (r *IPReputation) AllowCIDRs() []string {
+	return formatCIDRList(r.allow)
+}
+
+func (r *IPReputation) DenyCIDRs() []string {
+	return formatCIDRList(r.deny)
+}
+
+type memoryBanRecord struct {
+	ban      IPBan
+	forgetAt time.Time
+}
+
+// MemoryBanStore 是单实例内存封禁存储，也是 Redis 不可用时的回退。
+type MemoryBanStore struct {
+	mu      sync.Mutex
+	records map[string]memoryBanRecord
+}
+
+func NewMemoryBanStore() *MemoryBanStore {
+	return &MemoryBanStore{records: make(map[string]memoryBanRecord)}
+}
+
+func (s *MemoryBanStore) Active(ip string, now time.Time) (IPBan, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[ip]
+	if !ok || !now.Before(record.ban.Until) {
+		return IPBan{}, false
+	}
+	return record.ban, true
+}
+
+func (s *MemoryBanStore) Escalate(ip, reason string, ladder []time.Duration, now time.Time) IPBan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanupLocked(now)
+
+	record, exists := s.records[ip]
+	if exists && now.Before(record.ban.Until) {
+		return record.ban
+	}
+	level := 1
+	if exists {
+		level = record.ban.Level + 1
+	}
+	duration := banDurationForLevel(ladder, level)
+	ban := IPBan{
+		IP:        ip,
+		Level:     level,
+		Reason:    reason,
+		CreatedAt: now.UTC(),
+		Until:     now.UTC().Add(duration),
+	}
+	s.records[ip] = memoryBanRecord{ban: ban, forgetAt: ban.Until.Add(banOffenseMemory)}
+	return ban
+}
+
+func (s *MemoryBanStore) Put(ban IPBan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[ban.IP] = memoryBanRecord{ban: ban, forgetAt: ban.Until.Add(banOffenseMemory)}
+	return nil
+}
+
+func (s *MemoryBanStore) Lift(ip string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.records[ip]
+	delete(s.records, ip)
+	return exists, nil
+}
+
+func (s *MemoryBanStore) List(now time.Time) ([]IPBan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanupLocked(now)
+	result := make([]IPBan, 0, len(s.records))
+	for _, record := range s.records {
+		if now.Before(record.ban.Until) {
+			result = append(result, record.ban)
+		}
+	}
+	sortIPBans(result)
+	return result, nil
+}
+
+func (s *MemoryBanStore) cleanupLocked(now time.Time) {
+	for ip, record := range s.records {
+		if now.After(record.forgetAt) {
+			delete(s.records, ip)
+		}
+	}
+}
+
+func banDurationForLevel(ladder []time.Duration, level int) time.Duration {
+	if len(ladder) == 0 {
+		ladder = DefaultBanLadder
+	}
+	if level < 1 {
+		level = 1
+	}
+	if level > len(ladder) {
+		level = len(ladder)
+	}
+	return ladder[level-1]
+}
+
+func sortIPBans(bans []IPBan) {
+	sort.SliceStable(bans, func(i, j int) bool {
+		if !bans[i].Until.Equal(bans[j].Until) {
+			return bans[i].Until.After(bans[j].Until)
+		}
+		return bans[i].IP < bans[j].IP
+	})
+}
+
+func parseCIDRList(values []string) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("无效网段 %q", value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			value = fmt.Sprintf("%s/%d", ip.String(), bits)
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("无效网段 %q", value)
+		}
+		result = append(result, network)
+	}
+	return result, nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatCIDRList(networks []*net.IPNet) []string {
+	result := make([]string, 0, len(networks))
+	for _, network := range networks {
+		result = append(result, network.String())
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package security
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIPReputationEscalatesRepeatOffenders(t *testing.T) {
+	reputation, err := NewIPReputation(nil, nil, NewMemoryBanStore())
+	if err != nil {
+		t.Fatalf("初始化 IP 信誉失败: %v", err)
+	}
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	reputation.now = func() time.Time { return now }
+
+	expected := []time.Duration{10 * time.Minute, time.Hour, 24 * time.Hour, 24 * time.Hour}
+	for index, duration := range expected {
+		ban, banned := reputation.ReportOffense("203.0.113.7", "签名失败")
+		if !banned || ban.Level != index+1 || !ban.Until.Equal(now.Add(duration)) {
+			t.Fatalf("第 %d 次违规封禁不正确: %#v", index+1, ban)
+		}
+		if repeated, _ := reputation.ReportOffense("203.0.113.7", "签名失败"); repeated.Level != ban.Level {
+			t.Fatalf("封禁期间重复上报不应继续升级: %#v", repeated)
+		}
+		if verdict := reputation.Check("203.0.113.7"); verdict.Allowed || verdict.Ban == nil {
+			t.Fatalf("封禁期间应拒绝访问: %#v", verdict)
+		}
+		now = ban.Until.Add(time.Second)
+		if verdict := reputation.Check("203.0.113.7"); !verdict.Allowed {
+			t.Fatalf("封禁到期后应恢复访问: %#v", verdict)
+		}
+	}
+
+	now = now.Add(banOffenseMemory + time.Hour)
+	if ban, _ := reputation.ReportOffense("203.0.113.7", "签名失败"); ban.Level != 1 {
+		t.Fatalf("违规记录过期后应从第一级重新开始: %#v", ban)
+	}
+	if lifted, err := reputation.Lift("203.0.113.7"); err != nil || !lifted {
+		t.Fatalf("解除封禁失败: lifted=%t err=%v", lifted, err)
+	}
+	if verdict := reputation.Check("203.0.113.7"); !verdict.Allowed {
+		t.Fatalf("解除封禁后应恢复访问: %#v", verdict)
+	}
+}
+
+func TestIPReputationStaticLists(t *testing.T) {
+	reputation, err := NewIPReputation(
+		[]string{"10.0.0.0/8"},
+		[]string{"10.1.0.0/16", "198.51.100.0/24"},
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("初始化 IP 名单失败: %v", err)
+	}
+
+	if verdict := reputation.Check("10.1.2.3"); !verdict.Allowed || !verdict.Allowlisted {
+		t.Fatalf("允许名单应优先于拒绝名单: %#v", verdict)
+	}
+	if verdict := reputation.Check("198.51.100.20"); verdict.Allowed || !verdict.Denylisted {
+		t.Fatalf("拒绝名单内 IP 应被拦截: %#v", verdict)
+	}
+	if _, banned := reputation.ReportOffense("10.9.9.9", "签名失败"); banned {
+		t.Fatalf("允许名单内 IP 不应被自动封禁")
+	}
+	if _, err := reputation.Ban("not-an-ip", "", time.Hour); err != ErrInvalidIP {
+		t.Fatalf("无效 IP 应被拒绝，实际: %v", err)
+	}
+	if _, err := NewIPReputation([]string{"10.0.0.0/33"}, nil, nil); err == nil {
+		t.Fatalf("无效网段应返回错误")
+	}
+}
+
+type countingBanStore struct {
+	*MemoryBanStore
+	lookups int
+}
+
+func (s *countingBanStore) Active(ip string, now time.Time) (IPBan, bool) {
+	s.lookups++
+	return s.MemoryBanStore.Active(ip, now)
+}
+
+func TestIPReputationCachesBanLookups(t *testing.T) {
+	bans := &countingBanStore{MemoryBanStore: NewMemoryBanStore()}
+	reputation, err := NewIPReputation(nil, nil, bans)
+	if err != nil {
+		t.Fatalf("初始化 IP 信誉失败: %v", err)
+	}
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	reputation.now = func() time.Time { return now }
+
+	for range 3 {
+		if verdict := reputation.Check("203.0.113.7"); !verdict.Allowed {
+			t.Fatalf("未封禁的 IP 应允许访问: %#v", verdict)
+		}
+	}
+	if bans.lookups != 1 {
+		t.Fatalf("缓存有效期内不应重复查询封禁存储，实际查询 %d 次", bans.lookups)
+	}
+
+	// 本实例的封禁与解除立即生效，不等缓存过期。
+	if _, banned := reputation.ReportOffense("203.0.113.7", "签名失败"); !banned {
+		t.Fatal("违规上报应产生封禁")
+	}
+	if verdict := reputation.Check("203.0.113.7"); verdict.Allowed {
+		t.Fatalf("封禁后应立即拒绝访问: %#v", verdict)
+	}
+	if _, err := reputation.Lift("203.0.113.7"); err != nil {
+		t.Fatalf("解除封禁失败: %v", err)
+	}
+	if verdict := reputation.Check("203.0.113.7"); !verdict.Allowed {
+		t.Fatalf("解除封禁后应立即恢复访问: %#v", verdict)
+	}
+
+	// 其他实例写入的封禁在缓存过期后生效。
+	lookups := bans.lookups
+	_ = bans.Put(IPBan{IP: "203.0.113.7", Manual: true, Until: now.Add(time.Hour)})
+	if verdict := reputation.Check("203.0.113.7"); !verdict.Allowed || bans.lookups != lookups {
+		t.Fatalf("缓存有效期内应沿用缓存结果: %#v", verdict)
+	}
+	now = now.Add(banCacheTTL)
+	if verdict := reputation.Check("203.0.113.7"); verdict.Allowed {
+		t.Fatalf("缓存过期后应读取新的封禁: %#v", verdict)
+	}
+}
+
+func TestChallengeOffenseReporterRunsWithoutLock(t *testing.T) {
+	manager := NewChallengeManager(2*time.Minute, 90*time.Second, 1, 10*time.Minute)
+	reported := make(chan string, 1)
+	// 上报函数内再次签发 challenge；若仍持有 ChallengeManager 的锁会死锁。
+	manager.SetOffenseReporter(func(clientIP string) {
+		manager.Issue(clientIP, 0)
+		reported <- clientIP
+	})
+
+	bundle := manager.Issue("203.0.113.7", 0)
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.VerifySubmission(
+			"203.0.113.7",
+			bundle.ChallengeID,
+			strconv.FormatInt(time.Now().Unix(), 10),
+			"bad-signature",
+			"",
+			"",
+			"POST",
+			"/v1/feedback/issues",
+			nil,
+		)
+	}()
+	select {
+	case err := <-done:
+		if err != ErrSignatureInvalid {
+			t.Fatalf("错误签名应被拒绝，实际: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("上报违规时不应持有 challenge 锁")
+	}
+	if ip := <-reported; ip != "203.0.113.7" {
+		t.Fatalf("上报的 IP 不正确: %s", ip)
+	}
+}
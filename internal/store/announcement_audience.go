@@ -0,0 +1,135 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var announcementChannelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// AnnouncementAudience 描述请求公告的客户端。零值表示不做服务端筛选，
+// 此时仅隐藏指定了分发渠道的公告，因为旧客户端无法识别渠道字段。
+type AnnouncementAudience struct {
+	Platform string
+	// Build 仅在 HasBuild 为 true 时参与筛选。
+	Build    int
+	HasBuild bool
+	// Locale 非空时同一编号的公告只保留语言最匹配的一条。
+	Locale  string
+	Channel string
+}
+
+// ParseAnnouncementAudience 解析公告接口的查询参数，空值表示不限制对应维度。
+func ParseAnnouncementAudience(platform, build, locale, channel string) (AnnouncementAudience, error) {
+	audience := AnnouncementAudience{
+		Platform: normalizeAnnouncementPlatform(platform),
+		Locale:   strings.TrimSpace(locale),
+		Channel:  strings.ToLower(strings.TrimSpace(channel)),
+	}
+	if audience.Platform != "" && audience.Platform != "iOS" && audience.Platform != "watchOS" {
+		return AnnouncementAudience{}, fmt.Errorf("platform 仅支持 iOS 或 watchOS")
+	}
+	if build = strings.TrimSpace(build); build != "" {
+		parsed, err := strconv.Atoi(build)
+		if err != nil || parsed < 0 {
+			return AnnouncementAudience{}, fmt.Errorf("build 必须是非负整数")
+		}
+		audience.Build = parsed
+		audience.HasBuild = true
+	}
+	if len([]rune(audience.Locale)) > 32 {
+		return AnnouncementAudience{}, fmt.Errorf("locale 不能超过 32 个字符")
+	}
+	if audience.Channel != "" && !announcementChannelPattern.MatchString(audience.Channel) {
+		return AnnouncementAudience{}, fmt.Errorf("channel 只能包含小写字母、数字、下划线或连字符")
+	}
+	return audience, nil
+}
+
+// matches 判断单条公告的平台、构建号与渠道限制是否适用于该客户端。
+func (a AnnouncementAudience) matches(record AnnouncementRecord) bool {
+	return a.matchesTarget(record.Channel, record.Platform, record.MinBuild, record.MaxBuild)
+}
+
+// matchesTarget 按投放条件判断内容是否适用于该客户端，公告与意见征集共用同一规则。
+func (a AnnouncementAudience) matchesTarget(channel, platform, minBuild, maxBuild string) bool {
+	if channel != "" && channel != a.Channel {
+		return false
+	}
+	if a.Platform != "" && platform != "" && platform != a.Platform {
+		return false
+	}
+	if a.HasBuild {
+		if minimum, err := strconv.Atoi(minBuild); err == nil && minBuild != "" && a.Build < minimum {
+			return false
+		}
+		if maximum, err := strconv.Atoi(maxBuild); err == nil && maxBuild != "" && a.Build > maximum {
+			return false
+		}
+	}
+	return true
+}
+
+// selectLanguages 在同一编号的多语言公告中为 Locale 选出最佳的一条。
+// 优先级：逐级截短的语言标签精确匹配（zh-Hans-CN → zh-Hans → zh）、未指定语言、en、排序后的第一条。
+// records 需已按 sortAnnouncementRecords 排序。
+func (a AnnouncementAudience) selectLanguages(records []AnnouncementRecord) []AnnouncementRecord {
+	return selectLanguageVariants(records, a.Locale, func(record AnnouncementRecord) (int, string) {
+		return record.ID, record.Language
+	})
+}
+
+// selectLanguageVariants 对按编号相邻排列的 records 逐组选出与 locale 最匹配的一条；locale 为空时原样返回。
+func selectLanguageVariants[T any](records []T, locale string, variant func(T) (int, string)) []T {
+	if locale == "" {
+		return records
+	}
+	candidates := localeFallbacks(locale)
+
+	result := make([]T, 0, len(records))
+	for start := 0; start < len(records); {
+		id, language := variant(records[start])
+		best, bestRank := records[start], languageRank(language, candidates)
+		end := start + 1
+		for ; end < len(records); end++ {
+			nextID, nextLanguage := variant(records[end])
+			if nextID != id {
+				break
+			}
+			if rank := languageRank(nextLanguage, candidates); rank < bestRank {
+				best, bestRank = records[end], rank
+			}
+		}
+		result = append(result, best)
+		start = end
+	}
+	return result
+}
+
+func languageRank(language string, candidates []string) int {
+	language = strings.ToLower(language)
+	for index, candidate := range candidates {
+		if language == candidate {
+			return index
+		}
+	}
+	switch language {
+	case "":
+		return len(candidates)
+	case "en":
+		return len(candidates) + 1
+	default:
+		return len(candidates) + 2
+	}
+}
+
+func localeFallbacks(locale string) []string {
+	parts := strings.Split(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	candidates := make([]string, 0, len(parts))
+	for length := len(parts); length > 0; length-- {
+		candidates = append(candidates, strings.Join(parts[:length], "-"))
+	}
+	return candidates
+}
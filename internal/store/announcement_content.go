@@ -0,0 +1,199 @@
+package store
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// 公告正文格式。
+const (
+	AnnouncementBodyPlain    = "plain"
+	AnnouncementBodyMarkdown = "markdown"
+)
+
+// 公告按钮类型。
+const (
+	AnnouncementActionURL     = "url"
+	AnnouncementActionRoute   = "route"
+	AnnouncementActionDismiss = "dismiss"
+)
+
+const maxAnnouncementActions = 3
+
+var (
+	announcementRoutePattern = regexp.MustCompile(`^/[A-Za-z0-9._~/?=&%-]{0,199}$`)
+	markdownHTMLPattern      = regexp.MustCompile(`<[A-Za-z/!?]`)
+	markdownLinkPattern      = regexp.MustCompile(`(!?)\[[^\]]*\]\(([^)]*)\)`)
+	// markdownImagePattern 匹配行内图片与 ![alt][ref]、![alt] 等引用式图片。
+	markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]`)
+	// markdownReferencePattern 匹配 [ref]: url 形式的链接定义，引用式链接的地址写在这里。
+	markdownReferencePattern = regexp.MustCompile(`(?m)^\s{0,3}\[[^\]]+\]:\s*(\S+)`)
+)
+
+// AnnouncementAction 是公告底部的操作按钮：打开链接、跳转应用内页面或关闭公告。
+type AnnouncementAction struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	// URL 仅用于 url 类型，支持 https 与 App Store 的 itms-apps 链接。
+	URL string `json:"url,omitempty"`
+	// Route 仅用于 route 类型，是以 / 开头的应用内路由。
+	Route string `json:"route,omitempty"`
+}
+
+// AnnouncementImage 通过 SHA-256 引用官方数据中已上传的图片。
+type AnnouncementImage struct {
+	SHA256 string `json:"sha256"`
+	Alt    string `json:"alt,omitempty"`
+	// URL 不会持久化，由公开接口按官方数据文件解析后填充。
+	URL string `json:"url,omitempty"`
+}
+
+func normalizeAnnouncementContent(record *AnnouncementRecord) {
+	record.BodyFormat = strings.ToLower(strings.TrimSpace(record.BodyFormat))
+	if record.BodyFormat == AnnouncementBodyPlain {
+		record.BodyFormat = ""
+	}
+	record.Actions = normalizeAnnouncementActions(record.Actions)
+	if record.Image != nil {
+		image := AnnouncementImage{
+			SHA256: strings.ToLower(strings.TrimSpace(record.Image.SHA256)),
+			Alt:    strings.TrimSpace(record.Image.Alt),
+		}
+		record.Image = &image
+		if image.SHA256 == "" {
+			record.Image = nil
+		}
+	}
+}
+
+func normalizeAnnouncementActions(actions []AnnouncementAction) []AnnouncementAction {
+	if len(actions) == 0 {
+		return nil
+	}
+	normalized := make([]AnnouncementAction, 0, len(actions))
+	for _, action := range actions {
+		normalized = append(normalized, AnnouncementAction{
+			Type:  strings.ToLower(strings.TrimSpace(action.Type)),
+			Label: strings.TrimSpace(action.Label),
+			URL:   strings.TrimSpace(action.URL),
+			Route: strings.TrimSpace(action.Route),
+		})
+	}
+	return normalized
+}
+
+// validateAnnouncementContent 校验正文格式、按钮与图片引用。
+func validateAnnouncementContent(record AnnouncementRecord) error {
+	switch record.BodyFormat {
+	case "", AnnouncementBodyMarkdown:
+	default:
+		return fmt.Errorf("body_format 仅支持 plain 或 markdown")
+	}
+	if err := validateAnnouncementBody(record.BodyFormat, record.Body); err != nil {
+		return err
+	}
+	if err := validateAnnouncementActions(record.Actions); err != nil {
+		return err
+	}
+	for _, localization := range record.Localizations {
+		if err := validateAnnouncementBody(record.BodyFormat, localization.Body); err != nil {
+			return fmt.Errorf("%s 翻译: %w", localization.Language, err)
+		}
+		if err := validateAnnouncementActions(localization.Actions); err != nil {
+			return fmt.Errorf("%s 翻译: %w", localization.Language, err)
+		}
+		if len(localization.Actions) > 0 && len(localization.Actions) != len(record.Actions) {
+			return fmt.Errorf("%s 翻译的按钮数量必须与默认语言一致", localization.Language)
+		}
+	}
+	if record.Image != nil {
+		if len(record.Image.SHA256) != 64 {
+			return fmt.Errorf("图片 SHA-256 必须是 64 位十六进制")
+		}
+		if _, err := hex.DecodeString(record.Image.SHA256); err != nil {
+			return fmt.Errorf("图片 SHA-256 必须是 64 位十六进制")
+		}
+		if len([]rune(record.Image.Alt)) > 200 {
+			return fmt.Errorf("图片说明不能超过 200 个字符")
+		}
+	}
+	return nil
+}
+
+// validateAnnouncementBody 限定 Markdown 正文的可用语法：不允许内嵌 HTML 与图片（包括引用式图片），
+// 行内链接与引用式链接的定义都只能使用 https。
+func validateAnnouncementBody(format, body string) error {
+	if format != AnnouncementBodyMarkdown {
+		return nil
+	}
+	if markdownHTMLPattern.MatchString(body) {
+		return fmt.Errorf("Markdown 正文不能包含 HTML 标签")
+	}
+	if markdownImagePattern.MatchString(body) {
+		return fmt.Errorf("Markdown 正文不能内嵌图片，请使用 image 字段")
+	}
+	for _, match := range markdownLinkPattern.FindAllStringSubmatch(body, -1) {
+		if !isHTTPSMarkdownURL(match[2]) {
+			return fmt.Errorf("Markdown 链接只能使用 https")
+		}
+	}
+	for _, match := range markdownReferencePattern.FindAllStringSubmatch(body, -1) {
+		if !isHTTPSMarkdownURL(match[1]) {
+			return fmt.Errorf("Markdown 链接只能使用 https")
+		}
+	}
+	return nil
+}
+
+func isHTTPSMarkdownURL(raw string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(raw)), "https://")
+}
+
+func validateAnnouncementActions(actions []AnnouncementAction) error {
+	if len(actions) > maxAnnouncementActions {
+		return fmt.Errorf("按钮不能超过 %d 个", maxAnnouncementActions)
+	}
+	for _, action := range actions {
+		labelLength := len([]rune(action.Label))
+		if labelLength < 1 || labelLength > 40 {
+			return fmt.Errorf("按钮文字长度必须在 1 到 40 个字符之间")
+		}
+		switch action.Type {
+		case AnnouncementActionURL:
+			if action.Route != "" {
+				return fmt.Errorf("url 按钮不能设置 route")
+			}
+			if err := validateAnnouncementActionURL(action.URL); err != nil {
+				return err
+			}
+		case AnnouncementActionRoute:
+			if action.URL != "" {
+				return fmt.Errorf("route 按钮不能设置 url")
+			}
+			if !announcementRoutePattern.MatchString(action.Route) {
+				return fmt.Errorf("应用内路由必须以 / 开头，且不超过 200 个字符")
+			}
+		case AnnouncementActionDismiss:
+			if action.URL != "" || action.Route != "" {
+				return fmt.Errorf("dismiss 按钮不能设置 url 或 route")
+			}
+		default:
+			return fmt.Errorf("按钮类型仅支持 url、route 或 dismiss")
+		}
+	}
+	return nil
+}
+
+func validateAnnouncementActionURL(raw string) error {
+	if raw == "" || len(raw) > 2048 {
+		return fmt.Errorf("按钮链接不能为空且不能超过 2048 个字符")
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "itms-apps") {
+		return fmt.Errorf("按钮链接必须是 https 或 itms-apps 地址")
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const maxAnnouncementLocalizations = 50
+
+// AnnouncementLocalization 是公告在某一语言下的标题与正文。
+type AnnouncementLocalization struct {
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	// Actions 非空时替换默认语言的按钮，数量须与默认语言一致，用于翻译按钮文字。
+	Actions []AnnouncementAction `json:"actions,omitempty"`
+}
+
+// SetRequiredLanguages 设置已发布的多语言公告必须包含的语言，空列表表示不要求。
+func (s *AnnouncementStore) SetRequiredLanguages(languages []string) {
+	normalized := make([]string, 0, len(languages))
+	for _, language := range languages {
+		if language = strings.TrimSpace(language); language != "" {
+			normalized = append(normalized, language)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requiredLanguages = normalized
+}
+
+// MissingLanguages 返回同一编号的公告尚未覆盖的必需语言，供管理端提示补齐翻译。
+// 未指定语言且没有翻译的公告适用于所有语言，不会产生缺失提示。
+func (s *AnnouncementStore) MissingLanguages(record AnnouncementRecord) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.requiredLanguages) == 0 || record.universal() {
+		return nil
+	}
+
+	covered := record.languageSet()
+	for _, other := range s.records {
+		if other.ID == record.ID && other.Key != record.Key {
+			for language := range other.languageSet() {
+				covered[language] = true
+			}
+		}
+	}
+	return missingLanguages(s.requiredLanguages, covered)
+}
+
+// checkRequiredLanguagesLocked 拒绝发布缺少必需语言的多语言公告。
+// 旧式的单语言条目靠共享编号组成语言版本，只在管理端提示，不在保存时拦截。
+func (s *AnnouncementStore) checkRequiredLanguagesLocked(record AnnouncementRecord) error {
+	if !record.Enabled || len(record.Localizations) == 0 {
+		return nil
+	}
+	missing := missingLanguages(s.requiredLanguages, record.languageSet())
+	if len(missing) == 0 {
+		return nil
+	}
+	return coded(
+		ErrorInvalid,
+		"announcement_translation_missing",
+		fmt.Sprintf("发布前需要补齐以下语言的翻译: %s", strings.Join(missing, ", ")),
+	)
+}
+
+// languageVariants 把多语言公告展开为兼容旧客户端的单语言条目。
+// 默认语言以空 language 输出，使没有匹配翻译的客户端回退到默认语言。
+func (r AnnouncementRecord) languageVariants() []AnnouncementRecord {
+	if len(r.Localizations) == 0 {
+		return []AnnouncementRecord{r}
+	}
+
+	fallback := r
+	fallback.Language = ""
+	fallback.Localizations = nil
+	variants := []AnnouncementRecord{fallback}
+	for _, localization := range r.Localizations {
+		variant := fallback
+		variant.Language = localization.Language
+		variant.Title = localization.Title
+		variant.Body = localization.Body
+		if len(localization.Actions) > 0 {
+			variant.Actions = localization.Actions
+		}
+		variants = append(variants, variant)
+	}
+	return variants
+}
+
+func (r AnnouncementRecord) universal() bool {
+	return r.Language == "" && len(r.Localizations) == 0
+}
+
+func (r AnnouncementRecord) languageSet() map[string]bool {
+	languages := map[string]bool{}
+	if r.Language != "" {
+		languages[strings.ToLower(r.Language)] = true
+	}
+	for _, localization := range r.Localizations {
+		languages[strings.ToLower(localization.Language)] = true
+	}
+	return languages
+}
+
+func missingLanguages(required []string, covered map[string]bool) []string {
+	missing := make([]string, 0)
+	for _, language := range required {
+		if !covered[strings.ToLower(language)] {
+			missing = append(missing, language)
+		}
+	}
+	return missing
+}
+
+func normalizeAnnouncementLocalizations(localizations []AnnouncementLocalization) []AnnouncementLocalization {
+	if len(localizations) == 0 {
+		return nil
+	}
+	normalized := make([]AnnouncementLocalization, 0, len(localizations))
+	for _, localization := range localizations {
+		normalized = append(normalized, AnnouncementLocalization{
+			Language: strings.TrimSpace(localization.Language),
+			Title:    strings.TrimSpace(localization.Title),
+			Body:     strings.TrimSpace(localization.Body),
+			Actions:  normalizeAnnouncementActions(localization.Actions),
+		})
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].Language < normalized[j].Language
+	})
+	return normalized
+}
+
+// validateAnnouncementLocalizations 要求多语言公告指定默认语言，且每种语言只出现一次。
+func validateAnnouncementLocalizations(record AnnouncementRecord) error {
+	if len(record.Localizations) == 0 {
+		return nil
+	}
+	if record.Language == "" {
+		return fmt.Errorf("包含翻译的公告必须通过 language 指定默认语言")
+	}
+	if len(record.Localizations) > maxAnnouncementLocalizations {
+		return fmt.Errorf("翻译不能超过 %d 种语言", maxAnnouncementLocalizations)
+	}
+
+	seen := map[string]bool{strings.ToLower(record.Language): true}
+	for _, localization := range record.Localizations {
+		if localization.Language == "" {
+			return fmt.Errorf("翻译的语言标识不能为空")
+		}
+		if len([]rune(localization.Language)) > 32 {
+			return fmt.Errorf("语言标识不能超过 32 个字符")
+		}
+		key := strings.ToLower(localization.Language)
+		if seen[key] {
+			return fmt.Errorf("语言 %s 重复", localization.Language)
+		}
+		seen[key] = true
+		if err := validateAnnouncementText(localization.Title, localization.Body); err != nil {
+			return fmt.Errorf("%s 翻译: %w", localization.Language, err)
+		}
+	}
+	return nil
+}
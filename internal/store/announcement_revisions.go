@@ -0,0 +1,445 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	announcementRevisionFileVersion = 1
+	maxAnnouncementRevisions        = 5000
+	maxRevisionAuthorRunes          = 64
+	// announcementRevisionLogName 是修订的追加日志，每行一条修订。
+	announcementRevisionLogName = "announcement-revisions.jsonl"
+	// legacyAnnouncementRevisionFileName 是旧版整体重写的修订文件，启动时会迁移为追加日志。
+	legacyAnnouncementRevisionFileName = "announcement-revisions.json"
+)
+
+// 公告修订的操作类型。
+const (
+	AnnouncementActionCreate  = "create"
+	AnnouncementActionUpdate  = "update"
+	AnnouncementActionDelete  = "delete"
+	AnnouncementActionRestore = "restore"
+)
+
+var ErrAnnouncementRevisionNotFound = &Error{
+	Kind:    ErrorNotFound,
+	Code:    "announcement_revision_not_found",
+	Message: "公告修订不存在",
+}
+
+// AnnouncementRevision 是一次公告变更的不可变记录。
+// Record 为变更后的完整内容；删除操作保存删除前的内容，便于撤销删除。
+type AnnouncementRevision struct {
+	ID        int64                     `json:"id"`
+	Key       string                    `json:"key"`
+	Action    string                    `json:"action"`
+	Author    string                    `json:"author"`
+	CreatedAt time.Time                 `json:"created_at"`
+	Record    AnnouncementRecord        `json:"record"`
+	Changes   []AnnouncementFieldChange `json:"changes,omitempty"`
+	// RestoredFrom 仅在恢复操作中记录来源修订编号。
+	RestoredFrom int64 `json:"restored_from,omitempty"`
+}
+
+// AnnouncementFieldChange 描述单个字段在变更前后的 JSON 值，缺省字段为 null。
+type AnnouncementFieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// announcementRevisionFile 是旧版修订文件的格式。
+type announcementRevisionFile struct {
+	Version int                    `json:"version"`
+	Records []AnnouncementRevision `json:"records"`
+}
+
+// Revisions 返回修订历史，最新的在前；key 为空时返回全部公告的修订。
+func (s *AnnouncementStore) Revisions(key string) []AnnouncementRevision {
+	key = strings.TrimSpace(key)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]AnnouncementRevision, 0)
+	for index := len(s.revisions) - 1; index >= 0; index-- {
+		if key == "" || s.revisions[index].Key == key {
+			result = append(result, s.revisions[index])
+		}
+	}
+	return result
+}
+
+// Restore 把公告恢复到指定修订的内容；若公告已删除则以原 key 重新创建。
+// imageAvailable 检查修订引用的图片是否仍是已启用的官方数据图片，为 nil 时不允许恢复带图片的修订。
+func (s *AnnouncementStore) Restore(
+	revisionID int64,
+	author string,
+	imageAvailable func(checksum string) bool,
+) (AnnouncementRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var source *AnnouncementRevision
+	for index := range s.revisions {
+		if s.revisions[index].ID == revisionID {
+			source = &s.revisions[index]
+			break
+		}
+	}
+	if source == nil {
+		return AnnouncementRecord{}, ErrAnnouncementRevisionNotFound
+	}
+
+	restored := source.Record
+	restored.UpdatedAt = time.Now().UTC()
+	normalizeAnnouncementRecord(&restored)
+	if err := validateAnnouncementRecord(restored); err != nil {
+		return AnnouncementRecord{}, invalidError("announcement_invalid", err)
+	}
+	if restored.Image != nil && (imageAvailable == nil || !imageAvailable(restored.Image.SHA256)) {
+		return AnnouncementRecord{}, coded(
+			ErrorInvalid,
+			"announcement_invalid",
+			"修订引用的图片不是已启用的官方数据图片文件",
+		)
+	}
+	if err := s.checkRequiredLanguagesLocked(restored); err != nil {
+		return AnnouncementRecord{}, err
+	}
+	sourceID := source.ID
+
+	for index, current := range s.records {
+		if current.Key != restored.Key {
+			continue
+		}
+		restored.CreatedAt = current.CreatedAt
+		s.records[index] = restored
+		revision := newAnnouncementRevision(AnnouncementActionRestore, author, &current, restored)
+		revision.RestoredFrom = sourceID
+		if err := s.commitLocked(revision); err != nil {
+			s.records[index] = current
+			return AnnouncementRecord{}, err
+		}
+		return restored, nil
+	}
+
+	if len(s.records) >= maxAnnouncementRecords {
+		return AnnouncementRecord{}, coded(
+			ErrorConflict,
+			"announcement_limit_reached",
+			fmt.Sprintf("公告条目不能超过 %d 条", maxAnnouncementRecords),
+		)
+	}
+	s.records = append(s.records, restored)
+	revision := newAnnouncementRevision(AnnouncementActionRestore, author, nil, restored)
+	revision.RestoredFrom = sourceID
+	if err := s.commitLocked(revision); err != nil {
+		s.records = s.records[:len(s.records)-1]
+		return AnnouncementRecord{}, err
+	}
+	return restored, nil
+}
+
+// commitLocked 把修订追加到日志并持久化公告文件。公告文件写入失败时截掉本次追加的修订，
+// 调用方负责回滚内存中的公告列表。批量导入会一次提交多条修订。
+// 每次提交只写入新增的修订，超出保留数量的旧修订在日志积累到两倍上限时一并清理。
+func (s *AnnouncementStore) commitLocked(revisions ...AnnouncementRevision) error {
+	appended := make([]AnnouncementRevision, 0, len(revisions))
+	next := s.nextRevision
+	for _, revision := range revisions {
+		next++
+		revision.ID = next
+		appended = append(appended, revision)
+	}
+
+	previousSize, err := s.appendRevisionLinesLocked(appended)
+	if err != nil {
+		return err
+	}
+	// 即使撤回失败也不复用这些编号，避免日志中出现重复的修订编号。
+	s.nextRevision = next
+	if err := s.saveLocked(); err != nil {
+		if truncateErr := os.Truncate(s.revisionLog, previousSize); truncateErr != nil {
+			return fmt.Errorf("%w；撤回公告修订失败: %v", err, truncateErr)
+		}
+		return err
+	}
+
+	s.revisions = append(s.revisions, appended...)
+	if len(s.revisions) > maxAnnouncementRevisions {
+		s.revisions = append([]AnnouncementRevision(nil), s.revisions[len(s.revisions)-maxAnnouncementRevisions:]...)
+	}
+	s.revisionLogLines += len(appended)
+	if s.revisionLogLines >= 2*maxAnnouncementRevisions {
+		// 修订与公告都已写入，清理失败时旧修订留在日志中，下次提交或启动时再次尝试。
+		_ = s.compactRevisionsLocked()
+	}
+	return nil
+}
+
+func newAnnouncementRevision(
+	action string,
+	author string,
+	before *AnnouncementRecord,
+	after AnnouncementRecord,
+) AnnouncementRevision {
+	revision := AnnouncementRevision{
+		Key:       after.Key,
+		Action:    action,
+		Author:    normalizeRevisionAuthor(author),
+		CreatedAt: time.Now().UTC(),
+		Record:    after,
+	}
+	if action == AnnouncementActionDelete {
+		revision.Changes = diffAnnouncementRecords(&after, nil)
+	} else {
+		revision.Changes = diffAnnouncementRecords(before, &after)
+	}
+	return revision
+}
+
+func normalizeRevisionAuthor(author string) string {
+	author = strings.TrimSpace(author)
+	if author == "" {
+		return "unknown"
+	}
+	if runes := []rune(author); len(runes) > maxRevisionAuthorRunes {
+		author = string(runes[:maxRevisionAuthorRunes])
+	}
+	return author
+}
+
+// diffAnnouncementRecords 按 JSON 字段比较公告内容，忽略 key 与时间戳等元数据。
+func diffAnnouncementRecords(before, after *AnnouncementRecord) []AnnouncementFieldChange {
+	beforeFields := announcementContentFields(before)
+	afterFields := announcementContentFields(after)
+
+	names := make([]string, 0, len(beforeFields)+len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]AnnouncementFieldChange, 0)
+	for _, name := range names {
+		previous, current := beforeFields[name], afterFields[name]
+		if bytes.Equal(previous, current) {
+			continue
+		}
+		changes = append(changes, AnnouncementFieldChange{
+			Field:  name,
+			Before: jsonOrNull(previous),
+			After:  jsonOrNull(current),
+		})
+	}
+	return changes
+}
+
+func announcementContentFields(record *AnnouncementRecord) map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	if record == nil {
+		return fields
+	}
+	content := *record
+	content.Key = ""
+	content.CreatedAt = time.Time{}
+	content.UpdatedAt = time.Time{}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	delete(fields, "key")
+	delete(fields, "created_at")
+	delete(fields, "updated_at")
+	return fields
+}
+
+func jsonOrNull(value json.RawMessage) json.RawMessage {
+	if len(value) == 0 {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+// loadRevisions 逐行读取修订日志，只保留最近 maxAnnouncementRevisions 条。
+// 最后一行缺少换行符说明上次写入被中断，这一行会被丢弃并重写日志，其余无法解析的行视为文件损坏。
+func (s *AnnouncementStore) loadRevisions() error {
+	s.revisions = []AnnouncementRevision{}
+
+	file, err := os.Open(s.revisionLog)
+	if os.IsNotExist(err) {
+		return s.migrateLegacyRevisions()
+	}
+	if err != nil {
+		return fmt.Errorf("读取公告修订日志失败: %w", err)
+	}
+	defer file.Close()
+
+	needsCompaction := false
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("读取公告修订日志失败: %w", err)
+		}
+		complete := err == nil
+		if len(bytes.TrimSpace(line)) > 0 {
+			if !complete {
+				needsCompaction = true
+				break
+			}
+			var revision AnnouncementRevision
+			if err := json.Unmarshal(line, &revision); err != nil {
+				return fmt.Errorf("解析公告修订日志第 %d 行失败: %w", lineNumber, err)
+			}
+			if revision.ID <= s.nextRevision || revision.Key == "" {
+				return fmt.Errorf("公告修订日志第 %d 行的修订 %d 无效", lineNumber, revision.ID)
+			}
+			s.nextRevision = revision.ID
+			s.revisions = append(s.revisions, revision)
+			if len(s.revisions) > 2*maxAnnouncementRevisions {
+				s.revisions = append([]AnnouncementRevision(nil), s.revisions[len(s.revisions)-maxAnnouncementRevisions:]...)
+			}
+			s.revisionLogLines++
+		}
+		if !complete {
+			break
+		}
+	}
+	if len(s.revisions) > maxAnnouncementRevisions {
+		s.revisions = append([]AnnouncementRevision(nil), s.revisions[len(s.revisions)-maxAnnouncementRevisions:]...)
+	}
+	if needsCompaction || s.revisionLogLines >= 2*maxAnnouncementRevisions {
+		return s.compactRevisionsLocked()
+	}
+	return nil
+}
+
+// migrateLegacyRevisions 把旧版 announcement-revisions.json 转写为追加日志，写入成功后删除旧文件。
+func (s *AnnouncementStore) migrateLegacyRevisions() error {
+	data, err := os.ReadFile(s.legacyRevisionFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取旧版公告修订文件失败: %w", err)
+	}
+	if strings.TrimSpace(string(data)) != "" {
+		var payload announcementRevisionFile
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return fmt.Errorf("解析旧版公告修订文件失败: %w", err)
+		}
+		if payload.Version != announcementRevisionFileVersion {
+			return fmt.Errorf("不支持的公告修订文件版本: %d", payload.Version)
+		}
+		for _, revision := range payload.Records {
+			if revision.ID <= 0 || revision.Key == "" {
+				return fmt.Errorf("公告修订 %d 无效", revision.ID)
+			}
+			if revision.ID > s.nextRevision {
+				s.nextRevision = revision.ID
+			}
+		}
+		if payload.Records != nil {
+			s.revisions = payload.Records
+		}
+	}
+	if err := s.compactRevisionsLocked(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.legacyRevisionFile); err != nil {
+		return fmt.Errorf("删除旧版公告修订文件失败: %w", err)
+	}
+	return nil
+}
+
+// appendRevisionLinesLocked 把修订逐行追加到日志并同步到磁盘，返回追加前的文件大小供调用方撤回；
+// 写入失败时截掉可能写了一半的内容。
+func (s *AnnouncementStore) appendRevisionLinesLocked(revisions []AnnouncementRevision) (int64, error) {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for _, revision := range revisions {
+		if err := encoder.Encode(revision); err != nil {
+			return 0, fmt.Errorf("编码公告修订失败: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(s.revisionLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("打开公告修订日志失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return 0, fmt.Errorf("读取公告修订日志失败: %w", err)
+	}
+	if _, err := file.Write(data.Bytes()); err != nil {
+		_ = file.Truncate(info.Size())
+		file.Close()
+		return 0, fmt.Errorf("写入公告修订日志失败: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Truncate(info.Size())
+		file.Close()
+		return 0, fmt.Errorf("同步公告修订日志失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("关闭公告修订日志失败: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// compactRevisionsLocked 只保留内存中的最近修订，先写入临时文件再原子替换日志。
+func (s *AnnouncementStore) compactRevisionsLocked() error {
+	temp, err := os.CreateTemp(filepath.Dir(s.revisionLog), ".announcement-revisions-*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建公告修订临时文件失败: %w", err)
+	}
+	tempPath := temp.Name()
+	defer os.Remove(tempPath)
+
+	if err := temp.Chmod(0o600); err != nil {
+		temp.Close()
+		return fmt.Errorf("设置公告修订文件权限失败: %w", err)
+	}
+	buffered := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(buffered)
+	for _, revision := range s.revisions {
+		if err := encoder.Encode(revision); err != nil {
+			temp.Close()
+			return fmt.Errorf("编码公告修订失败: %w", err)
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		temp.Close()
+		return fmt.Errorf("写入公告修订临时文件失败: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("同步公告修订临时文件失败: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("关闭公告修订临时文件失败: %w", err)
+	}
+	if err := os.Rename(tempPath, s.revisionLog); err != nil {
+		return fmt.Errorf("替换公告修订日志失败: %w", err)
+	}
+	s.revisionLogLines = len(s.revisions)
+	return nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnnouncementRevisionsRecordChangesAndRestore(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+
+	created, err := store.Create(AnnouncementRecord{
+		ID:      1,
+		Type:    "info",
+		Title:   "原始标题",
+		Body:    "正文",
+		Enabled: true,
+	}, "cli:alice")
+	if err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+	created.Title = "修改后的标题"
+	if _, err := store.Update(created.Key, created, "web:0a1b2c3d"); err != nil {
+		t.Fatalf("更新公告失败: %v", err)
+	}
+	if err := store.Delete(created.Key, "cli:alice"); err != nil {
+		t.Fatalf("删除公告失败: %v", err)
+	}
+
+	reloaded, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载公告存储失败: %v", err)
+	}
+	revisions := reloaded.Revisions(created.Key)
+	if len(revisions) != 3 {
+		t.Fatalf("期望 3 条修订，实际 %+v", revisions)
+	}
+	deleted, updated, first := revisions[0], revisions[1], revisions[2]
+	if first.Action != AnnouncementActionCreate || first.Author != "cli:alice" {
+		t.Fatalf("创建修订不正确: %+v", first)
+	}
+	if updated.Action != AnnouncementActionUpdate || updated.Author != "web:0a1b2c3d" ||
+		len(updated.Changes) != 1 || updated.Changes[0].Field != "title" ||
+		string(updated.Changes[0].Before) != `"原始标题"` || string(updated.Changes[0].After) != `"修改后的标题"` {
+		t.Fatalf("更新修订的差异不正确: %+v", updated)
+	}
+	if deleted.Action != AnnouncementActionDelete || deleted.Record.Title != "修改后的标题" {
+		t.Fatalf("删除修订应保留删除前内容: %+v", deleted)
+	}
+
+	restored, err := reloaded.Restore(first.ID, "cli:bob", nil)
+	if err != nil {
+		t.Fatalf("撤销删除失败: %v", err)
+	}
+	if restored.Key != created.Key || restored.Title != "原始标题" || len(reloaded.List()) != 1 {
+		t.Fatalf("撤销删除结果不正确: %+v", restored)
+	}
+	latest := reloaded.Revisions("")[0]
+	if latest.Action != AnnouncementActionRestore || latest.RestoredFrom != first.ID || latest.Author != "cli:bob" {
+		t.Fatalf("恢复修订不正确: %+v", latest)
+	}
+
+	restored, err = reloaded.Restore(updated.ID, "cli:bob", nil)
+	if err != nil || restored.Title != "修改后的标题" || !restored.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("回滚到历史修订失败: %+v err=%v", restored, err)
+	}
+
+	if _, err := reloaded.Restore(999, "cli:bob", nil); !errors.Is(err, ErrAnnouncementRevisionNotFound) {
+		t.Fatalf("未知修订应返回 ErrAnnouncementRevisionNotFound，实际 %v", err)
+	}
+}
+
+func TestAnnouncementRevisionsAppendToLog(t *testing.T) {
+	dataDir := t.TempDir()
+	logPath := filepath.Join(dataDir, announcementRevisionLogName)
+
+	// 旧版整体重写的修订文件在启动时迁移为追加日志。
+	legacy := `{"version":1,"records":[{"id":4,"key":"legacy","action":"create","author":"cli","created_at":"2026-01-01T00:00:00Z","record":{"id":9}}]}`
+	if err := os.WriteFile(filepath.Join(dataDir, legacyAnnouncementRevisionFileName), []byte(legacy), 0o600); err != nil {
+		t.Fatalf("写入旧版修订文件失败: %v", err)
+	}
+	store, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("迁移旧版修订文件失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, legacyAnnouncementRevisionFileName)); !os.IsNotExist(err) {
+		t.Fatalf("迁移后应删除旧版修订文件: %v", err)
+	}
+
+	created, err := store.Create(AnnouncementRecord{ID: 1, Type: "info", Title: "标题", Body: "正文", Enabled: true}, "cli")
+	if err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+	before := mustReadFile(t, logPath)
+	created.Title = "新标题"
+	if _, err := store.Update(created.Key, created, "cli"); err != nil {
+		t.Fatalf("更新公告失败: %v", err)
+	}
+	after := mustReadFile(t, logPath)
+	if !bytes.HasPrefix(after, before) || bytes.Count(after[len(before):], []byte("\n")) != 1 {
+		t.Fatalf("每次修改只应追加一行修订，不重写已有内容")
+	}
+
+	// 中断写入留下的半行在启动时丢弃，编号继续递增。
+	if err := os.WriteFile(logPath, append(after, `{"id":99,"key":`...), 0o600); err != nil {
+		t.Fatalf("写入修订日志失败: %v", err)
+	}
+	reloaded, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载修订日志失败: %v", err)
+	}
+	revisions := reloaded.Revisions("")
+	if len(revisions) != 3 || revisions[0].ID != 6 || revisions[2].ID != 4 || revisions[0].Record.Title != "新标题" {
+		t.Fatalf("修订日志重放不正确: %+v", revisions)
+	}
+	if !bytes.Equal(mustReadFile(t, logPath), after) {
+		t.Fatal("丢弃半行后应重写日志")
+	}
+
+	// 日志积累到两倍上限时只保留最近的修订。
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for id := int64(1); id <= 2*maxAnnouncementRevisions; id++ {
+		_ = encoder.Encode(AnnouncementRevision{ID: id, Key: "bulk", Action: AnnouncementActionUpdate})
+	}
+	if err := os.WriteFile(logPath, data.Bytes(), 0o600); err != nil {
+		t.Fatalf("写入修订日志失败: %v", err)
+	}
+	compacted, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("加载过长的修订日志失败: %v", err)
+	}
+	if revisions := compacted.Revisions("bulk"); len(revisions) != maxAnnouncementRevisions ||
+		revisions[0].ID != 2*maxAnnouncementRevisions || revisions[len(revisions)-1].ID != maxAnnouncementRevisions+1 {
+		t.Fatalf("应只保留最近 %d 条修订，实际 %d 条", maxAnnouncementRevisions, len(revisions))
+	}
+	if lines := bytes.Count(mustReadFile(t, logPath), []byte("\n")); lines != maxAnnouncementRevisions {
+		t.Fatalf("清理后日志应只剩 %d 行，实际 %d 行", maxAnnouncementRevisions, lines)
+	}
+}
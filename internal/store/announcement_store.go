@@ -27,24 +27,46 @@ type PublicAnnouncement struct {
 	MaxBuild string `json:"max_build,omitempty"`
 	Language string `json:"language,omitempty"`
 	Platform string `json:"platform,omitempty"`
+	Channel  string `json:"channel,omitempty"`
 	Title    string `json:"title"`
 	Body     string `json:"body"`
+	// BodyFormat 为空表示纯文本；markdown 时客户端按受限的 Markdown 子集渲染。
+	BodyFormat string               `json:"body_format,omitempty"`
+	Actions    []AnnouncementAction `json:"actions,omitempty"`
+	Image      *AnnouncementImage   `json:"image,omitempty"`
+	// RolloutPercent 非零时只有桶号小于该值的客户端显示公告，桶号算法见 RolloutBucket。
+	RolloutPercent int    `json:"rollout_percent,omitempty"`
+	RolloutSalt    string `json:"rollout_salt,omitempty"`
 }
 
 // AnnouncementRecord 在公开公告字段之外保存管理状态。
 type AnnouncementRecord struct {
-	Key       string    `json:"key"`
-	ID        int       `json:"id"`
-	Type      string    `json:"type"`
-	MinBuild  string    `json:"min_build,omitempty"`
-	MaxBuild  string    `json:"max_build,omitempty"`
-	Language  string    `json:"language,omitempty"`
-	Platform  string    `json:"platform,omitempty"`
-	Title     string    `json:"title"`
-	Body      string    `json:"body"`
-	Enabled   bool      `json:"enabled"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Key      string `json:"key"`
+	ID       int    `json:"id"`
+	Type     string `json:"type"`
+	MinBuild string `json:"min_build,omitempty"`
+	MaxBuild string `json:"max_build,omitempty"`
+	Language string `json:"language,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	// Channel 非空时公告只发给声明了相同分发渠道的客户端，例如 testflight。
+	Channel string `json:"channel,omitempty"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	// BodyFormat、Actions 与 Image 是可选的富内容，校验规则见 validateAnnouncementContent。
+	BodyFormat string               `json:"body_format,omitempty"`
+	Actions    []AnnouncementAction `json:"actions,omitempty"`
+	Image      *AnnouncementImage   `json:"image,omitempty"`
+	// Localizations 保存默认语言（Language、Title、Body）之外的翻译，
+	// 各语言共享类型、构建号、平台、渠道与定时设置。
+	Localizations []AnnouncementLocalization `json:"localizations,omitempty"`
+	// RolloutPercent 为 1~99 时按匿名安装 ID 分阶段放量，0 或 100 表示全量。
+	RolloutPercent int  `json:"rollout_percent,omitempty"`
+	Enabled        bool `json:"enabled"`
+	// PublishAt 与 ExpireAt 限定已启用公告的公开时间窗，均为空时始终公开。
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	ExpireAt  *time.Time `json:"expire_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
 type announcementFile struct {
@@ -52,11 +74,26 @@ type announcementFile struct {
 	Records []AnnouncementRecord `json:"records"`
 }
 
-// AnnouncementStore 负责公告内容与发布状态的本地持久化。
+// AnnouncementStore 负责公告内容与发布状态的本地持久化，每次变更同时记录一条修订。
 type AnnouncementStore struct {
 	mu      sync.RWMutex
 	file    string
 	records []AnnouncementRecord
+	// 修订逐条追加到 revisionLog，内存中只保留最近 maxAnnouncementRevisions 条。
+	revisionLog        string
+	legacyRevisionFile string
+	revisions          []AnnouncementRevision
+	nextRevision       int64
+	revisionLogLines   int
+	// requiredLanguages 为空时不检查翻译是否齐全。
+	requiredLanguages []string
+	// 触达统计写入频繁，使用独立的锁与文件，不产生修订：每次上报追加到增量日志，定期合并为快照。
+	telemetryMu       sync.RWMutex
+	telemetryFile     string
+	telemetryLog      string
+	telemetry         []AnnouncementTelemetryBucket
+	telemetrySequence int64
+	telemetryLogLines int
 }
 
 func NewAnnouncementStore(dataDir string) (*AnnouncementStore, error) {
@@ -65,11 +102,21 @@ func NewAnnouncementStore(dataDir string) (*AnnouncementStore, error) {
 	}
 
 	store := &AnnouncementStore{
-		file: filepath.Join(dataDir, "announcements.json"),
+		file:               filepath.Join(dataDir, "announcements.json"),
+		revisionLog:        filepath.Join(dataDir, announcementRevisionLogName),
+		legacyRevisionFile: filepath.Join(dataDir, legacyAnnouncementRevisionFileName),
+		telemetryFile:      telemetryFilePath(dataDir),
+		telemetryLog:       telemetryLogPath(dataDir),
 	}
 	if err := store.load(); err != nil {
 		return nil, err
 	}
+	if err := store.loadRevisions(); err != nil {
+		return nil, err
+	}
+	if err := store.loadTelemetry(); err != nil {
+		return nil, err
+	}
 	return store, nil
 }
 
@@ -83,25 +130,66 @@ func (s *AnnouncementStore) List() []AnnouncementRecord {
 }
 
 func (s *AnnouncementStore) PublicList() []PublicAnnouncement {
+	result, _ := s.PublicListAt(time.Now(), AnnouncementAudience{})
+	return result
+}
+
+// PublicListAt 返回 now 时刻处于公开时间窗内且适用于 audience 的公告，
+// 以及之后最近一次定时上线或下线的时间。没有待发生的定时变化时 next 为零值。
+func (s *AnnouncementStore) PublicListAt(
+	now time.Time,
+	audience AnnouncementAudience,
+) (result []PublicAnnouncement, next time.Time) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.selectLocked(now, audience, false)
+}
 
+// selectLocked 实现客户端可见公告的筛选；includeDrafts 为 true 时把草稿视为已发布。
+func (s *AnnouncementStore) selectLocked(
+	now time.Time,
+	audience AnnouncementAudience,
+	includeDrafts bool,
+) (result []PublicAnnouncement, next time.Time) {
 	records := make([]AnnouncementRecord, 0, len(s.records))
 	for _, record := range s.records {
-		if record.Enabled {
-			records = append(records, record)
+		if includeDrafts {
+			record.Enabled = true
+		}
+		if !record.Enabled || !audience.matches(record) {
+			continue
+		}
+		if record.LiveAt(now) {
+			records = append(records, record.languageVariants()...)
 		}
+		next = earliestTransition(next, now, record.PublishAt, record.ExpireAt)
 	}
 	sortAnnouncementRecords(records)
+	records = audience.selectLanguages(records)
 
-	result := make([]PublicAnnouncement, 0, len(records))
+	result = make([]PublicAnnouncement, 0, len(records))
 	for _, record := range records {
 		result = append(result, record.Public())
 	}
-	return result
+	return result, next
 }
 
-func (s *AnnouncementStore) Create(record AnnouncementRecord) (AnnouncementRecord, error) {
+// LiveAt 判断公告在 now 时刻是否应向客户端公开。
+func (r AnnouncementRecord) LiveAt(now time.Time) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.PublishAt != nil && now.Before(*r.PublishAt) {
+		return false
+	}
+	if r.ExpireAt != nil && !now.Before(*r.ExpireAt) {
+		return false
+	}
+	return true
+}
+
+// Create 新建公告，author 记录在修订历史中。
+func (s *AnnouncementStore) Create(record AnnouncementRecord, author string) (AnnouncementRecord, error) {
 	now := time.Now().UTC()
 	key, err := newAnnouncementKey()
 	if err != nil {
@@ -112,36 +200,46 @@ func (s *AnnouncementStore) Create(record AnnouncementRecord) (AnnouncementRecor
 	record.UpdatedAt = now
 	normalizeAnnouncementRecord(&record)
 	if err := validateAnnouncementRecord(record); err != nil {
-		return AnnouncementRecord{}, err
+		return AnnouncementRecord{}, invalidError("announcement_invalid", err)
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := s.checkRequiredLanguagesLocked(record); err != nil {
+		return AnnouncementRecord{}, err
+	}
 	if len(s.records) >= maxAnnouncementRecords {
-		return AnnouncementRecord{}, fmt.Errorf("公告条目不能超过 %d 条", maxAnnouncementRecords)
+		return AnnouncementRecord{}, coded(
+			ErrorConflict,
+			"announcement_limit_reached",
+			fmt.Sprintf("公告条目不能超过 %d 条", maxAnnouncementRecords),
+		)
 	}
 
 	s.records = append(s.records, record)
-	if err := s.saveLocked(); err != nil {
+	if err := s.commitLocked(newAnnouncementRevision(AnnouncementActionCreate, author, nil, record)); err != nil {
 		s.records = s.records[:len(s.records)-1]
 		return AnnouncementRecord{}, err
 	}
 	return record, nil
 }
 
-func (s *AnnouncementStore) Update(key string, replacement AnnouncementRecord) (AnnouncementRecord, error) {
+func (s *AnnouncementStore) Update(key string, replacement AnnouncementRecord, author string) (AnnouncementRecord, error) {
 	key = strings.TrimSpace(key)
 	if key == "" {
-		return AnnouncementRecord{}, fmt.Errorf("公告 key 不能为空")
+		return AnnouncementRecord{}, coded(ErrorInvalid, "announcement_invalid", "公告 key 不能为空")
 	}
 	replacement.Key = key
 	normalizeAnnouncementRecord(&replacement)
 	if err := validateAnnouncementRecord(replacement); err != nil {
-		return AnnouncementRecord{}, err
+		return AnnouncementRecord{}, invalidError("announcement_invalid", err)
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if err := s.checkRequiredLanguagesLocked(replacement); err != nil {
+		return AnnouncementRecord{}, err
+	}
 	for index, current := range s.records {
 		if current.Key != key {
 			continue
@@ -151,19 +249,21 @@ func (s *AnnouncementStore) Update(key string, replacement AnnouncementRecord) (
 		replacement.CreatedAt = current.CreatedAt
 		replacement.UpdatedAt = time.Now().UTC()
 		s.records[index] = replacement
-		if err := s.saveLocked(); err != nil {
+		revision := newAnnouncementRevision(AnnouncementActionUpdate, author, &current, replacement)
+		if err := s.commitLocked(revision); err != nil {
 			s.records[index] = current
 			return AnnouncementRecord{}, err
 		}
 		return replacement, nil
 	}
-	return AnnouncementRecord{}, fmt.Errorf("公告不存在")
+	return AnnouncementRecord{}, ErrAnnouncementNotFound
 }
 
-func (s *AnnouncementStore) Delete(key string) error {
+// Delete 删除公告；删除前的内容保留在修订历史中，可通过 Restore 撤销。
+func (s *AnnouncementStore) Delete(key string, author string) error {
 	key = strings.TrimSpace(key)
 	if key == "" {
-		return fmt.Errorf("公告 key 不能为空")
+		return coded(ErrorInvalid, "announcement_invalid", "公告 key 不能为空")
 	}
 
 	s.mu.Lock()
@@ -175,26 +275,35 @@ func (s *AnnouncementStore) Delete(key string) error {
 
 		previous := append([]AnnouncementRecord(nil), s.records...)
 		s.records = append(s.records[:index], s.records[index+1:]...)
-		if err := s.saveLocked(); err != nil {
+		if err := s.commitLocked(newAnnouncementRevision(AnnouncementActionDelete, author, nil, record)); err != nil {
 			s.records = previous
 			return err
 		}
 		return nil
 	}
-	return fmt.Errorf("公告不存在")
+	return ErrAnnouncementNotFound
 }
 
 func (r AnnouncementRecord) Public() PublicAnnouncement {
-	return PublicAnnouncement{
-		ID:       r.ID,
-		Type:     r.Type,
-		MinBuild: r.MinBuild,
-		MaxBuild: r.MaxBuild,
-		Language: r.Language,
-		Platform: r.Platform,
-		Title:    r.Title,
-		Body:     r.Body,
-	}
+	public := PublicAnnouncement{
+		ID:         r.ID,
+		Type:       r.Type,
+		MinBuild:   r.MinBuild,
+		MaxBuild:   r.MaxBuild,
+		Language:   r.Language,
+		Platform:   r.Platform,
+		Channel:    r.Channel,
+		Title:      r.Title,
+		Body:       r.Body,
+		BodyFormat: r.BodyFormat,
+		Actions:    r.Actions,
+	}
+	public.RolloutPercent, public.RolloutSalt = publicRollout(rolloutKindAnnouncement, r.ID, r.RolloutPercent)
+	if r.Image != nil {
+		image := *r.Image
+		public.Image = &image
+	}
+	return public
 }
 
 func (s *AnnouncementStore) load() error {
@@ -279,13 +388,27 @@ func normalizeAnnouncementRecord(record *AnnouncementRecord) {
 	record.MaxBuild = strings.TrimSpace(record.MaxBuild)
 	record.Language = strings.TrimSpace(record.Language)
 	record.Platform = normalizeAnnouncementPlatform(record.Platform)
+	record.Channel = strings.ToLower(strings.TrimSpace(record.Channel))
 	record.Title = strings.TrimSpace(record.Title)
 	record.Body = strings.TrimSpace(record.Body)
+	record.Localizations = normalizeAnnouncementLocalizations(record.Localizations)
+	normalizeAnnouncementContent(record)
+	record.PublishAt = normalizeScheduleTime(record.PublishAt)
+	record.ExpireAt = normalizeScheduleTime(record.ExpireAt)
+	record.RolloutPercent = normalizeRolloutPercent(record.RolloutPercent)
+}
+
+func normalizeScheduleTime(value *time.Time) *time.Time {
+	if value == nil || value.IsZero() {
+		return nil
+	}
+	normalized := value.UTC().Truncate(time.Second)
+	return &normalized
 }
 
 func validateAnnouncementRecord(record AnnouncementRecord) error {
 	if record.Key == "" {
-		return fmt.Errorf("公告 key 不能为空")
+		return coded(ErrorInvalid, "announcement_invalid", "公告 key 不能为空")
 	}
 	if record.ID <= 0 {
 		return fmt.Errorf("公告编号必须大于 0")
@@ -304,11 +427,33 @@ func validateAnnouncementRecord(record AnnouncementRecord) error {
 	if record.Platform != "" && record.Platform != "iOS" && record.Platform != "watchOS" {
 		return fmt.Errorf("平台仅支持 iOS 或 watchOS")
 	}
-	titleLength := len([]rune(record.Title))
+	if record.Channel != "" && !announcementChannelPattern.MatchString(record.Channel) {
+		return fmt.Errorf("分发渠道只能包含小写字母、数字、下划线或连字符，且不超过 32 个字符")
+	}
+	if err := validateAnnouncementText(record.Title, record.Body); err != nil {
+		return err
+	}
+	if err := validateAnnouncementLocalizations(record); err != nil {
+		return err
+	}
+	if err := validateAnnouncementContent(record); err != nil {
+		return err
+	}
+	if record.PublishAt != nil && record.ExpireAt != nil && !record.ExpireAt.After(*record.PublishAt) {
+		return fmt.Errorf("expire_at 必须晚于 publish_at")
+	}
+	if err := validateRolloutPercent(record.RolloutPercent); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateAnnouncementText(title, body string) error {
+	titleLength := len([]rune(title))
 	if titleLength < 1 || titleLength > 200 {
 		return fmt.Errorf("标题长度必须在 1 到 200 个字符之间")
 	}
-	bodyLength := len([]rune(record.Body))
+	bodyLength := len([]rune(body))
 	if bodyLength < 1 || bodyLength > 20000 {
 		return fmt.Errorf("正文长度必须在 1 到 20000 个字符之间")
 	}
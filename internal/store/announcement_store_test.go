@@ -1,7 +1,9 @@
 package store
 
 import (
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestAnnouncementStoreCRUDAndPersistence(t *testing.T) {
@@ -21,7 +23,7 @@ func TestAnnouncementStoreCRUDAndPersistence(t *testing.T) {
 		Title:    "测试公告",
 		Body:     "这是一条用于验证持久化的公告。",
 		Enabled:  true,
-	})
+	}, "test")
 	if err != nil {
 		t.Fatalf("创建公告失败: %v", err)
 	}
@@ -30,7 +32,7 @@ func TestAnnouncementStoreCRUDAndPersistence(t *testing.T) {
 	}
 
 	created.Title = "更新后的标题"
-	updated, err := store.Update(created.Key, created)
+	updated, err := store.Update(created.Key, created, "test")
 	if err != nil {
 		t.Fatalf("更新公告失败: %v", err)
 	}
@@ -47,7 +49,7 @@ func TestAnnouncementStoreCRUDAndPersistence(t *testing.T) {
 		t.Fatalf("持久化内容不正确: %+v", public)
 	}
 
-	if err := reloaded.Delete(created.Key); err != nil {
+	if err := reloaded.Delete(created.Key, "test"); err != nil {
 		t.Fatalf("删除公告失败: %v", err)
 	}
 	if len(reloaded.List()) != 0 {
@@ -69,8 +71,278 @@ func TestAnnouncementStoreRejectsInvalidBuildRange(t *testing.T) {
 		Title:    "标题",
 		Body:     "正文",
 		Enabled:  true,
-	})
+	}, "test")
 	if err == nil {
 		t.Fatalf("无效构建号范围应被拒绝")
 	}
 }
+
+func TestAnnouncementStoreHonorsPublishWindow(t *testing.T) {
+	store, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	publishAt := now.Add(time.Hour)
+	expireAt := now.Add(3 * time.Hour)
+	if _, err := store.Create(AnnouncementRecord{
+		ID:        1,
+		Type:      "warning",
+		Title:     "维护通知",
+		Body:      "服务将在维护窗口内不可用。",
+		Enabled:   true,
+		PublishAt: &publishAt,
+		ExpireAt:  &expireAt,
+	}, "test"); err != nil {
+		t.Fatalf("创建定时公告失败: %v", err)
+	}
+
+	for _, tc := range []struct {
+		at       time.Time
+		visible  bool
+		wantNext time.Time
+	}{
+		{now, false, publishAt},
+		{publishAt, true, expireAt},
+		{expireAt.Add(-time.Second), true, expireAt},
+		{expireAt, false, time.Time{}},
+	} {
+		public, next := store.PublicListAt(tc.at, AnnouncementAudience{})
+		if (len(public) == 1) != tc.visible || !next.Equal(tc.wantNext) {
+			t.Fatalf("时刻 %s 公开状态不正确: public=%+v next=%s", tc.at, public, next)
+		}
+	}
+
+	_, err = store.Create(AnnouncementRecord{
+		ID:        2,
+		Type:      "info",
+		Title:     "标题",
+		Body:      "正文",
+		PublishAt: &expireAt,
+		ExpireAt:  &publishAt,
+	}, "test")
+	if err == nil {
+		t.Fatal("expire_at 早于 publish_at 时应被拒绝")
+	}
+}
+
+func TestAnnouncementStoreFiltersByAudience(t *testing.T) {
+	store, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	for _, record := range []AnnouncementRecord{
+		{ID: 3, Type: "info", Language: "en", Title: "English", Body: "正文", Enabled: true},
+		{ID: 3, Type: "info", Language: "zh-Hans", Title: "简体", Body: "正文", Enabled: true},
+		{ID: 3, Type: "info", Language: "zh-Hant", Title: "繁體", Body: "正文", Enabled: true},
+		{ID: 2, Type: "info", Platform: "watchOS", Title: "手表", Body: "正文", Enabled: true},
+		{ID: 1, Type: "info", MinBuild: "100", Title: "新版本", Body: "正文", Enabled: true},
+		{ID: 4, Type: "info", Channel: "testflight", Title: "测试版", Body: "正文", Enabled: true},
+	} {
+		if _, err := store.Create(record, "test"); err != nil {
+			t.Fatalf("创建公告失败: %v", err)
+		}
+	}
+
+	titles := func(audience AnnouncementAudience) []string {
+		public, _ := store.PublicListAt(time.Now(), audience)
+		result := make([]string, 0, len(public))
+		for _, record := range public {
+			result = append(result, record.Title)
+		}
+		return result
+	}
+
+	if got := titles(AnnouncementAudience{}); len(got) != 5 {
+		t.Fatalf("不带筛选条件时应返回除渠道公告外的全部公告: %v", got)
+	}
+
+	audience, err := ParseAnnouncementAudience("ios", "90", "zh-Hans-CN", "testflight")
+	if err != nil {
+		t.Fatalf("解析筛选条件失败: %v", err)
+	}
+	if got := titles(audience); len(got) != 2 || got[0] != "测试版" || got[1] != "简体" {
+		t.Fatalf("筛选结果不正确: %v", got)
+	}
+
+	audience, _ = ParseAnnouncementAudience("", "", "ja", "")
+	if got := titles(audience); len(got) != 3 || got[0] != "English" {
+		t.Fatalf("没有匹配语言时应回退到 en: %v", got)
+	}
+
+	if _, err := ParseAnnouncementAudience("android", "", "", ""); err == nil {
+		t.Fatal("未知平台应被拒绝")
+	}
+	if _, err := ParseAnnouncementAudience("", "-1", "", ""); err == nil {
+		t.Fatal("负数构建号应被拒绝")
+	}
+}
+
+func TestAnnouncementStoreExpandsLocalizations(t *testing.T) {
+	store, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	store.SetRequiredLanguages([]string{"zh-Hans", "en", "ja"})
+
+	bundle := AnnouncementRecord{
+		ID:       7,
+		Type:     "warning",
+		Platform: "iOS",
+		Language: "zh-Hans",
+		Title:    "维护通知",
+		Body:     "今晚维护。",
+		Localizations: []AnnouncementLocalization{
+			{Language: "en", Title: "Maintenance", Body: "Maintenance tonight."},
+		},
+		Enabled: true,
+	}
+	if _, err := store.Create(bundle, "test"); !errors.Is(err, &Error{Code: "announcement_translation_missing"}) {
+		t.Fatalf("缺少必需语言时应拒绝发布，实际 %v", err)
+	}
+
+	bundle.Enabled = false
+	draft, err := store.Create(bundle, "test")
+	if err != nil {
+		t.Fatalf("草稿缺少翻译时应允许保存: %v", err)
+	}
+	if missing := store.MissingLanguages(draft); len(missing) != 1 || missing[0] != "ja" {
+		t.Fatalf("缺失语言提示不正确: %v", missing)
+	}
+
+	draft.Localizations = append(draft.Localizations, AnnouncementLocalization{
+		Language: "ja",
+		Title:    "メンテナンス",
+		Body:     "今夜メンテナンスを行います。",
+	})
+	draft.Enabled = true
+	if _, err := store.Update(draft.Key, draft, "test"); err != nil {
+		t.Fatalf("补齐翻译后发布失败: %v", err)
+	}
+
+	public := store.PublicList()
+	if len(public) != 3 {
+		t.Fatalf("多语言公告应展开为 3 条兼容条目: %+v", public)
+	}
+	for _, item := range public {
+		if item.ID != 7 || item.Type != "warning" || item.Platform != "iOS" {
+			t.Fatalf("展开条目未共享投放条件: %+v", item)
+		}
+	}
+	if public[0].Language != "" || public[0].Title != "维护通知" {
+		t.Fatalf("默认语言应以空 language 作为回退输出: %+v", public[0])
+	}
+
+	audience, _ := ParseAnnouncementAudience("", "", "fr-FR", "")
+	selected, _ := store.PublicListAt(time.Now(), audience)
+	if len(selected) != 1 || selected[0].Title != "维护通知" {
+		t.Fatalf("没有匹配翻译时应回退到默认语言: %+v", selected)
+	}
+	audience, _ = ParseAnnouncementAudience("", "", "ja-JP", "")
+	selected, _ = store.PublicListAt(time.Now(), audience)
+	if len(selected) != 1 || selected[0].Title != "メンテナンス" {
+		t.Fatalf("应选择匹配的翻译: %+v", selected)
+	}
+
+	draft.Localizations = append(draft.Localizations, AnnouncementLocalization{
+		Language: "EN",
+		Title:    "Duplicate",
+		Body:     "Duplicate",
+	})
+	if _, err := store.Update(draft.Key, draft, "test"); err == nil {
+		t.Fatal("重复语言应被拒绝")
+	}
+	draft.Localizations = draft.Localizations[:1]
+	draft.Language = ""
+	if _, err := store.Update(draft.Key, draft, "test"); err == nil {
+		t.Fatal("包含翻译但未指定默认语言时应被拒绝")
+	}
+}
+
+func TestAnnouncementStoreValidatesRichContent(t *testing.T) {
+	store, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	base := AnnouncementRecord{
+		ID:       12,
+		Type:     "info",
+		Language: "zh-Hans",
+		Title:    "标题",
+		Body:     "正文",
+		Actions: []AnnouncementAction{
+			{Type: "ROUTE", Label: "打开设置", Route: "/settings/providers"},
+		},
+		Localizations: []AnnouncementLocalization{{
+			Language: "en",
+			Title:    "Title",
+			Body:     "Body",
+			Actions:  []AnnouncementAction{{Type: "route", Label: "Open Settings", Route: "/settings/providers"}},
+		}},
+	}
+	created, err := store.Create(base, "test")
+	if err != nil {
+		t.Fatalf("创建带按钮的公告失败: %v", err)
+	}
+	if created.Actions[0].Type != AnnouncementActionRoute {
+		t.Fatalf("按钮类型未规范化: %+v", created.Actions)
+	}
+
+	for name, mutate := range map[string]func(*AnnouncementRecord){
+		"未知正文格式": func(record *AnnouncementRecord) { record.BodyFormat = "html" },
+		"Markdown 内嵌 HTML": func(record *AnnouncementRecord) {
+			record.BodyFormat = "markdown"
+			record.Body = "<script>alert(1)</script>"
+		},
+		"Markdown 非 https 链接": func(record *AnnouncementRecord) {
+			record.BodyFormat = "markdown"
+			record.Body = "[点击](http://example.com)"
+		},
+		"Markdown 内嵌图片": func(record *AnnouncementRecord) {
+			record.BodyFormat = "markdown"
+			record.Body = "![图](https://example.com/a.png)"
+		},
+		"Markdown 引用式链接定义非 https": func(record *AnnouncementRecord) {
+			record.BodyFormat = "markdown"
+			record.Body = "[点这里][1]\n\n[1]: javascript:alert(1)"
+		},
+		"Markdown 引用式图片": func(record *AnnouncementRecord) {
+			record.BodyFormat = "markdown"
+			record.Body = "![x][1]\n\n[1]: https://tracker.example.com/p.png"
+		},
+		"Markdown 简写引用式图片": func(record *AnnouncementRecord) {
+			record.BodyFormat = "markdown"
+			record.Body = "![x]\n\n[x]: https://tracker.example.com/p.png"
+		},
+		"非 https 按钮链接": func(record *AnnouncementRecord) {
+			record.Actions = []AnnouncementAction{{Type: "url", Label: "打开", URL: "javascript:alert(1)"}}
+			record.Localizations = nil
+		},
+		"dismiss 携带链接": func(record *AnnouncementRecord) {
+			record.Actions = []AnnouncementAction{{Type: "dismiss", Label: "关闭", URL: "https://example.com"}}
+			record.Localizations = nil
+		},
+		"翻译按钮数量不一致": func(record *AnnouncementRecord) {
+			record.Actions = append(record.Actions, AnnouncementAction{Type: "dismiss", Label: "关闭"})
+		},
+		"图片摘要无效": func(record *AnnouncementRecord) {
+			record.Image = &AnnouncementImage{SHA256: "not-a-digest"}
+		},
+	} {
+		record := base
+		record.Actions = append([]AnnouncementAction(nil), base.Actions...)
+		mutate(&record)
+		if _, err := store.Create(record, "test"); !errors.Is(err, &Error{Code: "announcement_invalid"}) {
+			t.Fatalf("%s 应返回 announcement_invalid，实际 %v", name, err)
+		}
+	}
+
+	record := base
+	record.ID = 2
+	record.BodyFormat = "markdown"
+	record.Body = "详见[说明][docs]。\n\n  [docs]: https://example.com/docs \"说明\""
+	if _, err := store.Create(record, "test"); err != nil {
+		t.Fatalf("https 引用式链接应被接受: %v", err)
+	}
+}
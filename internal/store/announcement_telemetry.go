@@ -0,0 +1,434 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	announcementTelemetryFileVersion = 1
+	maxAnnouncementEventsPerReport   = 20
+	// maxAnnouncementTelemetryBuilds 是每条公告每个平台单独统计的构建号数，之后出现的构建号计入 other。
+	maxAnnouncementTelemetryBuilds = 50
+	// AnnouncementTelemetryOtherBuild 汇总超出公告构建号范围或超出单独统计数量的构建号。
+	AnnouncementTelemetryOtherBuild = "other"
+	// 增量日志累积到该行数后写入快照并清空日志，启动时同样检查。
+	minAnnouncementTelemetryCompactionLines = 1000
+)
+
+// 客户端可上报的公告触达事件。
+const (
+	AnnouncementEventShown        = "shown"
+	AnnouncementEventDismissed    = "dismissed"
+	AnnouncementEventActionTapped = "action_tapped"
+)
+
+// AnnouncementEventReport 是客户端一次上报的公告事件。
+// 与匿名答卷一样不包含设备标识或 IP，只保留平台与构建号用于聚合。
+type AnnouncementEventReport struct {
+	Platform string              `json:"platform,omitempty"`
+	AppBuild string              `json:"app_build,omitempty"`
+	Events   []AnnouncementEvent `json:"events"`
+}
+
+// AnnouncementEvent 指明公告的公开 ID 与发生的事件。
+type AnnouncementEvent struct {
+	AnnouncementID int    `json:"announcement_id"`
+	Event          string `json:"event"`
+}
+
+// AnnouncementTelemetryBucket 按公告 ID、平台与构建号累计事件次数。
+// UpdatedAt 只精确到天，避免从时间戳反推单个客户端。
+type AnnouncementTelemetryBucket struct {
+	AnnouncementID int       `json:"announcement_id"`
+	Platform       string    `json:"platform,omitempty"`
+	AppBuild       string    `json:"app_build,omitempty"`
+	Shown          int64     `json:"shown"`
+	Dismissed      int64     `json:"dismissed"`
+	ActionTapped   int64     `json:"action_tapped"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// announcementTelemetryFile 是触达统计的快照，Sequence 是快照已包含的最后一条增量日志序号。
+type announcementTelemetryFile struct {
+	Version  int                           `json:"version"`
+	Sequence int64                         `json:"sequence,omitempty"`
+	Records  []AnnouncementTelemetryBucket `json:"records"`
+}
+
+// announcementTelemetryEntry 是增量日志中的一行，对应一次上报计入的次数。
+// 写入快照后日志才会被清空，重放时跳过序号不大于快照 Sequence 的行，中途崩溃也不会重复计数。
+type announcementTelemetryEntry struct {
+	Sequence int64                         `json:"sequence"`
+	Buckets  []AnnouncementTelemetryBucket `json:"buckets"`
+}
+
+// RecordEvents 校验并累加一次上报的事件，返回实际计入的事件数。
+// 同一次上报中重复的公告与事件组合只计一次；只接受当前公开的公告。
+// 每次上报只向增量日志追加一行，不重写整个统计文件。
+func (s *AnnouncementStore) RecordEvents(report AnnouncementEventReport) (int, error) {
+	report.Platform = normalizeAnnouncementPlatform(report.Platform)
+	report.AppBuild = strings.TrimSpace(report.AppBuild)
+	for index := range report.Events {
+		report.Events[index].Event = strings.ToLower(strings.TrimSpace(report.Events[index].Event))
+	}
+	if err := validateAnnouncementEventReport(report); err != nil {
+		return 0, invalidError("announcement_event_invalid", err)
+	}
+
+	now := time.Now()
+	s.mu.RLock()
+	// live 记录每个公开公告编号的构建号范围，同一编号的语言版本共享投放范围。
+	live := make(map[int][2]string, len(s.records))
+	for _, record := range s.records {
+		if record.LiveAt(now) {
+			live[record.ID] = [2]string{record.MinBuild, record.MaxBuild}
+		}
+	}
+	s.mu.RUnlock()
+	for _, event := range report.Events {
+		if _, ok := live[event.AnnouncementID]; !ok {
+			return 0, coded(
+				ErrorInvalid,
+				"announcement_event_invalid",
+				fmt.Sprintf("公告 %d 不存在或未公开", event.AnnouncementID),
+			)
+		}
+	}
+
+	s.telemetryMu.Lock()
+	defer s.telemetryMu.Unlock()
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	seen := make(map[AnnouncementEvent]struct{}, len(report.Events))
+	deltas := make([]AnnouncementTelemetryBucket, 0, len(report.Events))
+	for _, event := range report.Events {
+		if _, exists := seen[event]; exists {
+			continue
+		}
+		seen[event] = struct{}{}
+
+		delta := AnnouncementTelemetryBucket{
+			AnnouncementID: event.AnnouncementID,
+			Platform:       report.Platform,
+			AppBuild:       s.telemetryBuildLocked(event.AnnouncementID, report.Platform, report.AppBuild, live[event.AnnouncementID]),
+			UpdatedAt:      today,
+		}
+		switch event.Event {
+		case AnnouncementEventShown:
+			delta.Shown = 1
+		case AnnouncementEventDismissed:
+			delta.Dismissed = 1
+		case AnnouncementEventActionTapped:
+			delta.ActionTapped = 1
+		}
+		deltas = append(deltas, delta)
+	}
+
+	entry := announcementTelemetryEntry{Sequence: s.telemetrySequence + 1, Buckets: deltas}
+	if err := s.appendTelemetryLocked(entry); err != nil {
+		return 0, err
+	}
+	s.applyTelemetryLocked(entry)
+	s.telemetryLogLines++
+	if s.telemetryLogLines >= minAnnouncementTelemetryCompactionLines {
+		// 增量已经同步到磁盘，写快照失败时保留日志，下次上报或启动时重试。
+		_ = s.compactTelemetryLocked()
+	}
+	return len(deltas), nil
+}
+
+// Telemetry 返回按公告 ID、平台与构建号排序的统计；announcementID 为 0 时返回全部公告。
+func (s *AnnouncementStore) Telemetry(announcementID int) []AnnouncementTelemetryBucket {
+	s.telemetryMu.RLock()
+	defer s.telemetryMu.RUnlock()
+
+	result := make([]AnnouncementTelemetryBucket, 0)
+	for _, bucket := range s.telemetry {
+		if announcementID == 0 || bucket.AnnouncementID == announcementID {
+			result = append(result, bucket)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].AnnouncementID != result[j].AnnouncementID {
+			return result[i].AnnouncementID < result[j].AnnouncementID
+		}
+		if result[i].Platform != result[j].Platform {
+			return result[i].Platform < result[j].Platform
+		}
+		// other 排在单独统计的构建号之后；构建号均为纯数字，先比长度再比字典序即可得到新构建在前的顺序。
+		if (result[i].AppBuild == AnnouncementTelemetryOtherBuild) != (result[j].AppBuild == AnnouncementTelemetryOtherBuild) {
+			return result[j].AppBuild == AnnouncementTelemetryOtherBuild
+		}
+		if len(result[i].AppBuild) != len(result[j].AppBuild) {
+			return len(result[i].AppBuild) > len(result[j].AppBuild)
+		}
+		return result[i].AppBuild > result[j].AppBuild
+	})
+	return result
+}
+
+// telemetryBuildLocked 返回计入统计的构建号。超出公告构建号范围的构建号，以及该公告该平台
+// 已单独统计 maxAnnouncementTelemetryBuilds 个构建号之后出现的新构建号都计入 other，
+// 避免伪造的构建号撑大统计。
+func (s *AnnouncementStore) telemetryBuildLocked(announcementID int, platform, build string, buildRange [2]string) string {
+	if build == "" {
+		return ""
+	}
+	if !buildInRange(build, buildRange[0], buildRange[1]) {
+		return AnnouncementTelemetryOtherBuild
+	}
+	builds := 0
+	for _, bucket := range s.telemetry {
+		if bucket.AnnouncementID != announcementID || bucket.Platform != platform {
+			continue
+		}
+		if bucket.AppBuild == build {
+			return build
+		}
+		if bucket.AppBuild != "" && bucket.AppBuild != AnnouncementTelemetryOtherBuild {
+			builds++
+		}
+	}
+	if builds >= maxAnnouncementTelemetryBuilds {
+		return AnnouncementTelemetryOtherBuild
+	}
+	return build
+}
+
+// buildInRange 判断纯数字构建号是否落在公告的构建号范围内，范围为空表示不限。
+func buildInRange(build, minBuild, maxBuild string) bool {
+	value, err := strconv.ParseInt(build, 10, 64)
+	if err != nil {
+		return false
+	}
+	if minimum, err := strconv.ParseInt(minBuild, 10, 64); err == nil && value < minimum {
+		return false
+	}
+	if maximum, err := strconv.ParseInt(maxBuild, 10, 64); err == nil && value > maximum {
+		return false
+	}
+	return true
+}
+
+// applyTelemetryLocked 把一行增量累加到内存中的分组。
+func (s *AnnouncementStore) applyTelemetryLocked(entry announcementTelemetryEntry) {
+	for _, delta := range entry.Buckets {
+		index := slices.IndexFunc(s.telemetry, func(bucket AnnouncementTelemetryBucket) bool {
+			return bucket.AnnouncementID == delta.AnnouncementID &&
+				bucket.Platform == delta.Platform &&
+				bucket.AppBuild == delta.AppBuild
+		})
+		if index < 0 {
+			s.telemetry = append(s.telemetry, AnnouncementTelemetryBucket{
+				AnnouncementID: delta.AnnouncementID,
+				Platform:       delta.Platform,
+				AppBuild:       delta.AppBuild,
+			})
+			index = len(s.telemetry) - 1
+		}
+		bucket := &s.telemetry[index]
+		bucket.Shown += delta.Shown
+		bucket.Dismissed += delta.Dismissed
+		bucket.ActionTapped += delta.ActionTapped
+		if delta.UpdatedAt.After(bucket.UpdatedAt) {
+			bucket.UpdatedAt = delta.UpdatedAt
+		}
+	}
+	s.telemetrySequence = max(s.telemetrySequence, entry.Sequence)
+}
+
+func validateAnnouncementEventReport(report AnnouncementEventReport) error {
+	if report.Platform != "" && report.Platform != "iOS" && report.Platform != "watchOS" {
+		return fmt.Errorf("平台仅支持 iOS 或 watchOS")
+	}
+	if report.AppBuild != "" && !isAnnouncementEventBuild(report.AppBuild) {
+		return fmt.Errorf("构建号必须是不超过 10 位的非负整数")
+	}
+	if len(report.Events) == 0 {
+		return fmt.Errorf("至少需要上报一个事件")
+	}
+	if len(report.Events) > maxAnnouncementEventsPerReport {
+		return fmt.Errorf("单次最多上报 %d 个事件", maxAnnouncementEventsPerReport)
+	}
+	for index, event := range report.Events {
+		if event.AnnouncementID <= 0 {
+			return fmt.Errorf("第 %d 个事件的公告 ID 必须是正整数", index+1)
+		}
+		switch event.Event {
+		case AnnouncementEventShown, AnnouncementEventDismissed, AnnouncementEventActionTapped:
+		default:
+			return fmt.Errorf("第 %d 个事件类型仅支持 shown、dismissed 或 action_tapped", index+1)
+		}
+	}
+	return nil
+}
+
+func isAnnouncementEventBuild(value string) bool {
+	if len(value) > 10 {
+		return false
+	}
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// loadTelemetry 读取快照并重放增量日志。日志最后一行缺少换行符说明上次写入被中断，这一行会被丢弃；
+// 重放的行数达到阈值或发现中断的写入时写入新快照并清空日志。
+func (s *AnnouncementStore) loadTelemetry() error {
+	s.telemetry = []AnnouncementTelemetryBucket{}
+	if err := s.loadTelemetrySnapshot(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(s.telemetryLog)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取公告触达统计日志失败: %w", err)
+	}
+	defer file.Close()
+
+	interrupted := false
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("读取公告触达统计日志失败: %w", err)
+		}
+		complete := err == nil
+		if len(bytes.TrimSpace(line)) > 0 {
+			if !complete {
+				interrupted = true
+				break
+			}
+			var entry announcementTelemetryEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return fmt.Errorf("解析公告触达统计日志第 %d 行失败: %w", lineNumber, err)
+			}
+			for _, bucket := range entry.Buckets {
+				if bucket.AnnouncementID <= 0 {
+					return fmt.Errorf("公告触达统计日志第 %d 行无效", lineNumber)
+				}
+			}
+			if entry.Sequence > s.telemetrySequence {
+				s.applyTelemetryLocked(entry)
+			}
+			s.telemetryLogLines++
+		}
+		if !complete {
+			break
+		}
+	}
+	if interrupted || s.telemetryLogLines >= minAnnouncementTelemetryCompactionLines {
+		return s.compactTelemetryLocked()
+	}
+	return nil
+}
+
+func (s *AnnouncementStore) loadTelemetrySnapshot() error {
+	data, err := os.ReadFile(s.telemetryFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取公告触达统计文件失败: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil
+	}
+
+	var payload announcementTelemetryFile
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return fmt.Errorf("解析公告触达统计文件失败: %w", err)
+	}
+	if payload.Version != announcementTelemetryFileVersion {
+		return fmt.Errorf("不支持的公告触达统计文件版本: %d", payload.Version)
+	}
+	for index, bucket := range payload.Records {
+		if bucket.AnnouncementID <= 0 {
+			return fmt.Errorf("第 %d 组公告触达统计无效", index+1)
+		}
+	}
+	if payload.Records != nil {
+		s.telemetry = payload.Records
+	}
+	s.telemetrySequence = payload.Sequence
+	return nil
+}
+
+// appendTelemetryLocked 把一行增量追加到日志并同步到磁盘，写入失败时截掉可能写了一半的内容。
+func (s *AnnouncementStore) appendTelemetryLocked(entry announcementTelemetryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("编码公告触达统计失败: %w", err)
+	}
+	line = append(line, '\n')
+
+	file, err := os.OpenFile(s.telemetryLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("打开公告触达统计日志失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取公告触达统计日志失败: %w", err)
+	}
+	if _, err := file.Write(line); err != nil {
+		_ = file.Truncate(info.Size())
+		file.Close()
+		return fmt.Errorf("写入公告触达统计日志失败: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Truncate(info.Size())
+		file.Close()
+		return fmt.Errorf("同步公告触达统计日志失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("关闭公告触达统计日志失败: %w", err)
+	}
+	return nil
+}
+
+// compactTelemetryLocked 先写入包含全部增量的快照，再清空日志。
+func (s *AnnouncementStore) compactTelemetryLocked() error {
+	if err := writeSurveyJSONAtomically(
+		s.telemetryFile,
+		".announcement-telemetry-*.tmp",
+		announcementTelemetryFile{
+			Version:  announcementTelemetryFileVersion,
+			Sequence: s.telemetrySequence,
+			Records:  s.telemetry,
+		},
+		"公告触达统计",
+	); err != nil {
+		return err
+	}
+	if err := os.Truncate(s.telemetryLog, 0); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("清空公告触达统计日志失败: %w", err)
+	}
+	s.telemetryLogLines = 0
+	return nil
+}
+
+func telemetryFilePath(dataDir string) string {
+	return filepath.Join(dataDir, "announcement-telemetry.json")
+}
+
+func telemetryLogPath(dataDir string) string {
+	return filepath.Join(dataDir, "announcement-telemetry.jsonl")
+}
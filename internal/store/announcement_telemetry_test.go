@@ -0,0 +1,197 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAnnouncementTelemetryAggregatesByPlatformAndBuild(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	if _, err := store.Create(AnnouncementRecord{
+		ID:      7,
+		Type:    "blocking",
+		Title:   "需要更新",
+		Body:    "请升级到最新版本",
+		Enabled: true,
+	}, "cli"); err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+
+	recorded, err := store.RecordEvents(AnnouncementEventReport{
+		Platform: "ios",
+		AppBuild: "120",
+		Events: []AnnouncementEvent{
+			{AnnouncementID: 7, Event: "shown"},
+			{AnnouncementID: 7, Event: "shown"},
+			{AnnouncementID: 7, Event: "action_tapped"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("记录公告事件失败: %v", err)
+	}
+	if recorded != 2 {
+		t.Fatalf("同一次上报的重复事件只应计一次，实际计入 %d", recorded)
+	}
+	if _, err := store.RecordEvents(AnnouncementEventReport{
+		Platform: "iOS",
+		AppBuild: "120",
+		Events:   []AnnouncementEvent{{AnnouncementID: 7, Event: "dismissed"}},
+	}); err != nil {
+		t.Fatalf("记录公告事件失败: %v", err)
+	}
+	if _, err := store.RecordEvents(AnnouncementEventReport{
+		Platform: "watchOS",
+		Events:   []AnnouncementEvent{{AnnouncementID: 7, Event: "shown"}},
+	}); err != nil {
+		t.Fatalf("记录公告事件失败: %v", err)
+	}
+
+	_, err = store.RecordEvents(AnnouncementEventReport{
+		Events: []AnnouncementEvent{{AnnouncementID: 8, Event: "shown"}},
+	})
+	if !errors.Is(err, &Error{Code: "announcement_event_invalid"}) {
+		t.Fatalf("不存在的公告应被拒绝，实际 %v", err)
+	}
+	_, err = store.RecordEvents(AnnouncementEventReport{
+		AppBuild: "1.0",
+		Events:   []AnnouncementEvent{{AnnouncementID: 7, Event: "shown"}},
+	})
+	if !errors.Is(err, &Error{Code: "announcement_event_invalid"}) {
+		t.Fatalf("非整数构建号应被拒绝，实际 %v", err)
+	}
+
+	reloaded, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载公告存储失败: %v", err)
+	}
+	buckets := reloaded.Telemetry(7)
+	if len(buckets) != 2 {
+		t.Fatalf("期望按平台与构建号分为 2 组，实际 %+v", buckets)
+	}
+	ios := buckets[0]
+	if ios.Platform != "iOS" || ios.AppBuild != "120" || ios.Shown != 1 || ios.Dismissed != 1 || ios.ActionTapped != 1 {
+		t.Fatalf("iOS 统计不正确: %+v", ios)
+	}
+	if !ios.UpdatedAt.Equal(ios.UpdatedAt.Truncate(24 * time.Hour)) {
+		t.Fatalf("统计时间应只精确到天: %v", ios.UpdatedAt)
+	}
+	if watch := buckets[1]; watch.Platform != "watchOS" || watch.AppBuild != "" || watch.Shown != 1 {
+		t.Fatalf("watchOS 统计不正确: %+v", watch)
+	}
+	if len(reloaded.Telemetry(8)) != 0 {
+		t.Fatalf("其他公告不应有统计")
+	}
+}
+
+func TestAnnouncementTelemetryBucketsOtherBuildsAndAppendsToLog(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	if _, err := store.Create(AnnouncementRecord{
+		ID:       3,
+		Type:     "info",
+		Title:    "新功能",
+		Body:     "欢迎体验",
+		MinBuild: "100",
+		MaxBuild: "999",
+		Enabled:  true,
+	}, "cli"); err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+	if _, err := store.Create(AnnouncementRecord{
+		ID:    4,
+		Type:  "info",
+		Title: "草稿",
+		Body:  "尚未启用",
+	}, "cli"); err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+
+	if _, err := store.RecordEvents(AnnouncementEventReport{
+		Events: []AnnouncementEvent{{AnnouncementID: 4, Event: "shown"}},
+	}); !errors.Is(err, &Error{Code: "announcement_event_invalid"}) {
+		t.Fatalf("未启用的公告应被拒绝，实际 %v", err)
+	}
+
+	report := func(build string) {
+		t.Helper()
+		if _, err := store.RecordEvents(AnnouncementEventReport{
+			Platform: "iOS",
+			AppBuild: build,
+			Events:   []AnnouncementEvent{{AnnouncementID: 3, Event: "shown"}},
+		}); err != nil {
+			t.Fatalf("记录构建 %s 的事件失败: %v", build, err)
+		}
+	}
+	report("99")
+	report("5000")
+	for build := 100; build < 100+maxAnnouncementTelemetryBuilds+5; build++ {
+		report(strconv.Itoa(build))
+	}
+	report("100")
+
+	buckets := store.Telemetry(3)
+	if len(buckets) != maxAnnouncementTelemetryBuilds+1 {
+		t.Fatalf("期望 %d 个单独统计的构建号与 1 个 other，实际 %d 组", maxAnnouncementTelemetryBuilds, len(buckets))
+	}
+	other := buckets[len(buckets)-1]
+	if other.AppBuild != AnnouncementTelemetryOtherBuild || other.Shown != 7 {
+		t.Fatalf("超出范围或超出数量的构建号应计入 other: %+v", other)
+	}
+	if first := buckets[len(buckets)-2]; first.AppBuild != "100" || first.Shown != 2 {
+		t.Fatalf("已单独统计的构建号应继续累加: %+v", first)
+	}
+
+	if _, err := os.Stat(filepath.Join(dataDir, "announcement-telemetry.json")); !os.IsNotExist(err) {
+		t.Fatalf("未达到合并阈值前不应重写统计快照: %v", err)
+	}
+	lines := bytes.Count(mustReadFile(t, filepath.Join(dataDir, "announcement-telemetry.jsonl")), []byte("\n"))
+	if lines != maxAnnouncementTelemetryBuilds+8 {
+		t.Fatalf("每次上报应追加一行增量，实际 %d 行", lines)
+	}
+
+	reloaded, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载公告存储失败: %v", err)
+	}
+	if !reflect.DeepEqual(reloaded.Telemetry(3), buckets) {
+		t.Fatalf("重放增量日志后的统计不一致: %+v", reloaded.Telemetry(3))
+	}
+
+	// 合并后快照包含全部增量并清空日志；快照写入后、清空日志前中断时，重放会跳过已合并的行。
+	log := mustReadFile(t, filepath.Join(dataDir, "announcement-telemetry.jsonl"))
+	reloaded.telemetryMu.Lock()
+	err = reloaded.compactTelemetryLocked()
+	reloaded.telemetryMu.Unlock()
+	if err != nil {
+		t.Fatalf("合并统计快照失败: %v", err)
+	}
+	if len(mustReadFile(t, filepath.Join(dataDir, "announcement-telemetry.jsonl"))) != 0 {
+		t.Fatal("合并后应清空增量日志")
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "announcement-telemetry.jsonl"), append(log, `{"sequence":`...), 0o600); err != nil {
+		t.Fatalf("写入增量日志失败: %v", err)
+	}
+	compacted, err := NewAnnouncementStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载合并后的统计失败: %v", err)
+	}
+	if !reflect.DeepEqual(compacted.Telemetry(3), buckets) {
+		t.Fatalf("已合并的增量不应重复计数: %+v", compacted.Telemetry(3))
+	}
+	if len(mustReadFile(t, filepath.Join(dataDir, "announcement-telemetry.jsonl"))) != 0 {
+		t.Fatal("发现中断的写入后应重新合并并清空增量日志")
+	}
+}
@@ -0,0 +1,344 @@
+package store
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveFormat 标识管理归档，用于在导入时拒绝其他 tar.gz 文件。
+const ArchiveFormat = "els-admin-archive"
+
+const (
+	archiveVersion      = 1
+	archiveManifestName = "manifest.json"
+	archiveBlobDir      = "distribution-files"
+)
+
+// 归档中各数据文件的名称。
+const (
+	archiveAnnouncementsName   = "announcements.json"
+	archiveSurveysName         = "surveys.json"
+	archiveSurveyResponsesName = "survey-responses.json"
+	archiveDistributionName    = "distribution.json"
+)
+
+// 导入时 key 冲突且内容不同的处理方式。
+const (
+	ImportPolicySkip      = "skip"
+	ImportPolicyOverwrite = "overwrite"
+	ImportPolicyRename    = "rename"
+)
+
+// 导入计划中每一项的动作。
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionRename    = "rename"
+	ImportActionSkip      = "skip"
+	ImportActionUnchanged = "unchanged"
+)
+
+// 导入计划中的数据类型。
+const (
+	ImportKindAnnouncement    = "announcement"
+	ImportKindSurvey          = "survey"
+	ImportKindSurveyResponses = "survey_responses"
+	ImportKindDistribution    = "distribution"
+)
+
+var archiveKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ArchiveManifest 记录归档版本与各类数据的数量。
+type ArchiveManifest struct {
+	Format          string    `json:"format"`
+	Version         int       `json:"version"`
+	CreatedAt       time.Time `json:"created_at"`
+	Announcements   int       `json:"announcements"`
+	Surveys         int       `json:"surveys"`
+	SurveyResponses int       `json:"survey_responses"`
+	Distribution    int       `json:"distribution"`
+}
+
+// Archive 是一次完整导出的内容。公告修订与触达统计属于运行数据，不进入归档。
+type Archive struct {
+	Manifest        ArchiveManifest
+	Announcements   []AnnouncementRecord
+	Surveys         []SurveyRecord
+	SurveyResponses []SurveyResponseRecord
+	Distribution    []DistributionRecord
+	// Blobs 按 SHA-256 保存官方数据文件内容，仅在读取归档时填充。
+	Blobs map[string][]byte
+}
+
+// ArchiveBlobSource 提供导出时写入归档的官方数据文件。
+type ArchiveBlobSource interface {
+	OpenBlob(checksum string) (io.ReadCloser, int64, error)
+}
+
+// ImportChange 描述导入计划中的一项。key 冲突时 Fields 列出内容不同的字段。
+type ImportChange struct {
+	Kind   string   `json:"kind"`
+	Key    string   `json:"key"`
+	Action string   `json:"action"`
+	NewKey string   `json:"new_key,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+	// Count 仅用于匿名答卷，表示该征集下按此动作处理的答卷数量。
+	Count  int    `json:"count,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ValidateImportPolicy 校验冲突策略，空值按 skip 处理。
+func ValidateImportPolicy(policy string) (string, error) {
+	switch strings.TrimSpace(policy) {
+	case "", ImportPolicySkip:
+		return ImportPolicySkip, nil
+	case ImportPolicyOverwrite:
+		return ImportPolicyOverwrite, nil
+	case ImportPolicyRename:
+		return ImportPolicyRename, nil
+	default:
+		return "", fmt.Errorf("冲突策略仅支持 skip、overwrite 或 rename")
+	}
+}
+
+// WriteArchive 把 archive 写为 tar.gz；blobs 为空时归档不能包含官方数据条目。
+func WriteArchive(writer io.Writer, archive Archive, blobs ArchiveBlobSource) error {
+	gzipWriter := gzip.NewWriter(writer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	modTime := archive.Manifest.CreatedAt
+
+	archive.Manifest.Format = ArchiveFormat
+	archive.Manifest.Version = archiveVersion
+	archive.Manifest.Announcements = len(archive.Announcements)
+	archive.Manifest.Surveys = len(archive.Surveys)
+	archive.Manifest.SurveyResponses = len(archive.SurveyResponses)
+	archive.Manifest.Distribution = len(archive.Distribution)
+
+	if archive.Announcements == nil {
+		archive.Announcements = []AnnouncementRecord{}
+	}
+	if archive.Surveys == nil {
+		archive.Surveys = []SurveyRecord{}
+	}
+	if archive.SurveyResponses == nil {
+		archive.SurveyResponses = []SurveyResponseRecord{}
+	}
+	if archive.Distribution == nil {
+		archive.Distribution = []DistributionRecord{}
+	}
+
+	entries := []struct {
+		name    string
+		payload any
+	}{
+		{archiveManifestName, archive.Manifest},
+		{archiveAnnouncementsName, archive.Announcements},
+		{archiveSurveysName, archive.Surveys},
+		{archiveSurveyResponsesName, archive.SurveyResponses},
+		{archiveDistributionName, archive.Distribution},
+	}
+	for _, entry := range entries {
+		data, err := json.MarshalIndent(entry.payload, "", "  ")
+		if err != nil {
+			return fmt.Errorf("编码归档文件 %s 失败: %w", entry.name, err)
+		}
+		data = append(data, '\n')
+		if err := writeArchiveEntry(tarWriter, entry.name, int64(len(data)), modTime, bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+
+	written := map[string]bool{}
+	for _, record := range archive.Distribution {
+		if written[record.SHA256] {
+			continue
+		}
+		if blobs == nil {
+			return fmt.Errorf("缺少官方数据文件来源")
+		}
+		if err := writeArchiveBlob(tarWriter, blobs, record.SHA256, modTime); err != nil {
+			return err
+		}
+		written[record.SHA256] = true
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("结束归档失败: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("压缩归档失败: %w", err)
+	}
+	return nil
+}
+
+func writeArchiveBlob(tarWriter *tar.Writer, blobs ArchiveBlobSource, checksum string, modTime time.Time) error {
+	reader, size, err := blobs.OpenBlob(checksum)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return writeArchiveEntry(tarWriter, path.Join(archiveBlobDir, checksum+".blob"), size, modTime, reader)
+}
+
+func writeArchiveEntry(tarWriter *tar.Writer, name string, size int64, modTime time.Time, reader io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    size,
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("写入归档文件 %s 失败: %w", name, err)
+	}
+	if _, err := io.CopyN(tarWriter, reader, size); err != nil {
+		return fmt.Errorf("写入归档文件 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// ReadArchive 读取 tar.gz 归档，解压后的总大小不能超过 maxBytes。
+// 这里只校验归档结构与文件哈希，记录内容由各存储在导入时校验；
+// 返回的错误保留底层读取错误，调用方可据此区分请求体超限。
+func ReadArchive(reader io.Reader, maxBytes int64) (Archive, error) {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return Archive{}, fmt.Errorf("归档不是有效的 gzip 文件: %w", err)
+	}
+	defer gzipReader.Close()
+
+	archive := Archive{Blobs: map[string][]byte{}}
+	seen := map[string]bool{}
+	remaining := maxBytes
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Archive{}, fmt.Errorf("读取归档失败: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return Archive{}, fmt.Errorf("归档只能包含普通文件: %s", header.Name)
+		}
+		if seen[header.Name] {
+			return Archive{}, fmt.Errorf("归档文件重复: %s", header.Name)
+		}
+		seen[header.Name] = true
+		if header.Size < 0 || header.Size > remaining {
+			return Archive{}, fmt.Errorf("归档解压后超过 %d MiB", maxBytes>>20)
+		}
+		remaining -= header.Size
+		data, err := io.ReadAll(io.LimitReader(tarReader, header.Size))
+		if err != nil {
+			return Archive{}, fmt.Errorf("读取归档文件 %s 失败: %w", header.Name, err)
+		}
+		if err := archive.decodeEntry(header.Name, data); err != nil {
+			return Archive{}, err
+		}
+	}
+
+	if !seen[archiveManifestName] {
+		return Archive{}, fmt.Errorf("归档缺少 %s", archiveManifestName)
+	}
+	for _, record := range archive.Distribution {
+		if _, ok := archive.Blobs[strings.ToLower(record.SHA256)]; !ok {
+			return Archive{}, fmt.Errorf("归档缺少官方数据文件 %s", record.SHA256)
+		}
+	}
+	return archive, nil
+}
+
+func (archive *Archive) decodeEntry(name string, data []byte) error {
+	var target any
+	switch name {
+	case archiveManifestName:
+		if err := json.Unmarshal(data, &archive.Manifest); err != nil {
+			return fmt.Errorf("解析归档清单失败: %w", err)
+		}
+		if archive.Manifest.Format != ArchiveFormat {
+			return fmt.Errorf("不是管理归档文件")
+		}
+		if archive.Manifest.Version != archiveVersion {
+			return fmt.Errorf("不支持的归档版本: %d", archive.Manifest.Version)
+		}
+		return nil
+	case archiveAnnouncementsName:
+		target = &archive.Announcements
+	case archiveSurveysName:
+		target = &archive.Surveys
+	case archiveSurveyResponsesName:
+		target = &archive.SurveyResponses
+	case archiveDistributionName:
+		target = &archive.Distribution
+	default:
+		directory, file := path.Split(name)
+		checksum, isBlob := strings.CutSuffix(file, ".blob")
+		if directory != archiveBlobDir+"/" || !isBlob {
+			return fmt.Errorf("归档包含未知文件: %s", name)
+		}
+		digest := sha256.Sum256(data)
+		if hex.EncodeToString(digest[:]) != checksum {
+			return fmt.Errorf("官方数据文件 %s 的 SHA-256 不一致", checksum)
+		}
+		archive.Blobs[checksum] = data
+		return nil
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("解析归档文件 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+func archiveError(err error) error {
+	return invalidError("archive_invalid", err)
+}
+
+func validArchiveKey(key string) bool {
+	return archiveKeyPattern.MatchString(key)
+}
+
+// archiveFieldDiff 按 JSON 字段比较两条记录，忽略 key 与时间戳，返回内容不同的字段名。
+func archiveFieldDiff(before, after any) []string {
+	beforeFields := archiveJSONFields(before)
+	afterFields := archiveJSONFields(after)
+	names := make([]string, 0)
+	for name, value := range afterFields {
+		if !bytes.Equal(beforeFields[name], value) {
+			names = append(names, name)
+		}
+	}
+	for name := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func archiveJSONFields(record any) map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	delete(fields, "key")
+	delete(fields, "created_at")
+	delete(fields, "updated_at")
+	return fields
+}
@@ -0,0 +1,525 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ImportAnnouncements 按冲突策略把归档中的公告合并到当前存储，key 相同视为同一条公告。
+// dryRun 时只返回计划；实际导入的每项变更都会以 author 记录修订。
+// imageAvailable 判断图片是否为已启用的官方数据图片，会写入的公告引用了不可用的图片时整个导入被拒绝；
+// 为 nil 时不允许引用图片。
+func (s *AnnouncementStore) ImportAnnouncements(
+	records []AnnouncementRecord,
+	policy string,
+	dryRun bool,
+	author string,
+	imageAvailable func(checksum string) bool,
+) ([]ImportChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	next := append([]AnnouncementRecord(nil), s.records...)
+	revisions := make([]AnnouncementRevision, 0)
+	changes := make([]ImportChange, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	for index, record := range records {
+		checkImage := func() error {
+			if record.Image == nil || (imageAvailable != nil && imageAvailable(record.Image.SHA256)) {
+				return nil
+			}
+			return archiveError(fmt.Errorf("第 %d 条公告引用的图片不是已启用的官方数据图片文件", index+1))
+		}
+		normalizeAnnouncementRecord(&record)
+		if !validArchiveKey(record.Key) || seen[record.Key] {
+			return nil, archiveError(fmt.Errorf("第 %d 条公告的 key 无效或重复", index+1))
+		}
+		seen[record.Key] = true
+		if err := validateAnnouncementRecord(record); err != nil {
+			return nil, archiveError(fmt.Errorf("第 %d 条公告无效: %w", index+1, err))
+		}
+		if err := s.checkRequiredLanguagesLocked(record); err != nil {
+			return nil, err
+		}
+		fillImportTimestamps(&record.CreatedAt, &record.UpdatedAt, now)
+
+		change := ImportChange{Kind: ImportKindAnnouncement, Key: record.Key, Action: ImportActionCreate}
+		position := -1
+		for candidate := range next {
+			if next[candidate].Key == record.Key {
+				position = candidate
+				break
+			}
+		}
+		if position < 0 {
+			if announcementLanguageTaken(next, record.Key, record) {
+				change.Action = ImportActionSkip
+				change.Reason = fmt.Sprintf("公告编号 %d 的同一语言已由其他公告使用", record.ID)
+				changes = append(changes, change)
+				continue
+			}
+			if err := checkImage(); err != nil {
+				return nil, err
+			}
+			next = append(next, record)
+			revisions = append(revisions, newAnnouncementRevision(AnnouncementActionCreate, author, nil, record))
+			changes = append(changes, change)
+			continue
+		}
+
+		current := next[position]
+		change.Fields = archiveFieldDiff(current, record)
+		switch {
+		case len(change.Fields) == 0:
+			change.Action = ImportActionUnchanged
+		case policy == ImportPolicyOverwrite && announcementLanguageTaken(next, record.Key, record):
+			change.Action = ImportActionSkip
+			change.Reason = fmt.Sprintf("公告编号 %d 的同一语言已由其他公告使用", record.ID)
+		case policy == ImportPolicyOverwrite:
+			if err := checkImage(); err != nil {
+				return nil, err
+			}
+			change.Action = ImportActionUpdate
+			record.CreatedAt = current.CreatedAt
+			record.UpdatedAt = now
+			next[position] = record
+			revisions = append(revisions, newAnnouncementRevision(AnnouncementActionUpdate, author, &current, record))
+		case policy == ImportPolicyRename && announcementLanguageTaken(next, "", record):
+			change.Action = ImportActionSkip
+			change.Reason = fmt.Sprintf("公告编号 %d 的同一语言已存在，不能以新 key 另存", record.ID)
+		case policy == ImportPolicyRename:
+			if err := checkImage(); err != nil {
+				return nil, err
+			}
+			change.Action = ImportActionRename
+			key, err := newAnnouncementKey()
+			if err != nil {
+				return nil, err
+			}
+			record.Key, record.CreatedAt, record.UpdatedAt = key, now, now
+			if !dryRun {
+				change.NewKey = key
+			}
+			next = append(next, record)
+			revisions = append(revisions, newAnnouncementRevision(AnnouncementActionCreate, author, nil, record))
+		default:
+			change.Action = ImportActionSkip
+		}
+		changes = append(changes, change)
+	}
+
+	if len(next) > maxAnnouncementRecords {
+		return nil, coded(
+			ErrorConflict,
+			"announcement_limit_reached",
+			fmt.Sprintf("导入后公告条目将超过 %d 条", maxAnnouncementRecords),
+		)
+	}
+	if dryRun || len(revisions) == 0 {
+		return changes, nil
+	}
+
+	previous := s.records
+	s.records = next
+	if err := s.commitLocked(revisions...); err != nil {
+		s.records = previous
+		return nil, err
+	}
+	return changes, nil
+}
+
+// announcementLanguageTaken 判断 records 中除 key 为 replacing 的公告外，是否已有与 record 编号相同且语言重叠的公告。
+// 同一编号同一语言出现两条公告时客户端无法区分，导入会跳过这类记录；未指定语言且没有翻译的公告视为占用空语言。
+func announcementLanguageTaken(records []AnnouncementRecord, replacing string, record AnnouncementRecord) bool {
+	languages := record.languageSet()
+	if record.universal() {
+		languages[""] = true
+	}
+	for _, other := range records {
+		if other.ID != record.ID || other.Key == replacing {
+			continue
+		}
+		if other.universal() && languages[""] {
+			return true
+		}
+		for language := range other.languageSet() {
+			if languages[language] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Export 返回全部意见征集定义与匿名答卷的副本。
+func (s *SurveyStore) Export() ([]SurveyRecord, []SurveyResponseRecord) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := cloneSurveyRecords(s.records)
+	sortSurveyRecords(records)
+	responses := make([]SurveyResponseRecord, 0, len(s.responses))
+	for _, response := range s.responses {
+		responses = append(responses, cloneSurveyResponse(response))
+	}
+	return records, responses
+}
+
+// ImportSurveys 按冲突策略合并意见征集与匿名答卷。答卷跟随所属征集：
+// 只有最终保留的题目与归档一致时才会合并，已存在的答卷 key 不重复写入，
+// 答题人令牌摘要已在目标征集中出现的答卷会被跳过；
+// 已有答卷的征集不会被覆盖为不同的题目。
+func (s *SurveyStore) ImportSurveys(
+	records []SurveyRecord,
+	responses []SurveyResponseRecord,
+	policy string,
+	dryRun bool,
+) ([]ImportChange, error) {
+	grouped := make(map[string][]SurveyResponseRecord)
+	for index := range responses {
+		response := responses[index]
+		normalizeSurveyResponseRecord(&response)
+		if !validArchiveKey(response.Key) || response.SubmittedAt.IsZero() {
+			return nil, archiveError(fmt.Errorf("第 %d 份匿名答卷元数据无效", index+1))
+		}
+		grouped[response.SurveyKey] = append(grouped[response.SurveyKey], response)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	nextRecords := cloneSurveyRecords(s.records)
+	nextResponses := append([]SurveyResponseRecord(nil), s.responses...)
+	responseKeys := make(map[string]string, len(nextResponses))
+	for _, response := range nextResponses {
+		responseKeys[response.Key] = response.SurveyKey
+	}
+	// importedRespondents 记录本次导入新增的答题人令牌摘要，与已有索引一起用于去重。
+	importedRespondents := make(map[string]map[string]struct{})
+	changes := make([]ImportChange, 0, len(records))
+	changedKeys := make([]string, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	for index, record := range records {
+		normalizeSurveyRecord(&record)
+		if !validArchiveKey(record.Key) || seen[record.Key] {
+			return nil, archiveError(fmt.Errorf("第 %d 条意见征集的 key 无效或重复", index+1))
+		}
+		seen[record.Key] = true
+		if err := validateSurveyRecord(record); err != nil {
+			return nil, archiveError(fmt.Errorf("第 %d 条意见征集无效: %w", index+1, err))
+		}
+		if err := validateSurveyVersions(record); err != nil {
+			return nil, archiveError(fmt.Errorf("第 %d 条意见征集的%w", index+1, err))
+		}
+		fillImportTimestamps(&record.CreatedAt, &record.UpdatedAt, now)
+		archiveKey := record.Key
+
+		change := ImportChange{Kind: ImportKindSurvey, Key: archiveKey, Action: ImportActionCreate}
+		position := -1
+		for candidate := range nextRecords {
+			if nextRecords[candidate].Key == record.Key {
+				position = candidate
+				break
+			}
+		}
+		// target 为导入后该 key 实际保存的征集，用于判断答卷是否可以合并。
+		target := record
+		renamed := false
+		if position < 0 {
+			nextRecords = append(nextRecords, record)
+			changedKeys = append(changedKeys, record.Key)
+		} else {
+			current := nextRecords[position]
+			target = current
+			change.Fields = archiveFieldDiff(current, record)
+			hasResponses := false
+			for _, response := range nextResponses {
+				if response.SurveyKey == current.Key {
+					hasResponses = true
+					break
+				}
+			}
+			switch {
+			case len(change.Fields) == 0:
+				change.Action = ImportActionUnchanged
+			case policy == ImportPolicyOverwrite && hasResponses && !surveyHistoryPreserved(current, record):
+				change.Action = ImportActionSkip
+				change.Reason = "已有答卷的意见征集只能覆盖为保留全部已有版本的定义"
+			case policy == ImportPolicyOverwrite:
+				change.Action = ImportActionUpdate
+				record.CreatedAt = current.CreatedAt
+				record.UpdatedAt = now
+				nextRecords[position] = record
+				target = record
+				changedKeys = append(changedKeys, record.Key)
+			case policy == ImportPolicyRename:
+				change.Action = ImportActionRename
+				key, err := newSurveyKey()
+				if err != nil {
+					return nil, err
+				}
+				record.Key, record.CreatedAt, record.UpdatedAt = key, now, now
+				if !dryRun {
+					change.NewKey = key
+				}
+				nextRecords = append(nextRecords, record)
+				target = record
+				renamed = true
+				changedKeys = append(changedKeys, record.Key)
+			default:
+				change.Action = ImportActionSkip
+			}
+		}
+		changes = append(changes, change)
+
+		created, unchanged, skipped := 0, 0, 0
+		for responseIndex, response := range grouped[archiveKey] {
+			definition, found := record.definitionAt(response.SurveyVersion)
+			if !found {
+				return nil, archiveError(fmt.Errorf("意见征集 %s 的第 %d 份答卷引用了不存在的版本 %d", archiveKey, responseIndex+1, response.SurveyVersion))
+			}
+			if err := validateSurveyResponse(definition, SurveyResponseInput{
+				Answers:  response.Answers,
+				Platform: response.Platform,
+				AppBuild: response.AppBuild,
+				Language: response.Language,
+			}); err != nil {
+				return nil, archiveError(fmt.Errorf("意见征集 %s 的第 %d 份答卷无效: %w", archiveKey, responseIndex+1, err))
+			}
+			// 只有答卷回答的版本在目标征集中定义相同时才能合并。
+			if !sameSurveyVersion(target, record, response.SurveyVersion) {
+				skipped++
+				continue
+			}
+			if owner, exists := responseKeys[response.Key]; exists || renamed {
+				if owner == target.Key && !renamed {
+					unchanged++
+					continue
+				}
+				key, err := newSurveyKey()
+				if err != nil {
+					return nil, err
+				}
+				response.Key = key
+			}
+			// 同一答题人已在目标征集中作答的答卷不再导入，保持每个令牌只有一份答卷。
+			if hash := response.RespondentHash; hash != "" {
+				if _, imported := importedRespondents[target.Key][hash]; imported || s.answeredLocked(target.Key, hash) {
+					skipped++
+					continue
+				}
+				if importedRespondents[target.Key] == nil {
+					importedRespondents[target.Key] = make(map[string]struct{})
+				}
+				importedRespondents[target.Key][hash] = struct{}{}
+			}
+			response.SurveyKey = target.Key
+			responseKeys[response.Key] = target.Key
+			nextResponses = append(nextResponses, response)
+			created++
+		}
+		for _, summary := range []struct {
+			action string
+			count  int
+		}{
+			{ImportActionCreate, created},
+			{ImportActionUnchanged, unchanged},
+			{ImportActionSkip, skipped},
+		} {
+			if summary.count > 0 {
+				changes = append(changes, ImportChange{
+					Kind:   ImportKindSurveyResponses,
+					Key:    archiveKey,
+					Action: summary.action,
+					Count:  summary.count,
+				})
+			}
+		}
+		delete(grouped, archiveKey)
+	}
+	if len(grouped) > 0 {
+		return nil, archiveError(fmt.Errorf("有 %d 组匿名答卷关联的意见征集不在归档中", len(grouped)))
+	}
+
+	if len(nextRecords) > maxSurveyRecords {
+		return nil, coded(ErrorConflict, "survey_limit_reached", fmt.Sprintf("导入后意见征集将超过 %d 条", maxSurveyRecords))
+	}
+	counts := make(map[string]int, len(nextRecords))
+	for _, response := range nextResponses {
+		counts[response.SurveyKey]++
+		if counts[response.SurveyKey] > maxSurveyResponses {
+			return nil, coded(ErrorConflict, "survey_response_limit_reached", fmt.Sprintf("导入后意见征集 %s 的答卷将超过上限", response.SurveyKey))
+		}
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	// 导入只会新增答卷：先保存定义，再把新答卷追加到日志，避免日志引用尚未保存的征集。
+	previousRecords := s.records
+	s.records = nextRecords
+	previousRevision, previousChanges := s.revision, s.changes
+	if err := s.commitDefinitionsLocked(changedKeys...); err != nil {
+		s.records = previousRecords
+		return nil, err
+	}
+	if err := s.appendResponsesLocked(nextResponses[len(s.responses):]...); err != nil {
+		s.records = previousRecords
+		s.revision, s.changes = previousRevision, previousChanges
+		_ = s.saveDefinitionsLocked()
+		return nil, err
+	}
+	return changes, nil
+}
+
+// OpenBlob 打开官方数据文件内容，供导出归档使用。
+func (s *DistributionStore) OpenBlob(checksum string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(s.blobPath(strings.ToLower(checksum)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("打开官方数据文件失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("读取官方数据文件信息失败: %w", err)
+	}
+	return file, info.Size(), nil
+}
+
+// ImportDistribution 按冲突策略合并官方数据元信息，blobs 提供归档中按 SHA-256 索引的文件内容。
+func (s *DistributionStore) ImportDistribution(
+	records []DistributionRecord,
+	blobs map[string][]byte,
+	policy string,
+	dryRun bool,
+) ([]ImportChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	next := append([]DistributionRecord(nil), s.records...)
+	written := make([]string, 0)
+	changes := make([]ImportChange, 0, len(records))
+	seen := make(map[string]bool, len(records))
+	for index, record := range records {
+		record.Key = strings.TrimSpace(record.Key)
+		record.SHA256 = strings.ToLower(strings.TrimSpace(record.SHA256))
+		if !validArchiveKey(record.Key) || seen[record.Key] {
+			return nil, archiveError(fmt.Errorf("第 %d 条官方数据的 key 无效或重复", index+1))
+		}
+		seen[record.Key] = true
+		fillImportTimestamps(&record.CreatedAt, &record.UpdatedAt, now)
+		input, _, err := normalizeDistributionInput(DistributionInput{
+			Name:            record.Name,
+			DestinationPath: record.DestinationPath,
+			Enabled:         record.Enabled,
+		}, nil)
+		if err == nil {
+			record.Name, record.DestinationPath = input.Name, input.DestinationPath
+			err = validateStoredDistributionRecord(record)
+		}
+		if err != nil {
+			return nil, archiveError(fmt.Errorf("第 %d 条官方数据无效: %w", index+1, err))
+		}
+		if data, ok := blobs[record.SHA256]; !ok || int64(len(data)) != record.Size {
+			return nil, archiveError(fmt.Errorf("第 %d 条官方数据的文件内容缺失或大小不一致", index+1))
+		}
+
+		change := ImportChange{Kind: ImportKindDistribution, Key: record.Key, Action: ImportActionCreate}
+		position := -1
+		for candidate := range next {
+			if next[candidate].Key == record.Key {
+				position = candidate
+				break
+			}
+		}
+		if position >= 0 {
+			current := next[position]
+			change.Fields = archiveFieldDiff(current, record)
+			switch {
+			case len(change.Fields) == 0:
+				change.Action = ImportActionUnchanged
+			case policy == ImportPolicyOverwrite:
+				change.Action = ImportActionUpdate
+				record.CreatedAt = current.CreatedAt
+				record.UpdatedAt = now
+			case policy == ImportPolicyRename:
+				change.Action = ImportActionRename
+				key, err := newDistributionKey()
+				if err != nil {
+					return nil, err
+				}
+				record.Key, record.CreatedAt, record.UpdatedAt = key, now, now
+				if !dryRun {
+					change.NewKey = key
+				}
+				position = -1
+			default:
+				change.Action = ImportActionSkip
+			}
+		}
+		changes = append(changes, change)
+		if change.Action == ImportActionUnchanged || change.Action == ImportActionSkip {
+			continue
+		}
+
+		if !dryRun {
+			if err := s.writeBlobLocked(record.SHA256, blobs[record.SHA256]); err != nil {
+				s.removeImportedBlobsLocked(written)
+				return nil, err
+			}
+			written = append(written, record.SHA256)
+		}
+		if position >= 0 {
+			next[position] = record
+		} else {
+			next = append(next, record)
+		}
+	}
+
+	if len(next) > maxDistributionRecords {
+		s.removeImportedBlobsLocked(written)
+		return nil, coded(
+			ErrorConflict,
+			"distribution_limit_reached",
+			fmt.Sprintf("导入后官方数据条目将超过 %d 条", maxDistributionRecords),
+		)
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	previous := s.records
+	s.records = next
+	if err := s.saveLocked(); err != nil {
+		s.records = previous
+		s.removeImportedBlobsLocked(written)
+		return nil, err
+	}
+	for _, record := range previous {
+		s.removeBlobIfUnusedLocked(record.SHA256)
+	}
+	return changes, nil
+}
+
+// removeImportedBlobsLocked 清理导入失败时写入但未被引用的文件。
+func (s *DistributionStore) removeImportedBlobsLocked(checksums []string) {
+	for _, checksum := range checksums {
+		s.removeBlobIfUnusedLocked(checksum)
+	}
+}
+
+// fillImportTimestamps 为缺少时间戳的归档记录补齐时间，保留原有的创建与更新时间。
+func fillImportTimestamps(createdAt, updatedAt *time.Time, now time.Time) {
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt.IsZero() {
+		*updatedAt = *createdAt
+	}
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveRoundTripAndImportPolicies(t *testing.T) {
+	sourceAnnouncements, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	announcement, err := sourceAnnouncements.Create(AnnouncementRecord{
+		ID:      3,
+		Type:    "info",
+		Title:   "维护通知",
+		Body:    "今晚维护",
+		Enabled: true,
+	}, "cli")
+	if err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+	sourceSurveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	survey, err := sourceSurveys.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	if _, err := sourceSurveys.Submit(survey.Key, SurveyResponseInput{
+		Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+	}); err != nil {
+		t.Fatalf("保存匿名答卷失败: %v", err)
+	}
+	sourceDistribution, err := NewDistributionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化官方数据存储失败: %v", err)
+	}
+	file, err := sourceDistribution.Create(
+		DistributionInput{Name: "默认提供商", DestinationPath: "/Documents/Providers", Enabled: true},
+		DistributionUpload{FileName: "provider.json", ContentType: "application/json", Data: []byte(`{"name":"first"}`)},
+	)
+	if err != nil {
+		t.Fatalf("创建官方数据失败: %v", err)
+	}
+
+	archive := Archive{
+		Manifest:      ArchiveManifest{CreatedAt: time.Now().UTC()},
+		Announcements: sourceAnnouncements.List(),
+		Distribution:  sourceDistribution.List(),
+	}
+	archive.Surveys, archive.SurveyResponses = sourceSurveys.Export()
+	var buffer bytes.Buffer
+	if err := WriteArchive(&buffer, archive, sourceDistribution); err != nil {
+		t.Fatalf("写入归档失败: %v", err)
+	}
+	decoded, err := ReadArchive(bytes.NewReader(buffer.Bytes()), 1<<20)
+	if err != nil {
+		t.Fatalf("读取归档失败: %v", err)
+	}
+	if decoded.Manifest.Format != ArchiveFormat || decoded.Manifest.SurveyResponses != 1 ||
+		len(decoded.Announcements) != 1 || len(decoded.Surveys) != 1 || len(decoded.Distribution) != 1 ||
+		string(decoded.Blobs[file.SHA256]) != `{"name":"first"}` {
+		t.Fatalf("归档内容不完整: %+v", decoded.Manifest)
+	}
+	if _, err := ReadArchive(bytes.NewReader(buffer.Bytes()), 16); err == nil {
+		t.Fatalf("解压后超过上限的归档应被拒绝")
+	}
+
+	targetDistribution, err := NewDistributionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化官方数据存储失败: %v", err)
+	}
+	changes, err := targetDistribution.ImportDistribution(decoded.Distribution, decoded.Blobs, ImportPolicySkip, false)
+	if err != nil || len(changes) != 1 || changes[0].Action != ImportActionCreate {
+		t.Fatalf("导入官方数据失败: %+v err=%v", changes, err)
+	}
+	if _, path, ok := targetDistribution.PublicFile(file.SHA256, file.FileName); !ok {
+		t.Fatalf("导入的官方数据应可公开读取")
+	} else if data, err := os.ReadFile(path); err != nil || string(data) != `{"name":"first"}` {
+		t.Fatalf("导入的官方数据内容不正确: %q err=%v", data, err)
+	}
+
+	targetAnnouncements, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	if _, err := targetAnnouncements.ImportAnnouncements(decoded.Announcements, ImportPolicySkip, false, "cli", nil); err != nil {
+		t.Fatalf("导入公告失败: %v", err)
+	}
+	local := announcement
+	local.Title = "本地标题"
+	if _, err := targetAnnouncements.Update(announcement.Key, local, "local"); err != nil {
+		t.Fatalf("修改本地公告失败: %v", err)
+	}
+	changes, err = targetAnnouncements.ImportAnnouncements(decoded.Announcements, ImportPolicySkip, true, "cli", nil)
+	if err != nil || len(changes) != 1 || changes[0].Action != ImportActionSkip ||
+		strings.Join(changes[0].Fields, ",") != "title" {
+		t.Fatalf("冲突公告应列出差异字段并跳过: %+v err=%v", changes, err)
+	}
+	changes, err = targetAnnouncements.ImportAnnouncements(decoded.Announcements, ImportPolicyRename, false, "cli", nil)
+	if err != nil || changes[0].Action != ImportActionSkip || changes[0].Reason == "" {
+		t.Fatalf("rename 后编号与语言重复时应跳过: %+v err=%v", changes, err)
+	}
+	changes, err = targetAnnouncements.ImportAnnouncements(decoded.Announcements, ImportPolicyOverwrite, false, "cli", nil)
+	if err != nil || changes[0].Action != ImportActionUpdate {
+		t.Fatalf("overwrite 应覆盖本地公告: %+v err=%v", changes, err)
+	}
+	if history := targetAnnouncements.Revisions(announcement.Key); len(history) != 3 || history[0].Author != "cli" {
+		t.Fatalf("覆盖导入应记录修订: %+v", history)
+	}
+	local.ID = 4
+	if _, err := targetAnnouncements.Update(announcement.Key, local, "local"); err != nil {
+		t.Fatalf("修改本地公告失败: %v", err)
+	}
+	changes, err = targetAnnouncements.ImportAnnouncements(decoded.Announcements, ImportPolicyRename, false, "cli", nil)
+	if err != nil || changes[0].Action != ImportActionRename || changes[0].NewKey == "" {
+		t.Fatalf("rename 应以新 key 导入: %+v err=%v", changes, err)
+	}
+	if records := targetAnnouncements.List(); len(records) != 2 {
+		t.Fatalf("rename 后应保留本地公告并新增一条: %+v", records)
+	}
+	changes, err = targetAnnouncements.ImportAnnouncements(decoded.Announcements, ImportPolicyOverwrite, true, "cli", nil)
+	if err != nil || changes[0].Action != ImportActionSkip || changes[0].Reason == "" {
+		t.Fatalf("覆盖后编号与语言重复时应跳过: %+v err=%v", changes, err)
+	}
+
+	targetSurveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	changes, err = targetSurveys.ImportSurveys(decoded.Surveys, decoded.SurveyResponses, ImportPolicySkip, false)
+	if err != nil || len(changes) != 2 || changes[1].Kind != ImportKindSurveyResponses || changes[1].Count != 1 {
+		t.Fatalf("导入意见征集与答卷失败: %+v err=%v", changes, err)
+	}
+	changes, err = targetSurveys.ImportSurveys(decoded.Surveys, decoded.SurveyResponses, ImportPolicySkip, false)
+	if err != nil || changes[0].Action != ImportActionUnchanged || changes[1].Action != ImportActionUnchanged {
+		t.Fatalf("重复导入不应重复写入答卷: %+v err=%v", changes, err)
+	}
+	if _, responses := targetSurveys.Export(); len(responses) != 1 {
+		t.Fatalf("重复导入后答卷数量不正确: %d", len(responses))
+	}
+
+	changed := decoded.Surveys[0]
+	changed.Questions[0].Question = "新的题目"
+	changes, err = targetSurveys.ImportSurveys([]SurveyRecord{changed}, nil, ImportPolicyOverwrite, false)
+	if err != nil || changes[0].Action != ImportActionSkip || changes[0].Reason == "" {
+		t.Fatalf("已有答卷的征集不应被覆盖为不同题目: %+v err=%v", changes, err)
+	}
+}
+
+func TestArchiveImportSkipsKnownRespondents(t *testing.T) {
+	source, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	survey, err := source.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	if _, err := source.Submit(survey.Key, SurveyResponseInput{
+		Answers:         []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+		RespondentToken: strings.Repeat("a", 64),
+	}); err != nil {
+		t.Fatalf("保存匿名答卷失败: %v", err)
+	}
+	records, responses := source.Export()
+
+	target, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	if _, err := target.ImportSurveys(records, responses, ImportPolicySkip, false); err != nil {
+		t.Fatalf("导入意见征集失败: %v", err)
+	}
+
+	// 另一份归档中同一答题人的答卷 key 不同，但令牌摘要已在目标征集中出现；同一归档内重复的摘要也只导入一份。
+	repeated := responses[0]
+	repeated.Key = "0123456789abcdef01234567"
+	first, second := responses[0], responses[0]
+	first.Key, second.Key = "1123456789abcdef01234567", "2123456789abcdef01234567"
+	first.RespondentHash = respondentHash(survey.Key, strings.Repeat("b", 64))
+	second.RespondentHash = first.RespondentHash
+	changes, err := target.ImportSurveys(records, []SurveyResponseRecord{repeated, first, second}, ImportPolicySkip, false)
+	if err != nil {
+		t.Fatalf("导入意见征集失败: %v", err)
+	}
+	if len(changes) != 3 || changes[1].Action != ImportActionCreate || changes[1].Count != 1 ||
+		changes[2].Action != ImportActionSkip || changes[2].Count != 2 {
+		t.Fatalf("已作答的答题人不应再导入答卷: %+v", changes)
+	}
+	if _, stored := target.Export(); len(stored) != 2 {
+		t.Fatalf("导入后答卷数量不正确: %d", len(stored))
+	}
+	if _, err := target.Submit(survey.Key, SurveyResponseInput{
+		Answers:         []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+		RespondentToken: strings.Repeat("b", 64),
+	}); !errors.Is(err, ErrSurveyAlreadyAnswered) {
+		t.Fatalf("导入的令牌摘要应参与重复提交判断: %v", err)
+	}
+}
+
+func TestArchiveImportRejectsUnavailableAnnouncementImage(t *testing.T) {
+	target, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	checksum := strings.Repeat("c", 64)
+	records := []AnnouncementRecord{{
+		Key:     "0123456789abcdef01234567",
+		ID:      8,
+		Type:    "info",
+		Title:   "新图标",
+		Body:    "见图",
+		Enabled: true,
+		Image:   &AnnouncementImage{SHA256: checksum},
+	}}
+	missing := func(string) bool { return false }
+	for _, dryRun := range []bool{true, false} {
+		_, err := target.ImportAnnouncements(records, ImportPolicySkip, dryRun, "cli", missing)
+		var storeErr *Error
+		if !errors.As(err, &storeErr) || storeErr.Code != "archive_invalid" || !strings.Contains(storeErr.Message, "图片") {
+			t.Fatalf("引用不存在图片的公告应拒绝导入 (dry_run=%v): %v", dryRun, err)
+		}
+	}
+	if stored := target.List(); len(stored) != 0 {
+		t.Fatalf("拒绝导入后不应写入公告: %+v", stored)
+	}
+
+	available := func(sum string) bool { return sum == checksum }
+	changes, err := target.ImportAnnouncements(records, ImportPolicySkip, false, "cli", available)
+	if err != nil || len(changes) != 1 || changes[0].Action != ImportActionCreate {
+		t.Fatalf("引用可用图片的公告应正常导入: %+v err=%v", changes, err)
+	}
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	attestationKeyFileVersion = 1
+	maxAttestationKeys        = 20000
+)
+
+// AttestationKeyRecord 保存一枚通过证书链校验的设备公钥，不包含任何设备标识。
+type AttestationKeyRecord struct {
+	KeyID        string    `json:"key_id"`
+	PublicKey    []byte    `json:"public_key"`
+	Subject      string    `json:"subject,omitempty"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+type attestationKeyFile struct {
+	Version int                    `json:"version"`
+	Records []AttestationKeyRecord `json:"records"`
+}
+
+// AttestationKeyStore 负责设备证明公钥的本地持久化。
+type AttestationKeyStore struct {
+	mu      sync.RWMutex
+	file    string
+	records map[string]AttestationKeyRecord
+}
+
+func NewAttestationKeyStore(dataDir string) (*AttestationKeyStore, error) {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建数据目录失败: %w", err)
+	}
+
+	store := &AttestationKeyStore{
+		file:    filepath.Join(dataDir, "attestation-keys.json"),
+		records: make(map[string]AttestationKeyRecord),
+	}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *AttestationKeyStore) AttestationPublicKey(keyID string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[strings.ToLower(strings.TrimSpace(keyID))]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), record.PublicKey...), true
+}
+
+// RegisterAttestationKey 登记设备公钥；同一密钥重复登记时保持首次记录。
+func (s *AttestationKeyStore) RegisterAttestationKey(keyID string, publicKeyDER []byte, subject string) error {
+	keyID = strings.ToLower(strings.TrimSpace(keyID))
+	if keyID == "" || len(publicKeyDER) == 0 {
+		return fmt.Errorf("设备密钥不能为空")
+	}
+	subject = strings.TrimSpace(subject)
+	if len([]rune(subject)) > 500 {
+		subject = string([]rune(subject)[:500])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.records[keyID]; exists {
+		return nil
+	}
+	if len(s.records) >= maxAttestationKeys {
+		return fmt.Errorf("设备密钥不能超过 %d 个", maxAttestationKeys)
+	}
+
+	s.records[keyID] = AttestationKeyRecord{
+		KeyID:        keyID,
+		PublicKey:    append([]byte(nil), publicKeyDER...),
+		Subject:      subject,
+		RegisteredAt: time.Now().UTC(),
+	}
+	if err := s.saveLocked(); err != nil {
+		delete(s.records, keyID)
+		return err
+	}
+	return nil
+}
+
+func (s *AttestationKeyStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+func (s *AttestationKeyStore) load() error {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取设备密钥文件失败: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil
+	}
+
+	var payload attestationKeyFile
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return fmt.Errorf("解析设备密钥文件失败: %w", err)
+	}
+	if payload.Version != attestationKeyFileVersion {
+		return fmt.Errorf("不支持的设备密钥文件版本: %d", payload.Version)
+	}
+	if len(payload.Records) > maxAttestationKeys {
+		return fmt.Errorf("设备密钥不能超过 %d 个", maxAttestationKeys)
+	}
+	for index, record := range payload.Records {
+		record.KeyID = strings.ToLower(strings.TrimSpace(record.KeyID))
+		if record.KeyID == "" || len(record.PublicKey) == 0 || record.RegisteredAt.IsZero() {
+			return fmt.Errorf("第 %d 个设备密钥无效", index+1)
+		}
+		s.records[record.KeyID] = record
+	}
+	return nil
+}
+
+func (s *AttestationKeyStore) saveLocked() error {
+	records := make([]AttestationKeyRecord, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sortAttestationKeyRecords(records)
+	return writeSurveyJSONAtomically(
+		s.file,
+		".attestation-keys-*.tmp",
+		attestationKeyFile{Version: attestationKeyFileVersion, Records: records},
+		"设备密钥",
+	)
+}
+
+func sortAttestationKeyRecords(records []AttestationKeyRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].RegisteredAt.Equal(records[j].RegisteredAt) {
+			return records[i].RegisteredAt.Before(records[j].RegisteredAt)
+		}
+		return records[i].KeyID < records[j].KeyID
+	})
+}
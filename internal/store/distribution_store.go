@@ -111,7 +111,7 @@ func (s *DistributionStore) Create(
 ) (DistributionRecord, error) {
 	normalizedInput, normalizedUpload, err := normalizeDistributionInput(input, &upload)
 	if err != nil {
-		return DistributionRecord{}, err
+		return DistributionRecord{}, invalidError("distribution_invalid", err)
 	}
 
 	key, err := newDistributionKey()
@@ -124,7 +124,11 @@ func (s *DistributionStore) Create(
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if len(s.records) >= maxDistributionRecords {
-		return DistributionRecord{}, fmt.Errorf("官方数据条目不能超过 %d 条", maxDistributionRecords)
+		return DistributionRecord{}, coded(
+			ErrorConflict,
+			"distribution_limit_reached",
+			fmt.Sprintf("官方数据条目不能超过 %d 条", maxDistributionRecords),
+		)
 	}
 	if err := s.writeBlobLocked(record.SHA256, normalizedUpload.Data); err != nil {
 		return DistributionRecord{}, err
@@ -146,12 +150,12 @@ func (s *DistributionStore) Update(
 ) (DistributionRecord, error) {
 	key = strings.TrimSpace(key)
 	if key == "" {
-		return DistributionRecord{}, fmt.Errorf("官方数据 key 不能为空")
+		return DistributionRecord{}, coded(ErrorInvalid, "distribution_invalid", "官方数据 key 不能为空")
 	}
 
 	normalizedInput, normalizedUpload, err := normalizeDistributionInput(input, upload)
 	if err != nil {
-		return DistributionRecord{}, err
+		return DistributionRecord{}, invalidError("distribution_invalid", err)
 	}
 
 	s.mu.Lock()
@@ -192,13 +196,13 @@ func (s *DistributionStore) Update(
 		}
 		return replacement, nil
 	}
-	return DistributionRecord{}, fmt.Errorf("官方数据条目不存在")
+	return DistributionRecord{}, ErrDistributionNotFound
 }
 
 func (s *DistributionStore) Delete(key string) error {
 	key = strings.TrimSpace(key)
 	if key == "" {
-		return fmt.Errorf("官方数据 key 不能为空")
+		return coded(ErrorInvalid, "distribution_invalid", "官方数据 key 不能为空")
 	}
 
 	s.mu.Lock()
@@ -217,7 +221,7 @@ func (s *DistributionStore) Delete(key string) error {
 		s.removeBlobIfUnusedLocked(record.SHA256)
 		return nil
 	}
-	return fmt.Errorf("官方数据条目不存在")
+	return ErrDistributionNotFound
 }
 
 func (s *DistributionStore) PublicFile(
@@ -237,6 +241,20 @@ func (s *DistributionStore) PublicFile(
 	return DistributionRecord{}, "", false
 }
 
+// PublicImage 按 SHA-256 查找已启用的图片文件，供公告引用。
+func (s *DistributionStore) PublicImage(checksum string) (DistributionRecord, bool) {
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, record := range s.records {
+		if record.Enabled && record.SHA256 == checksum && strings.HasPrefix(record.ContentType, "image/") {
+			return record, true
+		}
+	}
+	return DistributionRecord{}, false
+}
+
 func (s *DistributionStore) load() error {
 	data, err := os.ReadFile(s.file)
 	if err != nil {
@@ -0,0 +1,72 @@
+package store
+
+import "errors"
+
+// ErrorKind 描述存储错误的类别，API 层据此选择 HTTP 状态码。
+type ErrorKind int
+
+const (
+	// ErrorInvalid 表示输入内容不合法。
+	ErrorInvalid ErrorKind = iota + 1
+	// ErrorNotFound 表示目标记录不存在。
+	ErrorNotFound
+	// ErrorConflict 表示请求与现有状态冲突，例如已有答卷或容量已满。
+	ErrorConflict
+	// ErrorGone 表示目标记录存在但已不再接受写入。
+	ErrorGone
+)
+
+// Error 是带稳定错误码的存储错误。Code 面向客户端，Message 面向人工阅读；
+// Reason 可选，进一步说明同一错误码下的具体原因。
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Reason  string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is 按错误码比较，使带不同说明的同类错误也能与哨兵错误匹配。
+func (e *Error) Is(target error) bool {
+	var other *Error
+	if !errors.As(target, &other) {
+		return false
+	}
+	return e.Code == other.Code
+}
+
+var (
+	ErrAnnouncementNotFound = &Error{Kind: ErrorNotFound, Code: "announcement_not_found", Message: "公告不存在"}
+	ErrSurveyNotFound       = &Error{Kind: ErrorNotFound, Code: "survey_not_found", Message: "意见征集不存在"}
+	ErrSurveyClosed         = &Error{Kind: ErrorGone, Code: "survey_closed", Message: "意见征集已停止"}
+	ErrSurveyHasResponses   = &Error{
+		Kind:    ErrorConflict,
+		Code:    "survey_has_responses",
+		Message: "已有答卷的意见征集只能修改文字或新增选填题目、选项",
+	}
+	ErrSurveyAlreadyAnswered = &Error{
+		Kind:    ErrorConflict,
+		Code:    "survey_already_answered",
+		Message: "这台设备已经提交过该意见征集",
+	}
+	ErrDistributionNotFound = &Error{Kind: ErrorNotFound, Code: "distribution_not_found", Message: "官方数据条目不存在"}
+)
+
+// invalidError 把校验失败包装为带错误码的 ErrorInvalid。
+func invalidError(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		return err
+	}
+	return &Error{Kind: ErrorInvalid, Code: code, Message: err.Error()}
+}
+
+func coded(kind ErrorKind, code, message string) error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
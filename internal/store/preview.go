@@ -0,0 +1,44 @@
+package store
+
+import "time"
+
+// PreviewAnnouncements 按客户端的筛选规则返回 audience 在 now 时刻会收到的公告，供管理端预览。
+// includeDrafts 为 true 时草稿按已发布处理，便于在发布前检查投放条件。
+func (s *AnnouncementStore) PreviewAnnouncements(
+	now time.Time,
+	audience AnnouncementAudience,
+	includeDrafts bool,
+) []PublicAnnouncement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result, _ := s.selectLocked(now, audience, includeDrafts)
+	return result
+}
+
+// PreviewSurveys 模拟客户端对公开征集列表的筛选：只保留 now 时刻正在征集的条目，按平台与构建号过滤，
+// 再在同一编号的语言版本中选出与 Locale 最匹配的一条，包含翻译的征集按 Locale 选出语言。意见征集没有分发渠道。
+func (s *SurveyStore) PreviewSurveys(now time.Time, audience AnnouncementAudience, includeDrafts bool) []PublicSurvey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]SurveyRecord, 0, len(s.records))
+	for _, record := range s.records {
+		if includeDrafts {
+			record.Enabled = true
+		}
+		if s.closedReasonLocked(record, now) == "" &&
+			audience.matchesTarget("", record.Platform, record.MinBuild, record.MaxBuild) {
+			records = append(records, record)
+		}
+	}
+	sortSurveyRecords(records)
+	records = selectLanguageVariants(records, audience.Locale, func(record SurveyRecord) (int, string) {
+		return record.ID, record.Language
+	})
+
+	result := make([]PublicSurvey, 0, len(records))
+	for _, record := range records {
+		result = append(result, record.PublicIn(audience.Locale))
+	}
+	return result
+}
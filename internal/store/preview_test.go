@@ -0,0 +1,70 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreviewSelectsContentLikeClient(t *testing.T) {
+	announcements, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	if _, err := announcements.Create(AnnouncementRecord{
+		ID:       9,
+		Type:     "info",
+		Language: "zh-Hans",
+		Title:    "新功能",
+		Body:     "草稿",
+		MinBuild: "200",
+		Localizations: []AnnouncementLocalization{
+			{Language: "en", Title: "New feature", Body: "Draft"},
+		},
+	}, "cli"); err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+
+	audience, err := ParseAnnouncementAudience("iOS", "210", "en-US", "")
+	if err != nil {
+		t.Fatalf("解析客户端条件失败: %v", err)
+	}
+	now := time.Now()
+	if records := announcements.PreviewAnnouncements(now, audience, false); len(records) != 0 {
+		t.Fatalf("不包含草稿时不应返回未发布公告: %+v", records)
+	}
+	records := announcements.PreviewAnnouncements(now, audience, true)
+	if len(records) != 1 || records[0].Language != "en" || records[0].Title != "New feature" {
+		t.Fatalf("应按语言选出英文版本: %+v", records)
+	}
+	oldBuild, _ := ParseAnnouncementAudience("iOS", "150", "en-US", "")
+	if records := announcements.PreviewAnnouncements(now, oldBuild, true); len(records) != 0 {
+		t.Fatalf("低于最低构建号的客户端不应收到公告: %+v", records)
+	}
+
+	surveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	chinese := testSurveyRecord()
+	if _, err := surveys.Create(chinese); err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	english := testSurveyRecord()
+	english.Language = "en"
+	english.Title = "Layout survey"
+	if _, err := surveys.Create(english); err != nil {
+		t.Fatalf("创建英文征集失败: %v", err)
+	}
+
+	simplified, _ := ParseAnnouncementAudience("iOS", "", "zh-Hans-CN", "")
+	if selected := surveys.PreviewSurveys(time.Now(), simplified, false); len(selected) != 1 || selected[0].Language != "zh-Hans" {
+		t.Fatalf("简体中文客户端应收到中文征集: %+v", selected)
+	}
+	if selected := surveys.PreviewSurveys(time.Now(), audience, false); len(selected) != 1 || selected[0].Title != "Layout survey" {
+		t.Fatalf("英文客户端应收到英文征集: %+v", selected)
+	}
+	watch, _ := ParseAnnouncementAudience("watchOS", "", "en", "")
+	if selected := surveys.PreviewSurveys(time.Now(), watch, false); len(selected) != 0 {
+		t.Fatalf("仅 iOS 的征集不应下发给 watchOS: %+v", selected)
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// 分阶段放量的盐值前缀，区分公告与意见征集，避免同一编号的两类内容命中同一批客户端。
+const (
+	rolloutKindAnnouncement = "announcement"
+	rolloutKindSurvey       = "survey"
+)
+
+// RolloutBucket 返回匿名安装 ID 在指定盐值下的桶号（0~99）。
+// 客户端需实现相同算法：取 SHA256(salt + ":" + installationID) 前 8 字节按大端无符号整数对 100 取余，
+// 桶号小于 rollout_percent 时可见。百分比只增不减时，已命中的客户端始终保持命中。
+func RolloutBucket(salt, installationID string) int {
+	digest := sha256.Sum256([]byte(salt + ":" + installationID))
+	return int(binary.BigEndian.Uint64(digest[:8]) % 100)
+}
+
+// rolloutSalt 由内容类型与公开编号派生盐值，同一编号的各语言版本共享分桶结果，
+// 调整放量比例或改用新的 key 都不会改变客户端所在的桶。
+func rolloutSalt(kind string, id int) string {
+	digest := sha256.Sum256([]byte(kind + ":" + strconv.Itoa(id)))
+	return hex.EncodeToString(digest[:8])
+}
+
+// publicRollout 返回公开响应中的放量字段；未设置或已全量时均为零值，旧客户端照常显示。
+func publicRollout(kind string, id, percent int) (int, string) {
+	if percent <= 0 || percent >= 100 {
+		return 0, ""
+	}
+	return percent, rolloutSalt(kind, id)
+}
+
+// normalizeRolloutPercent 把全量放量统一保存为未设置。
+func normalizeRolloutPercent(percent int) int {
+	if percent == 100 {
+		return 0
+	}
+	return percent
+}
+
+// validateRolloutPercent 接受 0 到 100，0（留空）与 100 都表示全量。
+func validateRolloutPercent(percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("放量比例必须在 0 到 100 之间，0、留空或 100 表示全量")
+	}
+	return nil
+}
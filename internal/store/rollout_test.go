@@ -0,0 +1,107 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRolloutBucketIsStableAndWideningKeepsClients(t *testing.T) {
+	salt := rolloutSalt(rolloutKindAnnouncement, 7)
+	if salt != rolloutSalt(rolloutKindAnnouncement, 7) || salt == rolloutSalt(rolloutKindSurvey, 7) {
+		t.Fatalf("盐值应由类型与编号稳定派生")
+	}
+
+	included := func(percent int) map[string]bool {
+		result := map[string]bool{}
+		for index := 0; index < 2000; index++ {
+			installationID := fmt.Sprintf("install-%d", index)
+			bucket := RolloutBucket(salt, installationID)
+			if bucket < 0 || bucket > 99 {
+				t.Fatalf("桶号超出范围: %d", bucket)
+			}
+			if bucket < percent {
+				result[installationID] = true
+			}
+		}
+		return result
+	}
+	narrow, wide := included(10), included(50)
+	if len(narrow) < 100 || len(narrow) > 300 {
+		t.Fatalf("10%% 放量命中数量偏差过大: %d", len(narrow))
+	}
+	for installationID := range narrow {
+		if !wide[installationID] {
+			t.Fatalf("扩大放量后 %s 不应被移出", installationID)
+		}
+	}
+}
+
+func TestRolloutPercentIsExposedAndAdjustableAfterResponses(t *testing.T) {
+	announcements, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	created, err := announcements.Create(AnnouncementRecord{
+		ID:             7,
+		Type:           "info",
+		Title:          "新功能",
+		Body:           "先向部分用户开放",
+		RolloutPercent: 10,
+		Enabled:        true,
+	}, "cli")
+	if err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+	public := announcements.PublicList()
+	if len(public) != 1 || public[0].RolloutPercent != 10 || public[0].RolloutSalt != rolloutSalt(rolloutKindAnnouncement, 7) {
+		t.Fatalf("公开公告应包含放量比例与盐值: %+v", public)
+	}
+
+	created.RolloutPercent = 100
+	widened, err := announcements.Update(created.Key, created, "cli")
+	if err != nil {
+		t.Fatalf("扩大放量失败: %v", err)
+	}
+	if widened.Key != created.Key || widened.RolloutPercent != 0 {
+		t.Fatalf("全量放量应保留 key 并保存为未设置: %+v", widened)
+	}
+	if public := announcements.PublicList(); public[0].RolloutPercent != 0 || public[0].RolloutSalt != "" {
+		t.Fatalf("全量公告不应下发放量字段: %+v", public)
+	}
+
+	created.RolloutPercent = 101
+	if _, err := announcements.Update(created.Key, created, "cli"); !errors.Is(err, &Error{Code: "announcement_invalid"}) ||
+		!strings.Contains(err.Error(), "0 到 100") {
+		t.Fatalf("超出范围的放量比例应被拒绝，实际 %v", err)
+	}
+	created.RolloutPercent = 0
+	if full, err := announcements.Update(created.Key, created, "cli"); err != nil || full.RolloutPercent != 0 {
+		t.Fatalf("0 表示全量，应被接受: %+v err=%v", full, err)
+	}
+
+	surveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	record := testSurveyRecord()
+	record.RolloutPercent = 5
+	survey, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	if _, err := surveys.Submit(survey.Key, SurveyResponseInput{
+		Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+	}); err != nil {
+		t.Fatalf("保存匿名答卷失败: %v", err)
+	}
+	survey.RolloutPercent = 30
+	if _, err := surveys.Update(survey.Key, survey); err != nil {
+		t.Fatalf("已有答卷时仍应允许扩大放量: %v", err)
+	}
+	publicSurveys := surveys.PublicList()
+	if len(publicSurveys) != 1 || publicSurveys[0].RolloutPercent != 30 || publicSurveys[0].RolloutSalt == "" {
+		t.Fatalf("公开征集应包含放量字段: %+v", publicSurveys)
+	}
+}
@@ -0,0 +1,701 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSurveyAggregateTexts 是统计结果中每道题保留的文字回答条数，按提交时间从新到旧。
+const maxSurveyAggregateTexts = 100
+
+// SurveyResultQuery 限定参与统计的答卷，零值字段表示不限。
+// MinBuild/MaxBuild 按整数构建号比较，设置后构建号缺失或不是整数的答卷不参与统计；
+// Language 匹配相同语言标识或以它为前缀的子标识，如 zh 匹配 zh-Hans；
+// 提交时间落在 [From, To) 内的答卷才参与统计；SurveyVersion 只统计回答该定义版本的答卷。
+// CrossRow 与 CrossColumn 同时设置时返回这两道题的交叉表；ByLanguage 为 true 时另按征集语言拆分各题统计。
+type SurveyResultQuery struct {
+	Platform      string
+	Language      string
+	MinBuild      int
+	MaxBuild      int
+	From          time.Time
+	To            time.Time
+	SurveyVersion int
+	CrossRow      string
+	CrossColumn   string
+	ByLanguage    bool
+}
+
+// SurveyAggregate 是服务端计算的意见征集统计。
+type SurveyAggregate struct {
+	Survey SurveyRecord `json:"survey"`
+	// TotalCount 是征集的全部答卷数，ResponseCount 是符合筛选条件的答卷数，
+	// FlaggedCount 是其中文字回答被审核标记的答卷数。
+	TotalCount    int               `json:"total_count"`
+	ResponseCount int               `json:"response_count"`
+	FlaggedCount  int               `json:"flagged_count"`
+	Environment   SurveyEnvironment `json:"environment"`
+	// SurveyVersions 按定义版本从旧到新列出符合筛选条件的答卷数。
+	SurveyVersions []SurveyDefinitionCount   `json:"survey_versions"`
+	Questions      []SurveyQuestionAggregate `json:"questions"`
+	CrossTab       *SurveyCrossTab           `json:"cross_tab,omitempty"`
+	// Languages 是按征集语言拆分的统计，仅在 ByLanguage 时返回；Questions 始终合并全部语言。
+	Languages []SurveyLanguageAggregate `json:"languages,omitempty"`
+}
+
+// SurveyLanguageAggregate 是作答时看到某种征集语言的答卷的统计。征集自身的语言按 Languages 的顺序在前，
+// 已删除翻译的语言在后；Language 为空表示未指定语言的征集。
+type SurveyLanguageAggregate struct {
+	Language      string                    `json:"language"`
+	ResponseCount int                       `json:"response_count"`
+	Questions     []SurveyQuestionAggregate `json:"questions"`
+}
+
+// SurveyEnvironment 汇总答卷的客户端版本、平台与语言，空字符串表示未提供。
+type SurveyEnvironment struct {
+	Versions  []SurveyVersionCount `json:"versions"`
+	Platforms []SurveyTally        `json:"platforms"`
+	Languages []SurveyTally        `json:"languages"`
+}
+
+// SurveyVersionCount 是一个应用版本与构建号组合的答卷数。
+type SurveyVersionCount struct {
+	AppVersion string `json:"app_version"`
+	AppBuild   string `json:"app_build"`
+	Count      int    `json:"count"`
+}
+
+// SurveyDefinitionCount 是回答某个定义版本的答卷数。
+type SurveyDefinitionCount struct {
+	Version int `json:"version"`
+	Count   int `json:"count"`
+}
+
+// SurveyTally 是某个取值的答卷数。
+type SurveyTally struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SurveyQuestionAggregate 是一道题的统计。Hidden 是因显示条件未成立而未显示该题的答卷数，
+// Skipped 是显示了但未作答的答卷数，NotAsked 是回答的定义版本中还没有这道题的答卷数；
+// 百分比均以 Answered 为分母。
+type SurveyQuestionAggregate struct {
+	QuestionID string              `json:"question_id"`
+	Type       string              `json:"type"`
+	Answered   int                 `json:"answered"`
+	Skipped    int                 `json:"skipped"`
+	Hidden     int                 `json:"hidden"`
+	NotAsked   int                 `json:"not_asked,omitempty"`
+	Options    []SurveyOptionCount `json:"options,omitempty"`
+	OtherCount int                 `json:"other_count,omitempty"`
+	Values     *SurveyValueSummary `json:"values,omitempty"`
+	// Texts 是 text 题的回答或选择题的自定义回答，最多保留最新的 100 条。
+	Texts []SurveyTextAnswer `json:"texts,omitempty"`
+	// Themes 把全部文字回答按相似度聚为主题，见 clusterSurveyTexts。
+	Themes []SurveyTextTheme `json:"themes,omitempty"`
+}
+
+// SurveyOptionCount 是选项的统计。选择题的 Count 是选中次数，NotOffered 是作答时该选项尚未加入的答卷数，
+// 此时 Percent 以 Answered 减去 NotOffered 为分母；排序题的 Count 是排在第一的次数，
+// AveragePosition 是从 1 开始的平均名次。
+type SurveyOptionCount struct {
+	OptionID        string  `json:"option_id"`
+	Label           string  `json:"label"`
+	Count           int     `json:"count"`
+	Percent         float64 `json:"percent"`
+	NotOffered      int     `json:"not_offered,omitempty"`
+	AveragePosition float64 `json:"average_position,omitempty"`
+}
+
+// SurveyValueSummary 汇总 rating、nps 与 number 题的数值回答。
+// Distribution 仅用于 rating 与 nps，按分值从低到高列出每个整数分值的回答数。
+type SurveyValueSummary struct {
+	Average      float64            `json:"average"`
+	Median       float64            `json:"median"`
+	Min          float64            `json:"min"`
+	Max          float64            `json:"max"`
+	Distribution []SurveyValueCount `json:"distribution,omitempty"`
+	NPS          *SurveyNPS         `json:"nps,omitempty"`
+}
+
+// SurveyValueCount 是某个整数分值的回答数。
+type SurveyValueCount struct {
+	Value   int     `json:"value"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// SurveyNPS 是净推荐值：推荐者（9~10）占比减去贬损者（0~6）占比，取值 -100~100。
+type SurveyNPS struct {
+	Score      int `json:"score"`
+	Promoters  int `json:"promoters"`
+	Passives   int `json:"passives"`
+	Detractors int `json:"detractors"`
+}
+
+// SurveyTextAnswer 是一条文字回答及其匿名客户端信息。Flagged 表示所在答卷的文字回答被审核标记，
+// FlagReasons 是审核给出的原因。
+type SurveyTextAnswer struct {
+	Text        string    `json:"text"`
+	Flagged     bool      `json:"flagged,omitempty"`
+	FlagReasons []string  `json:"flag_reasons,omitempty"`
+	Platform    string    `json:"platform,omitempty"`
+	AppVersion  string    `json:"app_version,omitempty"`
+	AppBuild    string    `json:"app_build,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// SurveyCrossTab 是两道题的交叉表。Counts[i][j] 是行题目回答 Rows[i] 且列题目回答 Columns[j]
+// 的答卷数；多选题的一份答卷会计入每个选中的选项。Total 是两道题都作答的答卷数。
+type SurveyCrossTab struct {
+	RowQuestionID    string           `json:"row_question_id"`
+	ColumnQuestionID string           `json:"column_question_id"`
+	Rows             []SurveyCategory `json:"rows"`
+	Columns          []SurveyCategory `json:"columns"`
+	Counts           [][]int          `json:"counts"`
+	Total            int              `json:"total"`
+}
+
+// SurveyCategory 是交叉表的一行或一列：选择题的选项，或 rating、nps 题的分值。
+type SurveyCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// Aggregate 按 query 筛选 key 对应征集的答卷并计算统计。
+func (s *SurveyStore) Aggregate(key string, query SurveyResultQuery) (SurveyAggregate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	survey, found := s.findLocked(key)
+	if !found {
+		return SurveyAggregate{}, ErrSurveyNotFound
+	}
+	query.Platform = normalizeAnnouncementPlatform(query.Platform)
+	if err := query.validate(); err != nil {
+		return SurveyAggregate{}, invalidError("query_invalid", err)
+	}
+	var crossRow, crossColumn SurveyQuestion
+	crossRequested := query.CrossRow != "" || query.CrossColumn != ""
+	if crossRequested {
+		var err error
+		if crossRow, crossColumn, err = surveyCrossTabQuestions(survey, query.CrossRow, query.CrossColumn); err != nil {
+			return SurveyAggregate{}, invalidError("query_invalid", err)
+		}
+	}
+
+	aggregate := SurveyAggregate{Survey: cloneSurveyRecord(survey)}
+	responses := make([]SurveyResponseRecord, 0)
+	for _, response := range s.responses {
+		if response.SurveyKey != key {
+			continue
+		}
+		aggregate.TotalCount++
+		if query.matches(response) {
+			responses = append(responses, response)
+			if response.flagged() {
+				aggregate.FlaggedCount++
+			}
+		}
+	}
+	// 文字回答按提交时间从新到旧保留。
+	sort.SliceStable(responses, func(i, j int) bool {
+		return responses[i].SubmittedAt.After(responses[j].SubmittedAt)
+	})
+	aggregate.ResponseCount = len(responses)
+	aggregate.Environment = summarizeSurveyEnvironment(responses)
+	aggregate.SurveyVersions = countSurveyDefinitions(survey, responses)
+
+	visible := make([]map[string]SurveyAnswer, len(responses))
+	for index, response := range responses {
+		visible[index] = visibleSurveyAnswers(survey, response)
+	}
+	versions := surveyVersionQuestions(survey)
+	aggregate.Questions = make([]SurveyQuestionAggregate, 0, len(survey.Questions))
+	for _, question := range survey.Questions {
+		aggregate.Questions = append(aggregate.Questions, aggregateSurveyQuestion(question, responses, visible, versions))
+	}
+	if crossRequested {
+		aggregate.CrossTab = buildSurveyCrossTab(crossRow, crossColumn, visible)
+	}
+	if query.ByLanguage {
+		aggregate.Languages = aggregateSurveyLanguages(survey, responses, visible, versions)
+	}
+	return aggregate, nil
+}
+
+// aggregateSurveyLanguages 按答卷作答时看到的征集语言分组统计各题，见 SurveyResponseRecord.SurveyLanguage。
+func aggregateSurveyLanguages(
+	survey SurveyRecord,
+	responses []SurveyResponseRecord,
+	visible []map[string]SurveyAnswer,
+	versions map[int]map[string]SurveyQuestion,
+) []SurveyLanguageAggregate {
+	type group struct {
+		responses []SurveyResponseRecord
+		visible   []map[string]SurveyAnswer
+	}
+	groups := make(map[string]*group)
+	order := survey.Languages()
+	for index, response := range responses {
+		language := response.SurveyLanguage
+		if language == "" {
+			language = survey.Language
+		}
+		current, exists := groups[language]
+		if !exists {
+			current = &group{}
+			groups[language] = current
+			if !slices.Contains(order, language) {
+				order = append(order, language)
+			}
+		}
+		current.responses = append(current.responses, response)
+		current.visible = append(current.visible, visible[index])
+	}
+	// 征集自身的语言保持原有顺序，其余语言排在后面并按字母排序。
+	known := len(survey.Languages())
+	sort.Strings(order[known:])
+
+	result := make([]SurveyLanguageAggregate, 0, len(order))
+	for _, language := range order {
+		current, exists := groups[language]
+		if !exists {
+			current = &group{}
+		}
+		breakdown := SurveyLanguageAggregate{
+			Language:      language,
+			ResponseCount: len(current.responses),
+			Questions:     make([]SurveyQuestionAggregate, 0, len(survey.Questions)),
+		}
+		for _, question := range survey.Questions {
+			breakdown.Questions = append(breakdown.Questions, aggregateSurveyQuestion(question, current.responses, current.visible, versions))
+		}
+		result = append(result, breakdown)
+	}
+	return result
+}
+
+func (q SurveyResultQuery) validate() error {
+	if q.Platform != "" && q.Platform != "iOS" && q.Platform != "watchOS" {
+		return fmt.Errorf("platform 仅支持 iOS 或 watchOS")
+	}
+	if q.MinBuild < 0 || q.MaxBuild < 0 {
+		return fmt.Errorf("构建号必须是非负整数")
+	}
+	if q.MinBuild > 0 && q.MaxBuild > 0 && q.MinBuild > q.MaxBuild {
+		return fmt.Errorf("最低构建号不能高于最高构建号")
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return fmt.Errorf("开始时间必须早于结束时间")
+	}
+	if q.SurveyVersion < 0 {
+		return fmt.Errorf("征集版本必须是非负整数")
+	}
+	return nil
+}
+
+func (q SurveyResultQuery) matches(response SurveyResponseRecord) bool {
+	if q.Platform != "" && response.Platform != q.Platform {
+		return false
+	}
+	if q.Language != "" {
+		language, prefix := strings.ToLower(response.Language), strings.ToLower(q.Language)
+		if language != prefix && !strings.HasPrefix(language, prefix+"-") {
+			return false
+		}
+	}
+	if q.MinBuild > 0 || q.MaxBuild > 0 {
+		build, err := strconv.Atoi(response.AppBuild)
+		if err != nil || (q.MinBuild > 0 && build < q.MinBuild) || (q.MaxBuild > 0 && build > q.MaxBuild) {
+			return false
+		}
+	}
+	if !q.From.IsZero() && response.SubmittedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !response.SubmittedAt.Before(q.To) {
+		return false
+	}
+	if q.SurveyVersion > 0 && response.SurveyVersion != q.SurveyVersion {
+		return false
+	}
+	return true
+}
+
+// visibleSurveyAnswers 按显示条件重放一份答卷，返回已显示且已作答的题目回答。
+func visibleSurveyAnswers(survey SurveyRecord, response SurveyResponseRecord) map[string]SurveyAnswer {
+	submitted := make(map[string]SurveyAnswer, len(response.Answers))
+	for _, answer := range response.Answers {
+		submitted[answer.QuestionID] = answer
+	}
+	accepted := make(map[string]SurveyAnswer, len(response.Answers))
+	for _, question := range survey.Questions {
+		answer, exists := submitted[question.ID]
+		if exists && surveyAnswerProvided(question, answer) && surveyConditionsMet(question.ShowIf, accepted) {
+			accepted[question.ID] = answer
+		}
+	}
+	return accepted
+}
+
+// aggregateSurveyQuestion 统计一道题。versions 是各定义版本包含的题目，
+// 答卷按它回答的版本判断这道题和各选项当时是否存在。
+func aggregateSurveyQuestion(
+	question SurveyQuestion,
+	responses []SurveyResponseRecord,
+	visible []map[string]SurveyAnswer,
+	versions map[int]map[string]SurveyQuestion,
+) SurveyQuestionAggregate {
+	result := SurveyQuestionAggregate{QuestionID: question.ID, Type: question.Type}
+	answers := make([]SurveyAnswer, 0)
+	asked := make([]SurveyQuestion, 0)
+	texts := make([]surveyTextEntry, 0)
+	for index, response := range responses {
+		shown, exists := versions[response.SurveyVersion][question.ID]
+		if !exists {
+			result.NotAsked++
+			continue
+		}
+		answer, answered := visible[index][question.ID]
+		if !answered {
+			if surveyConditionsMet(question.ShowIf, visible[index]) {
+				result.Skipped++
+			} else {
+				result.Hidden++
+			}
+			continue
+		}
+		answers = append(answers, answer)
+		asked = append(asked, shown)
+		text := answer.Text
+		if text == "" {
+			text = answer.OtherText
+		}
+		if text == "" {
+			continue
+		}
+		texts = append(texts, surveyTextEntry{text: text, flagged: response.flagged()})
+		if len(result.Texts) < maxSurveyAggregateTexts {
+			textAnswer := SurveyTextAnswer{
+				Text:        text,
+				Flagged:     response.flagged(),
+				Platform:    response.Platform,
+				AppVersion:  response.AppVersion,
+				AppBuild:    response.AppBuild,
+				Language:    response.Language,
+				SubmittedAt: response.SubmittedAt,
+			}
+			if textAnswer.Flagged {
+				textAnswer.FlagReasons = append([]string(nil), response.Moderation.Reasons...)
+			}
+			result.Texts = append(result.Texts, textAnswer)
+		}
+	}
+	result.Answered = len(answers)
+	result.Themes = clusterSurveyTexts(texts)
+
+	switch question.Type {
+	case SurveyTypeSingleSelect, SurveyTypeMultiSelect:
+		for _, option := range question.Options {
+			count, notOffered := 0, 0
+			for index, answer := range answers {
+				if !slices.ContainsFunc(asked[index].Options, func(candidate SurveyOption) bool {
+					return candidate.ID == option.ID
+				}) {
+					notOffered++
+				} else if slices.Contains(answer.SelectedOptionIDs, option.ID) {
+					count++
+				}
+			}
+			result.Options = append(result.Options, SurveyOptionCount{
+				OptionID:   option.ID,
+				Label:      option.Label,
+				Count:      count,
+				Percent:    surveyPercent(count, len(answers)-notOffered),
+				NotOffered: notOffered,
+			})
+		}
+		for _, answer := range answers {
+			if answer.OtherText != "" {
+				result.OtherCount++
+			}
+		}
+	case SurveyTypeRanking:
+		for _, option := range question.Options {
+			first, positions := 0, 0
+			for _, answer := range answers {
+				position := slices.Index(answer.SelectedOptionIDs, option.ID) + 1
+				positions += position
+				if position == 1 {
+					first++
+				}
+			}
+			count := SurveyOptionCount{
+				OptionID: option.ID,
+				Label:    option.Label,
+				Count:    first,
+				Percent:  surveyPercent(first, len(answers)),
+			}
+			if len(answers) > 0 {
+				count.AveragePosition = roundSurveyNumber(float64(positions) / float64(len(answers)))
+			}
+			result.Options = append(result.Options, count)
+		}
+		sort.SliceStable(result.Options, func(i, j int) bool {
+			return result.Options[i].AveragePosition < result.Options[j].AveragePosition
+		})
+	case SurveyTypeRating, SurveyTypeNPS, SurveyTypeNumber:
+		values := make([]float64, 0, len(answers))
+		for _, answer := range answers {
+			values = append(values, *answer.Value)
+		}
+		result.Values = summarizeSurveyValues(question, values)
+	}
+	return result
+}
+
+func summarizeSurveyValues(question SurveyQuestion, values []float64) *SurveyValueSummary {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	middle := len(values) / 2
+	median := values[middle]
+	if len(values)%2 == 0 {
+		median = (values[middle-1] + values[middle]) / 2
+	}
+	summary := &SurveyValueSummary{
+		Average: roundSurveyNumber(total / float64(len(values))),
+		Median:  median,
+		Min:     values[0],
+		Max:     values[len(values)-1],
+	}
+	if question.Type == SurveyTypeNumber {
+		return summary
+	}
+
+	low, high := 1, question.Scale
+	if question.Type == SurveyTypeNPS {
+		low, high = 0, 10
+	}
+	counts := make(map[int]int, high-low+1)
+	for _, value := range values {
+		counts[int(value)]++
+	}
+	for value := low; value <= high; value++ {
+		summary.Distribution = append(summary.Distribution, SurveyValueCount{
+			Value:   value,
+			Count:   counts[value],
+			Percent: surveyPercent(counts[value], len(values)),
+		})
+	}
+	if question.Type == SurveyTypeNPS {
+		nps := &SurveyNPS{}
+		for _, value := range values {
+			switch {
+			case value >= 9:
+				nps.Promoters++
+			case value >= 7:
+				nps.Passives++
+			default:
+				nps.Detractors++
+			}
+		}
+		nps.Score = int(math.Round(float64(nps.Promoters-nps.Detractors) * 100 / float64(len(values))))
+		summary.NPS = nps
+	}
+	return summary
+}
+
+func summarizeSurveyEnvironment(responses []SurveyResponseRecord) SurveyEnvironment {
+	type version struct{ app, build string }
+	versions := map[version]int{}
+	platforms := map[string]int{}
+	languages := map[string]int{}
+	for _, response := range responses {
+		versions[version{response.AppVersion, response.AppBuild}]++
+		platforms[response.Platform]++
+		languages[response.Language]++
+	}
+
+	environment := SurveyEnvironment{
+		Versions:  make([]SurveyVersionCount, 0, len(versions)),
+		Platforms: sortedSurveyTallies(platforms),
+		Languages: sortedSurveyTallies(languages),
+	}
+	for key, count := range versions {
+		environment.Versions = append(environment.Versions, SurveyVersionCount{
+			AppVersion: key.app,
+			AppBuild:   key.build,
+			Count:      count,
+		})
+	}
+	sort.Slice(environment.Versions, func(i, j int) bool {
+		left, right := environment.Versions[i], environment.Versions[j]
+		if left.Count != right.Count {
+			return left.Count > right.Count
+		}
+		if left.AppVersion != right.AppVersion {
+			return left.AppVersion < right.AppVersion
+		}
+		return left.AppBuild < right.AppBuild
+	})
+	return environment
+}
+
+// countSurveyDefinitions 按定义版本从旧到新统计答卷数，没有答卷的版本也会列出。
+func countSurveyDefinitions(survey SurveyRecord, responses []SurveyResponseRecord) []SurveyDefinitionCount {
+	counts := make([]SurveyDefinitionCount, survey.Version)
+	for index := range counts {
+		counts[index].Version = index + 1
+	}
+	for _, response := range responses {
+		if response.SurveyVersion >= 1 && response.SurveyVersion <= survey.Version {
+			counts[response.SurveyVersion-1].Count++
+		}
+	}
+	return counts
+}
+
+func sortedSurveyTallies(counts map[string]int) []SurveyTally {
+	tallies := make([]SurveyTally, 0, len(counts))
+	for value, count := range counts {
+		tallies = append(tallies, SurveyTally{Value: value, Count: count})
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		if tallies[i].Count != tallies[j].Count {
+			return tallies[i].Count > tallies[j].Count
+		}
+		return tallies[i].Value < tallies[j].Value
+	})
+	return tallies
+}
+
+// surveyCrossTabQuestions 查找交叉表的两道题；只支持选择题、rating 与 nps 题。
+func surveyCrossTabQuestions(survey SurveyRecord, rowID, columnID string) (row, column SurveyQuestion, err error) {
+	if rowID == "" || columnID == "" {
+		return row, column, fmt.Errorf("交叉表需要同时指定行题目和列题目")
+	}
+	if rowID == columnID {
+		return row, column, fmt.Errorf("交叉表的行题目和列题目不能相同")
+	}
+	find := func(id string) (SurveyQuestion, error) {
+		for _, question := range survey.Questions {
+			if question.ID != id {
+				continue
+			}
+			if len(surveyCategories(question)) == 0 {
+				return question, fmt.Errorf("交叉表只支持选择题、rating 与 nps 题: %s", id)
+			}
+			return question, nil
+		}
+		return SurveyQuestion{}, fmt.Errorf("交叉表引用了不存在的题目: %s", id)
+	}
+	if row, err = find(rowID); err != nil {
+		return row, column, err
+	}
+	column, err = find(columnID)
+	return row, column, err
+}
+
+func surveyCategories(question SurveyQuestion) []SurveyCategory {
+	switch question.Type {
+	case SurveyTypeSingleSelect, SurveyTypeMultiSelect:
+		categories := make([]SurveyCategory, 0, len(question.Options))
+		for _, option := range question.Options {
+			categories = append(categories, SurveyCategory{ID: option.ID, Label: option.Label})
+		}
+		return categories
+	case SurveyTypeRating, SurveyTypeNPS:
+		low, high := 1, question.Scale
+		if question.Type == SurveyTypeNPS {
+			low, high = 0, 10
+		}
+		categories := make([]SurveyCategory, 0, high-low+1)
+		for value := low; value <= high; value++ {
+			label := strconv.Itoa(value)
+			categories = append(categories, SurveyCategory{ID: label, Label: label})
+		}
+		return categories
+	default:
+		return nil
+	}
+}
+
+// surveyAnswerCategories 返回回答落入的分类 ID。
+func surveyAnswerCategories(answer SurveyAnswer) []string {
+	if answer.Value != nil {
+		return []string{strconv.Itoa(int(*answer.Value))}
+	}
+	return answer.SelectedOptionIDs
+}
+
+func buildSurveyCrossTab(row, column SurveyQuestion, visible []map[string]SurveyAnswer) *SurveyCrossTab {
+	table := &SurveyCrossTab{
+		RowQuestionID:    row.ID,
+		ColumnQuestionID: column.ID,
+		Rows:             surveyCategories(row),
+		Columns:          surveyCategories(column),
+	}
+	rowIndex := categoryIndexes(table.Rows)
+	columnIndex := categoryIndexes(table.Columns)
+	table.Counts = make([][]int, len(table.Rows))
+	for index := range table.Counts {
+		table.Counts[index] = make([]int, len(table.Columns))
+	}
+	for _, answers := range visible {
+		rowAnswer, rowAnswered := answers[row.ID]
+		columnAnswer, columnAnswered := answers[column.ID]
+		if !rowAnswered || !columnAnswered {
+			continue
+		}
+		rows := surveyAnswerCategories(rowAnswer)
+		columns := surveyAnswerCategories(columnAnswer)
+		if len(rows) == 0 || len(columns) == 0 {
+			continue
+		}
+		table.Total++
+		for _, rowID := range rows {
+			for _, columnID := range columns {
+				i, rowKnown := rowIndex[rowID]
+				j, columnKnown := columnIndex[columnID]
+				if rowKnown && columnKnown {
+					table.Counts[i][j]++
+				}
+			}
+		}
+	}
+	return table
+}
+
+func categoryIndexes(categories []SurveyCategory) map[string]int {
+	indexes := make(map[string]int, len(categories))
+	for index, category := range categories {
+		indexes[category.ID] = index
+	}
+	return indexes
+}
+
+// surveyPercent 返回 count/total 的百分比，保留一位小数。
+func surveyPercent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(count)*1000/float64(total)) / 10
+}
+
+func roundSurveyNumber(value float64) float64 {
+	return math.Round(value*100) / 100
+}
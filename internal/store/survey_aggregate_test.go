@@ -0,0 +1,93 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSurveyAggregateFiltersAndCrossTabulates(t *testing.T) {
+	surveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	record := testSurveyRecord()
+	record.Questions = append(record.Questions,
+		SurveyQuestion{ID: "nps", Question: "推荐意愿", Type: "nps"},
+		SurveyQuestion{ID: "why", Question: "为什么？", Type: "text", ShowIf: []SurveyCondition{
+			{QuestionID: "design", OptionIDs: []string{"relaxed"}},
+		}},
+	)
+	created, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+
+	value := func(number float64) *float64 { return &number }
+	submit := func(platform, build, option string, score float64, why string) {
+		t.Helper()
+		answers := []SurveyAnswer{
+			{QuestionID: "design", SelectedOptionIDs: []string{option}},
+			{QuestionID: "nps", Value: value(score)},
+		}
+		if why != "" {
+			answers = append(answers, SurveyAnswer{QuestionID: "why", Text: why})
+		}
+		if _, err := surveys.Submit(created.Key, SurveyResponseInput{
+			Answers: answers, Platform: platform, AppBuild: build, Language: "zh-Hans",
+		}); err != nil {
+			t.Fatalf("提交答卷失败: %v", err)
+		}
+	}
+	submit("iOS", "120", "compact", 10, "")
+	submit("iOS", "130", "relaxed", 9, "留白更舒服")
+	submit("watchOS", "130", "relaxed", 3, "")
+	submit("iOS", "beta", "compact", 6, "")
+
+	all, err := surveys.Aggregate(created.Key, SurveyResultQuery{CrossRow: "design", CrossColumn: "nps"})
+	if err != nil {
+		t.Fatalf("统计意见征集失败: %v", err)
+	}
+	if all.TotalCount != 4 || all.ResponseCount != 4 {
+		t.Fatalf("答卷数量不正确: %+v", all)
+	}
+	design := all.Questions[0]
+	if design.Answered != 4 || design.Options[0].Count != 2 || design.Options[0].Percent != 50 {
+		t.Fatalf("选项统计不正确: %+v", design)
+	}
+	nps := all.Questions[1].Values
+	if nps == nil || nps.NPS == nil || nps.NPS.Score != 0 || nps.NPS.Promoters != 2 || len(nps.Distribution) != 11 {
+		t.Fatalf("NPS 统计不正确: %+v", nps)
+	}
+	why := all.Questions[2]
+	if why.Answered != 1 || why.Skipped != 1 || why.Hidden != 2 || len(why.Texts) != 1 || why.Texts[0].Text != "留白更舒服" {
+		t.Fatalf("条件题统计应区分跳过与未显示: %+v", why)
+	}
+	table := all.CrossTab
+	if table == nil || table.Total != 4 || len(table.Rows) != 2 || len(table.Columns) != 11 ||
+		table.Counts[0][10] != 1 || table.Counts[1][3] != 1 {
+		t.Fatalf("交叉表不正确: %+v", table)
+	}
+
+	filtered, err := surveys.Aggregate(created.Key, SurveyResultQuery{
+		Platform: "iOS", MinBuild: 125, Language: "zh", To: time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("筛选统计失败: %v", err)
+	}
+	if filtered.TotalCount != 4 || filtered.ResponseCount != 1 || filtered.Questions[0].Options[1].Count != 1 {
+		t.Fatalf("筛选后应只剩一份 iOS 130 答卷: %+v", filtered)
+	}
+	if future, _ := surveys.Aggregate(created.Key, SurveyResultQuery{From: time.Now().Add(time.Hour)}); future.ResponseCount != 0 {
+		t.Fatalf("晚于全部提交时间的起点应筛掉所有答卷: %+v", future)
+	}
+
+	var typed *Error
+	if _, err := surveys.Aggregate(created.Key, SurveyResultQuery{CrossRow: "design", CrossColumn: "why"}); !errors.As(err, &typed) ||
+		typed.Code != "query_invalid" {
+		t.Fatalf("文字题不能用于交叉表，实际错误: %v", err)
+	}
+	if _, err := surveys.Aggregate("missing", SurveyResultQuery{}); !errors.Is(err, ErrSurveyNotFound) {
+		t.Fatalf("不存在的征集应返回 ErrSurveyNotFound，实际错误: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// SurveyTable 是按答卷展开的二维表，每份答卷一行。Labels 与 Columns 一一对应，
+// 是便于人工阅读的题目与选项文字。单元格取值为 string、float64、int、bool 或 nil，nil 表示未作答。
+type SurveyTable struct {
+	Survey  SurveyRecord
+	Columns []string
+	Labels  []string
+	Rows    [][]any
+}
+
+// ExportTable 按 query 的筛选条件把答卷展开为表格，交叉表参数会被忽略。列按题目顺序排列：
+// 单选题、文字题与数值题各占一列；多选题每个选项一列，选中为 true；
+// 排序题每个选项一列，值为名次；允许自定义回答的题目另有一列 <题目>#other。
+// 选项列名为 <题目>:<选项>，标识符不能包含冒号和井号，因此列名不会冲突。
+func (s *SurveyStore) ExportTable(key string, query SurveyResultQuery) (SurveyTable, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	survey, found := s.findLocked(key)
+	if !found {
+		return SurveyTable{}, ErrSurveyNotFound
+	}
+	query.Platform = normalizeAnnouncementPlatform(query.Platform)
+	query.CrossRow, query.CrossColumn = "", ""
+	if err := query.validate(); err != nil {
+		return SurveyTable{}, invalidError("query_invalid", err)
+	}
+
+	responses := make([]SurveyResponseRecord, 0)
+	for _, response := range s.responses {
+		if response.SurveyKey == key && query.matches(response) {
+			responses = append(responses, response)
+		}
+	}
+	sort.SliceStable(responses, func(i, j int) bool {
+		return responses[i].SubmittedAt.Before(responses[j].SubmittedAt)
+	})
+
+	table := SurveyTable{
+		Survey:  cloneSurveyRecord(survey),
+		Columns: []string{"response_key", "submitted_at", "survey_version", "platform", "app_version", "app_build", "language"},
+		Labels:  []string{"答卷 key", "提交时间（UTC）", "征集版本", "平台", "应用版本", "构建号", "语言"},
+		Rows:    make([][]any, 0, len(responses)),
+	}
+	for _, question := range survey.Questions {
+		columns, labels := surveyExportColumns(question)
+		table.Columns = append(table.Columns, columns...)
+		table.Labels = append(table.Labels, labels...)
+	}
+	for _, response := range responses {
+		row := []any{
+			response.Key,
+			response.SubmittedAt.UTC().Format(time.RFC3339),
+			response.SurveyVersion,
+			response.Platform,
+			response.AppVersion,
+			response.AppBuild,
+			response.Language,
+		}
+		answers := make(map[string]SurveyAnswer, len(response.Answers))
+		for _, answer := range response.Answers {
+			answers[answer.QuestionID] = answer
+		}
+		for _, question := range survey.Questions {
+			answer, answered := answers[question.ID]
+			if answered && !surveyAnswerProvided(question, answer) {
+				answered = false
+			}
+			row = append(row, surveyExportCells(question, answer, answered)...)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+func surveyExportColumns(question SurveyQuestion) (columns, labels []string) {
+	switch question.Type {
+	case SurveyTypeMultiSelect, SurveyTypeRanking:
+		for _, option := range question.Options {
+			columns = append(columns, question.ID+":"+option.ID)
+			labels = append(labels, question.Question+" / "+option.Label)
+		}
+	default:
+		columns = append(columns, question.ID)
+		labels = append(labels, question.Question)
+	}
+	if question.AllowOther {
+		columns = append(columns, question.ID+"#other")
+		labels = append(labels, question.Question+" / 自定义回答")
+	}
+	return columns, labels
+}
+
+// surveyExportCells 返回一道题在一行中的单元格，数量与 surveyExportColumns 一致。
+func surveyExportCells(question SurveyQuestion, answer SurveyAnswer, answered bool) []any {
+	cells := make([]any, 0, len(question.Options)+1)
+	switch question.Type {
+	case SurveyTypeMultiSelect, SurveyTypeRanking:
+		for _, option := range question.Options {
+			position := slices.Index(answer.SelectedOptionIDs, option.ID)
+			switch {
+			case !answered:
+				cells = append(cells, nil)
+			case question.Type == SurveyTypeRanking && position >= 0:
+				cells = append(cells, position+1)
+			case question.Type == SurveyTypeRanking:
+				cells = append(cells, nil)
+			default:
+				cells = append(cells, position >= 0)
+			}
+		}
+	case SurveyTypeSingleSelect:
+		if answered && len(answer.SelectedOptionIDs) > 0 {
+			cells = append(cells, answer.SelectedOptionIDs[0])
+		} else {
+			cells = append(cells, nil)
+		}
+	case SurveyTypeText:
+		if answered {
+			cells = append(cells, answer.Text)
+		} else {
+			cells = append(cells, nil)
+		}
+	default:
+		if answered && answer.Value != nil {
+			cells = append(cells, *answer.Value)
+		} else {
+			cells = append(cells, nil)
+		}
+	}
+	if question.AllowOther {
+		if answered && answer.OtherText != "" {
+			cells = append(cells, answer.OtherText)
+		} else {
+			cells = append(cells, nil)
+		}
+	}
+	return cells
+}
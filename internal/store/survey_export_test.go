@@ -0,0 +1,50 @@
+package store
+
+import "testing"
+
+func TestSurveyExportTableExpandsQuestions(t *testing.T) {
+	surveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	record := testSurveyRecord()
+	record.Questions = append(record.Questions, SurveyQuestion{
+		ID: "order", Question: "排序", Type: "ranking", Options: []SurveyOption{
+			{ID: "speed", Label: "速度"},
+			{ID: "price", Label: "价格"},
+		},
+	})
+	created, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	for _, answers := range [][]SurveyAnswer{
+		{{QuestionID: "design", SelectedOptionIDs: []string{"relaxed"}}, {QuestionID: "order", SelectedOptionIDs: []string{"price", "speed"}}},
+		{{QuestionID: "design", OtherText: "都不喜欢"}},
+	} {
+		if _, err := surveys.Submit(created.Key, SurveyResponseInput{Answers: answers, Platform: "iOS"}); err != nil {
+			t.Fatalf("提交答卷失败: %v", err)
+		}
+	}
+
+	table, err := surveys.ExportTable(created.Key, SurveyResultQuery{})
+	if err != nil {
+		t.Fatalf("导出答卷失败: %v", err)
+	}
+	columns := table.Columns[7:]
+	if len(columns) != 4 || columns[0] != "design" || columns[1] != "design#other" ||
+		columns[2] != "order:speed" || columns[3] != "order:price" || len(table.Labels) != len(table.Columns) {
+		t.Fatalf("导出列不正确: %v", table.Columns)
+	}
+	first, second := table.Rows[0][7:], table.Rows[1][7:]
+	if first[0] != "relaxed" || first[1] != nil || first[2] != 2 || first[3] != 1 {
+		t.Fatalf("第一份答卷展开不正确: %v", first)
+	}
+	if second[0] != nil || second[1] != "都不喜欢" || second[2] != nil || second[3] != nil {
+		t.Fatalf("未作答的排序题应为空: %v", second)
+	}
+
+	if filtered, _ := surveys.ExportTable(created.Key, SurveyResultQuery{Platform: "watchos"}); len(filtered.Rows) != 0 {
+		t.Fatalf("平台筛选应生效: %v", filtered.Rows)
+	}
+}
@@ -0,0 +1,256 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const maxSurveyLocalizations = 50
+
+// SurveyLocalization 是意见征集在某一语言下的标题、说明与题目文字。
+// 题目与选项沿用默认语言的 ID，只翻译文字；未翻译的题目或选项回退到默认语言。
+// 翻译不属于征集定义，修改翻译不会生成新版本。
+type SurveyLocalization struct {
+	Language    string                       `json:"language"`
+	Title       string                       `json:"title"`
+	Description string                       `json:"description,omitempty"`
+	Questions   []SurveyQuestionLocalization `json:"questions,omitempty"`
+}
+
+// SurveyQuestionLocalization 是一道题的翻译，ID 对应默认语言中的题目。
+type SurveyQuestionLocalization struct {
+	ID       string                     `json:"id"`
+	Question string                     `json:"question,omitempty"`
+	Options  []SurveyOptionLocalization `json:"options,omitempty"`
+}
+
+// SurveyOptionLocalization 是一个选项的翻译，ID 对应默认语言中的选项。
+type SurveyOptionLocalization struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+}
+
+// Languages 返回征集提供的全部语言，默认语言在前。未指定语言且没有翻译的征集返回空列表。
+func (record SurveyRecord) Languages() []string {
+	if record.Language == "" {
+		return nil
+	}
+	languages := []string{record.Language}
+	for _, localization := range record.Localizations {
+		languages = append(languages, localization.Language)
+	}
+	return languages
+}
+
+// resolveLanguage 返回与 locale 最匹配的征集语言，规则与公告相同：逐级截短的语言标签精确匹配，
+// 否则回退到默认语言。locale 为空或征集没有翻译时返回默认语言。
+func (record SurveyRecord) resolveLanguage(locale string) string {
+	if locale == "" || len(record.Localizations) == 0 {
+		return record.Language
+	}
+	candidates := localeFallbacks(locale)
+	best, bestRank := record.Language, languageRank(record.Language, candidates)
+	for _, localization := range record.Localizations {
+		if rank := languageRank(localization.Language, candidates); rank < bestRank {
+			best, bestRank = localization.Language, rank
+		}
+	}
+	if bestRank >= len(candidates) {
+		return record.Language
+	}
+	return best
+}
+
+// localized 返回以 language 的文字替换默认文字后的定义，language 必须是 Languages 中的一项，
+// 否则原样返回。结果不含翻译列表。
+func (record SurveyRecord) localized(language string) SurveyRecord {
+	record = cloneSurveyRecord(record)
+	localizations := record.Localizations
+	record.Localizations = nil
+	index := slices.IndexFunc(localizations, func(localization SurveyLocalization) bool {
+		return strings.EqualFold(localization.Language, language)
+	})
+	if index < 0 {
+		return record
+	}
+	localization := localizations[index]
+	record.Language = localization.Language
+	record.Title = localization.Title
+	record.Description = localization.Description
+	for _, translated := range localization.Questions {
+		questionIndex := slices.IndexFunc(record.Questions, func(question SurveyQuestion) bool {
+			return question.ID == translated.ID
+		})
+		if questionIndex < 0 {
+			continue
+		}
+		question := &record.Questions[questionIndex]
+		if translated.Question != "" {
+			question.Question = translated.Question
+		}
+		for _, option := range translated.Options {
+			optionIndex := slices.IndexFunc(question.Options, func(candidate SurveyOption) bool {
+				return candidate.ID == option.ID
+			})
+			if optionIndex >= 0 {
+				question.Options[optionIndex].Label = option.Label
+				question.Options[optionIndex].Description = option.Description
+			}
+		}
+	}
+	return record
+}
+
+// PublicIn 返回按 locale 选出语言后的公开定义，Language 为实际使用的语言；
+// locale 为空时与 Public 相同。
+func (record SurveyRecord) PublicIn(locale string) PublicSurvey {
+	if locale == "" || len(record.Localizations) == 0 {
+		return record.Public()
+	}
+	localized := record.localized(record.resolveLanguage(locale))
+	public := localized.Public()
+	public.Language = localized.Language
+	public.Languages = record.Languages()
+	return public
+}
+
+func normalizeSurveyLocalizations(localizations []SurveyLocalization) []SurveyLocalization {
+	if len(localizations) == 0 {
+		return nil
+	}
+	normalized := make([]SurveyLocalization, 0, len(localizations))
+	for _, localization := range localizations {
+		questions := make([]SurveyQuestionLocalization, 0, len(localization.Questions))
+		for _, question := range localization.Questions {
+			options := make([]SurveyOptionLocalization, 0, len(question.Options))
+			for _, option := range question.Options {
+				options = append(options, SurveyOptionLocalization{
+					ID:          strings.TrimSpace(option.ID),
+					Label:       strings.TrimSpace(option.Label),
+					Description: strings.TrimSpace(option.Description),
+				})
+			}
+			if len(options) == 0 {
+				options = nil
+			}
+			questions = append(questions, SurveyQuestionLocalization{
+				ID:       strings.TrimSpace(question.ID),
+				Question: strings.TrimSpace(question.Question),
+				Options:  options,
+			})
+		}
+		if len(questions) == 0 {
+			questions = nil
+		}
+		normalized = append(normalized, SurveyLocalization{
+			Language:    strings.TrimSpace(localization.Language),
+			Title:       strings.TrimSpace(localization.Title),
+			Description: strings.TrimSpace(localization.Description),
+			Questions:   questions,
+		})
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].Language < normalized[j].Language
+	})
+	return normalized
+}
+
+// validateSurveyLocalizations 要求包含翻译的征集指定默认语言，每种语言只出现一次，
+// 且只翻译当前定义中存在的题目与选项。
+func validateSurveyLocalizations(record SurveyRecord) error {
+	if len(record.Localizations) == 0 {
+		return nil
+	}
+	if record.Language == "" {
+		return fmt.Errorf("包含翻译的意见征集必须通过 language 指定默认语言")
+	}
+	if len(record.Localizations) > maxSurveyLocalizations {
+		return fmt.Errorf("翻译不能超过 %d 种语言", maxSurveyLocalizations)
+	}
+
+	seen := map[string]bool{strings.ToLower(record.Language): true}
+	for _, localization := range record.Localizations {
+		if localization.Language == "" {
+			return fmt.Errorf("翻译的语言标识不能为空")
+		}
+		if len([]rune(localization.Language)) > 32 {
+			return fmt.Errorf("语言标识不能超过 32 个字符")
+		}
+		if err := validateSurveyLocalization(record, localization); err != nil {
+			return fmt.Errorf("%s 翻译%v", localization.Language, err)
+		}
+		key := strings.ToLower(localization.Language)
+		if seen[key] {
+			return fmt.Errorf("语言 %s 重复", localization.Language)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// validateSurveyLocalization 校验一种语言的翻译，返回的错误接在语言标识之后。
+func validateSurveyLocalization(record SurveyRecord, localization SurveyLocalization) error {
+	if count := len([]rune(localization.Title)); count < 1 || count > 200 {
+		return fmt.Errorf("的标题长度必须在 1 到 200 个字符之间")
+	}
+	if len([]rune(localization.Description)) > 2000 {
+		return fmt.Errorf("的说明不能超过 2000 个字符")
+	}
+	translated := make(map[string]bool, len(localization.Questions))
+	for _, question := range localization.Questions {
+		index := slices.IndexFunc(record.Questions, func(candidate SurveyQuestion) bool {
+			return candidate.ID == question.ID
+		})
+		if index < 0 {
+			return fmt.Errorf("引用了不存在的题目: %s", question.ID)
+		}
+		if translated[question.ID] {
+			return fmt.Errorf("的题目 %s 重复", question.ID)
+		}
+		translated[question.ID] = true
+		if len([]rune(question.Question)) > 500 {
+			return fmt.Errorf("的题目 %s 不能超过 500 个字符", question.ID)
+		}
+		options := make(map[string]bool, len(question.Options))
+		for _, option := range question.Options {
+			if !slices.ContainsFunc(record.Questions[index].Options, func(candidate SurveyOption) bool {
+				return candidate.ID == option.ID
+			}) {
+				return fmt.Errorf("的题目 %s 引用了不存在的选项: %s", question.ID, option.ID)
+			}
+			if options[option.ID] {
+				return fmt.Errorf("的题目 %s 选项 %s 重复", question.ID, option.ID)
+			}
+			options[option.ID] = true
+			if count := len([]rune(option.Label)); count < 1 || count > 200 {
+				return fmt.Errorf("的题目 %s 选项 %s 长度必须在 1 到 200 个字符之间", question.ID, option.ID)
+			}
+			if len([]rune(option.Description)) > 500 {
+				return fmt.Errorf("的题目 %s 选项 %s 说明不能超过 500 个字符", question.ID, option.ID)
+			}
+		}
+	}
+	return nil
+}
+
+func cloneSurveyLocalizations(localizations []SurveyLocalization) []SurveyLocalization {
+	if localizations == nil {
+		return nil
+	}
+	result := make([]SurveyLocalization, len(localizations))
+	for index, localization := range localizations {
+		result[index] = localization
+		result[index].Questions = make([]SurveyQuestionLocalization, len(localization.Questions))
+		for questionIndex, question := range localization.Questions {
+			result[index].Questions[questionIndex] = question
+			result[index].Questions[questionIndex].Options = append([]SurveyOptionLocalization(nil), question.Options...)
+		}
+		if localization.Questions == nil {
+			result[index].Questions = nil
+		}
+	}
+	return result
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSurveyLocalization() SurveyLocalization {
+	return SurveyLocalization{
+		Language: "en",
+		Title:    "Layout survey",
+		Questions: []SurveyQuestionLocalization{{
+			ID:       "design",
+			Question: "Which layout do you prefer?",
+			Options:  []SurveyOptionLocalization{{ID: "compact", Label: "Compact"}},
+		}},
+	}
+}
+
+func TestSurveyLocalizationsResolvePerLocaleAndCombineResults(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+
+	for name, edit := range map[string]func(record *SurveyRecord){
+		"必须通过 language 指定默认语言": func(record *SurveyRecord) { record.Language = "" },
+		"引用了不存在的题目":            func(record *SurveyRecord) { record.Localizations[0].Questions[0].ID = "missing" },
+		"引用了不存在的选项":            func(record *SurveyRecord) { record.Localizations[0].Questions[0].Options[0].ID = "missing" },
+		"语言 ZH-hans 重复": func(record *SurveyRecord) {
+			record.Localizations = append(record.Localizations, SurveyLocalization{Language: "ZH-hans", Title: "重复"})
+		},
+	} {
+		record := testSurveyRecord()
+		record.Localizations = []SurveyLocalization{testSurveyLocalization()}
+		edit(&record)
+		if _, err := surveys.Create(record); err == nil || !strings.Contains(err.Error(), name) {
+			t.Fatalf("无效的翻译应被拒绝并说明原因 %q，实际错误: %v", name, err)
+		}
+	}
+
+	record := testSurveyRecord()
+	record.Localizations = []SurveyLocalization{testSurveyLocalization()}
+	created, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建多语言意见征集失败: %v", err)
+	}
+
+	// 旧客户端不带 locale：默认文字，language 为空以免被按语言过滤掉。
+	if public := created.Public(); public.Language != "" || public.Title != "界面方案征集" ||
+		!slices.Equal(public.Languages, []string{"zh-Hans", "en"}) {
+		t.Fatalf("不带 locale 时应返回默认文字: %+v", public)
+	}
+	delta, _ := surveys.PublicSyncAt(time.Now(), "en-GB", 0)
+	if len(delta.Records) != 1 {
+		t.Fatalf("公开列表应包含多语言征集: %+v", delta.Records)
+	}
+	english := delta.Records[0]
+	if english.Language != "en" || english.Title != "Layout survey" ||
+		english.Questions[0].Question != "Which layout do you prefer?" ||
+		english.Questions[0].Options[0].Label != "Compact" || english.Questions[0].Options[1].Label != "宽松布局" {
+		t.Fatalf("应按 locale 返回翻译，未翻译的选项回退到默认语言: %+v", english)
+	}
+	if french := created.PublicIn("fr"); french.Language != "zh-Hans" || french.Title != "界面方案征集" {
+		t.Fatalf("没有匹配的翻译时应回退到默认语言: %+v", french)
+	}
+
+	for _, language := range []string{"en-US", "zh-Hans-CN", "fr", ""} {
+		if _, err := surveys.Submit(created.Key, SurveyResponseInput{
+			Language: language,
+			Answers:  []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+		}); err != nil {
+			t.Fatalf("提交答卷失败: %v", err)
+		}
+	}
+
+	// 已有答卷后修改翻译不生成新版本，但不能删除全部翻译。
+	replacement := cloneSurveyRecord(created)
+	replacement.Localizations[0].Title = "Layout poll"
+	updated, err := surveys.Update(created.Key, replacement)
+	if err != nil || updated.Version != 1 {
+		t.Fatalf("修改翻译不应生成新版本: %+v err=%v", updated, err)
+	}
+	replacement.Localizations = nil
+	if _, err := surveys.Update(created.Key, replacement); !errors.Is(err, ErrSurveyHasResponses) ||
+		!strings.Contains(err.Error(), "不能删除全部翻译") {
+		t.Fatalf("已有答卷时删除全部翻译应被拒绝: %v", err)
+	}
+
+	reloaded, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载意见征集存储失败: %v", err)
+	}
+	aggregate, err := reloaded.Aggregate(created.Key, SurveyResultQuery{ByLanguage: true})
+	if err != nil {
+		t.Fatalf("统计答卷失败: %v", err)
+	}
+	if aggregate.ResponseCount != 4 || aggregate.Questions[0].Options[0].Count != 4 {
+		t.Fatalf("各语言的答卷应合并统计: %+v", aggregate)
+	}
+	if len(aggregate.Languages) != 2 ||
+		aggregate.Languages[0].Language != "zh-Hans" || aggregate.Languages[0].ResponseCount != 3 ||
+		aggregate.Languages[1].Language != "en" || aggregate.Languages[1].Questions[0].Answered != 1 {
+		t.Fatalf("按语言拆分的统计不正确: %+v", aggregate.Languages)
+	}
+	if plain, _ := reloaded.Aggregate(created.Key, SurveyResultQuery{}); plain.Languages != nil {
+		t.Fatal("未要求时不应返回按语言拆分的统计")
+	}
+}
+
+func TestSurveyMergeLanguageVariant(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	chinese, err := surveys.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	variant := testSurveyRecord()
+	variant.Language = "en"
+	variant.Title = "Layout survey"
+	variant.Questions[0].Question = "Which layout do you prefer?"
+	variant.Questions[0].Options[0].Label = "Compact"
+	variant.Questions[0].Options[1].Label = "Relaxed"
+	english, err := surveys.Create(variant)
+	if err != nil {
+		t.Fatalf("创建英文版本失败: %v", err)
+	}
+	different := testSurveyRecord()
+	different.Language = "ja"
+	different.Questions[0].Options = different.Questions[0].Options[:1]
+	japanese, err := surveys.Create(different)
+	if err != nil {
+		t.Fatalf("创建日文版本失败: %v", err)
+	}
+	for _, key := range []string{chinese.Key, english.Key} {
+		if _, err := surveys.Submit(key, SurveyResponseInput{
+			Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"relaxed"}}},
+		}); err != nil {
+			t.Fatalf("提交答卷失败: %v", err)
+		}
+	}
+
+	if _, err := surveys.MergeLanguageVariant(chinese.Key, japanese.Key); err == nil || !strings.Contains(err.Error(), "题目结构不同") {
+		t.Fatalf("题目结构不同的版本不能合并: %v", err)
+	}
+	merged, err := surveys.MergeLanguageVariant(chinese.Key, english.Key)
+	if err != nil {
+		t.Fatalf("合并语言版本失败: %v", err)
+	}
+	if len(merged.Localizations) != 1 || merged.Localizations[0].Title != "Layout survey" ||
+		merged.PublicIn("en").Questions[0].Options[1].Label != "Relaxed" {
+		t.Fatalf("被合并的版本应成为翻译: %+v", merged.Localizations)
+	}
+	if _, _, err := surveys.Results(english.Key); !errors.Is(err, ErrSurveyNotFound) {
+		t.Fatalf("被合并的版本应被删除: %v", err)
+	}
+
+	// 日志中被覆盖的旧行仍指向已删除的征集，重新加载时不应报错。
+	reloaded, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载合并后的意见征集失败: %v", err)
+	}
+	aggregate, err := reloaded.Aggregate(chinese.Key, SurveyResultQuery{ByLanguage: true})
+	if err != nil {
+		t.Fatalf("统计合并后的答卷失败: %v", err)
+	}
+	if aggregate.ResponseCount != 2 || aggregate.Questions[0].Options[1].Count != 2 ||
+		len(aggregate.Languages) != 2 || aggregate.Languages[1].Language != "en" || aggregate.Languages[1].ResponseCount != 1 {
+		t.Fatalf("合并后的答卷应合并统计并保留语言: %+v", aggregate)
+	}
+}
+
+func TestSurveyTranslatedQuestionAddedAfterResponsesSurvivesReload(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	record := testSurveyRecord()
+	record.Localizations = []SurveyLocalization{testSurveyLocalization()}
+	created, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建多语言意见征集失败: %v", err)
+	}
+	if _, err := surveys.Submit(created.Key, SurveyResponseInput{
+		Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+	}); err != nil {
+		t.Fatalf("提交答卷失败: %v", err)
+	}
+
+	// 新增的选填题目带有翻译；历史版本中没有这道题，不能因此被判为无效定义。
+	replacement := cloneSurveyRecord(created)
+	replacement.Questions = append(replacement.Questions, SurveyQuestion{ID: "extra", Question: "补充", Type: SurveyTypeText})
+	replacement.Localizations[0].Questions = append(
+		replacement.Localizations[0].Questions,
+		SurveyQuestionLocalization{ID: "extra", Question: "Anything else?"},
+	)
+	updated, err := surveys.Update(created.Key, replacement)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("新增带翻译的选填题目应生成新版本: %+v err=%v", updated, err)
+	}
+
+	reloaded, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("新增带翻译的题目后应能重新加载: %v", err)
+	}
+	if _, _, err := reloaded.Results(created.Key); err != nil {
+		t.Fatalf("读取重新加载后的意见征集失败: %v", err)
+	}
+	if err := validateSurveyVersions(updated); err != nil {
+		t.Fatalf("历史版本不应按当前翻译校验: %v", err)
+	}
+}
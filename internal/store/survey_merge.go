@@ -0,0 +1,144 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MergeLanguageVariant 把旧式的单语言版本 sourceKey 并入同一编号的征集 key：source 的文字成为 key 的一种翻译，
+// 答卷改为属于 key 的当前版本并记录 source 的语言，然后删除 source。source 中有答卷的每个版本都必须与 key 的
+// 当前定义题目结构相同，即只有文字不同。答卷先写入日志再保存定义，保存失败时答卷已经并入 key，可以修正后重试。
+func (s *SurveyStore) MergeLanguageVariant(key, sourceKey string) (SurveyRecord, error) {
+	key, sourceKey = strings.TrimSpace(key), strings.TrimSpace(sourceKey)
+	if sourceKey == "" || sourceKey == key {
+		return SurveyRecord{}, coded(ErrorInvalid, "survey_invalid", "请指定另一份要合并的意见征集")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	target, found := s.findLocked(key)
+	if !found {
+		return SurveyRecord{}, ErrSurveyNotFound
+	}
+	source, found := s.findLocked(sourceKey)
+	if !found {
+		return SurveyRecord{}, ErrSurveyNotFound
+	}
+	if err := mergeableLanguageVariant(target, source); err != nil {
+		return SurveyRecord{}, invalidError("survey_invalid", err)
+	}
+
+	moved := make([]SurveyResponseRecord, 0, s.responseCountLocked(sourceKey))
+	for _, response := range s.responses {
+		if response.SurveyKey != sourceKey {
+			continue
+		}
+		definition, _ := source.definitionAt(response.SurveyVersion)
+		if !sameSurveyStructure(definition.Questions, target.Questions) {
+			return SurveyRecord{}, coded(
+				ErrorConflict,
+				ErrSurveyHasResponses.Code,
+				fmt.Sprintf("第 %d 版的答卷与目标征集的题目结构不同，无法合并", response.SurveyVersion),
+			)
+		}
+		response = cloneSurveyResponse(response)
+		response.SurveyKey = key
+		response.SurveyVersion = target.Version
+		response.SurveyLanguage = source.Language
+		moved = append(moved, response)
+	}
+	if s.responseCountLocked(key)+len(moved) > maxSurveyResponses {
+		return SurveyRecord{}, coded(ErrorConflict, "survey_response_limit_reached", "合并后的答卷数超过上限")
+	}
+
+	replacement := cloneSurveyRecord(target)
+	replacement.Localizations = normalizeSurveyLocalizations(append(replacement.Localizations, source.localization()))
+	replacement.UpdatedAt = time.Now().UTC()
+	if err := validateSurveyRecord(replacement); err != nil {
+		return SurveyRecord{}, invalidError("survey_invalid", err)
+	}
+	if err := s.replaceResponsesLocked(moved...); err != nil {
+		return SurveyRecord{}, err
+	}
+
+	previous := cloneSurveyRecords(s.records)
+	next := make([]SurveyRecord, 0, len(s.records)-1)
+	for _, record := range s.records {
+		switch record.Key {
+		case key:
+			next = append(next, replacement)
+		case sourceKey:
+		default:
+			next = append(next, record)
+		}
+	}
+	s.records = next
+	if err := s.commitDefinitionsLocked(key, sourceKey); err != nil {
+		s.records = previous
+		return SurveyRecord{}, err
+	}
+	return cloneSurveyRecord(replacement), nil
+}
+
+// mergeableLanguageVariant 检查 source 能否作为 target 的一种翻译并入，不检查答卷。
+func mergeableLanguageVariant(target, source SurveyRecord) error {
+	if target.ID != source.ID {
+		return fmt.Errorf("只能合并同一编号的语言版本")
+	}
+	if target.Language == "" || source.Language == "" {
+		return fmt.Errorf("两份征集都必须指定语言")
+	}
+	if len(source.Localizations) > 0 {
+		return fmt.Errorf("要合并的征集不能包含翻译")
+	}
+	if slices.ContainsFunc(target.Languages(), func(language string) bool {
+		return strings.EqualFold(language, source.Language)
+	}) {
+		return fmt.Errorf("目标征集已包含语言 %s", source.Language)
+	}
+	if !sameSurveyStructure(source.Questions, target.Questions) {
+		return fmt.Errorf("两份征集的题目结构不同，只有题目与选项文字可以不同")
+	}
+	return nil
+}
+
+// localization 把征集当前的文字转换为翻译。
+func (record SurveyRecord) localization() SurveyLocalization {
+	localization := SurveyLocalization{
+		Language:    record.Language,
+		Title:       record.Title,
+		Description: record.Description,
+	}
+	for _, question := range record.Questions {
+		translated := SurveyQuestionLocalization{ID: question.ID, Question: question.Question}
+		for _, option := range question.Options {
+			translated.Options = append(translated.Options, SurveyOptionLocalization{
+				ID:          option.ID,
+				Label:       option.Label,
+				Description: option.Description,
+			})
+		}
+		localization.Questions = append(localization.Questions, translated)
+	}
+	return localization
+}
+
+// sameSurveyStructure 判断两组题目是否只有题目与选项文字不同。
+func sameSurveyStructure(left, right []SurveyQuestion) bool {
+	return reflect.DeepEqual(surveyStructure(left), surveyStructure(right))
+}
+
+func surveyStructure(questions []SurveyQuestion) []SurveyQuestion {
+	structure := cloneSurveyQuestions(questions)
+	for questionIndex := range structure {
+		structure[questionIndex].Question = ""
+		for optionIndex := range structure[questionIndex].Options {
+			structure[questionIndex].Options[optionIndex].Label = ""
+			structure[questionIndex].Options[optionIndex].Description = ""
+		}
+	}
+	return structure
+}
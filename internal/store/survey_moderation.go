@@ -0,0 +1,38 @@
+package store
+
+// SurveyModeration 是一份答卷中文字回答的审核结果。被标记的答卷照常保存并参与统计，
+// 只在管理后台标出，由管理员决定如何处理；审核服务异常时按保护策略标记。
+type SurveyModeration struct {
+	Flagged    bool     `json:"flagged"`
+	Reasons    []string `json:"reasons,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// SurveyResponseTexts 按作答顺序返回答卷中的文字回答，包括 text 题的回答和选择题的自定义回答。
+func SurveyResponseTexts(answers []SurveyAnswer) []string {
+	texts := make([]string, 0)
+	for _, answer := range answers {
+		if answer.Text != "" {
+			texts = append(texts, answer.Text)
+		}
+		if answer.OtherText != "" {
+			texts = append(texts, answer.OtherText)
+		}
+	}
+	return texts
+}
+
+// flagged 判断答卷的文字回答是否被审核标记。
+func (response SurveyResponseRecord) flagged() bool {
+	return response.Moderation != nil && response.Moderation.Flagged
+}
+
+func cloneSurveyModeration(moderation *SurveyModeration) *SurveyModeration {
+	if moderation == nil {
+		return nil
+	}
+	copied := *moderation
+	copied.Reasons = append([]string(nil), moderation.Reasons...)
+	copied.Categories = append([]string(nil), moderation.Categories...)
+	return &copied
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// respondentTokenLength 是 respondent_token 的长度，即 SHA-256 的十六进制表示。
+const respondentTokenLength = 64
+
+// RespondentToken 返回客户端提交答卷时携带的 respondent_token：
+// SHA256(respondent_salt + ":" + 匿名安装 ID) 的小写十六进制。盐值每份征集不同，
+// 服务端只能判断同一设备是否重复提交同一份征集，无法还原安装 ID，也无法关联同一设备在不同征集中的答卷。
+func RespondentToken(salt, installationID string) string {
+	digest := sha256.Sum256([]byte(salt + ":" + installationID))
+	return hex.EncodeToString(digest[:])
+}
+
+// respondentSalt 由征集 key 派生盐值。key 随机生成且每份征集（包括同一编号的各语言版本）各不相同。
+func respondentSalt(key string) string {
+	digest := sha256.Sum256([]byte("survey-respondent:" + key))
+	return hex.EncodeToString(digest[:8])
+}
+
+// respondentHash 是服务端保存的令牌摘要，不直接保存客户端提交的令牌。
+func respondentHash(surveyKey, token string) string {
+	digest := sha256.Sum256([]byte(surveyKey + ":" + token))
+	return hex.EncodeToString(digest[:])
+}
+
+func validRespondentToken(token string) bool {
+	if len(token) != respondentTokenLength {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+// indexRespondentLocked 记录答卷的令牌摘要，用于拒绝同一设备的重复提交。
+func (s *SurveyStore) indexRespondentLocked(response SurveyResponseRecord) {
+	if response.RespondentHash == "" {
+		return
+	}
+	if s.respondents[response.SurveyKey] == nil {
+		s.respondents[response.SurveyKey] = make(map[string]struct{})
+	}
+	s.respondents[response.SurveyKey][response.RespondentHash] = struct{}{}
+}
+
+func (s *SurveyStore) answeredLocked(surveyKey, hash string) bool {
+	_, exists := s.respondents[surveyKey][hash]
+	return exists
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSurveyRespondentTokenRejectsRepeatedSubmissions(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	record := testSurveyRecord()
+	record.OneResponsePerDevice = true
+	first, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	second, err := surveys.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	if first.Public().RespondentSalt == second.Public().RespondentSalt {
+		t.Fatal("不同征集的 respondent_salt 不应相同")
+	}
+
+	const installationID = "6F1C0E5A-2B7D-4C1E-9A35-0D8B7E6F4A21"
+	answers := []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}}
+	if _, err := surveys.Submit(first.Key, SurveyResponseInput{Answers: answers}); err == nil ||
+		!strings.Contains(err.Error(), "respondent_token") {
+		t.Fatalf("要求令牌的征集应拒绝缺少令牌的答卷: %v", err)
+	}
+	if _, err := surveys.Submit(first.Key, SurveyResponseInput{Answers: answers, RespondentToken: "not-a-token"}); err == nil {
+		t.Fatal("格式无效的令牌应被拒绝")
+	}
+
+	token := RespondentToken(first.Public().RespondentSalt, installationID)
+	if _, err := surveys.Submit(first.Key, SurveyResponseInput{Answers: answers, RespondentToken: token}); err != nil {
+		t.Fatalf("首次提交失败: %v", err)
+	}
+	changed := []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"relaxed"}}}
+	if _, err := surveys.Submit(first.Key, SurveyResponseInput{Answers: changed, RespondentToken: strings.ToUpper(token)}); !errors.Is(err, ErrSurveyAlreadyAnswered) {
+		t.Fatalf("同一设备再次提交应返回 ErrSurveyAlreadyAnswered，实际错误: %v", err)
+	}
+	// 同一安装 ID 在另一份征集中的令牌不同，不受影响；未要求令牌的征集也会拒绝重复令牌。
+	otherToken := RespondentToken(second.Public().RespondentSalt, installationID)
+	if otherToken == token {
+		t.Fatal("同一安装 ID 在不同征集中的令牌不应相同")
+	}
+	if _, err := surveys.Submit(second.Key, SurveyResponseInput{Answers: answers, RespondentToken: otherToken}); err != nil {
+		t.Fatalf("另一份征集的首次提交失败: %v", err)
+	}
+	if _, err := surveys.Submit(second.Key, SurveyResponseInput{Answers: answers, RespondentToken: otherToken}); !errors.Is(err, ErrSurveyAlreadyAnswered) {
+		t.Fatalf("携带令牌的重复提交应被拒绝: %v", err)
+	}
+
+	// 日志只保存令牌摘要，重新加载后仍能识别重复提交。
+	if data := mustReadFile(t, filepath.Join(dataDir, surveyResponseLogName)); strings.Contains(string(data), token) ||
+		strings.Contains(string(data), installationID) {
+		t.Fatalf("答卷日志不应包含令牌或安装 ID: %s", data)
+	}
+	reloaded, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载意见征集存储失败: %v", err)
+	}
+	if _, err := reloaded.Submit(first.Key, SurveyResponseInput{Answers: changed, RespondentToken: token}); !errors.Is(err, ErrSurveyAlreadyAnswered) {
+		t.Fatalf("重新加载后仍应拒绝重复提交: %v", err)
+	}
+	if _, results, _ := reloaded.Results(first.Key); len(results) != 1 || results[0].RespondentHash != "" {
+		t.Fatalf("答卷结果不应返回令牌摘要: %+v", results)
+	}
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// surveyResponseLogName 是匿名答卷的追加日志，每行一份答卷。
+	surveyResponseLogName = "survey-responses.jsonl"
+	// legacySurveyResponseFileName 是旧版整体重写的答卷文件，启动时会迁移为追加日志。
+	legacySurveyResponseFileName = "survey-responses.json"
+	// 日志中被后续同 key 行覆盖的旧行不少于该数量、且不少于有效答卷数时压缩日志，启动与写入后都会检查。
+	minSurveyResponseCompactionLines = 1000
+)
+
+// legacySurveyResponseFile 是旧版答卷文件的格式。
+type legacySurveyResponseFile struct {
+	Version int                    `json:"version"`
+	Records []SurveyResponseRecord `json:"records"`
+}
+
+// loadResponses 逐行读取答卷日志，不会一次性把整个文件读入内存。后出现的行覆盖 key 相同的旧行，
+// 被覆盖的行可能属于已合并删除的征集，因此读完后只校验最终保留的答卷；
+// 最后一行缺少换行符说明上次写入被中断，这一行会被丢弃并压缩日志，其余无法解析的行视为文件损坏。
+func (s *SurveyStore) loadResponses() error {
+	s.responses = []SurveyResponseRecord{}
+	s.responseCounts = make(map[string]int)
+	s.respondents = make(map[string]map[string]struct{})
+
+	file, err := os.Open(s.responseLog)
+	if os.IsNotExist(err) {
+		return s.migrateLegacyResponses()
+	}
+	if err != nil {
+		return fmt.Errorf("读取匿名答卷日志失败: %w", err)
+	}
+	defer file.Close()
+
+	positions := make(map[string]int)
+	// lines 是每份答卷最后一次出现的行号，用于在校验失败时指出位置。
+	lines := make([]int, 0)
+	stale, needsCompaction := 0, false
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("读取匿名答卷日志失败: %w", err)
+		}
+		complete := err == nil
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record SurveyResponseRecord
+			if decodeErr := json.Unmarshal(line, &record); decodeErr != nil {
+				if complete {
+					return fmt.Errorf("解析匿名答卷日志第 %d 行失败: %w", lineNumber, decodeErr)
+				}
+				needsCompaction = true
+				break
+			}
+			if position, exists := positions[record.Key]; exists {
+				s.responses[position] = record
+				lines[position] = lineNumber
+				stale++
+			} else {
+				positions[record.Key] = len(s.responses)
+				s.responses = append(s.responses, record)
+				lines = append(lines, lineNumber)
+			}
+			needsCompaction = needsCompaction || !complete
+		}
+		if !complete {
+			break
+		}
+	}
+	for index := range s.responses {
+		if err := s.validateStoredResponse(&s.responses[index]); err != nil {
+			return fmt.Errorf("匿名答卷日志第 %d 行%v", lines[index], err)
+		}
+		s.responseCounts[s.responses[index].SurveyKey]++
+		s.indexRespondentLocked(s.responses[index])
+	}
+
+	s.staleResponseLines = stale
+	if needsCompaction || s.responseLogNeedsCompactionLocked() {
+		return s.compactResponsesLocked()
+	}
+	return nil
+}
+
+// responseLogNeedsCompactionLocked 判断被覆盖的旧行是否多到值得重写日志。
+func (s *SurveyStore) responseLogNeedsCompactionLocked() bool {
+	return s.staleResponseLines >= max(minSurveyResponseCompactionLines, len(s.responses))
+}
+
+// compactResponsesIfNeededLocked 在写入后按需压缩日志。答卷已经同步到磁盘，压缩失败不影响本次写入，
+// 旧行保留在日志中，下次写入或启动时再次尝试。
+func (s *SurveyStore) compactResponsesIfNeededLocked() {
+	if s.responseLogNeedsCompactionLocked() {
+		_ = s.compactResponsesLocked()
+	}
+}
+
+// migrateLegacyResponses 把旧版 survey-responses.json 转写为追加日志，写入成功后删除旧文件。
+func (s *SurveyStore) migrateLegacyResponses() error {
+	data, err := os.ReadFile(s.legacyResponseFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取旧版匿名答卷文件失败: %w", err)
+	}
+	if strings.TrimSpace(string(data)) != "" {
+		var payload legacySurveyResponseFile
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return fmt.Errorf("解析旧版匿名答卷文件失败: %w", err)
+		}
+		if payload.Version != surveyFileVersion {
+			return fmt.Errorf("不支持的匿名答卷文件版本: %d", payload.Version)
+		}
+		for index := range payload.Records {
+			if err := s.validateStoredResponse(&payload.Records[index]); err != nil {
+				return fmt.Errorf("第 %d 份匿名答卷%v", index+1, err)
+			}
+			s.responseCounts[payload.Records[index].SurveyKey]++
+		}
+		s.responses = payload.Records
+	}
+	if err := s.compactResponsesLocked(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.legacyResponseFile); err != nil {
+		return fmt.Errorf("删除旧版匿名答卷文件失败: %w", err)
+	}
+	return nil
+}
+
+// validateStoredResponse 规范化并校验已保存的答卷，返回的错误不含位置，由调用方拼接。
+func (s *SurveyStore) validateStoredResponse(record *SurveyResponseRecord) error {
+	normalizeSurveyResponseRecord(record)
+	survey, ok := s.findLocked(record.SurveyKey)
+	if !ok {
+		return errors.New("关联的意见征集不存在")
+	}
+	if record.Key == "" || record.SubmittedAt.IsZero() {
+		return errors.New("元数据无效")
+	}
+	definition, found := survey.definitionAt(record.SurveyVersion)
+	if !found {
+		return fmt.Errorf("关联的意见征集版本 %d 不存在", record.SurveyVersion)
+	}
+	if err := validateSurveyResponse(definition, SurveyResponseInput{
+		Answers:  record.Answers,
+		Platform: record.Platform,
+		AppBuild: record.AppBuild,
+		Language: record.Language,
+	}); err != nil {
+		return fmt.Errorf("无效: %w", err)
+	}
+	return nil
+}
+
+// appendResponsesLocked 把新答卷追加到日志并同步到磁盘，成功后才加入内存。
+// 提交耗时只与本次写入的答卷有关，不随已有答卷数量增长。
+func (s *SurveyStore) appendResponsesLocked(responses ...SurveyResponseRecord) error {
+	if len(responses) == 0 {
+		return nil
+	}
+	if err := s.writeResponseLinesLocked(responses); err != nil {
+		return err
+	}
+	for _, response := range responses {
+		s.responses = append(s.responses, response)
+		s.responseCounts[response.SurveyKey]++
+		s.indexRespondentLocked(response)
+	}
+	s.compactResponsesIfNeededLocked()
+	return nil
+}
+
+// replaceResponsesLocked 把修改后的已有答卷按 key 追加到日志，覆盖之前的行，成功后才替换内存中的答卷。
+func (s *SurveyStore) replaceResponsesLocked(responses ...SurveyResponseRecord) error {
+	if len(responses) == 0 {
+		return nil
+	}
+	if err := s.writeResponseLinesLocked(responses); err != nil {
+		return err
+	}
+	replacements := make(map[string]SurveyResponseRecord, len(responses))
+	for _, response := range responses {
+		replacements[response.Key] = response
+	}
+	for index, previous := range s.responses {
+		response, replaced := replacements[previous.Key]
+		if !replaced {
+			continue
+		}
+		s.responseCounts[previous.SurveyKey]--
+		delete(s.respondents[previous.SurveyKey], previous.RespondentHash)
+		s.responses[index] = response
+		s.responseCounts[response.SurveyKey]++
+		s.indexRespondentLocked(response)
+	}
+	s.staleResponseLines += len(responses)
+	s.compactResponsesIfNeededLocked()
+	return nil
+}
+
+// writeResponseLinesLocked 把答卷逐行追加到日志并同步到磁盘，写入失败时截掉可能写了一半的内容。
+func (s *SurveyStore) writeResponseLinesLocked(responses []SurveyResponseRecord) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for _, response := range responses {
+		if err := encoder.Encode(response); err != nil {
+			return fmt.Errorf("编码匿名答卷失败: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(s.responseLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("打开匿名答卷日志失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取匿名答卷日志失败: %w", err)
+	}
+	if _, err := file.Write(data.Bytes()); err != nil {
+		_ = file.Truncate(info.Size())
+		file.Close()
+		return fmt.Errorf("写入匿名答卷日志失败: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Truncate(info.Size())
+		file.Close()
+		return fmt.Errorf("同步匿名答卷日志失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("关闭匿名答卷日志失败: %w", err)
+	}
+	return nil
+}
+
+// compactResponsesLocked 只保留当前有效的答卷，先写入临时文件再原子替换日志。
+func (s *SurveyStore) compactResponsesLocked() error {
+	temp, err := os.CreateTemp(filepath.Dir(s.responseLog), ".survey-responses-*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建匿名答卷临时文件失败: %w", err)
+	}
+	tempPath := temp.Name()
+	defer os.Remove(tempPath)
+
+	if err := temp.Chmod(0o600); err != nil {
+		temp.Close()
+		return fmt.Errorf("设置匿名答卷文件权限失败: %w", err)
+	}
+	buffered := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(buffered)
+	for _, response := range s.responses {
+		if err := encoder.Encode(response); err != nil {
+			temp.Close()
+			return fmt.Errorf("编码匿名答卷失败: %w", err)
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		temp.Close()
+		return fmt.Errorf("写入匿名答卷临时文件失败: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("同步匿名答卷临时文件失败: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("关闭匿名答卷临时文件失败: %w", err)
+	}
+	if err := os.Rename(tempPath, s.responseLog); err != nil {
+		return fmt.Errorf("替换匿名答卷日志失败: %w", err)
+	}
+	s.staleResponseLines = 0
+	return nil
+}
@@ -0,0 +1,189 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSurveyResponseLogRecoversFromInterruptedAppend(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	created, err := surveys.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	input := SurveyResponseInput{Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}}}
+	for range 2 {
+		if _, err := surveys.Submit(created.Key, input); err != nil {
+			t.Fatalf("保存匿名答卷失败: %v", err)
+		}
+	}
+
+	logPath := filepath.Join(dataDir, surveyResponseLogName)
+	data, err := os.ReadFile(logPath)
+	if err != nil || strings.Count(string(data), "\n") != 2 {
+		t.Fatalf("每份答卷应追加为一行: %q err=%v", data, err)
+	}
+	// 模拟写入到一半时进程退出。
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("打开答卷日志失败: %v", err)
+	}
+	_, _ = file.WriteString(`{"key":"torn","survey_key":"` + created.Key)
+	file.Close()
+
+	reloaded, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("末尾不完整时应能恢复: %v", err)
+	}
+	if count := reloaded.responseCountLocked(created.Key); count != 2 {
+		t.Fatalf("恢复后答卷数量不正确: %d", count)
+	}
+	if data, _ := os.ReadFile(logPath); strings.Contains(string(data), "torn") {
+		t.Fatalf("不完整的行应在压缩时移除: %q", data)
+	}
+	if _, err := reloaded.Submit(created.Key, input); err != nil {
+		t.Fatalf("恢复后继续保存答卷失败: %v", err)
+	}
+	again, err := NewSurveyStore(dataDir)
+	if err != nil || again.responseCountLocked(created.Key) != 3 {
+		t.Fatalf("重新加载后答卷数量不正确: err=%v", err)
+	}
+
+	corrupted := t.TempDir()
+	if err := os.WriteFile(filepath.Join(corrupted, "surveys.json"), mustReadFile(t, filepath.Join(dataDir, "surveys.json")), 0o600); err != nil {
+		t.Fatalf("复制意见征集定义失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(corrupted, surveyResponseLogName), []byte("{broken\n"+string(mustReadFile(t, logPath))), 0o600); err != nil {
+		t.Fatalf("写入损坏的答卷日志失败: %v", err)
+	}
+	if _, err := NewSurveyStore(corrupted); err == nil || !strings.Contains(err.Error(), "第 1 行") {
+		t.Fatalf("中间行损坏时应拒绝启动: %v", err)
+	}
+}
+
+func TestSurveyResponseLogMigratesLegacyFileAndCompactsSupersededLines(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	created, err := surveys.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+
+	response := SurveyResponseRecord{
+		Key:         "legacy-response",
+		SurveyKey:   created.Key,
+		Answers:     []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"relaxed"}}},
+		Platform:    "iOS",
+		SubmittedAt: time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC),
+	}
+	legacy, _ := json.Marshal(legacySurveyResponseFile{Version: surveyFileVersion, Records: []SurveyResponseRecord{response}})
+	legacyPath := filepath.Join(dataDir, legacySurveyResponseFileName)
+	if err := os.WriteFile(legacyPath, legacy, 0o600); err != nil {
+		t.Fatalf("写入旧版答卷文件失败: %v", err)
+	}
+
+	migrated, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("迁移旧版答卷失败: %v", err)
+	}
+	if _, results, _ := migrated.Results(created.Key); len(results) != 1 || results[0].Key != response.Key {
+		t.Fatalf("迁移后的答卷不正确: %+v", results)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Fatalf("迁移后应删除旧版答卷文件: %v", err)
+	}
+
+	// 同一 key 的多行以最后一行为准，覆盖行足够多时启动会压缩日志。
+	var lines strings.Builder
+	for index := range minSurveyResponseCompactionLines + 1 {
+		response.Platform = []string{"iOS", "watchOS"}[index%2]
+		line, _ := json.Marshal(response)
+		lines.Write(append(line, '\n'))
+	}
+	logPath := filepath.Join(dataDir, surveyResponseLogName)
+	if err := os.WriteFile(logPath, []byte(lines.String()), 0o600); err != nil {
+		t.Fatalf("写入答卷日志失败: %v", err)
+	}
+	compacted, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("加载答卷日志失败: %v", err)
+	}
+	if _, results, _ := compacted.Results(created.Key); len(results) != 1 || results[0].Platform != "iOS" {
+		t.Fatalf("同 key 答卷应以最后一行为准: %+v", results)
+	}
+	if data := mustReadFile(t, logPath); strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("压缩后日志应只剩一行: %d", strings.Count(string(data), "\n"))
+	}
+}
+
+func TestSurveyResponseLogCompactsWhileRunning(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	created, err := surveys.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	response, err := surveys.Submit(created.Key, SurveyResponseInput{
+		Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+	})
+	if err != nil {
+		t.Fatalf("保存匿名答卷失败: %v", err)
+	}
+
+	// 反复覆盖同一份答卷，覆盖行累积到阈值后无需重启就会压缩日志。
+	logPath := filepath.Join(dataDir, surveyResponseLogName)
+	batch := make([]SurveyResponseRecord, 100)
+	for round := 1; round*len(batch) <= minSurveyResponseCompactionLines; round++ {
+		for index := range batch {
+			batch[index] = cloneSurveyResponse(response)
+			batch[index].Platform = []string{"iOS", "watchOS"}[index%2]
+		}
+		surveys.mu.Lock()
+		err := surveys.replaceResponsesLocked(batch...)
+		surveys.mu.Unlock()
+		if err != nil {
+			t.Fatalf("覆盖答卷失败: %v", err)
+		}
+		lines := strings.Count(string(mustReadFile(t, logPath)), "\n")
+		if round*len(batch) < minSurveyResponseCompactionLines && lines != round*len(batch)+1 {
+			t.Fatalf("未达到阈值前只应追加: 第 %d 轮共 %d 行", round, lines)
+		}
+	}
+	if lines := strings.Count(string(mustReadFile(t, logPath)), "\n"); lines != 1 {
+		t.Fatalf("达到阈值后应在运行中压缩日志，实际 %d 行", lines)
+	}
+	if _, results, _ := surveys.Results(created.Key); len(results) != 1 || results[0].Platform != "watchOS" {
+		t.Fatalf("压缩后应保留最后一次写入的答卷: %+v", results)
+	}
+	if _, err := surveys.Submit(created.Key, SurveyResponseInput{
+		Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"relaxed"}}},
+	}); err != nil {
+		t.Fatalf("压缩后继续提交失败: %v", err)
+	}
+	if lines := strings.Count(string(mustReadFile(t, logPath)), "\n"); lines != 2 {
+		t.Fatalf("压缩后应继续追加到新日志，实际 %d 行", lines)
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", path, err)
+	}
+	return data
+}
@@ -0,0 +1,74 @@
+package store
+
+import "time"
+
+// 意见征集停止接受答卷的原因，随 survey_closed 错误返回给客户端。
+const (
+	SurveyClosedDisabled     = "disabled"
+	SurveyClosedNotOpen      = "not_open"
+	SurveyClosedEnded        = "ended"
+	SurveyClosedQuotaReached = "quota_reached"
+)
+
+var surveyClosedMessages = map[string]string{
+	SurveyClosedDisabled:     "意见征集已停止",
+	SurveyClosedNotOpen:      "意见征集尚未开始",
+	SurveyClosedEnded:        "意见征集已截止",
+	SurveyClosedQuotaReached: "意见征集已收满答卷",
+}
+
+// SurveyState 是管理端查看的征集状态。ClosedReason 为空表示正在接受答卷。
+type SurveyState struct {
+	ResponseCount int    `json:"response_count"`
+	ClosedReason  string `json:"closed_reason,omitempty"`
+}
+
+// StateAt 返回 record 在 now 时刻的答卷数量与停止原因。
+func (s *SurveyStore) StateAt(record SurveyRecord, now time.Time) SurveyState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return SurveyState{
+		ResponseCount: s.responseCounts[record.Key],
+		ClosedReason:  s.closedReasonLocked(record, now),
+	}
+}
+
+// closedReasonLocked 判断征集在 now 时刻是否停止接受答卷，返回停止原因，正在征集时返回空字符串。
+func (s *SurveyStore) closedReasonLocked(record SurveyRecord, now time.Time) string {
+	switch {
+	case !record.Enabled:
+		return SurveyClosedDisabled
+	case record.OpensAt != nil && now.Before(*record.OpensAt):
+		return SurveyClosedNotOpen
+	case record.ClosesAt != nil && !now.Before(*record.ClosesAt):
+		return SurveyClosedEnded
+	case record.MaxResponses > 0 && s.responseCounts[record.Key] >= record.MaxResponses:
+		return SurveyClosedQuotaReached
+	}
+	return ""
+}
+
+// surveyClosedError 返回带停止原因的 survey_closed 错误，仍可用 errors.Is 与 ErrSurveyClosed 匹配。
+func surveyClosedError(reason string) error {
+	return &Error{Kind: ErrorGone, Code: ErrSurveyClosed.Code, Message: surveyClosedMessages[reason], Reason: reason}
+}
+
+// earliestTransition 返回 next 与 transitions 中晚于 now 的最早时间，next 为零值表示尚无候选。
+func earliestTransition(next, now time.Time, transitions ...*time.Time) time.Time {
+	for _, transition := range transitions {
+		if transition != nil && transition.After(now) && (next.IsZero() || transition.Before(next)) {
+			next = *transition
+		}
+	}
+	return next
+}
+
+// transitionedBetween 判断 transitions 中是否有时间落在 (from, now] 内。
+func transitionedBetween(from, now time.Time, transitions ...*time.Time) bool {
+	for _, transition := range transitions {
+		if transition != nil && transition.After(from) && !transition.After(now) {
+			return true
+		}
+	}
+	return false
+}
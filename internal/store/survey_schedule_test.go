@@ -0,0 +1,94 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSurveyScheduleControlsSubmissionAndSync(t *testing.T) {
+	surveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+
+	invalid := testSurveyRecord()
+	opensAt := time.Now().Add(time.Hour)
+	closesAt := opensAt.Add(-time.Minute)
+	invalid.OpensAt, invalid.ClosesAt = &opensAt, &closesAt
+	if _, err := surveys.Create(invalid); err == nil {
+		t.Fatal("截止时间早于开始时间时应拒绝保存")
+	}
+
+	scheduled := testSurveyRecord()
+	closesAt = opensAt.Add(time.Hour)
+	scheduled.OpensAt, scheduled.ClosesAt = &opensAt, &closesAt
+	created, err := surveys.Create(scheduled)
+	if err != nil {
+		t.Fatalf("创建定时意见征集失败: %v", err)
+	}
+	_, err = surveys.Submit(created.Key, SurveyResponseInput{})
+	var typed *Error
+	if !errors.Is(err, ErrSurveyClosed) || !errors.As(err, &typed) || typed.Reason != SurveyClosedNotOpen {
+		t.Fatalf("开始前提交应返回 not_open: %v", err)
+	}
+
+	now := time.Now()
+	delta, next := surveys.PublicSyncAt(now, "", 0)
+	if len(delta.Records) != 0 || !next.Equal(*created.OpensAt) {
+		t.Fatalf("开始前不应公开，且下一次变化为开始时间: %+v next=%s", delta.Records, next)
+	}
+	opened, next := surveys.PublicSyncAt(opensAt.Add(time.Minute), "", delta.Revision)
+	if opened.Full || len(opened.Records) != 1 || opened.Records[0].Key != created.Key || !next.Equal(*created.ClosesAt) {
+		t.Fatalf("到达开始时间后增量同步应包含该征集: %+v next=%s", opened, next)
+	}
+	ended, next := surveys.PublicSyncAt(closesAt, "", delta.Revision)
+	if len(ended.Records) != 0 || len(ended.Removed) != 1 || !next.IsZero() {
+		t.Fatalf("截止后增量同步应移除该征集: %+v next=%s", ended, next)
+	}
+	if state := surveys.StateAt(created, closesAt); state.ClosedReason != SurveyClosedEnded {
+		t.Fatalf("截止后的状态不正确: %+v", state)
+	}
+}
+
+func TestSurveyQuotaClosesSurvey(t *testing.T) {
+	surveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	record := testSurveyRecord()
+	record.MaxResponses = 2
+	created, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	revision := surveys.PublicSync(0).Revision
+
+	input := SurveyResponseInput{Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}}}
+	for range 2 {
+		if _, err := surveys.Submit(created.Key, input); err != nil {
+			t.Fatalf("配额内提交失败: %v", err)
+		}
+	}
+	_, err = surveys.Submit(created.Key, input)
+	var typed *Error
+	if !errors.As(err, &typed) || typed.Code != "survey_closed" || typed.Reason != SurveyClosedQuotaReached {
+		t.Fatalf("收满答卷后应返回 quota_reached: %v", err)
+	}
+	delta := surveys.PublicSync(revision)
+	if delta.Full || len(delta.Records) != 0 || len(delta.Removed) != 1 || delta.Removed[0] != created.Key {
+		t.Fatalf("收满答卷后增量同步应移除该征集: %+v", delta)
+	}
+	if state := surveys.StateAt(created, time.Now()); state.ResponseCount != 2 || state.ClosedReason != SurveyClosedQuotaReached {
+		t.Fatalf("配额状态不正确: %+v", state)
+	}
+
+	// 提高上限后重新开放。
+	created.MaxResponses = 3
+	if _, err := surveys.Update(created.Key, created); err != nil {
+		t.Fatalf("提高答卷上限失败: %v", err)
+	}
+	if _, err := surveys.Submit(created.Key, input); err != nil {
+		t.Fatalf("提高上限后提交失败: %v", err)
+	}
+}
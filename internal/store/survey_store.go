@@ -5,9 +5,11 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -18,10 +20,24 @@ import (
 const (
 	surveyFileVersion        = 1
 	maxSurveyRecords         = 100
-	maxSurveyResponses       = 50000
+	maxSurveyResponses       = 50000 // 单个意见征集的答卷上限
 	maxSurveyQuestions       = 10
 	maxSurveyOptions         = 20
 	maxSurveyCustomTextRunes = 1000
+	minSurveyRatingScale     = 2
+	maxSurveyRatingScale     = 10
+	maxSurveyConditions      = 5
+)
+
+// 意见征集支持的题型。选择题与排序题依赖选项，其余题型不设选项。
+const (
+	SurveyTypeSingleSelect = "single_select"
+	SurveyTypeMultiSelect  = "multi_select"
+	SurveyTypeText         = "text"
+	SurveyTypeRating       = "rating"
+	SurveyTypeNPS          = "nps"
+	SurveyTypeRanking      = "ranking"
+	SurveyTypeNumber       = "number"
 )
 
 // SurveyOption 是意见征集问题中的可选项。
@@ -31,14 +47,33 @@ type SurveyOption struct {
 	Description string `json:"description,omitempty"`
 }
 
-// SurveyQuestion 定义一道单选或多选题。
+// SurveyQuestion 定义一道题。Options 仅用于选择题和排序题；
+// MinLength/MaxLength 限制 text 题的字符数，Scale 是 rating 题的星级上限，
+// Min/Max 是 number 题的取值范围，未设置时不限。
+// ShowIf 非空时只有全部条件成立才显示该题，未显示的题目不计必填，也不能提交回答。
 type SurveyQuestion struct {
-	ID         string         `json:"id"`
-	Question   string         `json:"question"`
-	Type       string         `json:"type"`
-	Options    []SurveyOption `json:"options"`
-	AllowOther bool           `json:"allow_other,omitempty"`
-	Required   bool           `json:"required,omitempty"`
+	ID         string            `json:"id"`
+	Question   string            `json:"question"`
+	Type       string            `json:"type"`
+	Options    []SurveyOption    `json:"options"`
+	AllowOther bool              `json:"allow_other,omitempty"`
+	Required   bool              `json:"required,omitempty"`
+	MinLength  int               `json:"min_length,omitempty"`
+	MaxLength  int               `json:"max_length,omitempty"`
+	Scale      int               `json:"scale,omitempty"`
+	Min        *float64          `json:"min,omitempty"`
+	Max        *float64          `json:"max,omitempty"`
+	ShowIf     []SurveyCondition `json:"show_if,omitempty"`
+}
+
+// SurveyCondition 是题目的显示条件，只能引用前面的题目。
+// 选择题用 OptionIDs 表示选中其中任意一项；rating、nps 与 number 题用 Min/Max 表示回答落在范围内；
+// 两者都未设置时表示该题已作答。被引用的题目本身未显示时条件不成立。
+type SurveyCondition struct {
+	QuestionID string   `json:"question_id"`
+	OptionIDs  []string `json:"option_ids,omitempty"`
+	Min        *float64 `json:"min,omitempty"`
+	Max        *float64 `json:"max,omitempty"`
 }
 
 // PublicSurvey 是客户端可读取的意见征集定义。
@@ -52,6 +87,17 @@ type PublicSurvey struct {
 	Language    string           `json:"language,omitempty"`
 	Platform    string           `json:"platform,omitempty"`
 	Questions   []SurveyQuestion `json:"questions"`
+	// RolloutPercent 非零时只有桶号小于该值的客户端显示征集，桶号算法见 RolloutBucket。
+	RolloutPercent int    `json:"rollout_percent,omitempty"`
+	RolloutSalt    string `json:"rollout_salt,omitempty"`
+	// ClosesAt 是停止接受答卷的时间，客户端可据此提示截止日期。
+	ClosesAt *time.Time `json:"closes_at,omitempty"`
+	// Version 是定义的版本号，客户端提交答卷时原样带回 survey_version。
+	Version int `json:"version"`
+	// RespondentSalt 用于计算 respondent_token，算法见 RespondentToken。
+	RespondentSalt string `json:"respondent_salt"`
+	// Languages 是包含翻译的征集提供的全部语言，默认语言在前；客户端可带 locale 请求对应语言的文字。
+	Languages []string `json:"languages,omitempty"`
 }
 
 // SurveyRecord 在公开定义之外保存发布状态和管理元数据。
@@ -65,16 +111,36 @@ type SurveyRecord struct {
 	Language    string           `json:"language,omitempty"`
 	Platform    string           `json:"platform,omitempty"`
 	Questions   []SurveyQuestion `json:"questions"`
-	Enabled     bool             `json:"enabled"`
-	CreatedAt   time.Time        `json:"created_at"`
-	UpdatedAt   time.Time        `json:"updated_at"`
+	// Localizations 是其他语言的标题、说明与题目文字，Language 为默认语言。
+	// 包含翻译的征集面向所有语言投放，答卷不分语言合并统计。
+	Localizations []SurveyLocalization `json:"localizations,omitempty"`
+	// RolloutPercent 为 1~99 时按匿名安装 ID 分阶段放量，0 或 100 表示全量；
+	// 它不属于征集定义，已有答卷时仍可调整。
+	RolloutPercent int `json:"rollout_percent,omitempty"`
+	// OpensAt 与 ClosesAt 限定已启用征集接受答卷的时间窗，MaxResponses 为答卷数量上限，0 表示不限。
+	// 到达截止时间或上限后征集自动停止，它们同样不属于征集定义。
+	OpensAt      *time.Time `json:"opens_at,omitempty"`
+	ClosesAt     *time.Time `json:"closes_at,omitempty"`
+	MaxResponses int        `json:"max_responses,omitempty"`
+	// OneResponsePerDevice 要求答卷携带 respondent_token。无论是否开启，携带令牌的重复提交都会被拒绝。
+	OneResponsePerDevice bool `json:"one_response_per_device,omitempty"`
+	// Version 是当前定义的版本号，从 1 开始。已有答卷时的兼容修改会把旧定义存入 PreviousVersions
+	// 并递增版本号；没有答卷时直接修改当前版本。两者由服务端维护，写入时忽略请求中的值。
+	Version          int             `json:"version"`
+	PreviousVersions []SurveyVersion `json:"previous_versions,omitempty"`
+	Enabled          bool            `json:"enabled"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
 }
 
-// SurveyAnswer 是客户端对一道题的匿名回答。
+// SurveyAnswer 是客户端对一道题的匿名回答。选择题使用 SelectedOptionIDs，
+// 排序题按名次先后列出全部选项；text 题使用 Text；rating、nps 与 number 题使用 Value。
 type SurveyAnswer struct {
 	QuestionID        string   `json:"question_id"`
 	SelectedOptionIDs []string `json:"selected_option_ids,omitempty"`
 	OtherText         string   `json:"other_text,omitempty"`
+	Text              string   `json:"text,omitempty"`
+	Value             *float64 `json:"value,omitempty"`
 }
 
 // SurveyResponseInput 是客户端提交的匿名答卷。
@@ -84,37 +150,56 @@ type SurveyResponseInput struct {
 	AppVersion string         `json:"app_version,omitempty"`
 	AppBuild   string         `json:"app_build,omitempty"`
 	Language   string         `json:"language,omitempty"`
+	// SurveyVersion 是客户端显示的定义版本，缺省表示当前版本。
+	SurveyVersion int `json:"survey_version,omitempty"`
+	// RespondentToken 是按征集派生的匿名设备令牌，用于拒绝同一设备重复提交，算法见 RespondentToken。
+	RespondentToken string `json:"respondent_token,omitempty"`
+	// Moderation 是服务端对文字回答的审核结果，由接口层审核后填写，客户端不能提交。
+	Moderation *SurveyModeration `json:"-"`
 }
 
-// SurveyResponseRecord 保存匿名答卷，不记录 IP 或设备标识。
+// SurveyResponseRecord 保存匿名答卷，不记录 IP 或设备标识。SurveyVersion 是答卷回答的定义版本，
+// 旧数据缺省时视为第 1 版；RespondentHash 是 respondent_token 的摘要，只用于判断重复提交。
 type SurveyResponseRecord struct {
-	Key         string         `json:"key"`
-	SurveyKey   string         `json:"survey_key"`
-	Answers     []SurveyAnswer `json:"answers"`
-	Platform    string         `json:"platform,omitempty"`
-	AppVersion  string         `json:"app_version,omitempty"`
-	AppBuild    string         `json:"app_build,omitempty"`
-	Language    string         `json:"language,omitempty"`
-	SubmittedAt time.Time      `json:"submitted_at"`
+	Key            string         `json:"key"`
+	SurveyKey      string         `json:"survey_key"`
+	SurveyVersion  int            `json:"survey_version"`
+	Answers        []SurveyAnswer `json:"answers"`
+	Platform       string         `json:"platform,omitempty"`
+	AppVersion     string         `json:"app_version,omitempty"`
+	AppBuild       string         `json:"app_build,omitempty"`
+	Language       string         `json:"language,omitempty"`
+	SubmittedAt    time.Time      `json:"submitted_at"`
+	RespondentHash string         `json:"respondent_hash,omitempty"`
+	// SurveyLanguage 是按客户端语言为包含翻译的征集选出的语言，即答卷作答时看到的文字；
+	// 没有翻译的征集不记录，统计时视为征集的 Language。
+	SurveyLanguage string `json:"survey_language,omitempty"`
+	// Moderation 是文字回答的审核结果，没有文字回答或未经审核的答卷为空。
+	Moderation *SurveyModeration `json:"moderation,omitempty"`
 }
 
 type surveyDefinitionFile struct {
 	Version int            `json:"version"`
 	Records []SurveyRecord `json:"records"`
+	// Revision 与 Changes 用于客户端增量同步，旧文件缺省时从 0 开始。
+	Revision int64          `json:"revision,omitempty"`
+	Changes  []SurveyChange `json:"changes,omitempty"`
 }
 
-type surveyResponseFile struct {
-	Version int                    `json:"version"`
-	Records []SurveyResponseRecord `json:"records"`
-}
-
-// SurveyStore 分别持久化意见征集定义和匿名答卷。
+// SurveyStore 分别持久化意见征集定义和匿名答卷。定义整体重写，答卷追加到日志。
 type SurveyStore struct {
-	mu             sync.RWMutex
-	definitionFile string
-	responseFile   string
-	records        []SurveyRecord
-	responses      []SurveyResponseRecord
+	mu                 sync.RWMutex
+	definitionFile     string
+	responseLog        string
+	legacyResponseFile string
+	records            []SurveyRecord
+	responses          []SurveyResponseRecord
+	responseCounts     map[string]int
+	respondents        map[string]map[string]struct{}
+	// staleResponseLines 是日志中已被后续同 key 行覆盖的行数，压缩后清零。
+	staleResponseLines int
+	revision           int64
+	changes            []SurveyChange
 }
 
 func NewSurveyStore(dataDir string) (*SurveyStore, error) {
@@ -123,8 +208,9 @@ func NewSurveyStore(dataDir string) (*SurveyStore, error) {
 	}
 
 	store := &SurveyStore{
-		definitionFile: filepath.Join(dataDir, "surveys.json"),
-		responseFile:   filepath.Join(dataDir, "survey-responses.json"),
+		definitionFile:     filepath.Join(dataDir, "surveys.json"),
+		responseLog:        filepath.Join(dataDir, surveyResponseLogName),
+		legacyResponseFile: filepath.Join(dataDir, legacySurveyResponseFileName),
 	}
 	if err := store.loadDefinitions(); err != nil {
 		return nil, err
@@ -147,20 +233,30 @@ func (s *SurveyStore) List() []SurveyRecord {
 func (s *SurveyStore) PublicList() []PublicSurvey {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	result, _ := s.publicListLocked(time.Now(), "")
+	return result
+}
 
+// publicListLocked 返回 now 时刻正在接受答卷的征集，以及之后最近一次定时开始或截止的时间。
+// 包含翻译的征集按 locale 选出语言，见 PublicIn。
+func (s *SurveyStore) publicListLocked(now time.Time, locale string) (result []PublicSurvey, next time.Time) {
 	records := make([]SurveyRecord, 0, len(s.records))
 	for _, record := range s.records {
-		if record.Enabled {
+		if !record.Enabled {
+			continue
+		}
+		if s.closedReasonLocked(record, now) == "" {
 			records = append(records, record)
 		}
+		next = earliestTransition(next, now, record.OpensAt, record.ClosesAt)
 	}
 	sortSurveyRecords(records)
 
-	result := make([]PublicSurvey, 0, len(records))
+	result = make([]PublicSurvey, 0, len(records))
 	for _, record := range records {
-		result = append(result, record.Public())
+		result = append(result, record.PublicIn(locale))
 	}
-	return result
+	return result, next
 }
 
 func (s *SurveyStore) Create(record SurveyRecord) (SurveyRecord, error) {
@@ -172,19 +268,24 @@ func (s *SurveyStore) Create(record SurveyRecord) (SurveyRecord, error) {
 	record.Key = key
 	record.CreatedAt = now
 	record.UpdatedAt = now
+	record.Version, record.PreviousVersions = 1, nil
 	normalizeSurveyRecord(&record)
 	if err := validateSurveyRecord(record); err != nil {
-		return SurveyRecord{}, err
+		return SurveyRecord{}, invalidError("survey_invalid", err)
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if len(s.records) >= maxSurveyRecords {
-		return SurveyRecord{}, fmt.Errorf("意见征集不能超过 %d 条", maxSurveyRecords)
+		return SurveyRecord{}, coded(
+			ErrorConflict,
+			"survey_limit_reached",
+			fmt.Sprintf("意见征集不能超过 %d 条", maxSurveyRecords),
+		)
 	}
 
 	s.records = append(s.records, record)
-	if err := s.saveDefinitionsLocked(); err != nil {
+	if err := s.commitDefinitionsLocked(record.Key); err != nil {
 		s.records = s.records[:len(s.records)-1]
 		return SurveyRecord{}, err
 	}
@@ -194,12 +295,12 @@ func (s *SurveyStore) Create(record SurveyRecord) (SurveyRecord, error) {
 func (s *SurveyStore) Update(key string, replacement SurveyRecord) (SurveyRecord, error) {
 	key = strings.TrimSpace(key)
 	if key == "" {
-		return SurveyRecord{}, fmt.Errorf("意见征集 key 不能为空")
+		return SurveyRecord{}, coded(ErrorInvalid, "survey_invalid", "意见征集 key 不能为空")
 	}
 	replacement.Key = key
 	normalizeSurveyRecord(&replacement)
 	if err := validateSurveyRecord(replacement); err != nil {
-		return SurveyRecord{}, err
+		return SurveyRecord{}, invalidError("survey_invalid", err)
 	}
 
 	s.mu.Lock()
@@ -212,24 +313,50 @@ func (s *SurveyStore) Update(key string, replacement SurveyRecord) (SurveyRecord
 		replacement.Key = current.Key
 		replacement.CreatedAt = current.CreatedAt
 		replacement.UpdatedAt = time.Now().UTC()
+		replacement.Version = current.Version
+		replacement.PreviousVersions = cloneSurveyVersions(current.PreviousVersions)
+		// 删除全部翻译会把面向所有语言的征集收窄为只投放默认语言，与修改语言范围一样不允许。
+		if s.responseCountLocked(key) > 0 && len(current.Localizations) > 0 && len(replacement.Localizations) == 0 {
+			return SurveyRecord{}, coded(
+				ErrorConflict,
+				ErrSurveyHasResponses.Code,
+				fmt.Sprintf("%s：不能删除全部翻译", ErrSurveyHasResponses.Message),
+			)
+		}
+		// 已有答卷时定义的兼容修改生成新版本，旧答卷仍对应原来的版本。
 		if s.responseCountLocked(key) > 0 && !sameSurveyDefinition(current, replacement) {
-			return SurveyRecord{}, fmt.Errorf("已有答卷的意见征集不能修改题目内容，只能调整发布状态")
+			if err := compatibleSurveyEdit(current, replacement); err != nil {
+				return SurveyRecord{}, coded(
+					ErrorConflict,
+					ErrSurveyHasResponses.Code,
+					fmt.Sprintf("%s：%v", ErrSurveyHasResponses.Message, err),
+				)
+			}
+			if current.Version >= maxSurveyVersions {
+				return SurveyRecord{}, coded(
+					ErrorConflict,
+					ErrSurveyHasResponses.Code,
+					fmt.Sprintf("意见征集最多保留 %d 个版本，请创建新的意见征集", maxSurveyVersions),
+				)
+			}
+			replacement.PreviousVersions = append(replacement.PreviousVersions, current.snapshot(replacement.UpdatedAt))
+			replacement.Version = current.Version + 1
 		}
 
 		s.records[index] = replacement
-		if err := s.saveDefinitionsLocked(); err != nil {
+		if err := s.commitDefinitionsLocked(key); err != nil {
 			s.records[index] = current
 			return SurveyRecord{}, err
 		}
 		return cloneSurveyRecord(replacement), nil
 	}
-	return SurveyRecord{}, fmt.Errorf("意见征集不存在")
+	return SurveyRecord{}, ErrSurveyNotFound
 }
 
 func (s *SurveyStore) Delete(key string) error {
 	key = strings.TrimSpace(key)
 	if key == "" {
-		return fmt.Errorf("意见征集 key 不能为空")
+		return coded(ErrorInvalid, "survey_invalid", "意见征集 key 不能为空")
 	}
 
 	s.mu.Lock()
@@ -239,18 +366,18 @@ func (s *SurveyStore) Delete(key string) error {
 			continue
 		}
 		if s.responseCountLocked(key) > 0 {
-			return fmt.Errorf("已有答卷的意见征集不能删除，请改为停止发布")
+			return coded(ErrorConflict, ErrSurveyHasResponses.Code, "已有答卷的意见征集不能删除，请改为停止发布")
 		}
 
 		previous := cloneSurveyRecords(s.records)
 		s.records = append(s.records[:index], s.records[index+1:]...)
-		if err := s.saveDefinitionsLocked(); err != nil {
+		if err := s.commitDefinitionsLocked(key); err != nil {
 			s.records = previous
 			return err
 		}
 		return nil
 	}
-	return fmt.Errorf("意见征集不存在")
+	return ErrSurveyNotFound
 }
 
 func (s *SurveyStore) Submit(key string, input SurveyResponseInput) (SurveyResponseRecord, error) {
@@ -259,42 +386,97 @@ func (s *SurveyStore) Submit(key string, input SurveyResponseInput) (SurveyRespo
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	record, ok := s.findLocked(key)
-	if !ok {
-		return SurveyResponseRecord{}, fmt.Errorf("意见征集不存在")
-	}
-	if !record.Enabled {
-		return SurveyResponseRecord{}, fmt.Errorf("意见征集已停止")
-	}
-	if err := validateSurveyResponse(record, input); err != nil {
+	now := time.Now().UTC()
+	record, version, hash, err := s.checkResponseLocked(key, input, now)
+	if err != nil {
 		return SurveyResponseRecord{}, err
 	}
-	if len(s.responses) >= maxSurveyResponses {
-		return SurveyResponseRecord{}, fmt.Errorf("匿名答卷存储已达到上限")
-	}
 
 	responseKey, err := newSurveyKey()
 	if err != nil {
 		return SurveyResponseRecord{}, err
 	}
 	response := SurveyResponseRecord{
-		Key:         responseKey,
-		SurveyKey:   key,
-		Answers:     cloneSurveyAnswers(input.Answers),
-		Platform:    input.Platform,
-		AppVersion:  input.AppVersion,
-		AppBuild:    input.AppBuild,
-		Language:    input.Language,
-		SubmittedAt: time.Now().UTC(),
-	}
-	s.responses = append(s.responses, response)
-	if err := s.saveResponsesLocked(); err != nil {
-		s.responses = s.responses[:len(s.responses)-1]
+		Key:            responseKey,
+		SurveyKey:      key,
+		SurveyVersion:  version,
+		Answers:        cloneSurveyAnswers(input.Answers),
+		Platform:       input.Platform,
+		AppVersion:     input.AppVersion,
+		AppBuild:       input.AppBuild,
+		Language:       input.Language,
+		SubmittedAt:    now,
+		RespondentHash: hash,
+	}
+	if len(record.Localizations) > 0 {
+		response.SurveyLanguage = record.resolveLanguage(input.Language)
+	}
+	if input.Moderation != nil && len(SurveyResponseTexts(response.Answers)) > 0 {
+		response.Moderation = cloneSurveyModeration(input.Moderation)
+	}
+	if err := s.appendResponsesLocked(response); err != nil {
 		return SurveyResponseRecord{}, err
 	}
+	if record.MaxResponses > 0 && s.responseCounts[key] == record.MaxResponses {
+		// 达到上限的征集从公开列表中移除，记录一次同步修订让增量同步的客户端也删除它。
+		// 答卷已经保存，修订写入失败只会让客户端晚一些得知，下次提交仍会被拒绝。
+		_ = s.commitDefinitionsLocked(key)
+	}
 	return response, nil
 }
 
+// ValidateResponse 按 Submit 的规则检查答卷但不保存，调用方可以在耗时的内容审核之前先拒绝
+// 不会被保存的提交。检查之后征集状态仍可能变化，Submit 会再检查一次。
+func (s *SurveyStore) ValidateResponse(key string, input SurveyResponseInput) error {
+	key = strings.TrimSpace(key)
+	normalizeSurveyResponseInput(&input)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, _, _, err := s.checkResponseLocked(key, input, time.Now().UTC())
+	return err
+}
+
+// checkResponseLocked 检查征集是否存在且开放、答卷是否有效、是否重复提交，
+// 返回征集、答卷对应的版本和答题人令牌摘要。
+func (s *SurveyStore) checkResponseLocked(key string, input SurveyResponseInput, now time.Time) (SurveyRecord, int, string, error) {
+	record, ok := s.findLocked(key)
+	if !ok {
+		return SurveyRecord{}, 0, "", ErrSurveyNotFound
+	}
+	if reason := s.closedReasonLocked(record, now); reason != "" {
+		return SurveyRecord{}, 0, "", surveyClosedError(reason)
+	}
+	version := input.SurveyVersion
+	if version == 0 {
+		version = record.Version
+	}
+	definition, found := record.definitionAt(version)
+	if !found {
+		return SurveyRecord{}, 0, "", coded(ErrorInvalid, "survey_response_invalid", "意见征集版本不存在，请刷新后重试")
+	}
+	if err := validateSurveyResponse(definition, input); err != nil {
+		return SurveyRecord{}, 0, "", invalidError("survey_response_invalid", err)
+	}
+	var hash string
+	switch {
+	case input.RespondentToken != "":
+		if !validRespondentToken(input.RespondentToken) {
+			return SurveyRecord{}, 0, "", coded(ErrorInvalid, "survey_response_invalid", "respondent_token 必须是 64 位十六进制字符串")
+		}
+		hash = respondentHash(key, input.RespondentToken)
+		if s.answeredLocked(key, hash) {
+			return SurveyRecord{}, 0, "", ErrSurveyAlreadyAnswered
+		}
+	case record.OneResponsePerDevice:
+		return SurveyRecord{}, 0, "", coded(ErrorInvalid, "survey_response_invalid", "该意见征集要求提交 respondent_token，请更新客户端后重试")
+	}
+	if s.responseCounts[key] >= maxSurveyResponses {
+		return SurveyRecord{}, 0, "", coded(ErrorConflict, "survey_response_limit_reached", "该意见征集的答卷已达到上限")
+	}
+	return record, version, hash, nil
+}
+
 func (s *SurveyStore) Results(key string) (SurveyRecord, []SurveyResponseRecord, error) {
 	key = strings.TrimSpace(key)
 
@@ -302,12 +484,14 @@ func (s *SurveyStore) Results(key string) (SurveyRecord, []SurveyResponseRecord,
 	defer s.mu.RUnlock()
 	record, ok := s.findLocked(key)
 	if !ok {
-		return SurveyRecord{}, nil, fmt.Errorf("意见征集不存在")
+		return SurveyRecord{}, nil, ErrSurveyNotFound
 	}
 
 	responses := make([]SurveyResponseRecord, 0)
 	for _, response := range s.responses {
 		if response.SurveyKey == key {
+			// 令牌摘要只用于判断重复提交，不随答卷返回。
+			response.RespondentHash = ""
 			responses = append(responses, cloneSurveyResponse(response))
 		}
 	}
@@ -318,17 +502,27 @@ func (s *SurveyStore) Results(key string) (SurveyRecord, []SurveyResponseRecord,
 }
 
 func (record SurveyRecord) Public() PublicSurvey {
-	return PublicSurvey{
-		Key:         record.Key,
-		ID:          record.ID,
-		Title:       record.Title,
-		Description: record.Description,
-		MinBuild:    record.MinBuild,
-		MaxBuild:    record.MaxBuild,
-		Language:    record.Language,
-		Platform:    record.Platform,
-		Questions:   cloneSurveyQuestions(record.Questions),
-	}
+	public := PublicSurvey{
+		Key:            record.Key,
+		ID:             record.ID,
+		Title:          record.Title,
+		Description:    record.Description,
+		MinBuild:       record.MinBuild,
+		MaxBuild:       record.MaxBuild,
+		Language:       record.Language,
+		Platform:       record.Platform,
+		Questions:      cloneSurveyQuestions(record.Questions),
+		ClosesAt:       record.ClosesAt,
+		Version:        record.Version,
+		RespondentSalt: respondentSalt(record.Key),
+	}
+	public.RolloutPercent, public.RolloutSalt = publicRollout(rolloutKindSurvey, record.ID, record.RolloutPercent)
+	if len(record.Localizations) > 0 {
+		// 包含翻译的征集面向所有语言，不带 locale 的旧客户端按语言筛选时不应把它过滤掉，因此以空 language 输出默认文字。
+		public.Language = ""
+		public.Languages = record.Languages()
+	}
+	return public
 }
 
 func (s *SurveyStore) loadDefinitions() error {
@@ -362,58 +556,21 @@ func (s *SurveyStore) loadDefinitions() error {
 		if err := validateSurveyRecord(payload.Records[index]); err != nil {
 			return fmt.Errorf("第 %d 条意见征集无效: %w", index+1, err)
 		}
+		if err := validateSurveyVersions(payload.Records[index]); err != nil {
+			return fmt.Errorf("第 %d 条意见征集的%w", index+1, err)
+		}
 		if _, exists := keys[payload.Records[index].Key]; exists {
 			return fmt.Errorf("意见征集 key 重复: %s", payload.Records[index].Key)
 		}
 		keys[payload.Records[index].Key] = struct{}{}
 	}
-	s.records = payload.Records
-	return nil
-}
-
-func (s *SurveyStore) loadResponses() error {
-	data, err := os.ReadFile(s.responseFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			s.responses = []SurveyResponseRecord{}
-			return nil
-		}
-		return fmt.Errorf("读取匿名答卷文件失败: %w", err)
-	}
-	if strings.TrimSpace(string(data)) == "" {
-		s.responses = []SurveyResponseRecord{}
-		return nil
-	}
-
-	var payload surveyResponseFile
-	if err := json.Unmarshal(data, &payload); err != nil {
-		return fmt.Errorf("解析匿名答卷文件失败: %w", err)
-	}
-	if payload.Version != surveyFileVersion {
-		return fmt.Errorf("不支持的匿名答卷文件版本: %d", payload.Version)
-	}
-	if len(payload.Records) > maxSurveyResponses {
-		return fmt.Errorf("匿名答卷不能超过 %d 条", maxSurveyResponses)
-	}
-	for index := range payload.Records {
-		normalizeSurveyResponseRecord(&payload.Records[index])
-		survey, ok := s.findLocked(payload.Records[index].SurveyKey)
-		if !ok {
-			return fmt.Errorf("第 %d 份匿名答卷关联的意见征集不存在", index+1)
-		}
-		if payload.Records[index].Key == "" || payload.Records[index].SubmittedAt.IsZero() {
-			return fmt.Errorf("第 %d 份匿名答卷元数据无效", index+1)
-		}
-		if err := validateSurveyResponse(survey, SurveyResponseInput{
-			Answers:  payload.Records[index].Answers,
-			Platform: payload.Records[index].Platform,
-			AppBuild: payload.Records[index].AppBuild,
-			Language: payload.Records[index].Language,
-		}); err != nil {
-			return fmt.Errorf("第 %d 份匿名答卷无效: %w", index+1, err)
+	for _, change := range payload.Changes {
+		if change.Revision <= 0 || change.Revision > payload.Revision {
+			return fmt.Errorf("意见征集同步记录 %d 无效", change.Revision)
 		}
 	}
-	s.responses = payload.Records
+	s.records = payload.Records
+	s.revision, s.changes = payload.Revision, payload.Changes
 	return nil
 }
 
@@ -421,20 +578,16 @@ func (s *SurveyStore) saveDefinitionsLocked() error {
 	return writeSurveyJSONAtomically(
 		s.definitionFile,
 		".surveys-*.tmp",
-		surveyDefinitionFile{Version: surveyFileVersion, Records: s.records},
+		surveyDefinitionFile{
+			Version:  surveyFileVersion,
+			Records:  s.records,
+			Revision: s.revision,
+			Changes:  s.changes,
+		},
 		"意见征集",
 	)
 }
 
-func (s *SurveyStore) saveResponsesLocked() error {
-	return writeSurveyJSONAtomically(
-		s.responseFile,
-		".survey-responses-*.tmp",
-		surveyResponseFile{Version: surveyFileVersion, Records: s.responses},
-		"匿名答卷",
-	)
-}
-
 func writeSurveyJSONAtomically(file, pattern string, payload any, label string) error {
 	data, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
@@ -478,11 +631,46 @@ func normalizeSurveyRecord(record *SurveyRecord) {
 	record.MaxBuild = strings.TrimSpace(record.MaxBuild)
 	record.Language = strings.TrimSpace(record.Language)
 	record.Platform = normalizeAnnouncementPlatform(record.Platform)
-	for questionIndex := range record.Questions {
-		question := &record.Questions[questionIndex]
+	record.RolloutPercent = normalizeRolloutPercent(record.RolloutPercent)
+	record.OpensAt = normalizeScheduleTime(record.OpensAt)
+	record.ClosesAt = normalizeScheduleTime(record.ClosesAt)
+	// 旧文件没有版本号，当时有答卷后不能修改定义，因此都是第 1 版。
+	if record.Version == 0 {
+		record.Version = 1
+	}
+	record.Localizations = normalizeSurveyLocalizations(record.Localizations)
+	if len(record.PreviousVersions) == 0 {
+		record.PreviousVersions = nil
+	}
+	normalizeSurveyQuestions(record.Questions)
+	for index := range record.PreviousVersions {
+		previous := &record.PreviousVersions[index]
+		previous.Title = strings.TrimSpace(previous.Title)
+		previous.Description = strings.TrimSpace(previous.Description)
+		normalizeSurveyQuestions(previous.Questions)
+	}
+}
+
+func normalizeSurveyQuestions(questions []SurveyQuestion) {
+	for questionIndex := range questions {
+		question := &questions[questionIndex]
 		question.ID = strings.TrimSpace(question.ID)
 		question.Question = strings.TrimSpace(question.Question)
 		question.Type = strings.ToLower(strings.TrimSpace(question.Type))
+		if question.Options == nil {
+			question.Options = []SurveyOption{}
+		}
+		if len(question.ShowIf) == 0 {
+			question.ShowIf = nil
+		}
+		for conditionIndex := range question.ShowIf {
+			condition := &question.ShowIf[conditionIndex]
+			condition.QuestionID = strings.TrimSpace(condition.QuestionID)
+			condition.OptionIDs = uniqueTrimmedStrings(condition.OptionIDs)
+			if len(condition.OptionIDs) == 0 {
+				condition.OptionIDs = nil
+			}
+		}
 		for optionIndex := range question.Options {
 			option := &question.Options[optionIndex]
 			option.ID = strings.TrimSpace(option.ID)
@@ -497,30 +685,41 @@ func normalizeSurveyResponseInput(input *SurveyResponseInput) {
 	input.AppVersion = strings.TrimSpace(input.AppVersion)
 	input.AppBuild = strings.TrimSpace(input.AppBuild)
 	input.Language = strings.TrimSpace(input.Language)
+	input.RespondentToken = strings.ToLower(strings.TrimSpace(input.RespondentToken))
 	for index := range input.Answers {
 		answer := &input.Answers[index]
 		answer.QuestionID = strings.TrimSpace(answer.QuestionID)
 		answer.OtherText = strings.TrimSpace(answer.OtherText)
-		selected := make([]string, 0, len(answer.SelectedOptionIDs))
-		seen := make(map[string]struct{}, len(answer.SelectedOptionIDs))
-		for _, rawID := range answer.SelectedOptionIDs {
-			optionID := strings.TrimSpace(rawID)
-			if optionID == "" {
-				continue
-			}
-			if _, exists := seen[optionID]; exists {
-				continue
-			}
-			seen[optionID] = struct{}{}
-			selected = append(selected, optionID)
+		answer.Text = strings.TrimSpace(answer.Text)
+		answer.SelectedOptionIDs = uniqueTrimmedStrings(answer.SelectedOptionIDs)
+	}
+}
+
+// uniqueTrimmedStrings 去掉空白与重复项，并保留首次出现的顺序。
+func uniqueTrimmedStrings(values []string) []string {
+	result := make([]string, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, raw := range values {
+		value := strings.TrimSpace(raw)
+		if value == "" {
+			continue
 		}
-		answer.SelectedOptionIDs = selected
+		if _, exists := seen[value]; exists {
+			continue
+		}
+		seen[value] = struct{}{}
+		result = append(result, value)
 	}
+	return result
 }
 
 func normalizeSurveyResponseRecord(record *SurveyResponseRecord) {
 	record.Key = strings.TrimSpace(record.Key)
 	record.SurveyKey = strings.TrimSpace(record.SurveyKey)
+	record.SurveyLanguage = strings.TrimSpace(record.SurveyLanguage)
+	if record.SurveyVersion == 0 {
+		record.SurveyVersion = 1
+	}
 	input := SurveyResponseInput{
 		Answers:    record.Answers,
 		Platform:   record.Platform,
@@ -558,6 +757,15 @@ func validateSurveyRecord(record SurveyRecord) error {
 	if record.Platform != "" && record.Platform != "iOS" && record.Platform != "watchOS" {
 		return fmt.Errorf("平台仅支持 iOS 或 watchOS")
 	}
+	if err := validateRolloutPercent(record.RolloutPercent); err != nil {
+		return err
+	}
+	if record.OpensAt != nil && record.ClosesAt != nil && !record.ClosesAt.After(*record.OpensAt) {
+		return fmt.Errorf("closes_at 必须晚于 opens_at")
+	}
+	if record.MaxResponses < 0 || record.MaxResponses > maxSurveyResponses {
+		return fmt.Errorf("答卷数量上限必须在 1 到 %d 之间，留空表示不限", maxSurveyResponses)
+	}
 	if len(record.Questions) < 1 || len(record.Questions) > maxSurveyQuestions {
 		return fmt.Errorf("题目数量必须在 1 到 %d 道之间", maxSurveyQuestions)
 	}
@@ -574,11 +782,8 @@ func validateSurveyRecord(record SurveyRecord) error {
 		if count := len([]rune(question.Question)); count < 1 || count > 500 {
 			return fmt.Errorf("第 %d 道题长度必须在 1 到 500 个字符之间", questionIndex+1)
 		}
-		if question.Type != "single_select" && question.Type != "multi_select" {
-			return fmt.Errorf("第 %d 道题仅支持 single_select 或 multi_select", questionIndex+1)
-		}
-		if len(question.Options) < 1 || len(question.Options) > maxSurveyOptions {
-			return fmt.Errorf("第 %d 道题的选项数量必须在 1 到 %d 个之间", questionIndex+1, maxSurveyOptions)
+		if err := validateSurveyQuestionType(question); err != nil {
+			return fmt.Errorf("第 %d 道题%v", questionIndex+1, err)
 		}
 
 		optionIDs := make(map[string]struct{}, len(question.Options))
@@ -597,6 +802,62 @@ func validateSurveyRecord(record SurveyRecord) error {
 				return fmt.Errorf("第 %d 道题第 %d 个选项说明不能超过 500 个字符", questionIndex+1, optionIndex+1)
 			}
 		}
+		if err := validateSurveyConditions(question, record.Questions[:questionIndex]); err != nil {
+			return fmt.Errorf("第 %d 道题的显示条件%v", questionIndex+1, err)
+		}
+	}
+	return validateSurveyLocalizations(record)
+}
+
+// validateSurveyConditions 检查显示条件只引用 earlier 中的题目与选项。
+// 条件只能指向前面的题目，因此不会形成循环。
+func validateSurveyConditions(question SurveyQuestion, earlier []SurveyQuestion) error {
+	if len(question.ShowIf) > maxSurveyConditions {
+		return fmt.Errorf("不能超过 %d 条", maxSurveyConditions)
+	}
+	for _, condition := range question.ShowIf {
+		source, found := SurveyQuestion{}, false
+		for _, candidate := range earlier {
+			if candidate.ID == condition.QuestionID {
+				source, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			if condition.QuestionID == question.ID {
+				return fmt.Errorf("不能引用题目本身")
+			}
+			return fmt.Errorf("只能引用前面的题目: %s", condition.QuestionID)
+		}
+
+		if len(condition.OptionIDs) > 0 {
+			if source.Type != SurveyTypeSingleSelect && source.Type != SurveyTypeMultiSelect {
+				return fmt.Errorf("只能按选择题的选项判断: %s", source.ID)
+			}
+			for _, optionID := range condition.OptionIDs {
+				if !slices.ContainsFunc(source.Options, func(option SurveyOption) bool {
+					return option.ID == optionID
+				}) {
+					return fmt.Errorf("引用了不存在的选项: %s", optionID)
+				}
+			}
+		}
+		if condition.Min != nil || condition.Max != nil {
+			if source.Type != SurveyTypeRating && source.Type != SurveyTypeNPS && source.Type != SurveyTypeNumber {
+				return fmt.Errorf("只能按 rating、nps 或 number 题的数值判断: %s", source.ID)
+			}
+			if len(condition.OptionIDs) > 0 {
+				return fmt.Errorf("不能同时设置选项和数值范围")
+			}
+			for _, bound := range []*float64{condition.Min, condition.Max} {
+				if bound != nil && (math.IsNaN(*bound) || math.IsInf(*bound, 0)) {
+					return fmt.Errorf("中的数值范围必须是有限数字")
+				}
+			}
+			if condition.Min != nil && condition.Max != nil && *condition.Min > *condition.Max {
+				return fmt.Errorf("中的最小值不能大于最大值")
+			}
+		}
 	}
 	return nil
 }
@@ -626,44 +887,230 @@ func validateSurveyResponse(survey SurveyRecord, input SurveyResponseInput) erro
 		answers[answer.QuestionID] = answer
 	}
 
+	// 按题目顺序判断显示条件；条件只引用前面的题目，此时它们的回答已通过校验。
+	accepted := make(map[string]SurveyAnswer, len(answers))
 	for _, question := range survey.Questions {
 		answer, exists := answers[question.ID]
+		if !surveyConditionsMet(question.ShowIf, accepted) {
+			if exists && surveyAnswerProvided(question, answer) {
+				return fmt.Errorf("题目未显示，不能提交回答: %s", question.Question)
+			}
+			delete(answers, question.ID)
+			continue
+		}
 		if !exists {
 			if question.Required {
 				return fmt.Errorf("请回答必填题目: %s", question.Question)
 			}
 			continue
 		}
-		if question.Type == "single_select" && len(answer.SelectedOptionIDs) > 1 {
-			return fmt.Errorf("单选题只能选择一个选项: %s", question.Question)
+		if err := validateSurveyAnswer(question, answer); err != nil {
+			return err
+		}
+		if question.Required && !surveyAnswerProvided(question, answer) {
+			return fmt.Errorf("请回答必填题目: %s", question.Question)
 		}
-		if answer.OtherText != "" && !question.AllowOther {
-			return fmt.Errorf("题目不允许自定义输入: %s", question.Question)
+		if surveyAnswerProvided(question, answer) {
+			accepted[question.ID] = answer
 		}
-		if len([]rune(answer.OtherText)) > maxSurveyCustomTextRunes {
-			return fmt.Errorf("自定义输入不能超过 %d 个字符", maxSurveyCustomTextRunes)
+		delete(answers, question.ID)
+	}
+	if len(answers) > 0 {
+		return fmt.Errorf("答卷包含未知题目")
+	}
+	return nil
+}
+
+// validateSurveyQuestionType 检查题型及其专属设置，返回的错误接在“第 N 道题”之后。
+func validateSurveyQuestionType(question SurveyQuestion) error {
+	optionCount := len(question.Options)
+	switch question.Type {
+	case SurveyTypeSingleSelect, SurveyTypeMultiSelect:
+		if optionCount < 1 || optionCount > maxSurveyOptions {
+			return fmt.Errorf("的选项数量必须在 1 到 %d 个之间", maxSurveyOptions)
+		}
+	case SurveyTypeRanking:
+		if optionCount < 2 || optionCount > maxSurveyOptions {
+			return fmt.Errorf("的排序选项数量必须在 2 到 %d 个之间", maxSurveyOptions)
 		}
+	case SurveyTypeText, SurveyTypeRating, SurveyTypeNPS, SurveyTypeNumber:
+		if optionCount > 0 {
+			return fmt.Errorf("的题型 %s 不能设置选项", question.Type)
+		}
+	default:
+		return fmt.Errorf("的题型无效，仅支持 single_select、multi_select、text、rating、nps、ranking 或 number")
+	}
+
+	if question.AllowOther && question.Type != SurveyTypeSingleSelect && question.Type != SurveyTypeMultiSelect {
+		return fmt.Errorf("不能允许自定义回答，仅选择题支持")
+	}
+	if question.Type != SurveyTypeText && (question.MinLength != 0 || question.MaxLength != 0) {
+		return fmt.Errorf("不能设置字数限制，仅 text 题支持")
+	}
+	if question.Type != SurveyTypeRating && question.Scale != 0 {
+		return fmt.Errorf("不能设置星级，仅 rating 题支持")
+	}
+	if question.Type != SurveyTypeNumber && (question.Min != nil || question.Max != nil) {
+		return fmt.Errorf("不能设置取值范围，仅 number 题支持")
+	}
 
-		validOptions := make(map[string]struct{}, len(question.Options))
-		for _, option := range question.Options {
-			validOptions[option.ID] = struct{}{}
+	switch question.Type {
+	case SurveyTypeText:
+		maxLength := surveyTextMaxLength(question)
+		if question.MinLength < 0 || question.MaxLength < 0 || question.MaxLength > maxSurveyCustomTextRunes {
+			return fmt.Errorf("的字数限制必须在 0 到 %d 之间", maxSurveyCustomTextRunes)
+		}
+		if question.MinLength > maxLength {
+			return fmt.Errorf("的最少字数不能大于最多字数")
+		}
+	case SurveyTypeRating:
+		if question.Scale < minSurveyRatingScale || question.Scale > maxSurveyRatingScale {
+			return fmt.Errorf("的星级必须在 %d 到 %d 之间", minSurveyRatingScale, maxSurveyRatingScale)
 		}
-		for _, optionID := range answer.SelectedOptionIDs {
-			if _, valid := validOptions[optionID]; !valid {
-				return fmt.Errorf("题目包含未知选项: %s", question.Question)
+	case SurveyTypeNumber:
+		for _, bound := range []*float64{question.Min, question.Max} {
+			if bound != nil && (math.IsNaN(*bound) || math.IsInf(*bound, 0)) {
+				return fmt.Errorf("的取值范围必须是有限数字")
 			}
 		}
-		if question.Required && len(answer.SelectedOptionIDs) == 0 && answer.OtherText == "" {
-			return fmt.Errorf("请回答必填题目: %s", question.Question)
+		if question.Min != nil && question.Max != nil && *question.Min > *question.Max {
+			return fmt.Errorf("的最小值不能大于最大值")
 		}
-		delete(answers, question.ID)
 	}
-	if len(answers) > 0 {
-		return fmt.Errorf("答卷包含未知题目")
+	return nil
+}
+
+// validateSurveyAnswer 按题型检查一道题的回答；未作答的部分由调用方按 Required 处理。
+func validateSurveyAnswer(question SurveyQuestion, answer SurveyAnswer) error {
+	usesOptions := question.Type == SurveyTypeSingleSelect ||
+		question.Type == SurveyTypeMultiSelect ||
+		question.Type == SurveyTypeRanking
+	usesValue := question.Type == SurveyTypeRating ||
+		question.Type == SurveyTypeNPS ||
+		question.Type == SurveyTypeNumber
+	if !usesOptions && len(answer.SelectedOptionIDs) > 0 {
+		return fmt.Errorf("题目不接受选项回答: %s", question.Question)
+	}
+	if answer.OtherText != "" && !question.AllowOther {
+		return fmt.Errorf("题目不允许自定义输入: %s", question.Question)
+	}
+	if len([]rune(answer.OtherText)) > maxSurveyCustomTextRunes {
+		return fmt.Errorf("自定义输入不能超过 %d 个字符", maxSurveyCustomTextRunes)
+	}
+	if question.Type != SurveyTypeText && answer.Text != "" {
+		return fmt.Errorf("题目不接受文字回答: %s", question.Question)
+	}
+	if !usesValue && answer.Value != nil {
+		return fmt.Errorf("题目不接受数值回答: %s", question.Question)
+	}
+
+	validOptions := make(map[string]struct{}, len(question.Options))
+	for _, option := range question.Options {
+		validOptions[option.ID] = struct{}{}
+	}
+	for _, optionID := range answer.SelectedOptionIDs {
+		if _, valid := validOptions[optionID]; !valid {
+			return fmt.Errorf("题目包含未知选项: %s", question.Question)
+		}
+	}
+
+	switch question.Type {
+	case SurveyTypeSingleSelect:
+		if len(answer.SelectedOptionIDs) > 1 {
+			return fmt.Errorf("单选题只能选择一个选项: %s", question.Question)
+		}
+	case SurveyTypeRanking:
+		if count := len(answer.SelectedOptionIDs); count > 0 && count != len(question.Options) {
+			return fmt.Errorf("排序题需要为全部选项排序: %s", question.Question)
+		}
+	case SurveyTypeText:
+		if answer.Text == "" {
+			break
+		}
+		count := len([]rune(answer.Text))
+		if count < question.MinLength || count > surveyTextMaxLength(question) {
+			return fmt.Errorf(
+				"回答字数必须在 %d 到 %d 个字符之间: %s",
+				question.MinLength,
+				surveyTextMaxLength(question),
+				question.Question,
+			)
+		}
+	case SurveyTypeRating, SurveyTypeNPS, SurveyTypeNumber:
+		if answer.Value == nil {
+			break
+		}
+		value := *answer.Value
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("回答必须是有限数字: %s", question.Question)
+		}
+		minimum, maximum := surveyValueRange(question)
+		if question.Type != SurveyTypeNumber && value != math.Trunc(value) {
+			return fmt.Errorf("评分必须是整数: %s", question.Question)
+		}
+		if (minimum != nil && value < *minimum) || (maximum != nil && value > *maximum) {
+			return fmt.Errorf("回答超出取值范围: %s", question.Question)
+		}
 	}
 	return nil
 }
 
+// surveyConditionsMet 判断显示条件是否全部成立；accepted 只包含已显示且已作答的题目。
+func surveyConditionsMet(conditions []SurveyCondition, accepted map[string]SurveyAnswer) bool {
+	for _, condition := range conditions {
+		answer, answered := accepted[condition.QuestionID]
+		if !answered {
+			return false
+		}
+		if len(condition.OptionIDs) > 0 && !slices.ContainsFunc(answer.SelectedOptionIDs, func(optionID string) bool {
+			return slices.Contains(condition.OptionIDs, optionID)
+		}) {
+			return false
+		}
+		if condition.Min != nil || condition.Max != nil {
+			if answer.Value == nil ||
+				(condition.Min != nil && *answer.Value < *condition.Min) ||
+				(condition.Max != nil && *answer.Value > *condition.Max) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// surveyAnswerProvided 判断回答是否包含该题型的有效内容，用于必填检查。
+func surveyAnswerProvided(question SurveyQuestion, answer SurveyAnswer) bool {
+	switch question.Type {
+	case SurveyTypeText:
+		return answer.Text != ""
+	case SurveyTypeRating, SurveyTypeNPS, SurveyTypeNumber:
+		return answer.Value != nil
+	default:
+		return len(answer.SelectedOptionIDs) > 0 || answer.OtherText != ""
+	}
+}
+
+func surveyTextMaxLength(question SurveyQuestion) int {
+	if question.MaxLength > 0 {
+		return question.MaxLength
+	}
+	return maxSurveyCustomTextRunes
+}
+
+// surveyValueRange 返回数值类题目的取值范围，nil 表示该方向不限。
+func surveyValueRange(question SurveyQuestion) (minimum, maximum *float64) {
+	switch question.Type {
+	case SurveyTypeRating:
+		low, high := 1.0, float64(question.Scale)
+		return &low, &high
+	case SurveyTypeNPS:
+		low, high := 0.0, 10.0
+		return &low, &high
+	default:
+		return question.Min, question.Max
+	}
+}
+
 func validSurveyIdentifier(value string) bool {
 	if value == "" || len(value) > 64 {
 		return false
@@ -699,13 +1146,7 @@ func (s *SurveyStore) findLocked(key string) (SurveyRecord, bool) {
 }
 
 func (s *SurveyStore) responseCountLocked(key string) int {
-	count := 0
-	for _, response := range s.responses {
-		if response.SurveyKey == key {
-			count++
-		}
-	}
-	return count
+	return s.responseCounts[key]
 }
 
 func sortSurveyRecords(records []SurveyRecord) {
@@ -730,6 +1171,8 @@ func cloneSurveyRecords(records []SurveyRecord) []SurveyRecord {
 
 func cloneSurveyRecord(record SurveyRecord) SurveyRecord {
 	record.Questions = cloneSurveyQuestions(record.Questions)
+	record.Localizations = cloneSurveyLocalizations(record.Localizations)
+	record.PreviousVersions = cloneSurveyVersions(record.PreviousVersions)
 	return record
 }
 
@@ -737,7 +1180,18 @@ func cloneSurveyQuestions(questions []SurveyQuestion) []SurveyQuestion {
 	result := make([]SurveyQuestion, len(questions))
 	for index, question := range questions {
 		result[index] = question
-		result[index].Options = append([]SurveyOption(nil), question.Options...)
+		result[index].Options = append([]SurveyOption{}, question.Options...)
+		result[index].Min = cloneFloat(question.Min)
+		result[index].Max = cloneFloat(question.Max)
+		if question.ShowIf != nil {
+			result[index].ShowIf = make([]SurveyCondition, len(question.ShowIf))
+			for conditionIndex, condition := range question.ShowIf {
+				condition.OptionIDs = append([]string(nil), condition.OptionIDs...)
+				condition.Min = cloneFloat(condition.Min)
+				condition.Max = cloneFloat(condition.Max)
+				result[index].ShowIf[conditionIndex] = condition
+			}
+		}
 	}
 	return result
 }
@@ -747,12 +1201,22 @@ func cloneSurveyAnswers(answers []SurveyAnswer) []SurveyAnswer {
 	for index, answer := range answers {
 		result[index] = answer
 		result[index].SelectedOptionIDs = append([]string(nil), answer.SelectedOptionIDs...)
+		result[index].Value = cloneFloat(answer.Value)
 	}
 	return result
 }
 
+func cloneFloat(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
 func cloneSurveyResponse(response SurveyResponseRecord) SurveyResponseRecord {
 	response.Answers = cloneSurveyAnswers(response.Answers)
+	response.Moderation = cloneSurveyModeration(response.Moderation)
 	return response
 }
 
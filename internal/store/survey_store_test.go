@@ -1,6 +1,7 @@
 package store
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -49,11 +50,11 @@ func TestSurveyStorePersistsAnonymousResponsesAndFreezesDefinition(t *testing.T)
 		t.Fatalf("匿名答卷未正确持久化: %+v", responses)
 	}
 
-	replacement := created
-	replacement.Title = "修改后的标题"
+	replacement := cloneSurveyRecord(created)
+	replacement.Questions[0].Type = SurveyTypeMultiSelect
 	if _, err := reloaded.Update(created.Key, replacement); err == nil ||
-		!strings.Contains(err.Error(), "不能修改题目内容") {
-		t.Fatalf("已有答卷后应冻结定义，实际错误: %v", err)
+		!strings.Contains(err.Error(), "不能修改题型") {
+		t.Fatalf("已有答卷后应拒绝不兼容的修改，实际错误: %v", err)
 	}
 
 	replacement = created
@@ -61,8 +62,14 @@ func TestSurveyStorePersistsAnonymousResponsesAndFreezesDefinition(t *testing.T)
 	if _, err := reloaded.Update(created.Key, replacement); err != nil {
 		t.Fatalf("已有答卷后应允许停止发布: %v", err)
 	}
-	if err := reloaded.Delete(created.Key); err == nil {
-		t.Fatal("已有答卷的意见征集不应允许删除")
+	if err := reloaded.Delete(created.Key); !errors.Is(err, ErrSurveyHasResponses) {
+		t.Fatalf("已有答卷的意见征集不应允许删除，实际错误: %v", err)
+	}
+	if _, err := reloaded.Submit(created.Key, SurveyResponseInput{}); !errors.Is(err, ErrSurveyClosed) {
+		t.Fatalf("停止发布后提交应返回 ErrSurveyClosed，实际错误: %v", err)
+	}
+	if _, _, err := reloaded.Results("missing"); !errors.Is(err, ErrSurveyNotFound) {
+		t.Fatalf("不存在的意见征集应返回 ErrSurveyNotFound，实际错误: %v", err)
 	}
 }
 
@@ -82,11 +89,178 @@ func TestSurveyStoreRejectsInvalidAnonymousResponse(t *testing.T) {
 			SelectedOptionIDs: []string{"unknown"},
 		}},
 	})
-	if err == nil || !strings.Contains(err.Error(), "未知选项") {
+	var typed *Error
+	if !errors.As(err, &typed) || typed.Code != "survey_response_invalid" ||
+		!strings.Contains(err.Error(), "未知选项") {
 		t.Fatalf("未知选项应被拒绝，实际错误: %v", err)
 	}
 }
 
+func TestSurveyStoreValidatesTypedQuestions(t *testing.T) {
+	surveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	minimum, maximum := 0.0, 24.0
+	record := testSurveyRecord()
+	record.Questions = []SurveyQuestion{
+		{ID: "comment", Question: "还有什么建议？", Type: "text", MinLength: 2, MaxLength: 20},
+		{ID: "stars", Question: "整体满意度", Type: "rating", Scale: 5, Required: true},
+		{ID: "nps", Question: "你有多大可能推荐给朋友？", Type: "nps"},
+		{ID: "order", Question: "按重要程度排序", Type: "ranking", Options: []SurveyOption{
+			{ID: "speed", Label: "速度"},
+			{ID: "design", Label: "外观"},
+			{ID: "price", Label: "价格"},
+		}},
+		{ID: "hours", Question: "每天使用几小时？", Type: "number", Min: &minimum, Max: &maximum},
+	}
+	created, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建多题型征集失败: %v", err)
+	}
+	if created.Questions[0].Options == nil {
+		t.Fatalf("无选项的题目也应保存为空数组")
+	}
+
+	value := func(number float64) *float64 { return &number }
+	valid := []SurveyAnswer{
+		{QuestionID: "comment", Text: "  很好用  "},
+		{QuestionID: "stars", Value: value(4)},
+		{QuestionID: "nps", Value: value(0)},
+		{QuestionID: "order", SelectedOptionIDs: []string{"price", "speed", "design"}},
+		{QuestionID: "hours", Value: value(1.5)},
+	}
+	response, err := surveys.Submit(created.Key, SurveyResponseInput{Answers: valid})
+	if err != nil {
+		t.Fatalf("合法的多题型答卷应被接受: %v", err)
+	}
+	if response.Answers[0].Text != "很好用" || response.Answers[3].SelectedOptionIDs[0] != "price" {
+		t.Fatalf("答卷内容未按原顺序保存: %+v", response.Answers)
+	}
+
+	for name, answer := range map[string]SurveyAnswer{
+		"文字过短":   {QuestionID: "comment", Text: "好"},
+		"星级超出":   {QuestionID: "stars", Value: value(6)},
+		"评分非整数":  {QuestionID: "stars", Value: value(3.5)},
+		"NPS 超出": {QuestionID: "nps", Value: value(11)},
+		"排序不完整":  {QuestionID: "order", SelectedOptionIDs: []string{"price", "speed"}},
+		"排序重复":   {QuestionID: "order", SelectedOptionIDs: []string{"price", "price", "speed"}},
+		"数值超出":   {QuestionID: "hours", Value: value(25)},
+		"题型不符":   {QuestionID: "hours", Text: "三小时"},
+	} {
+		answers := []SurveyAnswer{{QuestionID: "stars", Value: value(5)}}
+		if answer.QuestionID == "stars" {
+			answers = nil
+		}
+		answers = append(answers, answer)
+		if _, err := surveys.Submit(created.Key, SurveyResponseInput{Answers: answers}); err == nil {
+			t.Fatalf("%s 的回答应被拒绝", name)
+		}
+	}
+	if _, err := surveys.Submit(created.Key, SurveyResponseInput{
+		Answers: []SurveyAnswer{{QuestionID: "comment", Text: "必填题未答"}},
+	}); err == nil || !strings.Contains(err.Error(), "必填") {
+		t.Fatalf("缺少必填评分应被拒绝，实际错误: %v", err)
+	}
+
+	for name, question := range map[string]SurveyQuestion{
+		"星级缺失":   {ID: "q", Question: "评分", Type: "rating"},
+		"文字题带选项": {ID: "q", Question: "建议", Type: "text", Options: []SurveyOption{{ID: "a", Label: "A"}}},
+		"排序仅一项":  {ID: "q", Question: "排序", Type: "ranking", Options: []SurveyOption{{ID: "a", Label: "A"}}},
+		"范围颠倒":   {ID: "q", Question: "数字", Type: "number", Min: &maximum, Max: &minimum},
+		"字数颠倒":   {ID: "q", Question: "建议", Type: "text", MinLength: 30, MaxLength: 10},
+		"评分允许其他": {ID: "q", Question: "评分", Type: "nps", AllowOther: true},
+		"未知题型":   {ID: "q", Question: "日期", Type: "date"},
+	} {
+		invalid := testSurveyRecord()
+		invalid.Questions = []SurveyQuestion{question}
+		if _, err := surveys.Create(invalid); err == nil {
+			t.Fatalf("%s 的题目定义应被拒绝", name)
+		}
+	}
+}
+
+func TestSurveyStoreEnforcesConditionalQuestions(t *testing.T) {
+	surveys, err := NewSurveyStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	low := 0.0
+	high := 6.0
+	record := testSurveyRecord()
+	record.Questions = []SurveyQuestion{
+		{ID: "device", Question: "你在哪些设备上使用？", Type: "multi_select", Required: true, Options: []SurveyOption{
+			{ID: "phone", Label: "iPhone"},
+			{ID: "watch", Label: "Apple Watch"},
+		}},
+		{ID: "watch-face", Question: "手表上最常用的功能？", Type: "text", Required: true, ShowIf: []SurveyCondition{
+			{QuestionID: "device", OptionIDs: []string{"watch"}},
+		}},
+		{ID: "nps", Question: "推荐意愿", Type: "nps"},
+		{ID: "why", Question: "哪里需要改进？", Type: "text", Required: true, ShowIf: []SurveyCondition{
+			{QuestionID: "nps", Min: &low, Max: &high},
+		}},
+	}
+	created, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建条件征集失败: %v", err)
+	}
+	public := created.Public()
+	if len(public.Questions[1].ShowIf) != 1 || public.Questions[1].ShowIf[0].OptionIDs[0] != "watch" {
+		t.Fatalf("公开定义应携带显示条件: %+v", public.Questions[1])
+	}
+
+	value := func(number float64) *float64 { return &number }
+	submit := func(answers ...SurveyAnswer) error {
+		_, err := surveys.Submit(created.Key, SurveyResponseInput{Answers: answers})
+		return err
+	}
+	if err := submit(SurveyAnswer{QuestionID: "device", SelectedOptionIDs: []string{"phone"}}); err != nil {
+		t.Fatalf("未显示的必填题不应要求作答: %v", err)
+	}
+	if err := submit(SurveyAnswer{QuestionID: "device", SelectedOptionIDs: []string{"watch"}}); err == nil ||
+		!strings.Contains(err.Error(), "必填") {
+		t.Fatalf("条件成立后必填题应要求作答，实际错误: %v", err)
+	}
+	if err := submit(
+		SurveyAnswer{QuestionID: "device", SelectedOptionIDs: []string{"phone"}},
+		SurveyAnswer{QuestionID: "watch-face", Text: "计时器"},
+	); err == nil || !strings.Contains(err.Error(), "未显示") {
+		t.Fatalf("未显示的题目不应接受回答，实际错误: %v", err)
+	}
+	if err := submit(
+		SurveyAnswer{QuestionID: "device", SelectedOptionIDs: []string{"phone"}},
+		SurveyAnswer{QuestionID: "nps", Value: value(9)},
+	); err != nil {
+		t.Fatalf("推荐者不应被要求填写改进建议: %v", err)
+	}
+	if err := submit(
+		SurveyAnswer{QuestionID: "device", SelectedOptionIDs: []string{"phone"}},
+		SurveyAnswer{QuestionID: "nps", Value: value(3)},
+	); err == nil {
+		t.Fatalf("贬损者应被要求填写改进建议")
+	}
+
+	for name, conditions := range map[string][]SurveyCondition{
+		"引用后面的题目": {{QuestionID: "later"}},
+		"引用自身":    {{QuestionID: "self"}},
+		"不存在的选项":  {{QuestionID: "device", OptionIDs: []string{"tablet"}}},
+		"文字题按选项":  {{QuestionID: "note", OptionIDs: []string{"phone"}}},
+		"选择题按数值":  {{QuestionID: "device", Min: &low}},
+	} {
+		invalid := testSurveyRecord()
+		invalid.Questions = []SurveyQuestion{
+			record.Questions[0],
+			{ID: "note", Question: "备注", Type: "text"},
+			{ID: "self", Question: "条件题", Type: "text", ShowIf: conditions},
+			{ID: "later", Question: "后续题", Type: "text"},
+		}
+		if _, err := surveys.Create(invalid); err == nil || !strings.Contains(err.Error(), "显示条件") {
+			t.Fatalf("%s 的显示条件应被拒绝，实际错误: %v", name, err)
+		}
+	}
+}
+
 func testSurveyRecord() SurveyRecord {
 	return SurveyRecord{
 		ID:          2026072401,
@@ -0,0 +1,167 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxSurveyTextThemes 是统计结果中每道题保留的主题数，按回答数从多到少。
+	maxSurveyTextThemes = 50
+	// surveyThemeSimilarity 是两段规范化文字归入同一主题所需的字符二元组 Jaccard 相似度。
+	surveyThemeSimilarity = 0.6
+)
+
+// SurveyTextTheme 是一组相似的文字回答。Text 是组内出现次数最多的原文，
+// Variants 是组内规范化后互不相同的写法数，Flagged 是其中被审核标记的回答数。
+type SurveyTextTheme struct {
+	Text     string `json:"text"`
+	Count    int    `json:"count"`
+	Variants int    `json:"variants"`
+	Flagged  int    `json:"flagged,omitempty"`
+}
+
+// surveyTextEntry 是参与聚类的一条文字回答。
+type surveyTextEntry struct {
+	text    string
+	flagged bool
+}
+
+// surveyTextGroup 是规范化后完全相同的回答。
+type surveyTextGroup struct {
+	key     string
+	count   int
+	flagged int
+	// texts 记录每种原文的出现次数，order 保留原文首次出现的顺序，用于在次数相同时挑选代表原文。
+	texts   map[string]int
+	order   []string
+	bigrams map[string]struct{}
+}
+
+// clusterSurveyTexts 在本地把文字回答聚为主题：先按规范化文字分组，再从回答最多的组开始，
+// 把与某个已有主题首组足够相似的组并入该主题。结果按回答数从多到少排列，最多保留 maxSurveyTextThemes 个。
+func clusterSurveyTexts(entries []surveyTextEntry) []SurveyTextTheme {
+	if len(entries) == 0 {
+		return nil
+	}
+	indexes := make(map[string]int)
+	groups := make([]*surveyTextGroup, 0)
+	for _, entry := range entries {
+		key := normalizeSurveyThemeText(entry.text)
+		index, exists := indexes[key]
+		if !exists {
+			index = len(groups)
+			indexes[key] = index
+			groups = append(groups, &surveyTextGroup{key: key, texts: make(map[string]int), bigrams: surveyTextBigrams(key)})
+		}
+		group := groups[index]
+		group.count++
+		if entry.flagged {
+			group.flagged++
+		}
+		if group.texts[entry.text] == 0 {
+			group.order = append(group.order, entry.text)
+		}
+		group.texts[entry.text]++
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].key < groups[j].key
+	})
+
+	type cluster struct {
+		seed  *surveyTextGroup
+		theme SurveyTextTheme
+	}
+	clusters := make([]*cluster, 0)
+	// postings 按二元组索引主题，只和至少有一个相同二元组的主题比较相似度。
+	postings := make(map[string][]int)
+	for _, group := range groups {
+		shared := make(map[int]int)
+		for bigram := range group.bigrams {
+			for _, candidate := range postings[bigram] {
+				shared[candidate]++
+			}
+		}
+		best, bestScore := -1, 0.0
+		for candidate, common := range shared {
+			seed := clusters[candidate].seed
+			score := float64(common) / float64(len(group.bigrams)+len(seed.bigrams)-common)
+			if score >= surveyThemeSimilarity && (score > bestScore || (score == bestScore && candidate < best)) {
+				best, bestScore = candidate, score
+			}
+		}
+		if best < 0 {
+			best = len(clusters)
+			clusters = append(clusters, &cluster{seed: group, theme: SurveyTextTheme{Text: group.representative()}})
+			for bigram := range group.bigrams {
+				postings[bigram] = append(postings[bigram], best)
+			}
+		}
+		theme := &clusters[best].theme
+		theme.Count += group.count
+		theme.Flagged += group.flagged
+		theme.Variants++
+	}
+
+	themes := make([]SurveyTextTheme, 0, len(clusters))
+	for _, cluster := range clusters {
+		themes = append(themes, cluster.theme)
+	}
+	sort.SliceStable(themes, func(i, j int) bool {
+		return themes[i].Count > themes[j].Count
+	})
+	if len(themes) > maxSurveyTextThemes {
+		themes = themes[:maxSurveyTextThemes]
+	}
+	return themes
+}
+
+// representative 返回组内出现次数最多的原文，次数相同时取最先出现的一条。
+func (g *surveyTextGroup) representative() string {
+	result := g.order[0]
+	for _, text := range g.order[1:] {
+		if g.texts[text] > g.texts[result] {
+			result = text
+		}
+	}
+	return result
+}
+
+// normalizeSurveyThemeText 把全角字符折叠为半角并转为小写，只保留字母和数字，
+// 使大小写、标点、空白与全半角不同的回答归为同一写法。只含标点的回答保留去掉空白后的原文。
+func normalizeSurveyThemeText(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= '！' && r <= '～':
+			r -= '！' - '!'
+		case r == '　':
+			r = ' '
+		}
+		r = unicode.ToLower(r)
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			builder.WriteRune(r)
+		}
+	}
+	if builder.Len() == 0 {
+		return strings.Join(strings.Fields(text), "")
+	}
+	return builder.String()
+}
+
+// surveyTextBigrams 返回相邻两个字符组成的二元组集合，只有一个字符时返回该字符。
+func surveyTextBigrams(text string) map[string]struct{} {
+	runes := []rune(text)
+	bigrams := make(map[string]struct{}, len(runes))
+	if len(runes) == 1 {
+		bigrams[text] = struct{}{}
+	}
+	for index := 0; index+1 < len(runes); index++ {
+		bigrams[string(runes[index:index+2])] = struct{}{}
+	}
+	return bigrams
+}
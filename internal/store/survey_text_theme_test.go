@@ -0,0 +1,76 @@
+package store
+
+import "testing"
+
+func TestClusterSurveyTextsGroupsSimilarAnswers(t *testing.T) {
+	entries := []surveyTextEntry{
+		{text: "Dark Mode!"},
+		{text: "dark mode"},
+		{text: "ＤＡＲＫ　ＭＯＤＥ"},
+		{text: "dark modes", flagged: true},
+		{text: "希望支持 iCloud 同步"},
+		{text: "希望支持iCloud同步。"},
+		{text: "？？？"},
+	}
+	themes := clusterSurveyTexts(entries)
+	if len(themes) != 3 {
+		t.Fatalf("应聚为 3 个主题: %+v", themes)
+	}
+	dark := themes[0]
+	if dark.Text != "Dark Mode!" || dark.Count != 4 || dark.Variants != 2 || dark.Flagged != 1 {
+		t.Fatalf("大小写、标点、全半角不同或相近的写法应归为同一主题: %+v", dark)
+	}
+	if sync := themes[1]; sync.Count != 2 || sync.Variants != 1 {
+		t.Fatalf("空白与标点不同的回答应视为同一写法: %+v", sync)
+	}
+	if other := themes[2]; other.Text != "？？？" || other.Count != 1 {
+		t.Fatalf("只含标点的回答应单独成组: %+v", other)
+	}
+	if clusterSurveyTexts(nil) != nil {
+		t.Fatal("没有文字回答时不应返回主题")
+	}
+}
+
+func TestSurveyModerationPersistsAndIsAggregated(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	record := testSurveyRecord()
+	record.Questions = append(record.Questions, SurveyQuestion{ID: "why", Question: "原因", Type: SurveyTypeText})
+	created, err := surveys.Create(record)
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	flagged := &SurveyModeration{Flagged: true, Reasons: []string{"包含辱骂"}}
+	for _, input := range []SurveyResponseInput{
+		{Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}}, Moderation: flagged},
+		{Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}, {QuestionID: "why", Text: "更紧凑"}}, Moderation: &SurveyModeration{}},
+		{Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"relaxed"}}, {QuestionID: "why", Text: "看着累"}}, Moderation: flagged},
+	} {
+		if _, err := surveys.Submit(created.Key, input); err != nil {
+			t.Fatalf("提交答卷失败: %v", err)
+		}
+	}
+
+	reloaded, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载意见征集存储失败: %v", err)
+	}
+	_, results, _ := reloaded.Results(created.Key)
+	if len(results) != 3 || results[2].Moderation != nil || results[0].Moderation == nil || !results[0].Moderation.Flagged {
+		t.Fatalf("只有包含文字回答的答卷应保存审核结果: %+v", results)
+	}
+	aggregate, err := reloaded.Aggregate(created.Key, SurveyResultQuery{})
+	if err != nil {
+		t.Fatalf("统计答卷失败: %v", err)
+	}
+	why := aggregate.Questions[1]
+	if aggregate.FlaggedCount != 1 || why.Answered != 2 || len(why.Themes) != 2 || why.Themes[0].Count != 1 {
+		t.Fatalf("被标记的答卷应照常统计: %+v", aggregate)
+	}
+	if texts := why.Texts; !texts[0].Flagged || texts[0].FlagReasons[0] != "包含辱骂" || texts[1].Flagged {
+		t.Fatalf("文字回答应带有审核标记: %+v", texts)
+	}
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// maxSurveyVersions 是单个意见征集最多保留的定义版本数，包括当前版本。
+const maxSurveyVersions = 20
+
+// SurveyVersion 是意见征集的一个历史定义。已有答卷时修改文字或新增选填题目、选项会生成新版本，
+// 旧版本保存在 SurveyRecord.PreviousVersions 中，答卷按 SurveyVersion 对应到它回答的定义。
+type SurveyVersion struct {
+	Version     int              `json:"version"`
+	Title       string           `json:"title"`
+	Description string           `json:"description,omitempty"`
+	Questions   []SurveyQuestion `json:"questions"`
+	// SupersededAt 是该版本被下一版本取代的时间。
+	SupersededAt time.Time `json:"superseded_at"`
+}
+
+// definitionAt 返回 version 版本的定义，版本不存在时返回 false。
+// 投放范围等其余字段在版本之间不会变化，沿用当前记录；翻译只对应当前版本的题目，历史版本不含翻译。
+func (record SurveyRecord) definitionAt(version int) (SurveyRecord, bool) {
+	if version == record.Version {
+		return record, true
+	}
+	for _, previous := range record.PreviousVersions {
+		if previous.Version == version {
+			record.Title = previous.Title
+			record.Description = previous.Description
+			record.Questions = previous.Questions
+			record.PreviousVersions = nil
+			record.Localizations = nil
+			record.Version = version
+			return record, true
+		}
+	}
+	return SurveyRecord{}, false
+}
+
+// snapshot 把当前定义保存为在 now 被取代的历史版本。
+func (record SurveyRecord) snapshot(now time.Time) SurveyVersion {
+	return SurveyVersion{
+		Version:      record.Version,
+		Title:        record.Title,
+		Description:  record.Description,
+		Questions:    cloneSurveyQuestions(record.Questions),
+		SupersededAt: now,
+	}
+}
+
+// validateSurveyVersions 检查历史版本从 1 开始连续编号，并且每个版本都是有效定义。
+func validateSurveyVersions(record SurveyRecord) error {
+	if record.Version < 1 || record.Version > maxSurveyVersions {
+		return fmt.Errorf("版本号必须在 1 到 %d 之间", maxSurveyVersions)
+	}
+	if len(record.PreviousVersions) != record.Version-1 {
+		return fmt.Errorf("历史版本数量与版本号不一致")
+	}
+	for index, previous := range record.PreviousVersions {
+		if previous.Version != index+1 {
+			return fmt.Errorf("历史版本必须从 1 开始连续编号")
+		}
+		definition, _ := record.definitionAt(previous.Version)
+		if err := validateSurveyRecord(definition); err != nil {
+			return fmt.Errorf("第 %d 版%v", previous.Version, err)
+		}
+	}
+	return nil
+}
+
+// compatibleSurveyEdit 判断已有答卷时能否把 current 修改为 replacement：旧答卷在新定义下必须仍然有效，
+// 并且统计口径不变。允许修改标题、说明、题目和选项文字，新增选填题目、选择题选项，以及放宽必填和字数、取值范围；
+// 返回的错误说明第一处不兼容的修改。
+func compatibleSurveyEdit(current, replacement SurveyRecord) error {
+	if current.ID != replacement.ID {
+		return fmt.Errorf("不能修改编号")
+	}
+	if current.MinBuild != replacement.MinBuild || current.MaxBuild != replacement.MaxBuild ||
+		current.Platform != replacement.Platform {
+		return fmt.Errorf("不能修改构建号、语言或平台范围")
+	}
+	// 包含翻译的征集面向所有语言，此时 language 只是默认语言，未限定语言的征集可以指定默认语言并添加翻译。
+	if current.Language != replacement.Language && !(current.Language == "" && len(replacement.Localizations) > 0) {
+		return fmt.Errorf("不能修改构建号、语言或平台范围")
+	}
+
+	position := 0
+	for _, question := range replacement.Questions {
+		if position < len(current.Questions) && current.Questions[position].ID == question.ID {
+			if err := compatibleSurveyQuestion(current.Questions[position], question); err != nil {
+				return fmt.Errorf("题目 %s %v", question.ID, err)
+			}
+			position++
+			continue
+		}
+		if slices.ContainsFunc(current.Questions, func(existing SurveyQuestion) bool {
+			return existing.ID == question.ID
+		}) {
+			return fmt.Errorf("不能调整已有题目的顺序: %s", question.ID)
+		}
+		if question.Required {
+			return fmt.Errorf("新增题目 %s 不能设为必填", question.ID)
+		}
+	}
+	if position < len(current.Questions) {
+		return fmt.Errorf("不能删除题目: %s", current.Questions[position].ID)
+	}
+	return nil
+}
+
+// compatibleSurveyQuestion 比较同一道题修改前后的设置，返回的错误接在题目 ID 之后。
+func compatibleSurveyQuestion(current, replacement SurveyQuestion) error {
+	if current.Type != replacement.Type {
+		return fmt.Errorf("不能修改题型")
+	}
+	if replacement.Required && !current.Required {
+		return fmt.Errorf("不能改为必填")
+	}
+	if current.AllowOther && !replacement.AllowOther {
+		return fmt.Errorf("不能取消自定义回答")
+	}
+	if current.Scale != replacement.Scale {
+		return fmt.Errorf("不能修改星级")
+	}
+	if replacement.MinLength > current.MinLength || surveyTextMaxLength(replacement) < surveyTextMaxLength(current) {
+		return fmt.Errorf("不能收紧字数限制")
+	}
+	if !surveyBoundWidened(current.Min, replacement.Min, false) || !surveyBoundWidened(current.Max, replacement.Max, true) {
+		return fmt.Errorf("不能收紧取值范围")
+	}
+	if !sameSurveyConditions(current.ShowIf, replacement.ShowIf) {
+		return fmt.Errorf("不能修改显示条件")
+	}
+	for _, option := range current.Options {
+		if !slices.ContainsFunc(replacement.Options, func(candidate SurveyOption) bool {
+			return candidate.ID == option.ID
+		}) {
+			return fmt.Errorf("不能删除选项: %s", option.ID)
+		}
+	}
+	// 排序题要求为全部选项排序，新增选项会让旧答卷不完整。
+	if current.Type == SurveyTypeRanking && len(replacement.Options) != len(current.Options) {
+		return fmt.Errorf("不能为排序题新增选项")
+	}
+	return nil
+}
+
+// surveyBoundWidened 判断取值范围的一端是否只放宽或保持不变，upper 表示上限。
+func surveyBoundWidened(current, replacement *float64, upper bool) bool {
+	switch {
+	case replacement == nil:
+		return true
+	case current == nil:
+		return false
+	case upper:
+		return *replacement >= *current
+	default:
+		return *replacement <= *current
+	}
+}
+
+func sameSurveyConditions(left, right []SurveyCondition) bool {
+	return slices.EqualFunc(left, right, func(a, b SurveyCondition) bool {
+		return a.QuestionID == b.QuestionID &&
+			slices.Equal(a.OptionIDs, b.OptionIDs) &&
+			sameFloat(a.Min, b.Min) &&
+			sameFloat(a.Max, b.Max)
+	})
+}
+
+func sameFloat(left, right *float64) bool {
+	return (left == nil && right == nil) || (left != nil && right != nil && *left == *right)
+}
+
+// sameSurveyVersion 判断两条记录的 version 版本是否都存在且定义相同，
+// 相同时该版本的答卷可以在两条记录之间对应。
+func sameSurveyVersion(left, right SurveyRecord, version int) bool {
+	leftDefinition, leftFound := left.definitionAt(version)
+	rightDefinition, rightFound := right.definitionAt(version)
+	return leftFound && rightFound && sameSurveyDefinition(leftDefinition, rightDefinition)
+}
+
+// surveyHistoryPreserved 判断 replacement 是否保留了 current 的全部版本，
+// 保留时 current 已有的答卷仍能对应到原来回答的定义。
+func surveyHistoryPreserved(current, replacement SurveyRecord) bool {
+	for version := 1; version <= current.Version; version++ {
+		if !sameSurveyVersion(current, replacement, version) {
+			return false
+		}
+	}
+	return true
+}
+
+// surveyVersionQuestions 按版本号索引每个版本包含的题目，用于区分答卷未作答与作答时还没有这道题。
+func surveyVersionQuestions(record SurveyRecord) map[int]map[string]SurveyQuestion {
+	index := make(map[int]map[string]SurveyQuestion, record.Version)
+	for version := 1; version <= record.Version; version++ {
+		definition, found := record.definitionAt(version)
+		if !found {
+			continue
+		}
+		questions := make(map[string]SurveyQuestion, len(definition.Questions))
+		for _, question := range definition.Questions {
+			questions[question.ID] = question
+		}
+		index[version] = questions
+	}
+	return index
+}
+
+func cloneSurveyVersions(versions []SurveyVersion) []SurveyVersion {
+	if versions == nil {
+		return nil
+	}
+	result := make([]SurveyVersion, len(versions))
+	for index, version := range versions {
+		result[index] = version
+		result[index].Questions = cloneSurveyQuestions(version.Questions)
+	}
+	return result
+}
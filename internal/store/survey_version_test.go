@@ -0,0 +1,108 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSurveyCompatibleEditCreatesVersion(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	created, err := surveys.Create(testSurveyRecord())
+	if err != nil || created.Version != 1 {
+		t.Fatalf("新建意见征集应为第 1 版: version=%d err=%v", created.Version, err)
+	}
+
+	// 没有答卷时直接修改当前版本。
+	draft := cloneSurveyRecord(created)
+	draft.Title = "界面方案调查"
+	if updated, err := surveys.Update(created.Key, draft); err != nil || updated.Version != 1 || updated.PreviousVersions != nil {
+		t.Fatalf("没有答卷时不应生成新版本: %+v err=%v", updated, err)
+	}
+	if _, err := surveys.Submit(created.Key, SurveyResponseInput{
+		Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"compact"}}},
+	}); err != nil {
+		t.Fatalf("保存第 1 版答卷失败: %v", err)
+	}
+
+	for name, edit := range map[string]func(record *SurveyRecord){
+		"不能修改题型": func(record *SurveyRecord) { record.Questions[0].Type = SurveyTypeMultiSelect },
+		"不能删除选项": func(record *SurveyRecord) { record.Questions[0].Options = record.Questions[0].Options[:1] },
+		"不能设为必填": func(record *SurveyRecord) {
+			record.Questions = append(record.Questions, SurveyQuestion{ID: "why", Question: "原因", Type: SurveyTypeText, Required: true})
+		},
+		"不能修改构建号、语言或平台范围": func(record *SurveyRecord) { record.Platform = "watchOS" },
+	} {
+		replacement := cloneSurveyRecord(created)
+		edit(&replacement)
+		if _, err := surveys.Update(created.Key, replacement); err == nil || !strings.Contains(err.Error(), name) {
+			t.Fatalf("不兼容的修改应被拒绝并说明原因 %q，实际错误: %v", name, err)
+		}
+	}
+
+	replacement := cloneSurveyRecord(created)
+	replacement.Title = "界面方案调查（修订）"
+	replacement.Questions[0].Question = "你更喜欢哪种列表布局？"
+	replacement.Questions[0].Options = append(replacement.Questions[0].Options, SurveyOption{ID: "cards", Label: "卡片布局"})
+	replacement.Questions = append(replacement.Questions, SurveyQuestion{ID: "why", Question: "原因", Type: SurveyTypeText})
+	updated, err := surveys.Update(created.Key, replacement)
+	if err != nil {
+		t.Fatalf("兼容的修改应生成新版本: %v", err)
+	}
+	if updated.Version != 2 || len(updated.PreviousVersions) != 1 ||
+		updated.PreviousVersions[0].Title != "界面方案调查" || len(updated.PreviousVersions[0].Questions[0].Options) != 2 {
+		t.Fatalf("新版本或历史版本不正确: %+v", updated)
+	}
+	if public := updated.Public(); public.Version != 2 {
+		t.Fatalf("公开定义应携带版本号: %d", public.Version)
+	}
+
+	// 缓存了旧定义的客户端按旧版本提交，旧版本中没有的题目和选项不能作答。
+	if _, err := surveys.Submit(created.Key, SurveyResponseInput{
+		SurveyVersion: 1,
+		Answers:       []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"cards"}}},
+	}); err == nil {
+		t.Fatal("按旧版本提交时不应接受后来新增的选项")
+	}
+	if _, err := surveys.Submit(created.Key, SurveyResponseInput{SurveyVersion: 3}); err == nil {
+		t.Fatal("不存在的版本应被拒绝")
+	}
+	old, err := surveys.Submit(created.Key, SurveyResponseInput{
+		SurveyVersion: 1,
+		Answers:       []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"relaxed"}}},
+	})
+	if err != nil || old.SurveyVersion != 1 {
+		t.Fatalf("按旧版本提交失败: %+v err=%v", old, err)
+	}
+	current, err := surveys.Submit(created.Key, SurveyResponseInput{
+		Answers: []SurveyAnswer{{QuestionID: "design", SelectedOptionIDs: []string{"cards"}}, {QuestionID: "why", Text: "更直观"}},
+	})
+	if err != nil || current.SurveyVersion != 2 {
+		t.Fatalf("缺省应按当前版本提交: %+v err=%v", current, err)
+	}
+
+	reloaded, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载多版本意见征集失败: %v", err)
+	}
+	aggregate, err := reloaded.Aggregate(created.Key, SurveyResultQuery{})
+	if err != nil {
+		t.Fatalf("统计多版本答卷失败: %v", err)
+	}
+	if len(aggregate.SurveyVersions) != 2 || aggregate.SurveyVersions[0].Count != 2 || aggregate.SurveyVersions[1].Count != 1 {
+		t.Fatalf("各版本答卷数不正确: %+v", aggregate.SurveyVersions)
+	}
+	design, why := aggregate.Questions[0], aggregate.Questions[1]
+	if why.NotAsked != 2 || why.Answered != 1 || why.Skipped != 0 {
+		t.Fatalf("后来新增的题目不应把旧版本答卷计为跳过: %+v", why)
+	}
+	if cards := design.Options[2]; cards.Count != 1 || cards.NotOffered != 2 || cards.Percent != 100 {
+		t.Fatalf("后来新增的选项应以提供了它的答卷为分母: %+v", cards)
+	}
+	if filtered, _ := reloaded.Aggregate(created.Key, SurveyResultQuery{SurveyVersion: 1}); filtered.ResponseCount != 2 {
+		t.Fatalf("按版本筛选不正确: %d", filtered.ResponseCount)
+	}
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// maxSurveyChanges 是意见征集保留的增量同步记录数；更早的 since 会回退为完整列表。
+const maxSurveyChanges = 1000
+
+// AnnouncementDelta 是公告增量同步的结果。公开公告不含 key，按编号同步：
+// Full 为 true 时 Records 是完整列表，客户端应整体替换；否则客户端先删除 Removed 与 Records
+// 中出现的编号的全部本地条目，再加入 Records。
+type AnnouncementDelta struct {
+	Revision int64                `json:"revision"`
+	Full     bool                 `json:"full"`
+	Records  []PublicAnnouncement `json:"records"`
+	Removed  []int                `json:"removed"`
+}
+
+// SurveyDelta 是意见征集增量同步的结果，语义与 AnnouncementDelta 相同，但按 key 同步。
+type SurveyDelta struct {
+	Revision int64          `json:"revision"`
+	Full     bool           `json:"full"`
+	Records  []PublicSurvey `json:"records"`
+	Removed  []string       `json:"removed"`
+}
+
+// SurveyChange 记录一次意见征集定义变更涉及的 key 与变更时间。
+type SurveyChange struct {
+	Revision int64     `json:"revision"`
+	Key      string    `json:"key"`
+	At       time.Time `json:"at"`
+}
+
+// PublicSyncAt 返回 now 时刻 audience 可见的公告相对 since 修订的变化，以及下一次定时变化的时间。
+// 公告沿用修订历史的编号作为同步修订号；since 为 0、晚于当前修订或早于保留的修订时返回完整列表。
+// 变化以公告编号为单位，同一编号的语言版本一起下发，客户端的语言择优结果才能随之更新；
+// 自 since 修订之后到达定时上线或下线时间的公告也计入变化。
+func (s *AnnouncementStore) PublicSyncAt(
+	now time.Time,
+	audience AnnouncementAudience,
+	since int64,
+) (AnnouncementDelta, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records, next := s.selectLocked(now, audience, false)
+	delta := AnnouncementDelta{Revision: s.nextRevision, Records: records, Removed: []int{}}
+	ids, from, ok := s.changedSinceLocked(since)
+	if !ok {
+		delta.Full = true
+		return delta, next
+	}
+
+	for _, record := range s.records {
+		if transitionedBetween(from, now, record.PublishAt, record.ExpireAt) {
+			ids[record.ID] = true
+		}
+	}
+	delta.Records = make([]PublicAnnouncement, 0)
+	visible := map[int]bool{}
+	for _, record := range records {
+		if ids[record.ID] {
+			delta.Records = append(delta.Records, record)
+			visible[record.ID] = true
+		}
+	}
+	for id := range ids {
+		if !visible[id] {
+			delta.Removed = append(delta.Removed, id)
+		}
+	}
+	sort.Ints(delta.Removed)
+	return delta, next
+}
+
+// changedSinceLocked 收集 since 之后的修订涉及的公告编号（含修改前的编号），from 为 since 修订的时间。
+func (s *AnnouncementStore) changedSinceLocked(since int64) (ids map[int]bool, from time.Time, ok bool) {
+	if since <= 0 || since > s.nextRevision {
+		return nil, time.Time{}, false
+	}
+	ids = map[int]bool{}
+	for _, revision := range s.revisions {
+		if revision.ID < since {
+			continue
+		}
+		if revision.ID == since {
+			from, ok = revision.CreatedAt, true
+			continue
+		}
+		ids[revision.Record.ID] = true
+		for _, change := range revision.Changes {
+			var previousID int
+			if change.Field == "id" && json.Unmarshal(change.Before, &previousID) == nil {
+				ids[previousID] = true
+			}
+		}
+	}
+	return ids, from, ok
+}
+
+// PublicSync 返回当前正在征集的意见征集相对 since 修订的变化，文字使用默认语言。
+func (s *SurveyStore) PublicSync(since int64) SurveyDelta {
+	delta, _ := s.PublicSyncAt(time.Now(), "", since)
+	return delta
+}
+
+// PublicSyncAt 返回 now 时刻正在征集的意见征集相对 since 修订的变化，以及下一次定时开始或截止的时间；
+// since 无法增量计算时返回完整列表。自 since 修订之后到达开始或截止时间的征集也计入变化。
+// 包含翻译的征集按 locale 选出语言；修改翻译同样记录修订，客户端切换语言后应重新拉取完整列表。
+func (s *SurveyStore) PublicSyncAt(now time.Time, locale string, since int64) (SurveyDelta, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records, next := s.publicListLocked(now, locale)
+	delta := SurveyDelta{Revision: s.revision, Records: records, Removed: []string{}}
+	// 最早一条记录所在的修订可能已被部分裁剪，只有之后的修订是完整的。
+	oldest := s.revision
+	if len(s.changes) > 0 {
+		oldest = s.changes[0].Revision
+	}
+	if since <= 0 || since > s.revision || since < oldest {
+		delta.Full = true
+		return delta, next
+	}
+
+	// 旧文件中的变更没有时间，此时 from 为零值，所有定时变化都会计入，结果仍然正确。
+	keys := map[string]bool{}
+	var from time.Time
+	for _, change := range s.changes {
+		if change.Revision == since {
+			from = change.At
+		}
+		if change.Revision > since {
+			keys[change.Key] = true
+		}
+	}
+	for _, record := range s.records {
+		if transitionedBetween(from, now, record.OpensAt, record.ClosesAt) {
+			keys[record.Key] = true
+		}
+	}
+	delta.Records = make([]PublicSurvey, 0)
+	visible := map[string]bool{}
+	for _, record := range records {
+		if keys[record.Key] {
+			delta.Records = append(delta.Records, record)
+			visible[record.Key] = true
+		}
+	}
+	for key := range keys {
+		if !visible[key] {
+			delta.Removed = append(delta.Removed, key)
+		}
+	}
+	sort.Strings(delta.Removed)
+	return delta, next
+}
+
+// commitDefinitionsLocked 为 keys 记录一次同步修订并保存意见征集定义；
+// 写入失败时撤回修订，调用方负责回滚内存中的征集列表。
+func (s *SurveyStore) commitDefinitionsLocked(keys ...string) error {
+	previousRevision, previousChanges := s.revision, s.changes
+	if len(keys) > 0 {
+		s.revision++
+		s.changes = append([]SurveyChange(nil), s.changes...)
+		now := time.Now().UTC()
+		for _, key := range keys {
+			s.changes = append(s.changes, SurveyChange{Revision: s.revision, Key: key, At: now})
+		}
+		if len(s.changes) > maxSurveyChanges {
+			s.changes = s.changes[len(s.changes)-maxSurveyChanges:]
+		}
+	}
+	if err := s.saveDefinitionsLocked(); err != nil {
+		s.revision, s.changes = previousRevision, previousChanges
+		return err
+	}
+	return nil
+}
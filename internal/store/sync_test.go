@@ -0,0 +1,123 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnnouncementSyncReturnsChangedIDs(t *testing.T) {
+	announcements, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	chinese, err := announcements.Create(AnnouncementRecord{
+		ID: 1, Type: "info", Language: "zh-Hans", Title: "中文", Body: "正文", Enabled: true,
+	}, "cli")
+	if err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+	if _, err := announcements.Create(AnnouncementRecord{
+		ID: 2, Type: "info", Title: "保持不变", Body: "正文", Enabled: true,
+	}, "cli"); err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+
+	now := time.Now()
+	english, _ := ParseAnnouncementAudience("", "", "en", "")
+	full, _ := announcements.PublicSyncAt(now, english, 0)
+	if !full.Full || full.Revision != 2 || len(full.Records) != 2 {
+		t.Fatalf("since 为 0 时应返回完整列表: %+v", full)
+	}
+	if unchanged, _ := announcements.PublicSyncAt(now, english, full.Revision); unchanged.Full ||
+		len(unchanged.Records) != 0 || len(unchanged.Removed) != 0 {
+		t.Fatalf("没有变化时增量应为空: %+v", unchanged)
+	}
+
+	// 新增同编号的英文版本会改变英文客户端的语言择优结果，整组编号一起下发。
+	englishRecord, err := announcements.Create(AnnouncementRecord{
+		ID: 1, Type: "info", Language: "en", Title: "English", Body: "Body", Enabled: true,
+	}, "cli")
+	if err != nil {
+		t.Fatalf("创建英文公告失败: %v", err)
+	}
+	delta, _ := announcements.PublicSyncAt(now, english, full.Revision)
+	if delta.Full || delta.Revision != 3 || len(delta.Records) != 1 || delta.Records[0].Title != "English" {
+		t.Fatalf("增量应只包含编号 1 的英文版本: %+v", delta)
+	}
+
+	chinese.Enabled = false
+	if _, err := announcements.Update(chinese.Key, chinese, "cli"); err != nil {
+		t.Fatalf("停用公告失败: %v", err)
+	}
+	if err := announcements.Delete(englishRecord.Key, "cli"); err != nil {
+		t.Fatalf("删除公告失败: %v", err)
+	}
+	removed, _ := announcements.PublicSyncAt(now, english, delta.Revision)
+	if len(removed.Records) != 0 || len(removed.Removed) != 1 || removed.Removed[0] != 1 {
+		t.Fatalf("整组停用后应返回移除的编号: %+v", removed)
+	}
+
+	if stale, _ := announcements.PublicSyncAt(now, english, 99); !stale.Full {
+		t.Fatalf("晚于当前修订的 since 应回退为完整列表: %+v", stale)
+	}
+}
+
+func TestAnnouncementSyncIncludesScheduledTransitions(t *testing.T) {
+	announcements, err := NewAnnouncementStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化公告存储失败: %v", err)
+	}
+	publishAt := time.Now().Add(time.Hour).UTC()
+	if _, err := announcements.Create(AnnouncementRecord{
+		ID: 5, Type: "info", Title: "定时公告", Body: "正文", PublishAt: &publishAt, Enabled: true,
+	}, "cli"); err != nil {
+		t.Fatalf("创建公告失败: %v", err)
+	}
+
+	before, _ := announcements.PublicSyncAt(time.Now(), AnnouncementAudience{}, 0)
+	if len(before.Records) != 0 {
+		t.Fatalf("上线前不应公开: %+v", before)
+	}
+	after, _ := announcements.PublicSyncAt(publishAt.Add(time.Minute), AnnouncementAudience{}, before.Revision)
+	if after.Full || len(after.Records) != 1 || after.Records[0].ID != 5 {
+		t.Fatalf("到达上线时间后增量应包含该公告: %+v", after)
+	}
+}
+
+func TestSurveySyncTracksDefinitionChanges(t *testing.T) {
+	dataDir := t.TempDir()
+	surveys, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("初始化意见征集存储失败: %v", err)
+	}
+	first, err := surveys.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	full := surveys.PublicSync(0)
+	if !full.Full || full.Revision != 1 || len(full.Records) != 1 {
+		t.Fatalf("since 为 0 时应返回完整列表: %+v", full)
+	}
+
+	second, err := surveys.Create(testSurveyRecord())
+	if err != nil {
+		t.Fatalf("创建意见征集失败: %v", err)
+	}
+	first.Enabled = false
+	if _, err := surveys.Update(first.Key, first); err != nil {
+		t.Fatalf("停用意见征集失败: %v", err)
+	}
+	delta := surveys.PublicSync(full.Revision)
+	if delta.Full || delta.Revision != 3 || len(delta.Records) != 1 || delta.Records[0].Key != second.Key ||
+		len(delta.Removed) != 1 || delta.Removed[0] != first.Key {
+		t.Fatalf("增量应包含新增与停用的征集: %+v", delta)
+	}
+
+	reloaded, err := NewSurveyStore(dataDir)
+	if err != nil {
+		t.Fatalf("重新加载意见征集失败: %v", err)
+	}
+	if persisted := reloaded.PublicSync(full.Revision); persisted.Full || persisted.Revision != 3 {
+		t.Fatalf("同步修订应持久化: %+v", persisted)
+	}
+}